@@ -0,0 +1,168 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Dir is where approved and received files are stored, relative to the
+//package under test
+var Dir = "testdata"
+
+//MergeTool is the command run with the received and approved files as its
+//last two arguments when they differ, e.g. "meld" or "code --wait --diff".
+//It defaults to $APPROVAL_MERGE_TOOL, an empty command disables it.
+var MergeTool = os.Getenv("APPROVAL_MERGE_TOOL")
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Name() string
+	Errorf(format string, args ...interface{})
+}
+
+// call identifies a test, by its t too when it can be a map key since a
+// rerun with -count has the same name
+type call struct {
+	t    interface{}
+	name string
+}
+
+var (
+	mu    sync.Mutex
+	calls = make(map[call]int)
+)
+
+//Verify compares got against Dir/<test name>.approved.txt and on mismatch
+//writes it to Dir/<test name>.received.txt, fails with a diff and starts
+//MergeTool if set. Approving the output is renaming the received file to the
+//approved one. A stale received file is removed when got matches. Strings
+//and []byte are stored as is, other values as indented JSON, and line
+//endings are normalized to \n before comparing. Later calls in a test use
+//<test name>_2 and so on.
+func Verify(t TestingT, got interface{}) bool {
+	t.Helper()
+	approved, received := paths(t)
+
+	act, err := serialize(got)
+	if err != nil {
+		t.Errorf("serialize %s: %v", received, err)
+		return false
+	}
+
+	var exp string
+	bs, err := ioutil.ReadFile(approved)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		t.Errorf("read %s: %v", approved, err)
+		return false
+	default:
+		exp = normalize(string(bs))
+	}
+
+	if err == nil && exp == act {
+		os.Remove(received)
+		return true
+	}
+
+	if err := write(received, act); err != nil {
+		t.Errorf("write %s: %v", received, err)
+		return false
+	}
+	msg := fmt.Sprintf("received output does not match %s, approve it with:\n\tmv %s %s", approved, received, approved)
+	if os.IsNotExist(err) {
+		msg = fmt.Sprintf("%s does not exist, approve the received output with:\n\tmv %s %s", approved, received, approved)
+	}
+	if err := merge(received, approved); err != nil {
+		msg += fmt.Sprintf("\nmerge tool %q failed: %v", MergeTool, err)
+	}
+	t.Errorf("%s\n%s", msg, tools.Diff(exp, act, tools.WithMode(tools.LineMode), tools.WithLabels(approved, received)))
+	return false
+}
+
+// count numbers the calls in test t, starting over when t has a Cleanup
+// method and ends
+func count(t TestingT) int {
+	k := call{name: t.Name()}
+	c, ok := t.(interface{ Cleanup(func()) })
+	if ok && reflect.TypeOf(t).Comparable() {
+		k.t = t
+	}
+	mu.Lock()
+	calls[k]++
+	n := calls[k]
+	mu.Unlock()
+	if n == 1 && ok {
+		c.Cleanup(func() {
+			mu.Lock()
+			delete(calls, k)
+			mu.Unlock()
+		})
+	}
+	return n
+}
+
+// paths returns the approved and received paths for the next Verify call in
+// test t
+func paths(t TestingT) (approved, received string) {
+	name := t.Name()
+	n := count(t)
+
+	file := filepath.FromSlash(name)
+	if n > 1 {
+		file = fmt.Sprintf("%s_%d", file, n)
+	}
+	base := filepath.Join(Dir, file)
+	return base + ".approved.txt", base + ".received.txt"
+}
+
+// merge starts MergeTool without waiting for it
+func merge(received, approved string) error {
+	args := strings.Fields(MergeTool)
+	if len(args) == 0 {
+		return nil
+	}
+	cmd := exec.Command(args[0], append(args[1:], received, approved)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func serialize(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return normalize(s), nil
+	case []byte:
+		return normalize(string(s)), nil
+	}
+
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(bs) + "\n", nil
+}
+
+func normalize(s string) string {
+	return strings.Replace(s, "\r\n", "\n", -1)
+}
+
+func write(path, data string) error {
+	err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777))
+	if err != nil {
+		return fmt.Errorf("make dir failed: %v", err)
+	}
+	return ioutil.WriteFile(path, []byte(data), os.FileMode(0666))
+}
@@ -0,0 +1,77 @@
+package approval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	orig := Dir
+	Dir = fs.Dir(t, fs.Tree{"TestReport/approved.approved.txt": "total: 3\r\nitems: a b c\r\n"})
+	defer func() { Dir = orig }()
+
+	verify := func(name string, got interface{}) (bool, *tools.TestResults) {
+		m := tools.Mock()
+		m.SetName(name)
+		ok := Verify(m, got)
+		return ok, m.Results()
+	}
+	received := filepath.Join(Dir, "TestReport", "approved.received.txt")
+	approved := filepath.Join(Dir, "TestReport", "approved.approved.txt")
+
+	ok, _ := verify("TestReport/approved", "total: 3\nitems: a b c\n")
+	assert.True(t, ok)
+
+	// a mismatch writes the received file next to the approved one
+	ok, res := verify("TestReport/approved", "total: 4\nitems: a b c d\n")
+	assert.False(t, ok)
+	assert.Contains(t, res.Err, "received output does not match "+approved+", approve it with:\n\tmv "+received+" "+approved+"\n")
+	assert.Contains(t, res.Err, "-total: 3\n")
+	assert.Contains(t, res.Err, "+total: 4\n")
+	bs, err := ioutil.ReadFile(received)
+	assert.NoError(t, err)
+	assert.Equal(t, "total: 4\nitems: a b c d\n", string(bs))
+
+	// a match removes the stale received file
+	ok, _ = verify("TestReport/approved", "total: 3\nitems: a b c\n")
+	assert.True(t, ok)
+	_, err = os.Stat(received)
+	assert.True(t, os.IsNotExist(err))
+
+	// a missing approved file fails with everything received
+	ok, res = verify("TestReport/new", map[string]int{"total": 1})
+	assert.False(t, ok)
+	assert.Contains(t, res.Err, filepath.Join(Dir, "TestReport", "new.approved.txt")+" does not exist")
+	bs, err = ioutil.ReadFile(filepath.Join(Dir, "TestReport", "new.received.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"total\": 1\n}\n", string(bs))
+
+	// approving is a rename
+	assert.NoError(t, os.Rename(filepath.Join(Dir, "TestReport", "new.received.txt"), filepath.Join(Dir, "TestReport", "new.approved.txt")))
+	ok, _ = verify("TestReport/new", map[string]int{"total": 1})
+	assert.True(t, ok)
+	assert.Empty(t, calls, "calls are reset when the test ends")
+}
+
+func TestVerifyMergeTool(t *testing.T) {
+	orig, origTool := Dir, MergeTool
+	Dir = fs.Dir(t, nil)
+	defer func() { Dir, MergeTool = orig, origTool }()
+
+	m := tools.Mock()
+	m.SetName("TestMerge")
+	MergeTool = "true --ignored"
+	Verify(m, "a\n")
+	MergeTool = "loupe-missing-merge-tool"
+	Verify(m, "b\n")
+	res := m.Results()
+	assert.Contains(t, res.Err, `merge tool "loupe-missing-merge-tool" failed: `)
+	assert.Equal(t, 1, strings.Count(res.Err, "merge tool"))
+}
@@ -0,0 +1,217 @@
+package assert
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//Equal verifies want and got have deep equal underlying values and fails
+//the test with a colored diff if not. Optional msgAndArgs are a format
+//string and its arguments, Msg messages and Ctx contexts.
+func Equal(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if tools.DeepEqual(want, got) {
+		return true
+	}
+	failValues(t, want, got, diff(want, got), fmt.Sprintf("Not Equal (%T/%T)", want, got), msgAndArgs)
+	return false
+}
+
+//RequireEqual is like Equal but stops the test on failure
+func RequireEqual(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if !Equal(t, want, got, msgAndArgs...) {
+		t.FailNow()
+		return false
+	}
+	return true
+}
+
+//EqualJSON verifies want and got are semantically equal JSON documents,
+//ignoring key order and whitespace. want and got can be strings or []byte.
+func EqualJSON(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return compareJSON(t, want, got, "JSON Not Equal", msgAndArgs)
+}
+
+//RequireEqualJSON is like EqualJSON but stops the test on failure
+func RequireEqualJSON(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if !EqualJSON(t, want, got, msgAndArgs...) {
+		t.FailNow()
+		return false
+	}
+	return true
+}
+
+//MatchJSONSubset verifies got contains every field of want with the same
+//value, ignoring fields only in got, like EqualJSON otherwise. The diff only
+//shows the violated expectations. Arrays still have to have the same length.
+func MatchJSONSubset(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return compareJSON(t, want, got, "JSON Subset Not Matched", msgAndArgs, tools.IgnoreExtraKeys())
+}
+
+//RequireMatchJSONSubset is like MatchJSONSubset but stops the test on
+//failure
+func RequireMatchJSONSubset(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if !MatchJSONSubset(t, want, got, msgAndArgs...) {
+		t.FailNow()
+		return false
+	}
+	return true
+}
+
+func compareJSON(t TestingT, want, got interface{}, header string, msgAndArgs []interface{}, opts ...tools.Option) bool {
+	t.Helper()
+	a, err := jsonBytes(want)
+	if err != nil {
+		fail(t, nil, fmt.Sprintf("Invalid want JSON: %v", err), msgAndArgs)
+		return false
+	}
+	b, err := jsonBytes(got)
+	if err != nil {
+		fail(t, nil, fmt.Sprintf("Invalid got JSON: %v", err), msgAndArgs)
+		return false
+	}
+
+	d := tools.DiffJSON(a, b, opts...)
+	if d.Equal() {
+		return true
+	}
+	failValues(t, a, b, d, header, msgAndArgs)
+	return false
+}
+
+//Similar verifies got is at least min similar to want, from 0 to 1, as
+//measured by tools.Similarity, and fails with a diff if not. Use it for
+//fuzzy output like transcripts where exact equality is too strict.
+func Similar(t TestingT, want, got string, min float64, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	sim := tools.Similarity(want, got)
+	if sim >= min {
+		return true
+	}
+	failValues(t, want, got, tools.Diff(want, got), fmt.Sprintf("Not Similar: %.2f%% similar, expected at least %.2f%%", 100*sim, 100*min), msgAndArgs)
+	return false
+}
+
+//InDelta verifies got is within delta of want. Numbers of any type are
+//compared as float64, structs, maps and slices are compared field by field
+//and the diff only shows the numbers that are out of tolerance.
+func InDelta(t TestingT, want, got interface{}, delta float64, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return inTolerance(t, want, got, tools.WithTolerance(delta, 0), fmt.Sprintf("Not InDelta: difference exceeds %g", delta), msgAndArgs)
+}
+
+//InEpsilon verifies got is within epsilon of want relative to want, e.g.
+//0.01 for 1%. Structs, maps and slices are compared like InDelta.
+func InEpsilon(t TestingT, want, got interface{}, epsilon float64, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return inTolerance(t, want, got, tools.WithTolerance(0, epsilon), fmt.Sprintf("Not InEpsilon: relative difference exceeds %g", epsilon), msgAndArgs)
+}
+
+func inTolerance(t TestingT, want, got interface{}, tol tools.Option, header string, msgAndArgs []interface{}) bool {
+	t.Helper()
+	a := reflect.ValueOf(tools.Value(want))
+	b := reflect.ValueOf(tools.Value(got))
+	if isNumber(a) && isNumber(b) {
+		// compare mixed number types as float64
+		a = a.Convert(reflect.TypeOf(float64(0)))
+		b = b.Convert(reflect.TypeOf(float64(0)))
+	}
+
+	var w, g interface{}
+	if a.IsValid() {
+		w = a.Interface()
+	}
+	if b.IsValid() {
+		g = b.Interface()
+	}
+	d := tools.DiffValues(w, g, tol)
+	if d.Equal() {
+		return true
+	}
+	failValues(t, w, g, d, header, msgAndArgs)
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// diff picks a text diff for strings and a structural diff otherwise
+func diff(want, got interface{}) tools.Differ {
+	_, ws := tools.Value(want).(string)
+	_, gs := tools.Value(got).(string)
+	if ws && gs {
+		return tools.Diff(want, got)
+	}
+	return tools.DiffValues(want, got)
+}
+
+func jsonBytes(v interface{}) ([]byte, error) {
+	var bs []byte
+	switch s := v.(type) {
+	case string:
+		bs = []byte(s)
+	case []byte:
+		bs = s
+	default:
+		return nil, fmt.Errorf("unsupported type %T, expected string or []byte", v)
+	}
+	if !json.Valid(bs) {
+		return nil, fmt.Errorf("%q", bs)
+	}
+	return bs, nil
+}
+
+func fail(t TestingT, d tools.Differ, header string, msgAndArgs []interface{}) {
+	t.Helper()
+	failWith(t, d, header, msgAndArgs, nil)
+}
+
+// failValues is fail for a comparison of want and got, which are reported
+// with the failure
+func failValues(t TestingT, want, got interface{}, d tools.Differ, header string, msgAndArgs []interface{}) {
+	t.Helper()
+	failWith(t, d, header, msgAndArgs, []interface{}{want, got})
+}
+
+func failWith(t TestingT, d tools.Differ, header string, msgAndArgs []interface{}, values []interface{}) {
+	t.Helper()
+	if msg := message(msgAndArgs); msg != "" {
+		header += "\n" + msg
+	}
+	s := ""
+	if d != nil {
+		s = d.String()
+	}
+	if values != nil {
+		tools.ReportFailureValues(t, header, s, values[0], values[1])
+	} else {
+		tools.ReportFailure(t, header, s)
+	}
+	if d == nil {
+		t.Errorf("%s", header)
+		return
+	}
+	t.Errorf("%s\n%s", header, s)
+}
@@ -0,0 +1,111 @@
+package assert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/internal"
+	"github.com/prasek/loupe/tools"
+)
+
+func similar(min float64) func(TestingT, interface{}, interface{}, ...interface{}) bool {
+	return func(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+		return Similar(t, want.(string), got.(string), min, msgAndArgs...)
+	}
+}
+
+func inDelta(delta float64) func(TestingT, interface{}, interface{}, ...interface{}) bool {
+	return func(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+		return InDelta(t, want, got, delta, msgAndArgs...)
+	}
+}
+
+func inEpsilon(epsilon float64) func(TestingT, interface{}, interface{}, ...interface{}) bool {
+	return func(t TestingT, want, got interface{}, msgAndArgs ...interface{}) bool {
+		return InEpsilon(t, want, got, epsilon, msgAndArgs...)
+	}
+}
+
+type point struct {
+	X, Y float64
+	Name string
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(TestingT, interface{}, interface{}, ...interface{}) bool
+		want    interface{}
+		got     interface{}
+		ok      bool
+		failNow bool
+		err     string
+	}{
+		{"equal", Equal, &internal.StructsA[0], internal.StructsA[0], true, false, ""},
+		{"not equal", Equal, internal.StructsA[0], internal.StructsA[1], false, false, "TestStruct.a:"},
+		{"strings", RequireEqual, "aaabbb", "aaaccc", false, true, "Not Equal (string/string)"},
+		{"json", EqualJSON, `{"a": [1, 2]}`, []byte(`{ "a":[1,2] }`), true, false, ""},
+		{"json not equal", RequireEqualJSON, `{"a": 1}`, `{"a": 2}`, false, true, "/a:"},
+		{"json invalid", EqualJSON, `{"a": 1}`, `{"a":`, false, false, "Invalid got JSON"},
+		{"json subset", MatchJSONSubset, `{"a": {"b": 1}}`, `{"a": {"b": 1, "c": 2}, "d": 3}`, true, false, ""},
+		{"json subset missing", MatchJSONSubset, `{"a": 1, "b": 2}`, `{"a": 1, "c": 3}`, false, false, "JSON Subset Not Matched"},
+		{"json subset changed", RequireMatchJSONSubset, `{"a": 1}`, `{"a": 2, "c": 3}`, false, true, "/a:"},
+		{"json subset invalid", MatchJSONSubset, `{"a":`, `{"a": 1}`, false, false, "Invalid want JSON"},
+		{"similar", similar(0.75), "the quick brown fox", "the quick brown cat", true, false, ""},
+		{"in delta", inDelta(1e-9), 0.3, 0.1 + 0.2, true, false, ""},
+		{"in delta mixed", inDelta(0.5), 3, 3.2, true, false, ""},
+		{"in delta struct", inDelta(1e-9), point{0.3, 1, "a"}, &point{0.1 + 0.2, 1, "a"}, true, false, ""},
+		{"not in delta", inDelta(0.01), []float64{1, 2, 3}, []float64{1.001, 2.5, 3}, false, false, "Not InDelta: difference exceeds 0.01"},
+		{"not in delta name", inDelta(0.01), point{1, 2, "a"}, point{1, 2, "b"}, false, false, "point.Name:"},
+		{"in epsilon", inEpsilon(0.01), 1000.0, 1009.0, true, false, ""},
+		{"not in epsilon", inEpsilon(0.01), 1000.0, 1011.0, false, false, "Not InEpsilon: relative difference exceeds 0.01"},
+		{"not similar", similar(0.9), "the quick brown fox", "the quick brown cat", false, false, "Not Similar: 84.21% similar, expected at least 90.00%"},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := test.fn(m, test.want, test.got, "case %s", test.name)
+		res := m.Results()
+
+		if ok != test.ok {
+			t.Errorf("%s: expected %v, got %v", test.name, test.ok, ok)
+		}
+		if res.FailNow != test.failNow {
+			t.Errorf("%s: expected FailNow %v, got %v", test.name, test.failNow, res.FailNow)
+		}
+		if !strings.Contains(res.Err, test.err) {
+			t.Errorf("%s: expected error containing %q, got %q", test.name, test.err, res.Err)
+		}
+		if !test.ok && !strings.Contains(res.Err, "case "+test.name) {
+			t.Errorf("%s: message missing from %q", test.name, res.Err)
+		}
+	}
+}
+
+func TestEqualAnnotates(t *testing.T) {
+	defer tools.AutoAnnotations()
+	tools.EnableAnnotations()
+
+	m := tools.Mock()
+	Equal(m, "aaabbb", "aaaccc")
+	res := m.Results()
+
+	if !strings.Contains(res.Out, "assert_test.go,line=") || !strings.Contains(res.Out, "title=Not Equal (string/string)::") {
+		t.Errorf("expected annotation, got %q", res.Out)
+	}
+	if !strings.Contains(res.Out, "::group::Not Equal (string/string)\n") || !strings.HasSuffix(res.Out, "::endgroup::\n") {
+		t.Errorf("expected group, got %q", res.Out)
+	}
+}
+
+func TestMatchJSONSubsetDiff(t *testing.T) {
+	m := tools.Mock()
+	MatchJSONSubset(m, `{"id": 1, "user": {"name": "ann"}}`, `{"id": 1, "user": {"name": "bob", "age": 3}, "extra": true}`)
+	res := m.Results()
+	if !strings.Contains(res.Err, "/user/name:") {
+		t.Errorf("expected the changed field, got %q", res.Err)
+	}
+	if strings.Contains(res.Err, "age") || strings.Contains(res.Err, "extra") {
+		t.Errorf("expected no extra fields, got %q", res.Err)
+	}
+}
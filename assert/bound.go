@@ -0,0 +1,140 @@
+package assert
+
+import "time"
+
+//Assert has the assertions bound to a TestingT, so they can be called
+//without passing t each time, e.g. a := assert.New(t); a.Equal(want, got)
+type Assert struct {
+	t TestingT
+}
+
+//New binds the assertions to t
+func New(t TestingT) *Assert {
+	return &Assert{t: t}
+}
+
+//Equal is Equal with the bound TestingT
+func (a *Assert) Equal(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return Equal(a.t, want, got, msgAndArgs...)
+}
+
+//RequireEqual is RequireEqual with the bound TestingT
+func (a *Assert) RequireEqual(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return RequireEqual(a.t, want, got, msgAndArgs...)
+}
+
+//EqualJSON is EqualJSON with the bound TestingT
+func (a *Assert) EqualJSON(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return EqualJSON(a.t, want, got, msgAndArgs...)
+}
+
+//RequireEqualJSON is RequireEqualJSON with the bound TestingT
+func (a *Assert) RequireEqualJSON(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return RequireEqualJSON(a.t, want, got, msgAndArgs...)
+}
+
+//MatchJSONSubset is MatchJSONSubset with the bound TestingT
+func (a *Assert) MatchJSONSubset(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return MatchJSONSubset(a.t, want, got, msgAndArgs...)
+}
+
+//RequireMatchJSONSubset is RequireMatchJSONSubset with the bound TestingT
+func (a *Assert) RequireMatchJSONSubset(want, got interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return RequireMatchJSONSubset(a.t, want, got, msgAndArgs...)
+}
+
+//MatchesSchema is MatchesSchema with the bound TestingT
+func (a *Assert) MatchesSchema(schemaPath string, doc interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return MatchesSchema(a.t, schemaPath, doc, msgAndArgs...)
+}
+
+//RequireMatchesSchema is RequireMatchesSchema with the bound TestingT
+func (a *Assert) RequireMatchesSchema(schemaPath string, doc interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return RequireMatchesSchema(a.t, schemaPath, doc, msgAndArgs...)
+}
+
+//Similar is Similar with the bound TestingT
+func (a *Assert) Similar(want, got string, min float64, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return Similar(a.t, want, got, min, msgAndArgs...)
+}
+
+//InDelta is InDelta with the bound TestingT
+func (a *Assert) InDelta(want, got interface{}, delta float64, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return InDelta(a.t, want, got, delta, msgAndArgs...)
+}
+
+//InEpsilon is InEpsilon with the bound TestingT
+func (a *Assert) InEpsilon(want, got interface{}, epsilon float64, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return InEpsilon(a.t, want, got, epsilon, msgAndArgs...)
+}
+
+//ErrorIs is ErrorIs with the bound TestingT
+func (a *Assert) ErrorIs(err, target error, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return ErrorIs(a.t, err, target, msgAndArgs...)
+}
+
+//ErrorAs is ErrorAs with the bound TestingT
+func (a *Assert) ErrorAs(err error, target interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return ErrorAs(a.t, err, target, msgAndArgs...)
+}
+
+//ErrorContains is ErrorContains with the bound TestingT
+func (a *Assert) ErrorContains(err error, substr string, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return ErrorContains(a.t, err, substr, msgAndArgs...)
+}
+
+//Panics is Panics with the bound TestingT
+func (a *Assert) Panics(fn func(), msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return Panics(a.t, fn, msgAndArgs...)
+}
+
+//PanicsWithValue is PanicsWithValue with the bound TestingT
+func (a *Assert) PanicsWithValue(want interface{}, fn func(), msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return PanicsWithValue(a.t, want, fn, msgAndArgs...)
+}
+
+//NotPanics is NotPanics with the bound TestingT
+func (a *Assert) NotPanics(fn func(), msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return NotPanics(a.t, fn, msgAndArgs...)
+}
+
+//Eventually is Eventually with the bound TestingT
+func (a *Assert) Eventually(cond func() bool, timeout, interval time.Duration, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return Eventually(a.t, cond, timeout, interval, msgAndArgs...)
+}
+
+//EventuallyEqual is EventuallyEqual with the bound TestingT
+func (a *Assert) EventuallyEqual(want interface{}, got func() interface{}, timeout, interval time.Duration, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return EventuallyEqual(a.t, want, got, timeout, interval, msgAndArgs...)
+}
+
+//Consistently is Consistently with the bound TestingT
+func (a *Assert) Consistently(cond func() bool, duration, interval time.Duration, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return Consistently(a.t, cond, duration, interval, msgAndArgs...)
+}
+
+//ConsistentlyEqual is ConsistentlyEqual with the bound TestingT
+func (a *Assert) ConsistentlyEqual(want interface{}, got func() interface{}, duration, interval time.Duration, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return ConsistentlyEqual(a.t, want, got, duration, interval, msgAndArgs...)
+}
@@ -0,0 +1,53 @@
+package assert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestAssert(t *testing.T) {
+	m := tools.Mock()
+	a := New(m)
+	ok := a.Equal(1, 1) &&
+		a.EqualJSON(`{"a": 1}`, `{"a":1}`) &&
+		a.MatchJSONSubset(`{"a": 1}`, `{"a":1,"b":2}`) &&
+		a.MatchesSchema("testdata/user.schema.json", `{"id": 1, "name": "ann"}`) &&
+		a.InDelta(1.0, 1.05, 0.1) &&
+		a.ErrorContains(errors.New("not found"), "found") &&
+		a.Panics(func() { panic("boom") }) &&
+		a.Eventually(func() bool { return true }, time.Second, time.Millisecond)
+	bad := a.Equal("aaa", "bbb", "case %d", 1)
+	res := m.Results()
+
+	if !ok {
+		t.Errorf("expected passing assertions, got %q", res.Err)
+	}
+	if bad || !strings.Contains(res.Err, "Not Equal (string/string)\ncase 1") {
+		t.Errorf("expected failure, got %q", res.Err)
+	}
+	if res.FailNow {
+		t.Errorf("expected no FailNow")
+	}
+
+	m = tools.Mock()
+	New(m).RequireEqual(1, 2)
+	if res := m.Results(); !res.FailNow {
+		t.Errorf("expected FailNow")
+	}
+
+	m = tools.Mock()
+	New(m).RequireMatchJSONSubset(`{"a": 1}`, `{"a":2}`)
+	if res := m.Results(); !res.FailNow {
+		t.Errorf("expected FailNow")
+	}
+
+	m = tools.Mock()
+	New(m).RequireMatchesSchema("testdata/user.schema.json", `{"id": 1, "age": -2}`)
+	if res := m.Results(); !res.FailNow {
+		t.Errorf("expected FailNow")
+	}
+}
@@ -0,0 +1,66 @@
+package assert
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Context is a key and value shown with the failure of an assertion, see Ctx
+type Context struct {
+	Key   string
+	Value interface{}
+}
+
+//Ctx attaches key and value to an assertion, passed with msgAndArgs, e.g.
+//assert.Equal(t, want, got, assert.Ctx("request_id", id)). On failure the
+//contexts are listed above the diff, so failures in loops are identifiable.
+func Ctx(key string, value interface{}) Context {
+	return Context{Key: key, Value: value}
+}
+
+//Message is a formatted message shown with the failure of an assertion, see
+//Msg
+type Message string
+
+//Msg formats a message for an assertion, passed with msgAndArgs, so it can
+//be combined with Ctx, e.g. assert.Msg("after retry %d", n)
+func Msg(format string, args ...interface{}) Message {
+	return Message(fmt.Sprintf(format, args...))
+}
+
+// message renders the messages and contexts of msgAndArgs, a leading
+// format string and its arguments, Msg messages and Ctx contexts in any
+// order. The contexts are listed last with their values aligned.
+func message(msgAndArgs []interface{}) string {
+	var plain []interface{}
+	var lines []string
+	var ctxs []Context
+	for _, a := range msgAndArgs {
+		switch v := a.(type) {
+		case Context:
+			ctxs = append(ctxs, v)
+		case Message:
+			lines = append(lines, string(v))
+		default:
+			plain = append(plain, a)
+		}
+	}
+	if len(plain) > 0 {
+		msg := fmt.Sprint(plain...)
+		if format, ok := plain[0].(string); ok {
+			msg = fmt.Sprintf(format, plain[1:]...)
+		}
+		lines = append([]string{msg}, lines...)
+	}
+
+	width := 0
+	for _, c := range ctxs {
+		if len(c.Key) > width {
+			width = len(c.Key)
+		}
+	}
+	for _, c := range ctxs {
+		lines = append(lines, fmt.Sprintf("  %-*s %v", width+1, c.Key+":", c.Value))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,42 @@
+package assert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestCtx(t *testing.T) {
+	m := tools.Mock()
+	for n := 1; n <= 2; n++ {
+		Equal(m, 1, n, Ctx("request_id", "r-42"), Msg("after retry %d", n), Ctx("n", n))
+	}
+	res := m.Results()
+
+	want := "Not Equal (int/int)\nafter retry 2\n  request_id: r-42\n  n:          2\n"
+	if !strings.HasPrefix(res.Err, want) {
+		t.Errorf("expected %q, got %q", want, res.Err)
+	}
+	if strings.Contains(res.Err, "after retry 1") {
+		t.Errorf("expected only the failed attempt, got %q", res.Err)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		args []interface{}
+		want string
+	}{
+		{nil, ""},
+		{[]interface{}{"case %d", 1}, "case 1"},
+		{[]interface{}{42}, "42"},
+		{[]interface{}{Msg("a %s", "b")}, "a b"},
+		{[]interface{}{Ctx("id", 7), "case %d", 1, Msg("more")}, "case 1\nmore\n  id: 7"},
+	}
+	for _, test := range tests {
+		if got := message(test.args); got != test.want {
+			t.Errorf("message(%v): expected %q, got %q", test.args, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//ErrorIs verifies errors.Is(err, target) and fails showing a word diff of
+//the target and actual messages and the unwrap chain of err if not
+func ErrorIs(t TestingT, err, target error, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if errors.Is(err, target) {
+		return true
+	}
+	header := fmt.Sprintf("Error Is: target %T not found in error chain", target)
+	if err == nil || target == nil {
+		fail(t, nil, header+"\n"+errorChain(err), msgAndArgs)
+		return false
+	}
+	fail(t, messageDiff(target.Error(), err.Error()), header+"\n"+errorChain(err), msgAndArgs)
+	return false
+}
+
+//ErrorAs verifies errors.As(err, target), target is a non nil pointer to
+//an error type or interface. It fails showing the unwrap chain of err if
+//not.
+func ErrorAs(t TestingT, err error, target interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		fail(t, nil, fmt.Sprintf("Error As: target must be a non nil pointer, got %T", target), msgAndArgs)
+		return false
+	}
+	if err != nil && errors.As(err, target) {
+		return true
+	}
+	fail(t, nil, fmt.Sprintf("Error As: no %s in error chain\n%s", v.Type().Elem(), errorChain(err)), msgAndArgs)
+	return false
+}
+
+//ErrorContains verifies err is not nil and its message contains substr,
+//and fails showing a word diff of substr and the message and the unwrap
+//chain of err if not
+func ErrorContains(t TestingT, err error, substr string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if err == nil {
+		fail(t, nil, fmt.Sprintf("Error Contains: expected an error containing %q, got nil", substr), msgAndArgs)
+		return false
+	}
+	if strings.Contains(err.Error(), substr) {
+		return true
+	}
+	fail(t, messageDiff(substr, err.Error()), fmt.Sprintf("Error Contains: %q not found in error message\n%s", substr, errorChain(err)), msgAndArgs)
+	return false
+}
+
+// messageDiff is a word diff of two error messages
+func messageDiff(want, got string) tools.Differ {
+	return tools.Diff(want, got, tools.WithMode(tools.WordMode))
+}
+
+// errorChain lists err and everything it wraps with their types, each
+// wrapped error indented below the one wrapping it
+func errorChain(err error) string {
+	if err == nil {
+		return "error chain: <nil>"
+	}
+	var b strings.Builder
+	b.WriteString("error chain:")
+	writeChain(&b, err, 1)
+	return b.String()
+}
+
+func writeChain(b *strings.Builder, err error, depth int) {
+	for err != nil {
+		fmt.Fprintf(b, "\n%s%T: %s", strings.Repeat("  ", depth), err, err)
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				writeChain(b, e, depth+1)
+			}
+			return
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+			depth++
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+var errNotFound = errors.New("user not found")
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.code)
+}
+
+func TestErrors(t *testing.T) {
+	wrapped := fmt.Errorf("load user 7: %w", errNotFound)
+	other := fmt.Errorf("load user 7: %w", &codeError{500})
+
+	tests := []struct {
+		name string
+		fn   func(TestingT) bool
+		ok   bool
+		err  []string
+	}{
+		{"is", func(t TestingT) bool { return ErrorIs(t, wrapped, errNotFound) }, true, nil},
+		{"is not", func(t TestingT) bool { return ErrorIs(t, other, errNotFound) }, false, []string{
+			"Error Is: target *errors.errorString not found in error chain",
+			"error chain:\n  *fmt.wrapError: load user 7: code 500\n    *assert.codeError: code 500\n",
+			"user not found",
+		}},
+		{"is nil", func(t TestingT) bool { return ErrorIs(t, nil, errNotFound) }, false, []string{"error chain: <nil>"}},
+		{"as", func(t TestingT) bool {
+			var ce *codeError
+			return ErrorAs(t, other, &ce) && ce.code == 500
+		}, true, nil},
+		{"as not", func(t TestingT) bool {
+			var pe *os.PathError
+			return ErrorAs(t, wrapped, &pe)
+		}, false, []string{"Error As: no *fs.PathError in error chain", "*errors.errorString: user not found"}},
+		{"as bad target", func(t TestingT) bool { return ErrorAs(t, wrapped, nil) }, false, []string{"target must be a non nil pointer"}},
+		{"contains", func(t TestingT) bool { return ErrorContains(t, wrapped, "not found") }, true, nil},
+		{"contains not", func(t TestingT) bool { return ErrorContains(t, wrapped, "load user 8") }, false, []string{
+			`Error Contains: "load user 8" not found in error message`,
+			"-8",
+			"+7: user not found",
+		}},
+		{"contains nil", func(t TestingT) bool { return ErrorContains(t, nil, "x") }, false, []string{"got nil"}},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := test.fn(m)
+		res := m.Results()
+		if ok != test.ok {
+			t.Errorf("%s: expected %v, got %v", test.name, test.ok, ok)
+		}
+		for _, s := range test.err {
+			if !strings.Contains(res.Err, s) {
+				t.Errorf("%s: expected error containing %q, got %q", test.name, s, res.Err)
+			}
+		}
+	}
+}
+
+type multiError []error
+
+func (m multiError) Error() string {
+	return "multiple errors"
+}
+
+func (m multiError) Unwrap() []error {
+	return m
+}
+
+func TestErrorChain(t *testing.T) {
+	err := fmt.Errorf("save: %w", multiError{errNotFound, fmt.Errorf("audit: %w", &codeError{1})})
+	exp := "error chain:\n" +
+		"  *fmt.wrapError: save: multiple errors\n" +
+		"    assert.multiError: multiple errors\n" +
+		"      *errors.errorString: user not found\n" +
+		"      *fmt.wrapError: audit: code 1\n" +
+		"        *assert.codeError: code 1"
+	if got := errorChain(err); got != exp {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+}
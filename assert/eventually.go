@@ -0,0 +1,102 @@
+package assert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+// maxBackoff caps how far the polling interval grows, unless the initial
+// interval is already longer
+const maxBackoff = time.Second
+
+// minInterval is the shortest polling interval, so an interval of 0 doesn't
+// spin until the deadline
+const minInterval = time.Millisecond
+
+// poll calls check until it returns stop or timeout elapses, starting with
+// interval between calls and doubling it up to maxBackoff. It returns the
+// number of calls and whether check stopped it.
+func poll(timeout, interval time.Duration, check func() (stop bool)) (int, bool) {
+	deadline := time.Now().Add(timeout)
+	if interval < minInterval {
+		interval = minInterval
+	}
+	max := maxBackoff
+	if interval > max {
+		max = interval
+	}
+	for n := 1; ; n++ {
+		if check() {
+			return n, true
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return n, false
+		}
+		if interval > left {
+			interval = left
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > max {
+			interval = max
+		}
+	}
+}
+
+//Eventually verifies cond returns true within timeout, polling it with
+//interval between calls at first and backing off from there
+func Eventually(t TestingT, cond func() bool, timeout, interval time.Duration, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	n, ok := poll(timeout, interval, cond)
+	if !ok {
+		fail(t, nil, fmt.Sprintf("Condition not met within %v (%d attempts)", timeout, n), msgAndArgs)
+	}
+	return ok
+}
+
+//EventuallyEqual verifies got returns a value deep equal to want within
+//timeout, polling it like Eventually, and fails with a diff of the last
+//value if not
+func EventuallyEqual(t TestingT, want interface{}, got func() interface{}, timeout, interval time.Duration, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	var last interface{}
+	n, ok := poll(timeout, interval, func() bool {
+		last = got()
+		return tools.DeepEqual(want, last)
+	})
+	if !ok {
+		failValues(t, want, last, diff(want, last), fmt.Sprintf("Not Equal within %v (%d attempts), last value (%T/%T)", timeout, n, want, last), msgAndArgs)
+	}
+	return ok
+}
+
+//Consistently verifies cond returns true on every call for duration,
+//polling it like Eventually, and fails as soon as it returns false
+func Consistently(t TestingT, cond func() bool, duration, interval time.Duration, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	n, failed := poll(duration, interval, func() bool {
+		return !cond()
+	})
+	if failed {
+		fail(t, nil, fmt.Sprintf("Condition not met on attempt %d", n), msgAndArgs)
+	}
+	return !failed
+}
+
+//ConsistentlyEqual verifies got returns a value deep equal to want on every
+//call for duration, polling it like Eventually, and fails with a diff of
+//the first value that isn't
+func ConsistentlyEqual(t TestingT, want interface{}, got func() interface{}, duration, interval time.Duration, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	var last interface{}
+	n, failed := poll(duration, interval, func() bool {
+		last = got()
+		return !tools.DeepEqual(want, last)
+	})
+	if failed {
+		failValues(t, want, last, diff(want, last), fmt.Sprintf("Not Equal on attempt %d (%T/%T)", n, want, last), msgAndArgs)
+	}
+	return !failed
+}
@@ -0,0 +1,89 @@
+package assert
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestEventually(t *testing.T) {
+	var n int32
+	counter := func() interface{} {
+		return int(atomic.AddInt32(&n, 1))
+	}
+
+	tests := []struct {
+		name string
+		fn   func(TestingT) bool
+		ok   bool
+		err  string
+	}{
+		{"eventually", func(t TestingT) bool {
+			atomic.StoreInt32(&n, 0)
+			return Eventually(t, func() bool { return counter().(int) >= 3 }, time.Second, time.Millisecond, "case %s", "eventually")
+		}, true, ""},
+		{"eventually timeout", func(t TestingT) bool {
+			return Eventually(t, func() bool { return false }, 20*time.Millisecond, time.Millisecond, "case %s", "eventually timeout")
+		}, false, "Condition not met within 20ms"},
+		{"eventually equal", func(t TestingT) bool {
+			atomic.StoreInt32(&n, 0)
+			return EventuallyEqual(t, 3, counter, time.Second, time.Millisecond, "case %s", "eventually equal")
+		}, true, ""},
+		{"eventually equal timeout", func(t TestingT) bool {
+			return EventuallyEqual(t, "ready", func() interface{} { return "starting" }, 20*time.Millisecond, time.Millisecond, "case %s", "eventually equal timeout")
+		}, false, "Not Equal within 20ms"},
+		{"consistently", func(t TestingT) bool {
+			return Consistently(t, func() bool { return true }, 20*time.Millisecond, time.Millisecond, "case %s", "consistently")
+		}, true, ""},
+		{"consistently fails", func(t TestingT) bool {
+			atomic.StoreInt32(&n, 0)
+			return Consistently(t, func() bool { return counter().(int) < 3 }, time.Second, time.Millisecond, "case %s", "consistently fails")
+		}, false, "Condition not met on attempt 3"},
+		{"consistently equal", func(t TestingT) bool {
+			return ConsistentlyEqual(t, 1, func() interface{} { return 1 }, 20*time.Millisecond, time.Millisecond, "case %s", "consistently equal")
+		}, true, ""},
+		{"consistently equal fails", func(t TestingT) bool {
+			atomic.StoreInt32(&n, 0)
+			return ConsistentlyEqual(t, 1, counter, time.Second, time.Millisecond, "case %s", "consistently equal fails")
+		}, false, "Not Equal on attempt 2 (int/int)"},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := test.fn(m)
+		res := m.Results()
+
+		if ok != test.ok {
+			t.Errorf("%s: expected %v, got %v", test.name, test.ok, ok)
+		}
+		if !strings.Contains(res.Err, test.err) {
+			t.Errorf("%s: expected error containing %q, got %q", test.name, test.err, res.Err)
+		}
+		if !test.ok && !strings.Contains(res.Err, "case "+test.name) {
+			t.Errorf("%s: message missing from %q", test.name, res.Err)
+		}
+	}
+}
+
+func TestEventuallyDiff(t *testing.T) {
+	m := tools.Mock()
+	EventuallyEqual(m, map[string]int{"ready": 2}, func() interface{} { return map[string]int{"ready": 1} }, 5*time.Millisecond, time.Millisecond)
+	res := m.Results()
+	if !strings.Contains(res.Err, "last value (map[string]int/map[string]int)\n") || !strings.Contains(res.Err, "-2") || !strings.Contains(res.Err, "+1") {
+		t.Errorf("expected a diff of the last value, got %q", res.Err)
+	}
+}
+
+func TestPollZeroInterval(t *testing.T) {
+	n, ok := poll(100*time.Millisecond, 0, func() bool { return false })
+	if ok {
+		t.Errorf("expected poll to time out")
+	}
+	// 1ms doubling up to the deadline is about 7 calls, spinning is millions
+	if n > 20 {
+		t.Errorf("expected a backoff from a zero interval, got %d calls", n)
+	}
+}
@@ -0,0 +1,70 @@
+package assert
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/prasek/loupe/pretty"
+	"github.com/prasek/loupe/tools"
+)
+
+// panicInfo is the outcome of calling a func that may panic
+type panicInfo struct {
+	panicked bool
+	value    interface{}
+	stack    string
+}
+
+// capture calls fn and recovers its panic, if any, including panic(nil)
+func capture(fn func()) (p panicInfo) {
+	p.panicked = true
+	defer func() {
+		if p.panicked {
+			p.value = recover()
+			p.stack = string(debug.Stack())
+		}
+	}()
+	fn()
+	p.panicked = false
+	return p
+}
+
+//Panics verifies fn panics
+func Panics(t TestingT, fn func(), msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if capture(fn).panicked {
+		return true
+	}
+	fail(t, nil, "Expected panic", msgAndArgs)
+	return false
+}
+
+//PanicsWithValue verifies fn panics with a value deep equal to want, and
+//fails with a diff of both values rendered by pretty.Sprint and the stack
+//of the panic if not
+func PanicsWithValue(t TestingT, want interface{}, fn func(), msgAndArgs ...interface{}) bool {
+	t.Helper()
+	p := capture(fn)
+	if !p.panicked {
+		fail(t, nil, fmt.Sprintf("Expected panic with value:\n%s", pretty.Sprint(want)), msgAndArgs)
+		return false
+	}
+	if tools.DeepEqual(want, p.value) {
+		return true
+	}
+	d := tools.Diff(pretty.Sprint(want), pretty.Sprint(p.value), tools.WithMode(tools.LineMode))
+	fail(t, d, fmt.Sprintf("Panic value Not Equal (%T/%T)\n%s", want, p.value, p.stack), msgAndArgs)
+	return false
+}
+
+//NotPanics verifies fn doesn't panic, and fails with the panic value
+//rendered by pretty.Sprint and its stack if it does
+func NotPanics(t TestingT, fn func(), msgAndArgs ...interface{}) bool {
+	t.Helper()
+	p := capture(fn)
+	if !p.panicked {
+		return true
+	}
+	fail(t, nil, fmt.Sprintf("Unexpected panic: %s\n%s", pretty.Sprint(p.value), p.stack), msgAndArgs)
+	return false
+}
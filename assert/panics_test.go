@@ -0,0 +1,58 @@
+package assert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+type panicValue struct {
+	Code int
+	Msg  string
+}
+
+func TestPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(TestingT) bool
+		ok   bool
+		err  []string
+	}{
+		{"panics", func(t TestingT) bool { return Panics(t, func() { panic("boom") }) }, true, nil},
+		{"panics nil", func(t TestingT) bool { return Panics(t, func() { panic(nil) }) }, true, nil},
+		{"no panic", func(t TestingT) bool { return Panics(t, func() {}) }, false, []string{"Expected panic"}},
+		{"value", func(t TestingT) bool {
+			return PanicsWithValue(t, panicValue{1, "a"}, func() { panic(panicValue{1, "a"}) })
+		}, true, nil},
+		{"value differs", func(t TestingT) bool {
+			return PanicsWithValue(t, panicValue{1, "a"}, func() { panic(panicValue{2, "a"}) })
+		}, false, []string{
+			"Panic value Not Equal (assert.panicValue/assert.panicValue)",
+			"panics_test.go:",
+			"-  Code: 1,\n+  Code: 2,\n",
+		}},
+		{"value no panic", func(t TestingT) bool {
+			return PanicsWithValue(t, "boom", func() {})
+		}, false, []string{"Expected panic with value:\n\"boom\""}},
+		{"not panics", func(t TestingT) bool { return NotPanics(t, func() {}) }, true, nil},
+		{"unexpected panic", func(t TestingT) bool {
+			return NotPanics(t, func() { panic(errors.New("boom")) })
+		}, false, []string{"Unexpected panic: boom\n", "goroutine "}},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := test.fn(m)
+		res := m.Results()
+		if ok != test.ok {
+			t.Errorf("%s: expected %v, got %v", test.name, test.ok, ok)
+		}
+		for _, s := range test.err {
+			if !strings.Contains(res.Err, s) {
+				t.Errorf("%s: expected error containing %q, got %q", test.name, s, res.Err)
+			}
+		}
+	}
+}
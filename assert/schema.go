@@ -0,0 +1,47 @@
+package assert
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//MatchesSchema verifies the JSON document doc, a string or []byte, is valid
+//against the JSON Schema in the file schemaPath. It fails listing each
+//violation by the path of the offending value, with the requirement it
+//breaks and the value, colored like a diff.
+func MatchesSchema(t TestingT, schemaPath string, doc interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	schema, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		fail(t, nil, fmt.Sprintf("Invalid schema: %v", err), msgAndArgs)
+		return false
+	}
+	bs, err := jsonBytes(doc)
+	if err != nil {
+		fail(t, nil, fmt.Sprintf("Invalid JSON: %v", err), msgAndArgs)
+		return false
+	}
+
+	d := tools.DiffJSONSchema(schema, bs)
+	if err := d.Error(); err != nil {
+		fail(t, nil, fmt.Sprintf("Invalid schema %s: %v", schemaPath, err), msgAndArgs)
+		return false
+	}
+	if d.Equal() {
+		return true
+	}
+	failValues(t, schema, bs, d, "Schema Not Matched: "+schemaPath, msgAndArgs)
+	return false
+}
+
+//RequireMatchesSchema is like MatchesSchema but stops the test on failure
+func RequireMatchesSchema(t TestingT, schemaPath string, doc interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if !MatchesSchema(t, schemaPath, doc, msgAndArgs...) {
+		t.FailNow()
+		return false
+	}
+	return true
+}
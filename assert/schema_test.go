@@ -0,0 +1,52 @@
+package assert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestMatchesSchema(t *testing.T) {
+	const schema = "testdata/user.schema.json"
+	tests := []struct {
+		name    string
+		fn      func(TestingT) bool
+		ok      bool
+		failNow bool
+		err     string
+	}{
+		{"valid", func(t TestingT) bool { return MatchesSchema(t, schema, `{"id": 1, "name": "ann"}`) }, true, false, ""},
+		{"invalid", func(t TestingT) bool { return MatchesSchema(t, schema, []byte(`{"id": 1, "age": -2}`)) }, false, false, "Schema Not Matched: testdata/user.schema.json"},
+		{"require", func(t TestingT) bool { return RequireMatchesSchema(t, schema, `{"id": "1", "name": "a"}`) }, false, true, "/id:"},
+		{"bad json", func(t TestingT) bool { return MatchesSchema(t, schema, `{"id":`) }, false, false, "Invalid JSON"},
+		{"missing schema", func(t TestingT) bool { return MatchesSchema(t, "testdata/none.json", `{}`) }, false, false, "Invalid schema"},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := test.fn(m)
+		res := m.Results()
+
+		if ok != test.ok {
+			t.Errorf("%s: expected %v, got %v", test.name, test.ok, ok)
+		}
+		if res.FailNow != test.failNow {
+			t.Errorf("%s: expected FailNow %v, got %v", test.name, test.failNow, res.FailNow)
+		}
+		if !strings.Contains(res.Err, test.err) {
+			t.Errorf("%s: expected error containing %q, got %q", test.name, test.err, res.Err)
+		}
+	}
+}
+
+func TestMatchesSchemaViolations(t *testing.T) {
+	m := tools.Mock()
+	MatchesSchema(m, "testdata/user.schema.json", `{"id": 1, "age": -2}`)
+	res := m.Results()
+	for _, want := range []string{"missing properties: 'name'", "/age:", "must be >= 0 but found -2", "+-2"} {
+		if !strings.Contains(res.Err, want) {
+			t.Errorf("expected %q in %q", want, res.Err)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package benchdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Option configures Compare
+type Option func(*options)
+
+type options struct {
+	alpha     float64
+	threshold float64
+}
+
+//WithAlpha sets the significance level, changes with a higher p-value are
+//reported as ~, 0.05 by default
+func WithAlpha(alpha float64) Option {
+	return func(o *options) {
+		o.alpha = alpha
+	}
+}
+
+//WithThreshold sets the smallest significant change, in percent, that is
+//a regression, e.g. 5 doesn't count a 3% slowdown. Any significant change
+//is a regression by default.
+func WithThreshold(percent float64) Option {
+	return func(o *options) {
+		o.threshold = percent
+	}
+}
+
+//Comparison compares the benchmarks of two runs
+type Comparison struct {
+	Rows      []Row   `json:"benchmarks"`
+	Alpha     float64 `json:"alpha"`
+	Threshold float64 `json:"threshold"`
+}
+
+//Row compares one benchmark and unit
+type Row struct {
+	Pkg  string `json:"pkg,omitempty"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+
+	//Old or New has no values when the benchmark only ran once
+	Old Summary `json:"old"`
+	New Summary `json:"new"`
+
+	//Delta is the change of the median in percent
+	Delta float64 `json:"delta"`
+
+	//P is the p-value of the Mann-Whitney U test
+	P float64 `json:"p"`
+
+	//Significant is set when P is below the significance level
+	Significant bool `json:"significant"`
+
+	//Regression is set when the change is significant, worse and at least
+	//the threshold. Lower values are better, except for units per second
+	//like MB/s.
+	Regression bool `json:"regression"`
+
+	//Improvement is set when the change is significant and better
+	Improvement bool `json:"improvement"`
+}
+
+type key struct {
+	pkg, name, unit string
+}
+
+//Compare compares the results of an old run, before, and a new run, after.
+//Each benchmark may have run several times, e.g. with -count=10, and is
+//compared by the median of each unit like benchstat.
+func Compare(before, after []Result, opts ...Option) *Comparison {
+	o := options{alpha: 0.05}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var keys []key
+	samples := [2]map[key][]float64{{}, {}}
+	for i, results := range [][]Result{before, after} {
+		for _, r := range results {
+			for _, v := range r.Values {
+				k := key{r.Pkg, r.Name, v.Unit}
+				if _, ok := samples[0][k]; !ok {
+					if _, ok := samples[1][k]; !ok {
+						keys = append(keys, k)
+					}
+				}
+				samples[i][k] = append(samples[i][k], v.Value)
+			}
+		}
+	}
+
+	c := &Comparison{Alpha: o.alpha, Threshold: o.threshold}
+	for _, k := range groupKeys(keys) {
+		row := Row{Pkg: k.pkg, Name: k.name, Unit: k.unit, Old: summarize(samples[0][k]), New: summarize(samples[1][k]), P: 1}
+		if row.Old.N > 0 && row.New.N > 0 && row.Old.Center != 0 {
+			row.Delta = (row.New.Center - row.Old.Center) / row.Old.Center * 100
+			row.P = mannWhitney(row.Old.Values, row.New.Values)
+			row.Significant = row.P < o.alpha && row.Delta != 0
+			worse := (row.Delta > 0) != higherIsBetter(k.unit)
+			row.Regression = row.Significant && worse && math.Abs(row.Delta) >= o.threshold
+			row.Improvement = row.Significant && !worse
+		}
+		c.Rows = append(c.Rows, row)
+	}
+	return c
+}
+
+// groupKeys orders keys by package and unit, keeping the order in which
+// they were first seen
+func groupKeys(keys []key) []key {
+	var pkgs, units []string
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if !seen["pkg:"+k.pkg] {
+			seen["pkg:"+k.pkg] = true
+			pkgs = append(pkgs, k.pkg)
+		}
+		if !seen["unit:"+k.unit] {
+			seen["unit:"+k.unit] = true
+			units = append(units, k.unit)
+		}
+	}
+	grouped := make([]key, 0, len(keys))
+	for _, p := range pkgs {
+		for _, u := range units {
+			for _, k := range keys {
+				if k.pkg == p && k.unit == u {
+					grouped = append(grouped, k)
+				}
+			}
+		}
+	}
+	return grouped
+}
+
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+//Regressions returns the rows that are regressions
+func (c *Comparison) Regressions() []Row {
+	var rows []Row
+	for _, r := range c.Rows {
+		if r.Regression {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+//String returns the comparison as tables, like WriteText to os.Stdout
+func (c *Comparison) String() string {
+	var buf bytes.Buffer
+	c.write(&buf, tools.NewPalette(nil))
+	return buf.String()
+}
+
+//WriteText writes a table per package and unit like benchstat, with
+//regressions colored like deleted lines and improvements like inserted
+//lines. WithColor, WithNoColor and WithTheme apply.
+func (c *Comparison) WriteText(w io.Writer, opts ...tools.Option) error {
+	var buf bytes.Buffer
+	c.write(&buf, tools.NewPalette(w, opts...))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+//WriteJSON writes the comparison as indented JSON
+func (c *Comparison) WriteJSON(w io.Writer) error {
+	bs, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(bs, '\n'))
+	return err
+}
+
+func (c *Comparison) write(w io.Writer, p tools.Palette) {
+	pkgs := 0
+	for i := 0; i < len(c.Rows); {
+		j := i
+		for j < len(c.Rows) && c.Rows[j].Pkg == c.Rows[i].Pkg {
+			j++
+		}
+		if pkgs > 0 {
+			io.WriteString(w, "\n")
+		}
+		if c.Rows[i].Pkg != "" {
+			fmt.Fprintf(w, "pkg: %s\n", c.Rows[i].Pkg)
+		}
+		c.writePkg(w, p, c.Rows[i:j])
+		pkgs++
+		i = j
+	}
+}
+
+func (c *Comparison) writePkg(w io.Writer, p tools.Palette, rows []Row) {
+	for i := 0; i < len(rows); {
+		j := i
+		for j < len(rows) && rows[j].Unit == rows[i].Unit {
+			j++
+		}
+		if i > 0 {
+			io.WriteString(w, "\n")
+		}
+		writeTable(w, p, rows[i:j])
+		i = j
+	}
+}
+
+// writeTable aligns the columns by their plain text and colors the delta
+// after padding, so color codes don't break the alignment
+func writeTable(w io.Writer, p tools.Palette, rows []Row) {
+	unit := unitName(rows[0].Unit)
+	cells := [][]string{{"name", "old " + unit, "new " + unit, "delta", ""}}
+	for _, r := range rows {
+		cells = append(cells, []string{r.Name, formatSummary(r.Old, r.Unit), formatSummary(r.New, r.Unit), formatDelta(r), formatP(r)})
+	}
+
+	widths := make([]int, len(cells[0]))
+	for _, row := range cells {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for n, row := range cells {
+		var line strings.Builder
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == 3 {
+				// right aligned
+				line.WriteString(pad + colorDelta(p, rows, n, cell))
+			} else {
+				line.WriteString(cell + pad)
+			}
+			if i < len(row)-1 {
+				line.WriteString("  ")
+			}
+		}
+		io.WriteString(w, strings.TrimRight(line.String(), " ")+"\n")
+	}
+}
+
+func colorDelta(p tools.Palette, rows []Row, n int, cell string) string {
+	if n == 0 {
+		return cell
+	}
+	switch r := rows[n-1]; {
+	case r.Regression:
+		return p.Delete(cell)
+	case r.Improvement:
+		return p.Insert(cell)
+	}
+	return cell
+}
+
+func unitName(unit string) string {
+	switch unit {
+	case "ns/op":
+		return "time/op"
+	case "B/op":
+		return "alloc/op"
+	case "MB/s":
+		return "speed"
+	}
+	return unit
+}
+
+func formatSummary(s Summary, unit string) string {
+	if s.N == 0 {
+		return "-"
+	}
+	v := formatValue(s.Center, unit)
+	if s.N == 1 {
+		return v
+	}
+	return fmt.Sprintf("%s ± %.0f%%", v, s.Spread*100)
+}
+
+func formatDelta(r Row) string {
+	switch {
+	case r.Old.N == 0 || r.New.N == 0:
+		return ""
+	case !r.Significant:
+		return "~"
+	}
+	return fmt.Sprintf("%+.2f%%", r.Delta)
+}
+
+func formatP(r Row) string {
+	if r.Old.N == 0 || r.New.N == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(p=%.3f n=%d+%d)", r.P, r.Old.N, r.New.N)
+}
+
+// formatValue scales v to 3 significant digits with the unit's suffix
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "ns/op":
+		return scale(v, 1000, "ns", "µs", "ms", "s")
+	case "B/op":
+		return scale(v, 1000, "B", "kB", "MB", "GB", "TB")
+	case "MB/s":
+		return scale(v, 1000, "MB/s", "GB/s", "TB/s")
+	}
+	return scale(v, 1000, "", "k", "M", "G", "T")
+}
+
+func scale(v, base float64, suffixes ...string) string {
+	i := 0
+	for math.Abs(v) >= base && i < len(suffixes)-1 {
+		v /= base
+		i++
+	}
+	a := math.Abs(v)
+	switch {
+	case a >= 100:
+		return fmt.Sprintf("%.0f%s", v, suffixes[i])
+	case a >= 10:
+		return fmt.Sprintf("%.1f%s", v, suffixes[i])
+	}
+	return fmt.Sprintf("%.2f%s", v, suffixes[i])
+}
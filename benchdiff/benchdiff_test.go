@@ -0,0 +1,100 @@
+package benchdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func load(t *testing.T, path string) []Result {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	results, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestCompare(t *testing.T) {
+	c := Compare(load(t, "testdata/old.txt"), load(t, "testdata/new.txt"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.WriteText(&buf, tools.WithNoColor()))
+	assert.Equal(t, `pkg: example.com/codec
+name       old time/op  new time/op    delta
+Encode-8   1.20µs ± 1%  1.50µs ± 1%  +25.00%  (p=0.008 n=5+5)
+Decode-8   20.0µs ± 0%  20.0µs ± 1%        ~  (p=0.841 n=5+5)
+Removed-8  10.0ns       -
+Added-8    -            12.0ns
+
+name      old alloc/op  new alloc/op  delta
+Encode-8  512B ± 0%     512B ± 0%         ~  (p=1.000 n=5+5)
+
+name      old allocs/op  new allocs/op  delta
+Encode-8  4.00 ± 0%      4.00 ± 0%          ~  (p=1.000 n=5+5)
+
+name      old speed     new speed       delta
+Decode-8  100MB/s ± 0%  125MB/s ± 1%  +25.00%  (p=0.008 n=5+5)
+`, buf.String())
+
+	regressions := c.Regressions()
+	if assert.Len(t, regressions, 1) {
+		assert.Equal(t, "Encode-8", regressions[0].Name)
+		assert.Equal(t, "ns/op", regressions[0].Unit)
+	}
+	assert.True(t, c.Rows[len(c.Rows)-1].Improvement, "MB/s is better when higher")
+
+	buf.Reset()
+	c.WriteText(&buf, tools.WithColor(tools.ColorAlways))
+	assert.Contains(t, buf.String(), "  \x1b[31m+25.00%\x1b[0m  (p=0.008 n=5+5)")
+	assert.Contains(t, buf.String(), "  \x1b[32m+25.00%\x1b[0m  (p=0.008 n=5+5)")
+
+	assert.Empty(t, Compare(load(t, "testdata/old.txt"), load(t, "testdata/new.txt"), WithThreshold(30)).Regressions())
+	assert.Empty(t, Compare(load(t, "testdata/old.txt"), load(t, "testdata/new.txt"), WithAlpha(0.001)).Regressions())
+}
+
+func TestWriteJSON(t *testing.T) {
+	c := Compare(
+		[]Result{{Name: "A", Values: []Value{{100, "ns/op"}}}, {Name: "A", Values: []Value{{102, "ns/op"}}}},
+		[]Result{{Name: "A", Values: []Value{{150, "ns/op"}}}, {Name: "A", Values: []Value{{152, "ns/op"}}}},
+		WithThreshold(10),
+	)
+	var buf bytes.Buffer
+	assert.NoError(t, c.WriteJSON(&buf))
+	var report map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, 10.0, report["threshold"])
+	row := report["benchmarks"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "A", row["name"])
+	assert.InDelta(t, 49.5, row["delta"], 0.01)
+	assert.Equal(t, false, row["significant"], "n=2+2 can't be significant at 0.05")
+	assert.Equal(t, map[string]interface{}{"center": 101.0, "spread": 1 / 101.0, "n": 2.0, "values": []interface{}{100.0, 102.0}}, row["old"])
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{15, "ns/op", "15.0ns"},
+		{1234567, "ns/op", "1.23ms"},
+		{3.2e9, "ns/op", "3.20s"},
+		{2048, "B/op", "2.05kB"},
+		{1500, "MB/s", "1.50GB/s"},
+		{42, "widgets/op", "42.0"},
+		{0, "allocs/op", "0.00"},
+	} {
+		if got := formatValue(tc.v, tc.unit); got != tc.want {
+			t.Errorf("formatValue(%v, %s) = %s, want %s", tc.v, tc.unit, got, tc.want)
+		}
+	}
+}
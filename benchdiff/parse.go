@@ -0,0 +1,88 @@
+package benchdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//Result is one benchmark result line of go test -bench output, in the
+//benchmark data format (golang.org/x/perf/benchfmt)
+type Result struct {
+	//Pkg is the value of the last pkg: configuration line
+	Pkg string
+
+	//Name is the benchmark name without the Benchmark prefix, including
+	//sub-benchmarks and the -N GOMAXPROCS suffix, e.g. Parse/small-8
+	Name string
+
+	Iters  int
+	Values []Value
+}
+
+//Value is a measurement of a Result
+type Value struct {
+	Value float64
+	Unit  string
+}
+
+//Parse reads the results of go test -bench output, lines that aren't
+//results or pkg: configuration are skipped, like the benchmark names that
+//go test -v prints alone. A malformed result line is reported with its line
+//number.
+func Parse(r io.Reader) ([]Result, error) {
+	var results []Result
+	pkg := ""
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if strings.HasPrefix(line, "pkg:") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg:"))
+			continue
+		}
+		if !isResult(line) || len(strings.Fields(line)) == 1 {
+			continue
+		}
+		res, err := parseResult(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		res.Pkg = pkg
+		results = append(results, res)
+	}
+	return results, s.Err()
+}
+
+// isResult reports whether line starts with a benchmark name, Benchmark
+// followed by an upper case letter, digit or nothing before the fields
+func isResult(line string) bool {
+	if !strings.HasPrefix(line, "Benchmark") {
+		return false
+	}
+	rest := []rune(strings.TrimPrefix(line, "Benchmark"))
+	return len(rest) > 0 && !unicode.IsLower(rest[0])
+}
+
+func parseResult(line string) (Result, error) {
+	f := strings.Fields(line)
+	if len(f) < 4 || len(f)%2 != 0 {
+		return Result{}, fmt.Errorf("want name, iterations and value unit pairs, got %q", line)
+	}
+	iters, err := strconv.Atoi(f[1])
+	if err != nil {
+		return Result{}, fmt.Errorf("iterations: %v", err)
+	}
+	res := Result{Name: strings.TrimPrefix(f[0], "Benchmark"), Iters: iters}
+	for i := 2; i < len(f); i += 2 {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return Result{}, fmt.Errorf("value of %s: %v", f[i+1], err)
+		}
+		res.Values = append(res.Values, Value{Value: v, Unit: f[i+1]})
+	}
+	return res, nil
+}
@@ -0,0 +1,26 @@
+package benchdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	results, err := Parse(strings.NewReader("goos: linux\npkg: example.com/a\n" +
+		"BenchmarkQuery/small-8 \t 2000\t 512.5 ns/op\t 64 B/op\t 1 allocs/op\n" +
+		"Benchmarking is fun\n--- BENCH: BenchmarkQuery\nPASS\n" +
+		"BenchmarkParent\nBenchmarkParent/sub \t\n" +
+		"pkg: example.com/b\nBenchmark 10 3 ns/op\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Pkg: "example.com/a", Name: "Query/small-8", Iters: 2000, Values: []Value{{512.5, "ns/op"}, {64, "B/op"}, {1, "allocs/op"}}},
+		{Pkg: "example.com/b", Name: "", Iters: 10, Values: []Value{{3, "ns/op"}}},
+	}, results)
+
+	_, err = Parse(strings.NewReader("PASS\nBenchmarkX-8 100 fast ns/op\n"))
+	assert.EqualError(t, err, `line 2: value of ns/op: strconv.ParseFloat: parsing "fast": invalid syntax`)
+	_, err = Parse(strings.NewReader("BenchmarkX-8 100 12\n"))
+	assert.EqualError(t, err, `line 1: want name, iterations and value unit pairs, got "BenchmarkX-8 100 12"`)
+}
@@ -0,0 +1,138 @@
+package benchdiff
+
+import (
+	"math"
+	"sort"
+)
+
+//Summary describes the samples of one benchmark and unit in a run
+type Summary struct {
+	//Center is the median
+	Center float64 `json:"center"`
+
+	//Spread is the largest distance of a sample from the median, as a
+	//fraction of the median
+	Spread float64 `json:"spread"`
+
+	N      int       `json:"n"`
+	Values []float64 `json:"values"`
+}
+
+func summarize(values []float64) Summary {
+	s := Summary{N: len(values), Values: values}
+	if len(values) == 0 {
+		return s
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	s.Center = sorted[mid]
+	if len(sorted)%2 == 0 {
+		s.Center = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	if s.Center != 0 {
+		dev := math.Max(s.Center-sorted[0], sorted[len(sorted)-1]-s.Center)
+		s.Spread = math.Abs(dev / s.Center)
+	}
+	return s
+}
+
+// mannWhitney returns the two-sided p-value of the Mann-Whitney U test that
+// the samples x and y come from the same distribution, like benchstat. It's
+// exact for small samples without ties and uses the normal approximation
+// with a tie correction otherwise.
+func mannWhitney(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		v     float64
+		fromX bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range y {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// rank with ties averaged, summing the ranks of x and the tie sizes for
+	// the variance correction
+	rankX, ties := 0.0, 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties += t*t*t - t
+		}
+		i = j
+	}
+	u := rankX - float64(n1*(n1+1))/2
+
+	if ties == 0 && n1*n2 <= 400 {
+		return exactP(n1, n2, u)
+	}
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * (n + 1 - ties/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	// continuity correction
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		return 1
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactP returns the two-sided p-value of u from the distribution of U for
+// samples of n1 and n2 values without ties
+func exactP(n1, n2 int, u float64) float64 {
+	// counts[i][j][k] is the number of orderings of i x values and j y values
+	// where k pairs have x > y, built up by appending the largest value
+	maxU := n1 * n2
+	counts := make([][][]float64, n1+1)
+	for i := range counts {
+		counts[i] = make([][]float64, n2+1)
+		for j := range counts[i] {
+			counts[i][j] = make([]float64, maxU+1)
+			if i == 0 || j == 0 {
+				counts[i][j][0] = 1
+				continue
+			}
+			for k := 0; k <= i*j; k++ {
+				c := counts[i][j-1][k]
+				if k >= j {
+					c += counts[i-1][j][k-j]
+				}
+				counts[i][j][k] = c
+			}
+		}
+	}
+
+	dist := counts[n1][n2]
+	total, below, above := 0.0, 0.0, 0.0
+	for k, c := range dist {
+		total += c
+		if float64(k) <= u {
+			below += c
+		}
+		if float64(k) >= u {
+			above += c
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
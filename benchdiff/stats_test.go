@@ -0,0 +1,40 @@
+package benchdiff
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	s := summarize([]float64{10, 12, 9, 11})
+	assert.Equal(t, 10.5, s.Center)
+	assert.InDelta(t, 1.5/10.5, s.Spread, 1e-9)
+	assert.Equal(t, 4, s.N)
+
+	assert.Equal(t, Summary{Center: 3, Values: []float64{3}, N: 1}, summarize([]float64{3}))
+	assert.Equal(t, 0, summarize(nil).N)
+}
+
+func TestMannWhitney(t *testing.T) {
+	for _, tc := range []struct {
+		x, y []float64
+		p    float64
+	}{
+		{[]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{[]float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 2.0 / 252},
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},
+		{[]float64{1, 3, 5}, []float64{2, 4, 6}, 0.7},
+		{[]float64{1}, []float64{2}, 1},
+		{[]float64{1, 2}, nil, 1},
+		// ties use the normal approximation
+		{[]float64{4, 4, 4, 4, 4}, []float64{4, 4, 4, 4, 4}, 1},
+		{[]float64{1, 1, 2, 2, 3}, []float64{3, 4, 4, 5, 5}, 0.0147},
+	} {
+		p := mannWhitney(tc.x, tc.y)
+		if math.Abs(p-tc.p) > 1e-3 {
+			t.Errorf("mannWhitney(%v, %v) = %.4f, want %.4f", tc.x, tc.y, p, tc.p)
+		}
+	}
+}
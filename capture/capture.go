@@ -0,0 +1,165 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//Output runs fn with os.Stdout and os.Stderr redirected and returns what it
+//wrote to each. The color package writers are redirected too. They are
+//restored when fn returns, even if it panics. Output written through
+//ColorAuto printers is not colored since the destination isn't a terminal.
+func Output(t TestingT, fn func()) (stdout, stderr string) {
+	t.Helper()
+	c := Start(t)
+	defer func() {
+		if r := recover(); r != nil {
+			c.Stop()
+			panic(r)
+		}
+	}()
+	fn()
+	return c.Stop()
+}
+
+//Capture is an ongoing capture of os.Stdout and os.Stderr
+type Capture struct {
+	out, err         *stream
+	origOut, origErr *os.File
+	colorOut         io.Writer
+	colorErr         io.Writer
+	once             sync.Once
+	stdout, stderr   string
+}
+
+type stream struct {
+	r, w *os.File
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	done chan struct{}
+}
+
+func newStream() (*stream, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	s := &stream{r: r, w: w, done: make(chan struct{})}
+	go s.run()
+	return s, nil
+}
+
+func (s *stream) run() {
+	defer close(s.done)
+	b := make([]byte, 4096)
+	for {
+		n, err := s.r.Read(b)
+		s.mu.Lock()
+		s.buf.Write(b[:n])
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *stream) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func (s *stream) close() string {
+	s.w.Close()
+	<-s.done
+	s.r.Close()
+	return s.String()
+}
+
+//Start redirects os.Stdout, os.Stderr and the color package writers until
+//Stop is called, or the test completes, for functions that keep running
+//while their output is checked with Stdout, Stderr or WaitFor. Captures
+//can be nested but not overlap, each Stop restores the writers Start
+//replaced.
+func Start(t TestingT) *Capture {
+	t.Helper()
+	out, err := newStream()
+	if err != nil {
+		t.Errorf("capture stdout: %v", err)
+		t.FailNow()
+		return nil
+	}
+	errs, err := newStream()
+	if err != nil {
+		out.close()
+		t.Errorf("capture stderr: %v", err)
+		t.FailNow()
+		return nil
+	}
+
+	c := &Capture{
+		out:      out,
+		err:      errs,
+		origOut:  os.Stdout,
+		origErr:  os.Stderr,
+		colorOut: color.Output,
+		colorErr: color.Error,
+	}
+	os.Stdout, os.Stderr = out.w, errs.w
+	color.Output, color.Error = out.w, errs.w
+	t.Cleanup(func() {
+		c.Stop()
+	})
+	return c
+}
+
+//Stdout returns what was written to os.Stdout so far
+func (c *Capture) Stdout() string {
+	return c.out.String()
+}
+
+//Stderr returns what was written to os.Stderr so far
+func (c *Capture) Stderr() string {
+	return c.err.String()
+}
+
+//WaitFor waits up to timeout for substr to be written to os.Stdout or
+//os.Stderr and reports whether it was
+func (c *Capture) WaitFor(substr string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if strings.Contains(c.Stdout(), substr) || strings.Contains(c.Stderr(), substr) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+//Stop restores the writers and returns everything written to os.Stdout and
+//os.Stderr, calling it again returns the same output
+func (c *Capture) Stop() (stdout, stderr string) {
+	c.once.Do(func() {
+		os.Stdout, os.Stderr = c.origOut, c.origErr
+		color.Output, color.Error = c.colorOut, c.colorErr
+		c.stdout = c.out.close()
+		c.stderr = c.err.close()
+	})
+	return c.stdout, c.stderr
+}
@@ -0,0 +1,65 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	orig, origErr := os.Stdout, os.Stderr
+	stdout, stderr := Output(t, func() {
+		tools.Diff("aaa", "abb").Print()
+		color.New(color.FgGreen).Println("ok")
+		fmt.Fprintln(os.Stderr, "warning")
+		color.New(color.FgRed).Fprintln(color.Error, "failed")
+	})
+	assert.Contains(t, stdout, "@@ -1,3 +1,3 @@\n a\n-aa\n+bb\n")
+	assert.True(t, strings.HasSuffix(stdout, "\nok\n"))
+	assert.Equal(t, "warning\nfailed\n", stderr)
+	assert.Equal(t, orig, os.Stdout)
+	assert.Equal(t, origErr, os.Stderr)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		Output(t, func() {
+			fmt.Print("before")
+			panic("boom")
+		})
+	})
+	assert.Equal(t, orig, os.Stdout)
+	assert.Equal(t, origErr, os.Stderr)
+}
+
+func TestStart(t *testing.T) {
+	orig := os.Stdout
+	c := Start(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 3; i++ {
+			fmt.Printf("tick %d\n", i)
+			time.Sleep(time.Millisecond)
+		}
+		fmt.Fprintln(os.Stderr, "stopped")
+	}()
+
+	assert.True(t, c.WaitFor("tick 2", time.Second))
+	assert.Contains(t, c.Stdout(), "tick 1\n")
+	assert.True(t, c.WaitFor("stopped", time.Second))
+	assert.False(t, c.WaitFor("tick 4", 10*time.Millisecond))
+	<-done
+
+	stdout, stderr := c.Stop()
+	assert.Equal(t, "tick 1\ntick 2\ntick 3\n", stdout)
+	assert.Equal(t, "stopped\n", stderr)
+	assert.Equal(t, orig, os.Stdout)
+
+	stdout, _ = c.Stop()
+	assert.Equal(t, "tick 1\ntick 2\ntick 3\n", stdout)
+}
@@ -0,0 +1,215 @@
+package clitest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+type build struct {
+	path string
+	err  error
+}
+
+var (
+	mu     sync.Mutex
+	dir    string
+	builds = map[string]build{}
+)
+
+//Build builds the main package pkg, a directory like ./cmd/app or an
+//import path, with go build and returns the path of the binary. Each
+//package is built once per test binary, later calls return the cached
+//binary. A failed build fails the test with the build output and stops it.
+func Build(t TestingT, pkg string) string {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+
+	b, ok := builds[pkg]
+	if !ok {
+		b = goBuild(pkg)
+		builds[pkg] = b
+	}
+	if b.err != nil {
+		t.Errorf("build %s: %v", pkg, b.err)
+		t.FailNow()
+	}
+	return b.path
+}
+
+func goBuild(pkg string) build {
+	if dir == "" {
+		d, err := ioutil.TempDir("", "clitest")
+		if err != nil {
+			return build{err: err}
+		}
+		dir = d
+	}
+	name := filepath.Base(strings.TrimSuffix(pkg, "/"))
+	if name == "." || name == string(filepath.Separator) {
+		wd, _ := os.Getwd()
+		name = filepath.Base(wd)
+	}
+	// a directory per build keeps the binary name for String
+	out := filepath.Join(dir, strconv.Itoa(len(builds)), name)
+	if runtime.GOOS == "windows" {
+		out += ".exe"
+	}
+	if bs, err := exec.Command("go", "build", "-o", out, pkg).CombinedOutput(); err != nil {
+		return build{err: fmt.Errorf("%v\n%s", err, bs)}
+	}
+	return build{path: out}
+}
+
+//Clean removes the binaries built by Build, call it from TestMain after
+//m.Run
+func Clean() {
+	mu.Lock()
+	defer mu.Unlock()
+	if dir != "" {
+		os.RemoveAll(dir)
+	}
+	dir = ""
+	builds = map[string]build{}
+}
+
+//Cmd is a command to run
+type Cmd struct {
+	//Path is the binary, e.g. from Build
+	Path string
+
+	//Args are the arguments, without the command name
+	Args []string
+
+	//Env is added to the environment of the test, entries are KEY=value
+	Env []string
+
+	//Dir is the working directory, the current directory if empty
+	Dir string
+
+	//Stdin is the standard input
+	Stdin string
+}
+
+//Command returns a Cmd running the binary at path with args
+func Command(path string, args ...string) Cmd {
+	return Cmd{Path: path, Args: args}
+}
+
+//String returns the command line
+func (c Cmd) String() string {
+	parts := []string{filepath.Base(c.Path)}
+	for _, a := range c.Args {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\$") {
+			a = strconv.Quote(a)
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+//Result is the outcome of running a Cmd, every check reports a failure
+//with t.Errorf and returns the Result so checks can be chained
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+
+	t    TestingT
+	cmd  Cmd
+	opts []tools.Option
+}
+
+//Run runs the command and waits for it to exit. A command that can't be
+//started fails the test with ExitCode -1. opts configure the Differ used
+//for output mismatches.
+func (c Cmd) Run(t TestingT, opts ...tools.Option) *Result {
+	t.Helper()
+	cmd := exec.Command(c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.Stdin = strings.NewReader(c.Stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	r := &Result{t: t, cmd: c, opts: opts}
+	err := cmd.Run()
+	r.Stdout, r.Stderr = stdout.String(), stderr.String()
+	if e, ok := err.(*exec.ExitError); ok {
+		r.ExitCode = e.ExitCode()
+	} else if err != nil {
+		r.ExitCode = -1
+		t.Errorf("run %s: %v", c, err)
+	}
+	return r
+}
+
+//Code verifies the exit code
+func (r *Result) Code(code int) *Result {
+	r.t.Helper()
+	if r.ExitCode == code {
+		return r
+	}
+	if r.Stderr == "" {
+		r.t.Errorf("%s: expected exit code %d, got %d", r.cmd, code, r.ExitCode)
+	} else {
+		r.t.Errorf("%s: expected exit code %d, got %d\nstderr:\n%s", r.cmd, code, r.ExitCode, r.Stderr)
+	}
+	return r
+}
+
+//StdoutEquals verifies stdout is want and fails with a diff if not
+func (r *Result) StdoutEquals(want string) *Result {
+	r.t.Helper()
+	r.equals("stdout", want, r.Stdout)
+	return r
+}
+
+//StderrEquals verifies stderr is want and fails with a diff if not
+func (r *Result) StderrEquals(want string) *Result {
+	r.t.Helper()
+	r.equals("stderr", want, r.Stderr)
+	return r
+}
+
+func (r *Result) equals(name, want, got string) {
+	r.t.Helper()
+	opts := append([]tools.Option{tools.WithMode(tools.LineMode), tools.WithLabels("expected", name)}, r.opts...)
+	d := tools.Diff(want, got, opts...)
+	if d.Equal() {
+		return
+	}
+	header := fmt.Sprintf("%s: %s Not Equal", r.cmd, name)
+	tools.ReportFailureValues(r.t, header, d.String(), want, got)
+	r.t.Errorf("%s\n%s", header, d)
+}
+
+//Golden compares stdout, stderr and the exit code with the golden files
+//prefix.stdout, prefix.stderr and prefix.code, written with -update like
+//golden.Assert
+func (r *Result) Golden(prefix string) *Result {
+	r.t.Helper()
+	golden.Assert(r.t, r.Stdout, prefix+".stdout")
+	golden.Assert(r.t, r.Stderr, prefix+".stderr")
+	golden.Assert(r.t, fmt.Sprintf("%d\n", r.ExitCode), prefix+".code")
+	return r
+}
@@ -0,0 +1,50 @@
+package clitest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	Clean()
+	os.Exit(code)
+}
+
+func TestRun(t *testing.T) {
+	bin := Build(t, "./testdata/greet")
+	assert.Equal(t, bin, Build(t, "./testdata/greet"))
+
+	r := Cmd{Path: bin, Args: []string{"world"}, Env: []string{"GREETING=Hello"}}.Run(t)
+	r.Code(0).StdoutEquals("Hello, world!\n").StderrEquals("")
+
+	r = Cmd{Path: bin, Args: []string{"-"}, Env: []string{"GREETING=Hi"}, Stdin: "stdin"}.Run(t)
+	assert.Equal(t, "Hi, stdin!\n", r.Stdout)
+
+	Command(bin).Run(t).Golden("testdata/usage")
+}
+
+func TestRunFail(t *testing.T) {
+	bin := Build(t, "./testdata/greet")
+
+	m := tools.Mock()
+	Command(bin, "my name").Run(m).Code(1).StdoutEquals("Hello, my name!\n")
+	res := m.Results()
+	assert.Equal(t, "greet \"my name\": expected exit code 1, got 0"+
+		"greet \"my name\": stdout Not Equal\n--- expected\n+++ stdout\n@@ -1 +1 @@\n-Hello, my name!\n+, my name!\n", res.Err)
+
+	m = tools.Mock()
+	r := Command("./testdata/missing").Run(m)
+	res = m.Results()
+	assert.Equal(t, -1, r.ExitCode)
+	assert.Contains(t, res.Err, "run missing: ")
+
+	m = tools.Mock()
+	Build(m, "./testdata/nothing")
+	res = m.Results()
+	assert.True(t, res.FailNow)
+	assert.Contains(t, res.Err, "build ./testdata/nothing: ")
+}
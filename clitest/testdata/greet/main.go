@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: greet <name>|-")
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-" {
+		bs, _ := ioutil.ReadAll(os.Stdin)
+		name = string(bs)
+	}
+	fmt.Printf("%s, %s!\n", os.Getenv("GREETING"), name)
+}
@@ -0,0 +1,214 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock tells and waits for time, code under test takes a Clock instead of
+//calling the time package so tests can pass a Fake
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+//Ticker delivers ticks on C like a time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+//Real returns the Clock of the time package
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+//Fake is a Clock that only moves when Advance or Set is called, so time
+//is frozen for golden files and timers fire deterministically
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{}
+}
+
+type waiter struct {
+	at     time.Time
+	c      chan time.Time
+	period time.Duration
+}
+
+//NewFake creates a Fake set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+//Now returns the time of the Fake
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+//Since returns the time elapsed on the Fake since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+//Sleep blocks until the Fake is advanced by d
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+//After returns a channel that receives the time once the Fake is advanced
+//by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{at: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w.c
+	}
+	f.add(w)
+	return w.c
+}
+
+//NewTicker returns a Ticker that ticks every d the Fake is advanced by. Like
+//a time.Ticker it drops ticks for slow receivers. It panics if d <= 0.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{at: f.now.Add(d), c: make(chan time.Time, 1), period: d}
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+//Advance moves the Fake forward by d, firing timers and tickers that are
+//due in order
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+//Set moves the Fake to t, firing timers and tickers that are due in order.
+//Moving it backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) > 0 && !f.waiters[0].at.After(t) {
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+			f.add(w)
+		}
+	}
+	f.now = t
+}
+
+//Waiters returns the number of pending Sleep, After and Ticker calls
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+//BlockUntil blocks until at least n Sleep, After or Ticker calls are
+//pending, so a test can advance the Fake once the code under test waits
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		<-changed
+	}
+}
+
+// add schedules w, keeping waiters sorted by time and in call order for the
+// same time
+func (f *Fake) add(w *waiter) {
+	i := sort.Search(len(f.waiters), func(i int) bool {
+		return f.waiters[i].at.After(w.at)
+	})
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[i+1:], f.waiters[i:])
+	f.waiters[i] = w
+	f.notify()
+}
+
+func (f *Fake) remove(w *waiter) {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notify()
+			return
+		}
+	}
+}
+
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+	t.w.period = d
+	t.w.at = t.f.now.Add(d)
+	t.f.add(t.w)
+}
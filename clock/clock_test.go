@@ -0,0 +1,90 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestFake(t *testing.T) {
+	f := NewFake(start)
+	assert.Equal(t, start, f.Now())
+
+	a := f.After(2 * time.Second)
+	b := f.After(time.Second)
+	assert.Equal(t, 2, f.Waiters())
+	select {
+	case <-f.After(0):
+	default:
+		t.Error("After(0) should fire immediately")
+	}
+
+	f.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-b)
+	assert.Len(t, a, 0)
+	assert.Equal(t, time.Second, f.Since(start))
+
+	f.Set(start.Add(time.Minute))
+	assert.Equal(t, start.Add(2*time.Second), <-a)
+	assert.Equal(t, 0, f.Waiters())
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(start)
+	done := make(chan time.Time)
+	go func() {
+		f.Sleep(time.Hour)
+		done <- f.Now()
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(59 * time.Minute)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned early")
+	case <-time.After(10 * time.Millisecond):
+	}
+	f.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Hour), <-done)
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(start)
+	tk := f.NewTicker(time.Second)
+
+	f.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-tk.C())
+
+	// ticks are dropped for slow receivers
+	f.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-tk.C())
+	assert.Len(t, tk.C(), 0)
+
+	tk.Reset(time.Minute)
+	f.Advance(time.Second)
+	assert.Len(t, tk.C(), 0)
+	f.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute+4*time.Second), <-tk.C())
+
+	tk.Stop()
+	assert.Equal(t, 0, f.Waiters())
+	f.Advance(time.Hour)
+	assert.Len(t, tk.C(), 0)
+
+	assert.Panics(t, func() { f.NewTicker(0) })
+}
+
+func TestReal(t *testing.T) {
+	c := Real()
+	now := c.Now()
+	c.Sleep(time.Millisecond)
+	assert.True(t, c.Since(now) >= time.Millisecond)
+	<-c.After(time.Millisecond)
+
+	tk := c.NewTicker(time.Millisecond)
+	<-tk.C()
+	tk.Stop()
+}
@@ -0,0 +1,115 @@
+//benchdiff compares two runs of go test -bench like benchstat, colors
+//regressions and improvements, and exits with status 1 when a benchmark
+//regressed, so CI can fail on slowdowns.
+//
+//	go test -run=NONE -bench=. -count=10 > old.txt
+//	go test -run=NONE -bench=. -count=10 > new.txt
+//	benchdiff [flags] old.txt new.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/prasek/loupe/benchdiff"
+	"github.com/prasek/loupe/tools"
+)
+
+const usage = `usage: benchdiff [flags] old.txt new.txt
+
+Compares the go test -bench output in old.txt and new.txt and exits 0 if no
+benchmark regressed, 1 if one did and 2 if there was an error.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("benchdiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	alpha := fs.Float64("alpha", 0.05, "significance level, changes with a higher p-value are ignored")
+	threshold := fs.Float64("threshold", 0, "smallest slowdown, in percent, that fails")
+	asJSON := fs.Bool("json", false, "write the comparison as JSON instead of tables")
+	report := fs.String("report", "", "also write the comparison as JSON to this file")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	before, err := parseFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "benchdiff: %v\n", err)
+		return 2
+	}
+	after, err := parseFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "benchdiff: %v\n", err)
+		return 2
+	}
+	c := benchdiff.Compare(before, after, benchdiff.WithAlpha(*alpha), benchdiff.WithThreshold(*threshold))
+
+	var opts []tools.Option
+	if *noColor {
+		opts = append(opts, tools.WithNoColor())
+	}
+	if *asJSON {
+		err = c.WriteJSON(stdout)
+	} else {
+		err = c.WriteText(stdout, opts...)
+	}
+	if err == nil && *report != "" {
+		err = writeReport(*report, c)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "benchdiff: %v\n", err)
+		return 2
+	}
+
+	if n := len(c.Regressions()); n > 0 {
+		if n == 1 {
+			fmt.Fprintln(stderr, "benchdiff: 1 regression")
+		} else {
+			fmt.Fprintf(stderr, "benchdiff: %d regressions\n", n)
+		}
+		return 1
+	}
+	return 0
+}
+
+func parseFile(path string) ([]benchdiff.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	results, err := benchdiff.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return results, nil
+}
+
+func writeReport(path string, c *benchdiff.Comparison) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
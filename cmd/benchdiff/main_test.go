@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath, newPath := "../../benchdiff/testdata/old.txt", "../../benchdiff/testdata/new.txt"
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	report := filepath.Join(dir, "report.json")
+	code, out, errOut := run("--no-color", "--report", report, oldPath, newPath)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "benchdiff: 1 regression\n", errOut)
+	assert.True(t, strings.HasPrefix(out, "pkg: example.com/codec\nname       old time/op  new time/op    delta\nEncode-8   1.20µs ± 1%  1.50µs ± 1%  +25.00%  (p=0.008 n=5+5)\n"), out)
+	bs, err := ioutil.ReadFile(report)
+	if assert.NoError(t, err) {
+		assert.True(t, json.Valid(bs))
+	}
+
+	code, _, errOut = run("--threshold", "30", oldPath, newPath)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", errOut)
+
+	code, out, _ = run("--json", oldPath, oldPath)
+	assert.Equal(t, 0, code)
+	var c struct {
+		Benchmarks []struct{ Name string }
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &c))
+	assert.Len(t, c.Benchmarks, 6)
+
+	code, _, errOut = run(oldPath, filepath.Join(dir, "missing.txt"))
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "benchdiff: open ")
+
+	code, _, errOut = run(oldPath)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "usage: benchdiff")
+}
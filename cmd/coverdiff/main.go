@@ -0,0 +1,85 @@
+//coverdiff compares two coverage profiles of go test -coverprofile by file
+//and function, and exits with status 1 when a touched file is below the
+//minimum coverage or its coverage dropped, so CI can fail on changes that
+//aren't tested.
+//
+//	coverdiff [flags] before.out after.out
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/prasek/loupe/coverdiff"
+	"github.com/prasek/loupe/tools"
+)
+
+const usage = `usage: coverdiff [flags] before.out after.out
+
+Compares the coverprofiles before.out and after.out and exits 0 if the gates
+pass, 1 if a touched file fails one and 2 if there was an error.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("coverdiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	minCoverage := fs.Float64("min", -1, "minimum coverage of touched files in percent, off when negative")
+	maxDrop := fs.Float64("max-drop", -1, "largest coverage drop of touched files in percentage points, off when negative")
+	beforeSrc := fs.String("before-src", "", "module directory before.out was created from, the current module by default")
+	afterSrc := fs.String("after-src", "", "module directory after.out was created from, the current module by default")
+	asJSON := fs.Bool("json", false, "write the report as JSON")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []coverdiff.Option{coverdiff.WithMinCoverage(*minCoverage), coverdiff.WithMaxDrop(*maxDrop)}
+	if *beforeSrc != "" || *afterSrc != "" {
+		opts = append(opts, coverdiff.WithSourceDirs(*beforeSrc, *afterSrc))
+	}
+	r, err := coverdiff.CompareFiles(fs.Arg(0), fs.Arg(1), opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "coverdiff: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		var bs []byte
+		bs, err = json.MarshalIndent(r, "", "  ")
+		if err == nil {
+			_, err = stdout.Write(append(bs, '\n'))
+		}
+	} else {
+		var topts []tools.Option
+		if *noColor {
+			topts = append(topts, tools.WithNoColor())
+		}
+		err = r.WriteText(stdout, topts...)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "coverdiff: %v\n", err)
+		return 2
+	}
+
+	if r.Failed() {
+		return 1
+	}
+	return 0
+}
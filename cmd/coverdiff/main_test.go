@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+	testdata := "../../coverdiff/testdata/"
+	src := []string{"--before-src", testdata + "before", "--after-src", testdata + "after"}
+
+	code, out, _ := run(append(src, "--no-color", "--min", "70", testdata+"before.out", testdata+"after.out")...)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "  Div:7                    66.7%  100.0%  +33.3\n")
+	assert.Contains(t, out, "FAIL: coverage of example.com/calc/util.go is 66.7%, below the minimum of 70.0%\n")
+
+	code, _, _ = run(append(src, "--max-drop", "0", testdata+"before.out", testdata+"after.out")...)
+	assert.Equal(t, 0, code)
+
+	code, out, _ = run("--json", testdata+"before.out", testdata+"after.out")
+	assert.Equal(t, 0, code)
+	var r struct {
+		Files []struct{ Name string }
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &r))
+	assert.Len(t, r.Files, 2)
+
+	code, _, errOut := run(testdata+"missing.out", testdata+"after.out")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "coverdiff: open ")
+
+	code, _, errOut = run()
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "usage: coverdiff")
+}
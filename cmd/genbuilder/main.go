@@ -0,0 +1,342 @@
+//genbuilder generates fluent builders for struct types, so tests can
+//construct fixtures tersely and compare them with tools.DiffValues. Use it
+//with go:generate next to the type:
+//
+//	//go:generate go run github.com/prasek/loupe/cmd/genbuilder -type User
+//
+//For a type User it writes user_builder_test.go with a UserBuilder that
+//starts from the zero User, a With<Field> method per field and Build:
+//
+//	u := NewUserBuilder().WithName("alice").WithTags("admin").Build()
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const usage = `usage: genbuilder -type T[,U...] [flags]
+
+Generates a fluent builder for each struct type in the package in dir.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("genbuilder", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	types := fs.String("type", "", "comma separated struct type names, required")
+	dir := fs.String("dir", ".", "directory of the package declaring the types")
+	output := fs.String("output", "", "output file, default <type>_builder_test.go, or builders_test.go for several types")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *types == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	names := strings.Split(*types, ",")
+	src, err := generate(*dir, names)
+	if err != nil {
+		fmt.Fprintf(stderr, "genbuilder: %v\n", err)
+		return 1
+	}
+
+	out := *output
+	if out == "" {
+		out = "builders_test.go"
+		if len(names) == 1 {
+			out = strings.ToLower(names[0]) + "_builder_test.go"
+		}
+	}
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(*dir, out)
+	}
+	if err := ioutil.WriteFile(out, src, 0644); err != nil {
+		fmt.Fprintf(stderr, "genbuilder: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// structType is a struct type found in the package and the file declaring it
+type structType struct {
+	name string
+	typ  *ast.StructType
+	file *ast.File
+}
+
+// generate returns the formatted source of the builders for the struct
+// types names declared in the package in dir
+func generate(dir string, names []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg string
+	var found []structType
+	for _, name := range names {
+		var st *structType
+		for pn, p := range pkgs {
+			if s := findStruct(p, name); s != nil {
+				st, pkg = s, pn
+				break
+			}
+		}
+		if st == nil {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		found = append(found, *st)
+	}
+
+	g := &generator{fset: fset, imports: make(map[string]string)}
+	var body bytes.Buffer
+	for _, st := range found {
+		if err := g.builder(&body, st); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genbuilder -type %s; DO NOT EDIT.\n\npackage %s\n", strings.Join(names, ","), pkg)
+	if len(g.imports) > 0 {
+		buf.WriteString("\nimport (\n")
+		std, other := g.sortedImports()
+		for _, spec := range std {
+			fmt.Fprintf(&buf, "\t%s\n", spec)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, spec := range other {
+			fmt.Fprintf(&buf, "\t%s\n", spec)
+		}
+		buf.WriteString(")\n")
+	}
+	body.WriteTo(&buf)
+	return format.Source(buf.Bytes())
+}
+
+func findStruct(p *ast.Package, name string) *structType {
+	files := make([]string, 0, len(p.Files))
+	for f := range p.Files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, fn := range files {
+		f := p.Files[fn]
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if st, ok := ts.Type.(*ast.StructType); ok && ts.Name.Name == name {
+					return &structType{name: name, typ: st, file: f}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	fset *token.FileSet
+
+	// imports maps the import paths used by fields to their import specs
+	imports map[string]string
+}
+
+// builder writes the builder of st
+func (g *generator) builder(w io.Writer, st structType) error {
+	b := st.name + "Builder"
+	fmt.Fprintf(w, `
+// %[1]s builds %[2]s values, starting from the zero value.
+type %[1]s struct {
+	v %[2]s
+}
+
+// New%[1]s returns a %[1]s for the zero %[2]s.
+func New%[1]s() *%[1]s {
+	return &%[1]s{}
+}
+
+// %[1]sFrom returns a %[1]s starting from a copy of v.
+func %[1]sFrom(v %[2]s) *%[1]s {
+	return &%[1]s{v: v}
+}
+`, b, st.name)
+
+	for _, f := range st.typ.Fields.List {
+		typ, err := g.typeString(f.Type, st.file)
+		if err != nil {
+			return err
+		}
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(embeddedName(f.Type))}
+		}
+		for _, n := range names {
+			if n.Name == "_" {
+				continue
+			}
+			param, assign := "v "+typ, "v"
+			if at, ok := f.Type.(*ast.ArrayType); ok && at.Len == nil {
+				elem, err := g.typeString(at.Elt, st.file)
+				if err != nil {
+					return err
+				}
+				param = "v ..." + elem
+			}
+			fmt.Fprintf(w, `
+// With%[3]s sets %[4]s.
+func (b *%[1]s) With%[3]s(%[5]s) *%[1]s {
+	b.v.%[4]s = %[6]s
+	return b
+}
+`, b, st.name, exported(n.Name), n.Name, param, assign)
+		}
+	}
+
+	fmt.Fprintf(w, `
+// Build returns the %[2]s.
+func (b *%[1]s) Build() %[2]s {
+	return b.v
+}
+
+// BuildPtr returns a pointer to a copy of the %[2]s.
+func (b *%[1]s) BuildPtr() *%[2]s {
+	v := b.v
+	return &v
+}
+`, b, st.name)
+	return nil
+}
+
+// typeString prints the type expression e and records the imports of f it
+// refers to
+func (g *generator) typeString(e ast.Expr, f *ast.File) (string, error) {
+	var err error
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if spec, ok := importSpec(f, x.Name); ok {
+				g.imports[spec.path] = spec.String()
+			} else if err == nil {
+				err = fmt.Errorf("no import for %s in %s", x.Name, g.fset.File(f.Pos()).Name())
+			}
+		}
+		return false
+	})
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, e)
+	return buf.String(), err
+}
+
+type importRef struct {
+	name string
+	path string
+}
+
+func (r importRef) String() string {
+	if r.name == guessName(r.path) {
+		return strconv.Quote(r.path)
+	}
+	return r.name + " " + strconv.Quote(r.path)
+}
+
+// importSpec finds the import of f with the local name name
+func importSpec(f *ast.File, name string) (importRef, bool) {
+	for _, is := range f.Imports {
+		p, err := strconv.Unquote(is.Path.Value)
+		if err != nil {
+			continue
+		}
+		local := guessName(p)
+		if is.Name != nil {
+			local = is.Name.Name
+		}
+		if local == name {
+			return importRef{name: local, path: p}, true
+		}
+	}
+	return importRef{}, false
+}
+
+var version = regexp.MustCompile(`[.]v[0-9]+$`)
+
+// guessName is the package name an import path is usually imported as,
+// e.g. yaml for gopkg.in/yaml.v3 and isatty for go-isatty
+func guessName(p string) string {
+	name := version.ReplaceAllString(path.Base(p), "")
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// embeddedName is the field name of an embedded type
+func embeddedName(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// sortedImports returns the import specs sorted by path, standard library
+// packages first like goimports groups them
+func (g *generator) sortedImports() (std, other []string) {
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			other = append(other, g.imports[p])
+		} else {
+			std = append(std, g.imports[p])
+		}
+	}
+	return std, other
+}
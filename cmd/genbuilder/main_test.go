@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("testdata", []string{"User", "Base"})
+	if assert.NoError(t, err) {
+		golden.Assert(t, src, filepath.Join("testdata", "user_builder.golden"))
+	}
+
+	_, err = generate("testdata", []string{"Missing"})
+	assert.EqualError(t, err, "struct type Missing not found in testdata")
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genbuilder")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "user.go"))
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "user.go"), bs, 0666))
+
+	var stderr bytes.Buffer
+	assert.Equal(t, 0, run([]string{"-type", "User", "-dir", dir}, &stderr))
+	assert.Empty(t, stderr.String())
+	out, err := ioutil.ReadFile(filepath.Join(dir, "user_builder_test.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "func (b *UserBuilder) WithTags(v ...string) *UserBuilder {")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run(nil, &stderr))
+	assert.Contains(t, stderr.String(), "usage: genbuilder")
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"-type", "Nope", "-dir", dir}, &stderr))
+	assert.Contains(t, stderr.String(), "genbuilder: struct type Nope not found")
+}
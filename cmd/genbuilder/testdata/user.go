@@ -0,0 +1,25 @@
+package models
+
+import (
+	"net/url"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+type Base struct {
+	ID int
+}
+
+type User struct {
+	Base
+	Name, Email string
+	Tags        []string
+	Roles       map[string]bool
+	Created     time.Time
+	Homepage    *url.URL
+	Config      yaml.Node
+	Scores      [3]int
+	deleted     bool
+	_           struct{}
+}
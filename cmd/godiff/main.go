@@ -0,0 +1,147 @@
+//godiff compares two files with the loupe diff tools and exits with status
+//1 when they differ, so the same diff output can be used from shell based
+//test harnesses.
+//
+//	godiff [flags] a b
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/prasek/loupe/tools"
+)
+
+const usage = `usage: godiff [flags] a b
+
+Compares files a and b and exits 0 if they are equal, 1 if they differ and
+2 if there was an error.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("godiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "compare as JSON documents, ignoring key order and formatting")
+	asYAML := fs.Bool("yaml", false, "compare as YAML documents, ignoring key order, comments and formatting")
+	asTOML := fs.Bool("toml", false, "compare as TOML documents, ignoring key and table order, comments and formatting")
+	asHTML := fs.Bool("html", false, "write the diff as a standalone HTML page")
+	sideBySide := fs.Bool("side-by-side", false, "use the side by side HTML layout, implies --html")
+	asMarkdown := fs.Bool("markdown", false, "write the diff as Markdown for a pull request comment")
+	collapsed := fs.Bool("collapsed", false, "put each hunk in a collapsed <details> element, implies --markdown")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	context := fs.Int("U", 3, "number of context lines")
+	allSpace := fs.Bool("w", false, "ignore all whitespace")
+	spaceChange := fs.Bool("b", false, "ignore changes in the amount of whitespace")
+	blankLines := fs.Bool("ignore-blank-lines", false, "ignore inserted and deleted blank lines")
+	trailingSpace := fs.Bool("ignore-space-at-eol", false, "ignore whitespace at the end of lines")
+	stripCR := fs.Bool("strip-trailing-cr", false, "compare CRLF and LF line endings as equal")
+	algorithm := fs.String("diff-algorithm", "myers", "line diff algorithm: myers, patience or histogram")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	algorithms := map[string]tools.Algorithm{
+		"myers":     tools.Myers,
+		"patience":  tools.Patience,
+		"histogram": tools.Histogram,
+	}
+	alg, ok := algorithms[*algorithm]
+	if !ok {
+		fmt.Fprintf(stderr, "godiff: unknown diff algorithm %q\n", *algorithm)
+		return 2
+	}
+
+	pathA, pathB := fs.Arg(0), fs.Arg(1)
+	a, err := ioutil.ReadFile(pathA)
+	if err != nil {
+		fmt.Fprintf(stderr, "godiff: %v\n", err)
+		return 2
+	}
+	b, err := ioutil.ReadFile(pathB)
+	if err != nil {
+		fmt.Fprintf(stderr, "godiff: %v\n", err)
+		return 2
+	}
+
+	opts := []tools.Option{
+		tools.WithLabels(pathA, pathB),
+		tools.WithContextLines(*context),
+		tools.WithAlgorithm(alg),
+	}
+	if *noColor {
+		opts = append(opts, tools.WithNoColor())
+	}
+	switch {
+	case *allSpace:
+		opts = append(opts, tools.IgnoreAllSpace())
+	case *spaceChange:
+		opts = append(opts, tools.IgnoreSpaceChange())
+	case *trailingSpace:
+		opts = append(opts, tools.IgnoreTrailingWhitespace())
+	}
+	if *blankLines {
+		opts = append(opts, tools.IgnoreBlankLines())
+	}
+	if *stripCR {
+		opts = append(opts, tools.IgnoreLineEndings())
+	}
+
+	var d tools.Differ
+	switch {
+	case *asJSON:
+		d = tools.DiffJSON(a, b, opts...)
+	case *asYAML:
+		d = tools.DiffYAML(a, b, opts...)
+	case *asTOML:
+		d = tools.DiffTOML(a, b, opts...)
+	default:
+		d = tools.Diff(a, b, append(opts, tools.WithMode(tools.LineMode))...)
+	}
+	if err := d.Error(); err != nil {
+		fmt.Fprintf(stderr, "godiff: %v\n", err)
+		return 2
+	}
+
+	switch {
+	case *asHTML || *sideBySide:
+		layout := tools.Inline
+		if *sideBySide {
+			layout = tools.SideBySide
+		}
+		err = d.HTML(stdout, layout)
+	case *asMarkdown || *collapsed:
+		style := tools.Fenced
+		if *collapsed {
+			style = tools.Collapsed
+		}
+		err = d.Markdown(stdout, style)
+	default:
+		_, err = d.WriteTo(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "godiff: %v\n", err)
+		return 2
+	}
+
+	if !d.Equal() {
+		return 1
+	}
+	return 0
+}
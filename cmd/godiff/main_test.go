@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godiff")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0666))
+		return path
+	}
+	a := write("a.txt", "a\nb\nc\n")
+	b := write("b.txt", "a\nB\nc\n")
+	ja := write("a.json", `{"a":1,"b":2}`)
+	jb := write("b.json", `{"b":2, "a":1}`)
+	jc := write("c.json", `{"a":1,"b":3}`)
+
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, out, _ := run("--no-color", a, b)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "--- "+a+"\n+++ "+b+"\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n", out)
+
+	code, out, _ = run("--no-color", a, a)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", out)
+
+	code, _, _ = run("--json", ja, jb)
+	assert.Equal(t, 0, code)
+
+	code, out, _ = run("--json", "--no-color", ja, jc)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "/b:\n-2\n+3\n", out)
+
+	code, out, _ = run("--side-by-side", a, b)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, "colspan=\"4\"")
+
+	ta := write("a.toml", "[x]\na = 1\nb = 2\n")
+	tb := write("b.toml", "x.b = 2\nx.a = 1\n")
+	code, _, _ = run("--toml", ta, tb)
+	assert.Equal(t, 0, code)
+
+	code, out, _ = run("--markdown", a, b)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "```diff\n--- "+a+"\n+++ "+b+"\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n```\n", out)
+
+	code, out, _ = run("--collapsed", a, b)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(out, "<details>\n<summary>"+b+" @@ -1,3 +1,3 @@</summary>\n"))
+
+	code, out, _ = run("--no-color", "--diff-algorithm=histogram", a, b)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "--- "+a+"\n+++ "+b+"\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n", out)
+
+	c := write("c.txt", "a\r\n  b\r\nc\r\n")
+	code, _, _ = run("-w", "--strip-trailing-cr", a, c)
+	assert.Equal(t, 0, code)
+
+	code, _, _ = run("-b", a, c)
+	assert.Equal(t, 1, code)
+
+	code, _, errOut := run("--diff-algorithm=git", a, b)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "unknown diff algorithm")
+
+	code, _, errOut = run(a)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "usage: godiff")
+
+	code, _, errOut = run(a, filepath.Join(dir, "missing"))
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "godiff: ")
+
+	invalid := write("invalid.json", `{"a":`)
+	code, out, errOut = run("--json", invalid, invalid)
+	assert.Equal(t, 2, code)
+	assert.Empty(t, out)
+	assert.Contains(t, errOut, "godiff: ")
+}
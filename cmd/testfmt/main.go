@@ -0,0 +1,162 @@
+//testfmt renders go test -json output with colored results, the elapsed
+//time of each package, the failures grouped at the end with their diffs
+//colored and a summary, and exits with status 1 when a test failed. When
+//it runs go test itself, -rerun-fails reruns the failed tests to tell flaky
+//tests from failing ones, -history records them and -quarantine skips the
+//flaky ones.
+//
+//	go test -json ./... | testfmt [flags]
+//	testfmt [flags] [--] [go test flags] [packages]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/prasek/loupe/report"
+	"github.com/prasek/loupe/testjson"
+	"github.com/prasek/loupe/tools"
+)
+
+const usage = `usage: go test -json [packages] | testfmt [flags]
+       testfmt [flags] [--] [go test flags] [packages]
+
+Renders the go test -json events read from stdin or, when arguments are
+given, of running go test -json with them; go test flags follow --. Exits 0
+if all tests passed, 1 if a test or package failed and 2 if there was an
+error. With -rerun-fails, tests that pass on a rerun are flaky and don't
+fail the run.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("testfmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "also print each test as it finishes")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	reruns := fs.Int("rerun-fails", 0, "rerun failed tests `n` times, the ones that pass are flaky")
+	history := fs.String("history", "", "record the flaky and failing tests in the JSON `file`")
+	quarantine := fs.Bool("quarantine", false, "skip the flaky tests in the -history file that call report.Quarantine")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*reruns > 0 || *quarantine) && fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "testfmt: -rerun-fails and -quarantine need packages to run")
+		return 2
+	}
+	if *quarantine && *history == "" {
+		fmt.Fprintln(stderr, "testfmt: -quarantine needs a -history file")
+		return 2
+	}
+
+	var opts []testjson.Option
+	var colors []tools.Option
+	if *verbose {
+		opts = append(opts, testjson.WithVerbose())
+	}
+	if *noColor {
+		opts = append(opts, testjson.WithColor(tools.ColorNever))
+		colors = append(colors, tools.WithColor(tools.ColorNever))
+	}
+
+	env := os.Environ()
+	if *quarantine {
+		// go test runs each package in its own directory
+		path, err := filepath.Abs(*history)
+		if err != nil {
+			fmt.Fprintf(stderr, "testfmt: %v\n", err)
+			return 2
+		}
+		env = append(env, "LOUPE_QUARANTINE="+path)
+	}
+
+	var cmd *exec.Cmd
+	in := stdin
+	if fs.NArg() > 0 {
+		cmd = exec.Command("go", append([]string{"test", "-json"}, fs.Args()...)...)
+		cmd.Env = env
+		cmd.Stderr = stderr
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			fmt.Fprintf(stderr, "testfmt: %v\n", err)
+			return 2
+		}
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(stderr, "testfmt: %v\n", err)
+			return 2
+		}
+		in = pipe
+	}
+
+	s, err := testjson.Format(in, stdout, opts...)
+	if cmd != nil {
+		// go test exits 1 when a test failed, which the summary reports
+		if werr := cmd.Wait(); werr != nil && err == nil && s.OK() {
+			err = werr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "testfmt: %v\n", err)
+		return 2
+	}
+	if *reruns == 0 || s.OK() {
+		if !s.OK() {
+			return 1
+		}
+		return 0
+	}
+
+	results, err := rerunFailures(s.Failures, fs.Args(), *reruns, env, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "testfmt: rerun: %v\n", err)
+		return 2
+	}
+	writeReruns(stdout, results, tools.NewPalette(stdout, colors...))
+	if *history != "" {
+		if err := record(*history, results); err != nil {
+			fmt.Fprintf(stderr, "testfmt: %v\n", err)
+			return 2
+		}
+	}
+
+	code := 0
+	for _, f := range s.Failures {
+		if f.Test == "" {
+			code = 1
+		}
+	}
+	for _, r := range results {
+		if !r.flaky() {
+			code = 1
+		}
+	}
+	return code
+}
+
+// record adds the reruns to the history at path, counting the failed first
+// run too
+func record(path string, reruns []rerun) error {
+	h, err := report.LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, r := range reruns {
+		h.Record(r.pkg, r.test, r.runs+1, r.failures+1, now)
+	}
+	return h.Save(path)
+}
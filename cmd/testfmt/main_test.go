@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	run := func(stdin io.Reader, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, stdin, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	f, err := os.Open("../../testjson/testdata/run.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	code, out, _ := run(f, "--no-color", "-v")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "--- FAIL: TestDiv/by_zero (0.000s)\n")
+	assert.True(t, strings.HasSuffix(out, "\nDONE 6 tests, 1 skipped, 2 failures, 1 package error in 0.424s\n"), out)
+
+	code, out, _ = run(strings.NewReader(`{"Action":"pass","Package":"p","Elapsed":0.5}`+"\n"), "--no-color")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ok   p\t0.500s\n\nDONE 0 tests in 0.000s\n", out)
+
+	code, _, errOut := run(nil, "--bogus")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "usage: go test -json")
+}
+
+func TestRunGoTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	code, out, errOut := func() (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--no-color", "--", "-count=1", "./testdata/pass"}, nil, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}()
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, out, "/cmd/testfmt/testdata/pass\t")
+	assert.Contains(t, out, "DONE 1 test in ")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/prasek/loupe/testjson"
+	"github.com/prasek/loupe/tools"
+)
+
+// rerun is a failed test and how it did when it was rerun
+type rerun struct {
+	pkg, test      string
+	runs, failures int
+}
+
+// flaky reports whether the test passed on a rerun
+func (r rerun) flaky() bool {
+	return r.failures < r.runs
+}
+
+// valueFlags are the go test flags that take a value, which may be the
+// next argument
+var valueFlags = map[string]bool{
+	"asmflags": true, "bench": true, "benchtime": true, "blockprofile": true,
+	"blockprofilerate": true, "C": true, "count": true, "covermode": true,
+	"coverpkg": true, "coverprofile": true, "cpu": true, "cpuprofile": true,
+	"exec": true, "fuzz": true, "fuzztime": true, "gccgoflags": true,
+	"gcflags": true, "installsuffix": true, "ldflags": true, "list": true,
+	"memprofile": true, "memprofilerate": true, "mod": true, "modfile": true,
+	"mutexprofile": true, "mutexprofilefraction": true, "o": true,
+	"outputdir": true, "overlay": true, "p": true, "parallel": true,
+	"pgo": true, "pkgdir": true, "run": true, "shuffle": true, "skip": true,
+	"tags": true, "timeout": true, "toolexec": true, "trace": true,
+	"vet": true,
+}
+
+// splitArgs splits go test arguments into flags, packages and the
+// arguments after -args, which go to the test binaries
+func splitArgs(args []string) (flags, pkgs, binArgs []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			return flags, pkgs, args[i:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			pkgs = append(pkgs, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if !strings.Contains(name, "=") && valueFlags[name] && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags, pkgs, nil
+}
+
+// rerunFailures runs the top-level tests of the failed tests n more times,
+// a go test run per package with the flags of the first run, and counts
+// how often each failed test passed. Failed packages without a failed test
+// can't be rerun and are left out.
+func rerunFailures(failures []testjson.TestResult, args []string, n int, env []string, stderr io.Writer) ([]rerun, error) {
+	flags, _, binArgs := splitArgs(args)
+	var pkgs []string
+	tests := map[string][]string{}
+	for _, f := range failures {
+		if f.Test == "" {
+			continue
+		}
+		if _, ok := tests[f.Package]; !ok {
+			pkgs = append(pkgs, f.Package)
+		}
+		tests[f.Package] = append(tests[f.Package], f.Test)
+	}
+
+	var reruns []rerun
+	for _, pkg := range pkgs {
+		var tops []string
+		seen := map[string]bool{}
+		for _, test := range tests[pkg] {
+			top := strings.SplitN(test, "/", 2)[0]
+			if !seen[top] {
+				seen[top] = true
+				tops = append(tops, regexp.QuoteMeta(top))
+			}
+		}
+
+		a := append([]string{"test", "-json"}, flags...)
+		a = append(a, fmt.Sprintf("-count=%d", n), "-run", "^("+strings.Join(tops, "|")+")$", pkg)
+		a = append(a, binArgs...)
+		results, err := runEvents(exec.Command("go", a...), env, stderr)
+		if err != nil {
+			return nil, err
+		}
+		for _, test := range tests[pkg] {
+			r := results[test]
+			reruns = append(reruns, rerun{pkg: pkg, test: test, runs: r.runs, failures: r.failures})
+		}
+	}
+	return reruns, nil
+}
+
+// runEvents runs go test -json and counts the runs and failures of each
+// test, the exit status is ignored since the failures are counted
+func runEvents(cmd *exec.Cmd, env []string, stderr io.Writer) (map[string]rerun, error) {
+	cmd.Env = env
+	cmd.Stderr = stderr
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	results := map[string]rerun{}
+	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e testjson.Event
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Test == "" {
+			continue
+		}
+		r := results[e.Test]
+		switch e.Action {
+		case "pass":
+			r.runs++
+		case "fail":
+			r.runs++
+			r.failures++
+		}
+		results[e.Test] = r
+	}
+	err = scanner.Err()
+	cmd.Wait()
+	return results, err
+}
+
+// writeReruns writes how each failed test did when it was rerun
+func writeReruns(w io.Writer, reruns []rerun, palette tools.Palette) {
+	fmt.Fprintf(w, "\n%s\n", palette.Dim("=== Rerun"))
+	for _, r := range reruns {
+		if r.flaky() {
+			fmt.Fprintf(w, "%s %s %s (passed %d of %d reruns)\n", palette.Dim("FLAKY"), r.pkg, r.test, r.runs-r.failures, r.runs)
+		} else {
+			fmt.Fprintf(w, "%s  %s %s (failed %d of %d reruns)\n", palette.Delete("FAIL"), r.pkg, r.test, r.failures, r.runs)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	flags, pkgs, binArgs := splitArgs([]string{"-race", "-run", "TestA", "./a", "-timeout=5m", "--tags", "e2e", "./b/...", "-args", "-update", "x"})
+	assert.Equal(t, []string{"-race", "-run", "TestA", "-timeout=5m", "--tags", "e2e"}, flags)
+	assert.Equal(t, []string{"./a", "./b/..."}, pkgs)
+	assert.Equal(t, []string{"-args", "-update", "x"}, binArgs)
+}
+
+func TestRerun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	dir, err := ioutil.TempDir("", "testfmt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	history := filepath.Join(dir, "flaky.json")
+	defer os.Setenv("TESTFMT_STATE", os.Getenv("TESTFMT_STATE"))
+	os.Setenv("TESTFMT_STATE", filepath.Join(dir, "state"))
+
+	const pkg = "github.com/prasek/loupe/cmd/testfmt/testdata/flaky"
+	run := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, nil, &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	code, out := run("--no-color", "--rerun-fails", "2", "--history", history, "--", "-count=1", "./testdata/flaky")
+	assert.Equal(t, 1, code, out)
+	assert.Contains(t, out, "\n=== Rerun\n")
+	assert.Contains(t, out, "\nFLAKY "+pkg+" TestFlaky (passed 2 of 2 reruns)\n")
+	assert.Contains(t, out, "\nFAIL  "+pkg+" TestBroken/sub (failed 2 of 2 reruns)\n")
+
+	h, err := report.LoadHistory(history)
+	if assert.NoError(t, err) && assert.Len(t, h.Tests, 2) {
+		assert.Equal(t, "TestBroken/sub", h.Tests[0].Test)
+		assert.Equal(t, report.Failing, h.Tests[0].Status)
+		assert.Equal(t, 3, h.Tests[0].Failures)
+		assert.Equal(t, "TestFlaky", h.Tests[1].Test)
+		assert.Equal(t, report.Flaky, h.Tests[1].Status)
+		assert.Equal(t, 3, h.Tests[1].Runs)
+		assert.Equal(t, 1, h.Tests[1].Failures)
+	}
+
+	os.Remove(filepath.Join(dir, "state"))
+	code, out = run("--no-color", "-v", "--quarantine", "--history", history, "--", "-count=1", "-run", "TestFlaky", "./testdata/flaky")
+	assert.Equal(t, 0, code, out)
+	assert.Contains(t, out, "--- SKIP: TestFlaky")
+
+	os.Remove(filepath.Join(dir, "state"))
+	code, out = run("--no-color", "--rerun-fails", "1", "--", "-count=1", "-run", "TestFlaky", "./testdata/flaky")
+	assert.Equal(t, 0, code, out)
+	assert.Contains(t, out, "FLAKY "+pkg+" TestFlaky (passed 1 of 1 reruns)\n")
+
+	code, out = run("--quarantine", "./testdata/flaky")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out, "-quarantine needs a -history file")
+	code, out = run("--rerun-fails", "1")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out, "need packages to run")
+}
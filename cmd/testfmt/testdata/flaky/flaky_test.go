@@ -0,0 +1,31 @@
+package flaky
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/prasek/loupe/report"
+)
+
+// TestFlaky fails the first time it runs after $TESTFMT_STATE was removed
+func TestFlaky(t *testing.T) {
+	state := os.Getenv("TESTFMT_STATE")
+	if state == "" {
+		t.Skip("run by the testfmt tests")
+	}
+	report.Quarantine(t)
+	if _, err := os.Stat(state); os.IsNotExist(err) {
+		ioutil.WriteFile(state, nil, 0644)
+		t.Fatal("first run")
+	}
+}
+
+func TestBroken(t *testing.T) {
+	if os.Getenv("TESTFMT_STATE") == "" {
+		t.Skip("run by the testfmt tests")
+	}
+	t.Run("sub", func(t *testing.T) {
+		t.Error("always fails")
+	})
+}
@@ -0,0 +1,69 @@
+package concurrent
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Run calls fn from n goroutines, with i from 0 to n-1, and waits for them to
+//return. The goroutines wait for each other before calling fn so the calls
+//overlap as much as possible. Panics are recovered and all of them are
+//reported in one failure, labeled with i.
+func Run(t TestingT, n int, fn func(i int)) bool {
+	t.Helper()
+	return RunE(t, n, func(i int) error {
+		fn(i)
+		return nil
+	})
+}
+
+//RunE is like Run but also reports the errors returned by fn
+func RunE(t TestingT, n int, fn func(i int) error) bool {
+	t.Helper()
+	errs := make([]string, n)
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	ready.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer done.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Sprintf("panic: %v\n%s", r, debug.Stack())
+				}
+			}()
+			ready.Done()
+			<-start
+			if err := fn(i); err != nil {
+				errs[i] = err.Error()
+			}
+		}(i)
+	}
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	var buf bytes.Buffer
+	failed := 0
+	for i, err := range errs {
+		if err != "" {
+			failed++
+			fmt.Fprintf(&buf, "\n[goroutine %d] %s", i, err)
+		}
+	}
+	if failed > 0 {
+		t.Errorf("%d of %d goroutines failed:%s", failed, n, buf.String())
+		return false
+	}
+	return true
+}
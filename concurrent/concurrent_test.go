@@ -0,0 +1,48 @@
+package concurrent
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	assert.True(t, Run(t, 8, func(i int) {
+		mu.Lock()
+		seen[i] = true
+		mu.Unlock()
+	}))
+	assert.Len(t, seen, 8)
+
+	m := tools.Mock()
+	ok := Run(m, 4, func(i int) {
+		if i%2 == 1 {
+			panic("boom")
+		}
+	})
+	res := m.Results()
+	assert.False(t, ok)
+	assert.True(t, strings.HasPrefix(res.Err, "2 of 4 goroutines failed:\n[goroutine 1] panic: boom\n"))
+	assert.Contains(t, res.Err, "\n[goroutine 3] panic: boom\n")
+	assert.Contains(t, res.Err, "concurrent_test.go:")
+	assert.NotContains(t, res.Err, "[goroutine 0]")
+}
+
+func TestRunE(t *testing.T) {
+	m := tools.Mock()
+	ok := RunE(m, 3, func(i int) error {
+		if i == 2 {
+			return errors.New("conflict")
+		}
+		return nil
+	})
+	res := m.Results()
+	assert.False(t, ok)
+	assert.Equal(t, "1 of 3 goroutines failed:\n[goroutine 2] conflict", res.Err)
+}
@@ -0,0 +1,306 @@
+package containers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+	Skipf(format string, args ...interface{})
+}
+
+//DefaultTimeout is how long Run waits for a container to become ready
+const DefaultTimeout = time.Minute
+
+// docker runs the docker CLI and returns its trimmed stdout, tests replace
+// it with a fake
+var docker = func(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errNotInstalled
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("docker %s: %v: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("docker %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+var errNotInstalled = errors.New("docker is not installed")
+
+var (
+	availableOnce sync.Once
+	availableErr  error
+)
+
+//Available reports why Docker can't be used, or nil if it can. It's
+//checked once with docker info.
+func Available() error {
+	availableOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := docker(ctx, "info", "--format", "{{.ServerVersion}}")
+		if err != nil && err != errNotInstalled {
+			err = fmt.Errorf("docker daemon is not reachable: %v", err)
+		}
+		availableErr = err
+	})
+	return availableErr
+}
+
+//Probe reports whether a started container is ready, e.g. accepts
+//connections
+type Probe func(ctx context.Context, c *Container) error
+
+//Spec describes a container to run
+type Spec struct {
+	//Image is the image to run, e.g. postgres:16-alpine
+	Image string
+
+	//Env is set in the container
+	Env map[string]string
+
+	//Ports are the container ports to publish on random host ports, e.g.
+	//5432/tcp
+	Ports []string
+
+	//Cmd overrides the image's command
+	Cmd []string
+
+	//Ready is polled until it returns nil, by default the published ports
+	//are probed with TCPProbe
+	Ready Probe
+
+	//Timeout is how long to wait for Ready, DefaultTimeout if 0
+	Timeout time.Duration
+}
+
+//Container is a running container
+type Container struct {
+	//ID is the container ID
+	ID string
+
+	//Host is the host the published ports listen on
+	Host string
+
+	ports map[string]string
+}
+
+//Port returns the host port port of the container, e.g. "5432/tcp", is
+//published on
+func (c *Container) Port(port string) string {
+	return c.ports[normalizePort(port)]
+}
+
+//Addr returns the host:port address port of the container is published on
+func (c *Container) Addr(port string) string {
+	return net.JoinHostPort(c.Host, c.Port(port))
+}
+
+//Run starts a throwaway container for spec and waits until it's ready.
+//It's removed with t.Cleanup. The test is skipped if Docker isn't available
+//and stopped with the container logs if it doesn't start or become ready.
+func Run(t TestingT, spec Spec) *Container {
+	t.Helper()
+	if err := Available(); err != nil {
+		t.Skipf("containers: skipping, %v", err)
+		return nil
+	}
+
+	ctx := context.Background()
+	args := []string{"run", "-d", "--rm"}
+	for _, k := range sortedKeys(spec.Env) {
+		args = append(args, "-e", k+"="+spec.Env[k])
+	}
+	for _, p := range spec.Ports {
+		args = append(args, "-p", "127.0.0.1::"+normalizePort(p))
+	}
+	args = append(args, spec.Image)
+	args = append(args, spec.Cmd...)
+
+	id, err := docker(ctx, args...)
+	if err != nil {
+		t.Errorf("containers: start %s: %v", spec.Image, err)
+		t.FailNow()
+		return nil
+	}
+	c := &Container{ID: id, Host: "127.0.0.1", ports: make(map[string]string)}
+	t.Cleanup(func() {
+		docker(context.Background(), "rm", "-f", "-v", id)
+	})
+
+	for _, p := range spec.Ports {
+		out, err := docker(ctx, "port", id, normalizePort(p))
+		if err != nil {
+			failLogs(t, c, fmt.Sprintf("containers: port %s of %s: %v", p, spec.Image, err))
+			return nil
+		}
+		// one line per address, e.g. 127.0.0.1:49153
+		line := strings.SplitN(out, "\n", 2)[0]
+		_, hostPort, err := net.SplitHostPort(strings.TrimSpace(line))
+		if err != nil {
+			failLogs(t, c, fmt.Sprintf("containers: port %s of %s: unexpected %q", p, spec.Image, out))
+			return nil
+		}
+		c.ports[normalizePort(p)] = hostPort
+	}
+
+	ready := spec.Ready
+	if ready == nil {
+		ready = TCPProbe(spec.Ports...)
+	}
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if err := wait(ctx, c, ready, timeout); err != nil {
+		failLogs(t, c, fmt.Sprintf("containers: %s not ready after %s: %v", spec.Image, timeout, err))
+		return nil
+	}
+	return c
+}
+
+// wait polls ready until it succeeds or timeout passes, returning the last
+// error
+func wait(ctx context.Context, c *Container, ready Probe, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := ready(ctx, c)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// failLogs stops the test with msg and the end of the container's logs
+func failLogs(t TestingT, c *Container, msg string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if logs, err := docker(ctx, "logs", "--tail", "50", c.ID); err == nil && logs != "" {
+		msg += "\ncontainer logs:\n" + logs
+	}
+	t.Errorf("%s", msg)
+	t.FailNow()
+}
+
+//TCPProbe is ready when the published ports accept TCP connections
+func TCPProbe(ports ...string) Probe {
+	return func(ctx context.Context, c *Container) error {
+		for _, p := range ports {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", c.Addr(p))
+			if err != nil {
+				return err
+			}
+			conn.Close()
+		}
+		return nil
+	}
+}
+
+//ExecProbe is ready when cmd exits with 0 inside the container, e.g.
+//ExecProbe("pg_isready", "-U", "postgres")
+func ExecProbe(cmd ...string) Probe {
+	return func(ctx context.Context, c *Container) error {
+		_, err := docker(ctx, append([]string{"exec", c.ID}, cmd...)...)
+		return err
+	}
+}
+
+//LogProbe is ready when the container logs contain s
+func LogProbe(s string) Probe {
+	return func(ctx context.Context, c *Container) error {
+		logs, err := docker(ctx, "logs", c.ID)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(logs, s) {
+			return fmt.Errorf("logs don't contain %q yet", s)
+		}
+		return nil
+	}
+}
+
+//AllProbes is ready when all probes are ready, in order
+func AllProbes(probes ...Probe) Probe {
+	return func(ctx context.Context, c *Container) error {
+		for _, p := range probes {
+			if err := p(ctx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//Postgres runs a postgres:16-alpine container and returns it with the
+//connection URL of its postgres database, user postgres and password test
+func Postgres(t TestingT) (*Container, string) {
+	t.Helper()
+	c := Run(t, Spec{
+		Image: "postgres:16-alpine",
+		Env:   map[string]string{"POSTGRES_PASSWORD": "test"},
+		Ports: []string{"5432"},
+		Ready: AllProbes(ExecProbe("pg_isready", "-h", "127.0.0.1", "-U", "postgres"), TCPProbe("5432")),
+	})
+	if c == nil {
+		return nil, ""
+	}
+	return c, fmt.Sprintf("postgres://postgres:test@%s/postgres?sslmode=disable", c.Addr("5432"))
+}
+
+//Redis runs a redis:7-alpine container and returns it with the address of
+//the server
+func Redis(t TestingT) (*Container, string) {
+	t.Helper()
+	c := Run(t, Spec{
+		Image: "redis:7-alpine",
+		Ports: []string{"6379"},
+		Ready: AllProbes(ExecProbe("redis-cli", "ping"), TCPProbe("6379")),
+	})
+	if c == nil {
+		return nil, ""
+	}
+	return c, c.Addr("6379")
+}
+
+// normalizePort adds the default tcp protocol, 5432 becomes 5432/tcp
+func normalizePort(p string) string {
+	if strings.Contains(p, "/") {
+		return p
+	}
+	return p + "/tcp"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDocker replaces the docker CLI, port publishes every port on the
+// address of a local listener
+func fakeDocker(t *testing.T, fn func(args []string) (string, error)) *[]string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	var mu sync.Mutex
+	orig := docker
+	docker = func(ctx context.Context, args ...string) (string, error) {
+		mu.Lock()
+		calls = append(calls, strings.Join(args, " "))
+		mu.Unlock()
+		if out, err := fn(args); out != "" || err != nil {
+			return out, err
+		}
+		if args[0] == "port" {
+			return lis.Addr().String() + "\n[::1]:1", nil
+		}
+		return "", nil
+	}
+	availableOnce = sync.Once{}
+	t.Cleanup(func() {
+		docker = orig
+		availableOnce = sync.Once{}
+		lis.Close()
+	})
+	return &calls
+}
+
+func TestRun(t *testing.T) {
+	calls := fakeDocker(t, func(args []string) (string, error) {
+		if args[0] == "run" {
+			return "c1", nil
+		}
+		return "", nil
+	})
+
+	m := tools.Mock()
+	c := Run(m, Spec{Image: "redis:7", Env: map[string]string{"B": "2", "A": "1"}, Ports: []string{"6379"}, Cmd: []string{"--save", ""}})
+	if assert.NotNil(t, c) {
+		assert.Equal(t, "c1", c.ID)
+		assert.Equal(t, c.Port("6379/tcp"), c.Port("6379"))
+		assert.True(t, strings.HasPrefix(c.Addr("6379"), "127.0.0.1:"))
+	}
+	res := m.Results()
+	assert.Equal(t, "", res.Err)
+	assert.Equal(t, []string{
+		"info --format {{.ServerVersion}}",
+		"run -d --rm -e A=1 -e B=2 -p 127.0.0.1::6379/tcp redis:7 --save ",
+		"port c1 6379/tcp",
+		"rm -f -v c1",
+	}, *calls)
+}
+
+func TestRunNotReady(t *testing.T) {
+	fakeDocker(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "run":
+			return "c2", nil
+		case "exec":
+			return "", errors.New("exit status 2")
+		case "logs":
+			return "starting up", nil
+		}
+		return "", nil
+	})
+
+	m := tools.Mock()
+	c := Run(m, Spec{Image: "db", Ready: ExecProbe("check"), Timeout: 250 * time.Millisecond})
+	assert.Nil(t, c)
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.Equal(t, "containers: db not ready after 250ms: exit status 2\ncontainer logs:\nstarting up", res.Err)
+}
+
+func TestRunStartFails(t *testing.T) {
+	fakeDocker(t, func(args []string) (string, error) {
+		if args[0] == "run" {
+			return "", errors.New("docker run: exit status 125: pull access denied")
+		}
+		return "", nil
+	})
+	m := tools.Mock()
+	assert.Nil(t, Run(m, Spec{Image: "nope"}))
+	assert.Equal(t, "containers: start nope: docker run: exit status 125: pull access denied", m.Results().Err)
+}
+
+func TestSkip(t *testing.T) {
+	fakeDocker(t, func(args []string) (string, error) {
+		return "", errNotInstalled
+	})
+	m := tools.Mock()
+	c, url := Postgres(m)
+	assert.Nil(t, c)
+	assert.Equal(t, "", url)
+	res := m.Results()
+	assert.True(t, res.Skipped)
+	assert.Equal(t, "containers: skipping, docker is not installed\n", res.Log)
+
+	availableOnce = sync.Once{}
+	fakeDocker(t, func(args []string) (string, error) {
+		return "", errors.New("docker info: exit status 1: Cannot connect to the Docker daemon")
+	})
+	assert.EqualError(t, Available(), "docker daemon is not reachable: docker info: exit status 1: Cannot connect to the Docker daemon")
+}
+
+func TestProbes(t *testing.T) {
+	fakeDocker(t, func(args []string) (string, error) {
+		if args[0] == "logs" {
+			return "ready to accept connections", nil
+		}
+		return "", nil
+	})
+	c := &Container{ID: "c3"}
+	ctx := context.Background()
+	assert.NoError(t, LogProbe("ready to accept")(ctx, c))
+	assert.EqualError(t, LogProbe("done")(ctx, c), `logs don't contain "done" yet`)
+	assert.NoError(t, AllProbes(ExecProbe("true"), LogProbe("ready"))(ctx, c))
+}
+
+func TestRedis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a container")
+	}
+	c, addr := Redis(t)
+	conn, err := net.Dial("tcp", addr)
+	if assert.NoError(t, err, c.ID) {
+		conn.Close()
+	}
+}
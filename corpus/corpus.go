@@ -0,0 +1,467 @@
+package corpus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prasek/loupe/pretty"
+	"github.com/prasek/loupe/tools"
+)
+
+const header = "go test fuzz v1\n"
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Failed() bool
+	Logf(format string, args ...interface{})
+}
+
+//Entry is the arguments of one fuzz input, in the order the fuzz function
+//takes them. Values are []byte, string, bool, float32, float64 or any int
+//or uint type.
+type Entry []interface{}
+
+//Dir returns the corpus directory go test reads for the fuzz target name,
+//testdata/fuzz/<name>
+func Dir(name string) string {
+	return filepath.Join("testdata", "fuzz", name)
+}
+
+//Marshal encodes e in the go test fuzz v1 format of corpus files
+func Marshal(e Entry) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(header)
+	for i, v := range e {
+		s, err := literal(v)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %v", i, err)
+		}
+		b.WriteString(s)
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// literal returns v as a Go conversion expression like go test writes it
+func literal(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case []byte:
+		return fmt.Sprintf("[]byte(%q)", t), nil
+	case string:
+		return fmt.Sprintf("string(%q)", t), nil
+	case rune:
+		if utf8.ValidRune(t) {
+			return fmt.Sprintf("rune(%q)", t), nil
+		}
+		return fmt.Sprintf("int32(%d)", t), nil
+	case byte:
+		return fmt.Sprintf("byte(%q)", t), nil
+	case float32:
+		if f := float64(t); math.IsNaN(f) || math.IsInf(f, 0) || (f == 0 && math.Signbit(f)) {
+			return fmt.Sprintf("math.Float32frombits(0x%x)", math.Float32bits(t)), nil
+		}
+		return "float32(" + strconv.FormatFloat(float64(t), 'g', -1, 32) + ")", nil
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) || (t == 0 && math.Signbit(t)) {
+			return fmt.Sprintf("math.Float64frombits(0x%x)", math.Float64bits(t)), nil
+		}
+		return "float64(" + strconv.FormatFloat(t, 'g', -1, 64) + ")", nil
+	case bool, int, int8, int16, int64, uint, uint16, uint32, uint64:
+		return fmt.Sprintf("%T(%v)", t, t), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+//Unmarshal decodes a corpus file written by go test or Marshal
+func Unmarshal(data []byte) (Entry, error) {
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	if len(lines) == 0 || lines[0]+"\n" != header {
+		return nil, fmt.Errorf("missing %q header", strings.TrimSpace(header))
+	}
+	var e Entry
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, err := parseValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		e = append(e, v)
+	}
+	return e, nil
+}
+
+// parseValue parses a conversion expression like int(-5) or []byte("x")
+func parseValue(line string) (interface{}, error) {
+	expr, err := parser.ParseExpr(line)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, fmt.Errorf("expected a conversion like int(1), got %s", line)
+	}
+
+	var typ string
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		typ = fn.Name
+	case *ast.ArrayType:
+		if elt, ok := fn.Elt.(*ast.Ident); ok && fn.Len == nil && (elt.Name == "byte" || elt.Name == "uint8") {
+			typ = "[]byte"
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := fn.X.(*ast.Ident); ok && pkg.Name == "math" {
+			typ = "math." + fn.Sel.Name
+		}
+	}
+
+	if typ == "bool" {
+		if id, ok := call.Args[0].(*ast.Ident); ok && (id.Name == "true" || id.Name == "false") {
+			return id.Name == "true", nil
+		}
+		return nil, fmt.Errorf("invalid bool %s", line)
+	}
+
+	lit, neg := call.Args[0], false
+	if u, ok := lit.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		lit, neg = u.X, true
+	}
+	bl, ok := lit.(*ast.BasicLit)
+	if !ok {
+		return nil, fmt.Errorf("expected a literal, got %s", line)
+	}
+	val := bl.Value
+	if neg {
+		val = "-" + val
+	}
+
+	switch typ {
+	case "[]byte", "string":
+		if bl.Kind != token.STRING || neg {
+			return nil, fmt.Errorf("expected a string literal, got %s", line)
+		}
+		s, err := strconv.Unquote(bl.Value)
+		if err != nil {
+			return nil, err
+		}
+		if typ == "string" {
+			return s, nil
+		}
+		return []byte(s), nil
+	case "math.Float64frombits", "math.Float32frombits":
+		bits, err := strconv.ParseUint(bl.Value, 0, 64)
+		if err != nil || neg {
+			return nil, fmt.Errorf("invalid bits %s", line)
+		}
+		if typ == "math.Float32frombits" {
+			return math.Float32frombits(uint32(bits)), nil
+		}
+		return math.Float64frombits(bits), nil
+	case "float32", "float64":
+		size := 64
+		if typ == "float32" {
+			size = 32
+		}
+		f, err := strconv.ParseFloat(val, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 32 {
+			return float32(f), nil
+		}
+		return f, nil
+	}
+
+	if bl.Kind == token.CHAR {
+		r, _, _, err := strconv.UnquoteChar(bl.Value[1:len(bl.Value)-1], '\'')
+		if err != nil {
+			return nil, err
+		}
+		val = strconv.Itoa(int(r))
+		if neg {
+			val = "-" + val
+		}
+	}
+	switch typ {
+	case "int", "int8", "int16", "int32", "rune", "int64":
+		n, err := strconv.ParseInt(val, 0, bitSize(typ))
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "int":
+			return int(n), nil
+		case "int8":
+			return int8(n), nil
+		case "int16":
+			return int16(n), nil
+		case "int32", "rune":
+			return int32(n), nil
+		}
+		return n, nil
+	case "uint", "uint8", "byte", "uint16", "uint32", "uint64":
+		n, err := strconv.ParseUint(val, 0, bitSize(typ))
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "uint":
+			return uint(n), nil
+		case "uint8", "byte":
+			return uint8(n), nil
+		case "uint16":
+			return uint16(n), nil
+		case "uint32":
+			return uint32(n), nil
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unsupported type in %s", line)
+}
+
+func bitSize(typ string) int {
+	switch typ {
+	case "int8", "uint8", "byte":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "rune", "uint32":
+		return 32
+	}
+	return 64
+}
+
+//Name returns the file name go test gives the corpus file data, the first
+//16 hex digits of its SHA-256
+func Name(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+//Add writes the values as a corpus file in dir, e.g. Dir("FuzzParse"), and
+//returns its path. Adding the same values again rewrites the same file.
+func Add(dir string, values ...interface{}) (string, error) {
+	data, err := Marshal(Entry(values))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, os.FileMode(0777)); err != nil {
+		return "", fmt.Errorf("make dir failed: %v", err)
+	}
+	path := filepath.Join(dir, Name(data))
+	return path, ioutil.WriteFile(path, data, os.FileMode(0666))
+}
+
+//Read returns the entries in dir by file name, a missing dir has none
+func Read(dir string) (map[string]Entry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return map[string]Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]Entry)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		e, err := Unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Join(dir, f.Name()), err)
+		}
+		entries[f.Name()] = e
+	}
+	return entries, nil
+}
+
+//Import adds the files matching the glob pattern, e.g. golden or e2e
+//fixtures, to the corpus in dir as []byte entries and returns the corpus
+//paths
+func Import(dir, pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		if fi, err := os.Stat(f); err != nil || fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		path, err := Add(dir, data)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+//Export writes the entries in dir that are a single []byte or string, such
+//as inputs found by the fuzzer, as plain files in out named after the
+//corpus file, with ext appended, so they can be used as fixtures. It returns
+//the paths written, other entries are skipped.
+func Export(dir, out, ext string) ([]string, error) {
+	entries, err := Read(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(out, os.FileMode(0777)); err != nil {
+		return nil, fmt.Errorf("make dir failed: %v", err)
+	}
+	var paths []string
+	for _, name := range sortedNames(entries) {
+		e := entries[name]
+		if len(e) != 1 {
+			continue
+		}
+		var data []byte
+		switch v := e[0].(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			continue
+		}
+		path := filepath.Join(out, name+ext)
+		if err := ioutil.WriteFile(path, data, os.FileMode(0666)); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+//Dedupe removes corpus files in dir with the same key as another one,
+//keeping the smallest, and returns the paths removed. key is typically
+//a hash of what the input covers, see DedupeCoverage, nil compares the
+//decoded values, which catches the same input encoded differently.
+func Dedupe(dir string, key func(Entry) (string, error)) ([]string, error) {
+	entries, err := Read(dir)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		key = valueKey
+	}
+
+	names := sortedNames(entries)
+	sizes := make(map[string]int64)
+	for _, name := range names {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		sizes[name] = fi.Size()
+	}
+	sort.SliceStable(names, func(i, j int) bool { return sizes[names[i]] < sizes[names[j]] })
+
+	seen := make(map[string]bool)
+	var removed []string
+	for _, name := range names {
+		k, err := key(entries[name])
+		if err != nil {
+			return removed, fmt.Errorf("%s: %v", filepath.Join(dir, name), err)
+		}
+		if !seen[k] {
+			seen[k] = true
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+func valueKey(e Entry) (string, error) {
+	data, err := Marshal(e)
+	return string(data), err
+}
+
+func sortedNames(entries map[string]Entry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Format renders e one value per line with pretty.Sprint, with []byte
+//and string values as text so they diff line by line
+func Format(e Entry) string {
+	var b strings.Builder
+	for i, v := range e {
+		fmt.Fprintf(&b, "[%d] %T\n", i, v)
+		s, ok := v.(string)
+		if bs, isBytes := v.([]byte); isBytes {
+			s, ok = string(bs), true
+		}
+		if !ok {
+			s = pretty.Sprint(v)
+		}
+		b.WriteString(s)
+		if !strings.HasSuffix(s, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+//Diff diffs the entries a and b rendered by Format, e.g. a failing input
+//against the fixture it was derived from
+func Diff(a, b Entry, opts ...tools.Option) tools.Differ {
+	opts = append([]tools.Option{tools.WithMode(tools.LineMode)}, opts...)
+	return tools.Diff(Format(a), Format(b), opts...)
+}
+
+//Log logs the values under the corpus file name go test gives them when
+//the test fails. Call it first in a fuzz function so a reproduced failure
+//shows the input readably:
+//
+//	f.Fuzz(func(t *testing.T, data []byte) {
+//		corpus.Log(t, data)
+//		...
+//	})
+func Log(t TestingT, values ...interface{}) {
+	t.Helper()
+	e := Entry(values)
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		name := "?"
+		if data, err := Marshal(e); err == nil {
+			name = Name(data)
+		}
+		t.Logf("fuzz input %s:\n%s", name, Format(e))
+	})
+}
@@ -0,0 +1,171 @@
+package corpus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	e := Entry{[]byte("a\nb"), "x", true, 'r', byte('b'), -5, int8(-1), int64(7), uint(3), uint16(4), 1.5, float32(-0.25), math.Inf(-1), math.Copysign(0, -1)}
+	data, err := Marshal(e)
+	assert.NoError(t, err)
+	assert.Equal(t, `go test fuzz v1
+[]byte("a\nb")
+string("x")
+bool(true)
+rune('r')
+byte('b')
+int(-5)
+int8(-1)
+int64(7)
+uint(3)
+uint16(4)
+float64(1.5)
+float32(-0.25)
+math.Float64frombits(0xfff0000000000000)
+math.Float64frombits(0x8000000000000000)
+`, string(data))
+
+	got, err := Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, e[:13], got[:13])
+	assert.True(t, math.Signbit(got[13].(float64)))
+
+	_, err = Marshal(Entry{struct{}{}})
+	assert.EqualError(t, err, "value 0: unsupported type struct {}")
+}
+
+func TestUnmarshal(t *testing.T) {
+	got, err := Unmarshal([]byte("go test fuzz v1\r\nint32(-0x10)\nuint8(255)\nbyte('\\x01')\nrune(-1)\nfloat64(-2)\n\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Entry{int32(-16), uint8(255), uint8(1), int32(-1), -2.0}, got)
+
+	for data, msg := range map[string]string{
+		"int(1)\n":                     `missing "go test fuzz v1" header`,
+		"go test fuzz v1\nint8(300)\n": `line 2: strconv.ParseInt: parsing "300": value out of range`,
+		"go test fuzz v1\nfoo(1)\n":    "line 2: unsupported type in foo(1)",
+		"go test fuzz v1\nstring(x)\n": "line 2: expected a literal, got string(x)",
+		"go test fuzz v1\n[]byte(1)\n": "line 2: expected a string literal, got []byte(1)",
+		"go test fuzz v1\nbool(1)\n":   "line 2: invalid bool bool(1)",
+		"go test fuzz v1\n1\n":         "line 2: expected a conversion like int(1), got 1",
+	} {
+		_, err := Unmarshal([]byte(data))
+		assert.EqualError(t, err, msg, data)
+	}
+}
+
+func TestAddRead(t *testing.T) {
+	dir := filepath.Join(fs.Dir(t, fs.Tree{}), Dir("FuzzParse"))
+	path, err := Add(dir, []byte("hello"), 3)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Name(data), filepath.Base(path))
+	assert.Len(t, filepath.Base(path), 16)
+
+	entries, err := Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Entry{filepath.Base(path): {[]byte("hello"), 3}}, entries)
+
+	entries, err = Read(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestImportExport(t *testing.T) {
+	root := fs.Dir(t, fs.Tree{
+		"golden/a.golden": "alpha\n",
+		"golden/b.golden": "beta\n",
+		"golden/c.txt":    "skipped",
+	})
+	dir := filepath.Join(root, "corpus")
+	paths, err := Import(dir, filepath.Join(root, "golden", "*.golden"))
+	assert.NoError(t, err)
+	assert.Len(t, paths, 2)
+	_, err = Add(dir, "gamma")
+	assert.NoError(t, err)
+	_, err = Add(dir, 1, 2)
+	assert.NoError(t, err)
+
+	out := filepath.Join(root, "fixtures")
+	exported, err := Export(dir, out, ".txt")
+	assert.NoError(t, err)
+	assert.Len(t, exported, 3)
+
+	var contents []string
+	for _, p := range exported {
+		bs, err := ioutil.ReadFile(p)
+		assert.NoError(t, err)
+		contents = append(contents, string(bs))
+	}
+	assert.ElementsMatch(t, []string{"alpha\n", "beta\n", "gamma"}, contents)
+}
+
+func TestDedupe(t *testing.T) {
+	dir := fs.Dir(t, fs.Tree{
+		"a": "go test fuzz v1\nint(10)\n",
+		"b": "go test fuzz v1\nint(0xa)\n",
+		"c": "go test fuzz v1\nint(11)\n",
+	})
+	removed, err := Dedupe(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "b")}, removed)
+
+	// a key grouping by parity keeps the smallest input of each
+	removed, err = Dedupe(dir, func(e Entry) (string, error) {
+		return fmt.Sprint(e[0].(int) % 2), nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+
+	_, err = Add(dir, 12)
+	assert.NoError(t, err)
+	removed, err = Dedupe(dir, func(e Entry) (string, error) {
+		return fmt.Sprint(e[0].(int) % 2), nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	entries, err := Read(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFormatDiff(t *testing.T) {
+	a := Entry{[]byte("line 1\nline 2\n"), 3}
+	b := Entry{[]byte("line 1\nline two\n"), 3}
+	assert.Equal(t, "[0] []uint8\nline 1\nline 2\n[1] int\n3\n", Format(a))
+	d := Diff(a, b).String()
+	assert.Contains(t, d, "-line 2\n+line two\n")
+}
+
+type fakeT struct {
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()          {}
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Failed() bool     { return t.failed }
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func TestLog(t *testing.T) {
+	ft := &fakeT{}
+	Log(ft, "ok")
+	ft.cleanups[0]()
+	assert.Empty(t, ft.logs)
+
+	ft = &fakeT{failed: true}
+	Log(ft, "bad input")
+	ft.cleanups[0]()
+	data, _ := Marshal(Entry{"bad input"})
+	assert.Equal(t, []string{"fuzz input " + Name(data) + ":\n[0] string\nbad input\n"}, ft.logs)
+}
@@ -0,0 +1,77 @@
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//DedupeCoverage removes corpus files of the fuzz target name in the package
+//in pkgDir that cover the same code as another one, keeping the smallest,
+//and returns the paths removed. Each file is run on its own with
+//go test -run=<name>/<file> -coverprofile, and the blocks it reaches are
+//hashed, so this is slow for large corpora.
+func DedupeCoverage(pkgDir, name string) ([]string, error) {
+	dir := filepath.Join(pkgDir, Dir(name))
+	entries, err := Read(dir)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for _, file := range sortedNames(entries) {
+		h, err := coverageHash(pkgDir, name, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Join(dir, file), err)
+		}
+		k, err := valueKey(entries[file])
+		if err != nil {
+			return nil, err
+		}
+		hashes[k] = h
+	}
+	return Dedupe(dir, func(e Entry) (string, error) {
+		k, err := valueKey(e)
+		return hashes[k], err
+	})
+}
+
+// coverageHash runs one corpus file and hashes the covered blocks
+func coverageHash(pkgDir, name, file string) (string, error) {
+	tmp, err := ioutil.TempFile("", "corpus-*.out")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	run := fmt.Sprintf("^%s$/^%s$", regexp.QuoteMeta(name), regexp.QuoteMeta(file))
+	cmd := exec.Command("go", "test", "-count=1", "-run="+run, "-coverprofile="+tmp.Name(), ".")
+	cmd.Dir = pkgDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("go test failed: %v\n%s", err, out)
+	}
+
+	profile, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	var blocks []string
+	sc := bufio.NewScanner(bytes.NewReader(profile))
+	for sc.Scan() {
+		// file:start,end statements count
+		f := strings.Fields(sc.Text())
+		if len(f) == 3 && f[2] != "0" {
+			blocks = append(blocks, f[0])
+		}
+	}
+	sort.Strings(blocks)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(blocks, "\n")))), nil
+}
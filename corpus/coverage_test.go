@@ -0,0 +1,54 @@
+package corpus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeCoverage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	dir := fs.Dir(t, fs.Tree{
+		"go.mod": "module fuzzme\n\ngo 1.18\n",
+		"classify.go": `package fuzzme
+
+func Classify(n int) string {
+	if n < 0 {
+		return "negative"
+	}
+	return "positive"
+}
+`,
+		"classify_test.go": `package fuzzme
+
+import "testing"
+
+func FuzzClassify(f *testing.F) {
+	f.Fuzz(func(t *testing.T, n int) { Classify(n) })
+}
+`,
+	})
+	corpus := filepath.Join(dir, Dir("FuzzClassify"))
+	for _, n := range []int{1, 200, -1} {
+		_, err := Add(corpus, n)
+		assert.NoError(t, err)
+	}
+
+	removed, err := DedupeCoverage(dir, "FuzzClassify")
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	entries, err := Read(corpus)
+	assert.NoError(t, err)
+	var kept []int
+	for _, e := range entries {
+		kept = append(kept, e[0].(int))
+	}
+	assert.ElementsMatch(t, []int{1, -1}, kept)
+
+	_, err = DedupeCoverage(dir, "FuzzMissing")
+	assert.NoError(t, err)
+}
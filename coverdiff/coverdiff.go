@@ -0,0 +1,339 @@
+package coverdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prasek/loupe/tools"
+	"golang.org/x/tools/cover"
+)
+
+//Option configures Compare
+type Option func(*options)
+
+type options struct {
+	minCoverage float64
+	maxDrop     float64
+	beforeDir   string
+	afterDir    string
+}
+
+//WithMinCoverage fails the report when a touched file, one that's new or
+//whose statements changed, is covered less than percent
+func WithMinCoverage(percent float64) Option {
+	return func(o *options) {
+		o.minCoverage = percent
+	}
+}
+
+//WithMaxDrop fails the report when the coverage of a touched file drops by
+//more than points percentage points, 0 fails any drop
+func WithMaxDrop(points float64) Option {
+	return func(o *options) {
+		o.maxDrop = points
+	}
+}
+
+//WithSourceDirs sets the module directories the profiles before and after
+//were created from, used to find the functions of each file. By default
+//both are the module of the working directory, which is exact for files
+//that didn't change. Files that can't be found have no function coverage.
+func WithSourceDirs(before, after string) Option {
+	return func(o *options) {
+		o.beforeDir, o.afterDir = before, after
+	}
+}
+
+//Coverage counts the statements of a file or function and how many of
+//them ran
+type Coverage struct {
+	Covered    int `json:"covered"`
+	Statements int `json:"statements"`
+}
+
+//Percent returns the covered statements in percent, 0 without statements
+func (c Coverage) Percent() float64 {
+	if c.Statements == 0 {
+		return 0
+	}
+	return float64(c.Covered) / float64(c.Statements) * 100
+}
+
+func (c *Coverage) add(b cover.ProfileBlock) {
+	c.Statements += b.NumStmt
+	if b.Count > 0 {
+		c.Covered += b.NumStmt
+	}
+}
+
+//File compares the coverage of a file, Before or After is nil when the file
+//is only in one profile
+type File struct {
+	Name   string    `json:"name"`
+	Before *Coverage `json:"before"`
+	After  *Coverage `json:"after"`
+
+	//Touched is set when the file is new or its statements changed
+	Touched bool `json:"touched"`
+
+	Funcs []Func `json:"funcs,omitempty"`
+}
+
+//Func compares the coverage of a function, Before or After is nil when the
+//function is only in one version of the file
+type Func struct {
+	//Name is the function name, or Type.Method for a method
+	Name string `json:"name"`
+
+	//Line is where the function starts in the new version of the file, or
+	//the old one if it was removed
+	Line int `json:"line"`
+
+	Before *Coverage `json:"before"`
+	After  *Coverage `json:"after"`
+}
+
+//Report compares two coverage profiles
+type Report struct {
+	//Files are sorted by name
+	Files []File `json:"files"`
+
+	//Before and After are the totals of the profiles
+	Before Coverage `json:"before"`
+	After  Coverage `json:"after"`
+
+	//Failures describe the files that fail WithMinCoverage or WithMaxDrop
+	Failures []string `json:"failures,omitempty"`
+}
+
+//CompareFiles parses the coverprofiles at the paths, written by go test
+//-coverprofile, and compares them
+func CompareFiles(before, after string, opts ...Option) (*Report, error) {
+	b, err := cover.ParseProfiles(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := cover.ParseProfiles(after)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(b, a, opts...), nil
+}
+
+//Compare compares the coverage of the files in the profiles before and
+//after
+func Compare(before, after []*cover.Profile, opts ...Option) *Report {
+	o := options{minCoverage: -1, maxDrop: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.beforeDir == "" && o.afterDir == "" {
+		o.beforeDir = moduleRoot(".")
+		o.afterDir = o.beforeDir
+	}
+	srcBefore, srcAfter := newSource(o.beforeDir), newSource(o.afterDir)
+
+	profiles := [2]map[string]*cover.Profile{{}, {}}
+	var names []string
+	for i, ps := range [][]*cover.Profile{before, after} {
+		for _, p := range ps {
+			if profiles[0][p.FileName] == nil && profiles[1][p.FileName] == nil {
+				names = append(names, p.FileName)
+			}
+			profiles[i][p.FileName] = p
+		}
+	}
+	sort.Strings(names)
+
+	r := &Report{}
+	for _, name := range names {
+		pb, pa := profiles[0][name], profiles[1][name]
+		f := File{Name: name, Before: total(pb), After: total(pa)}
+		f.Touched = pa != nil && (pb == nil || !sameBlocks(pb.Blocks, pa.Blocks))
+		f.Funcs = compareFuncs(srcBefore.funcs(name, pb), srcAfter.funcs(name, pa))
+		if f.Before != nil {
+			r.Before.Covered += f.Before.Covered
+			r.Before.Statements += f.Before.Statements
+		}
+		if f.After != nil {
+			r.After.Covered += f.After.Covered
+			r.After.Statements += f.After.Statements
+		}
+		r.Failures = append(r.Failures, o.check(f)...)
+		r.Files = append(r.Files, f)
+	}
+	return r
+}
+
+func (o options) check(f File) []string {
+	if !f.Touched {
+		return nil
+	}
+	var failures []string
+	after := f.After.Percent()
+	if o.minCoverage >= 0 && after < o.minCoverage {
+		failures = append(failures, fmt.Sprintf("coverage of %s is %.1f%%, below the minimum of %.1f%%", f.Name, after, o.minCoverage))
+	}
+	if o.maxDrop >= 0 && f.Before != nil {
+		if drop := f.Before.Percent() - after; drop > o.maxDrop {
+			failures = append(failures, fmt.Sprintf("coverage of %s dropped by %.1f points, from %.1f%% to %.1f%%", f.Name, drop, f.Before.Percent(), after))
+		}
+	}
+	return failures
+}
+
+func total(p *cover.Profile) *Coverage {
+	if p == nil {
+		return nil
+	}
+	c := &Coverage{}
+	for _, b := range p.Blocks {
+		c.add(b)
+	}
+	return c
+}
+
+// sameBlocks reports whether a and b have the same statements, ignoring
+// whether they ran
+func sameBlocks(a, b []cover.ProfileBlock) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		x.Count, y.Count = 0, 0
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// compareFuncs matches the functions of the old and new version of a file
+// by name, in the order of the new version followed by removed functions
+func compareFuncs(before, after []funcCoverage) []Func {
+	old := map[string]funcCoverage{}
+	for _, fc := range before {
+		old[fc.name] = fc
+	}
+	var funcs []Func
+	seen := map[string]bool{}
+	for _, fc := range after {
+		c := fc.cov
+		f := Func{Name: fc.name, Line: fc.line, After: &c}
+		if b, ok := old[fc.name]; ok {
+			bc := b.cov
+			f.Before = &bc
+		}
+		seen[fc.name] = true
+		funcs = append(funcs, f)
+	}
+	for _, fc := range before {
+		if !seen[fc.name] {
+			c := fc.cov
+			funcs = append(funcs, Func{Name: fc.name, Line: fc.line, Before: &c})
+		}
+	}
+	return funcs
+}
+
+//Failed reports whether a file failed WithMinCoverage or WithMaxDrop
+func (r *Report) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+//String returns the report like WriteText to os.Stdout
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	r.write(&buf, tools.NewPalette(nil))
+	return buf.String()
+}
+
+//WriteText writes a table of the files whose coverage changed, each with
+//the functions whose coverage changed, followed by the totals and the
+//failures. Drops are colored like deleted lines and gains like inserted
+//lines. WithColor, WithNoColor and WithTheme apply.
+func (r *Report) WriteText(w io.Writer, opts ...tools.Option) error {
+	var buf bytes.Buffer
+	r.write(&buf, tools.NewPalette(w, opts...))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (r *Report) write(w io.Writer, p tools.Palette) {
+	rows := [][]string{{"file", "before", "after", "delta"}}
+	for _, f := range r.Files {
+		if !changed(f.Before, f.After) && !f.Touched {
+			continue
+		}
+		rows = append(rows, covRow(f.Name, f.Before, f.After))
+		for _, fn := range f.Funcs {
+			if changed(fn.Before, fn.After) {
+				rows = append(rows, covRow(fmt.Sprintf("  %s:%d", fn.Name, fn.Line), fn.Before, fn.After))
+			}
+		}
+	}
+	rows = append(rows, covRow("total", &r.Before, &r.After))
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch {
+			case i == 0:
+				line.WriteString(cell + pad)
+			case i == 3 && strings.HasPrefix(cell, "-"):
+				line.WriteString("  " + pad + p.Delete(cell))
+			case i == 3 && strings.HasPrefix(cell, "+") && cell != "+0.0":
+				line.WriteString("  " + pad + p.Insert(cell))
+			default:
+				line.WriteString("  " + pad + cell)
+			}
+		}
+		io.WriteString(w, strings.TrimRight(line.String(), " ")+"\n")
+	}
+
+	for _, f := range r.Failures {
+		io.WriteString(w, p.Delete("FAIL: "+f)+"\n")
+	}
+}
+
+func changed(before, after *Coverage) bool {
+	if before == nil || after == nil {
+		return before != after
+	}
+	return *before != *after
+}
+
+func covRow(name string, before, after *Coverage) []string {
+	row := []string{name, percent(before), percent(after), ""}
+	if before != nil && after != nil {
+		d := after.Percent() - before.Percent()
+		if math.Abs(d) < 0.05 {
+			// keeps rounding from showing -0.0
+			d = 0
+		}
+		row[3] = fmt.Sprintf("%+.1f", d)
+	}
+	return row
+}
+
+func percent(c *Coverage) string {
+	if c == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", c.Percent())
+}
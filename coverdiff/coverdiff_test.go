@@ -0,0 +1,61 @@
+package coverdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareFiles(t *testing.T) {
+	r, err := CompareFiles("testdata/before.out", "testdata/after.out", WithSourceDirs("testdata/before", "testdata/after"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, r.Failed())
+	assert.Equal(t, Coverage{Covered: 3, Statements: 4}, r.Before)
+	assert.Equal(t, Coverage{Covered: 6, Statements: 8}, r.After)
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.WriteText(&buf, tools.WithNoColor()))
+	assert.Equal(t, ""+
+		"file                      before   after  delta\n"+
+		"example.com/calc/calc.go   75.0%   80.0%   +5.0\n"+
+		"  Div:7                    66.7%  100.0%  +33.3\n"+
+		"  Mul:14                       -    0.0%\n"+
+		"example.com/calc/util.go       -   66.7%\n"+
+		"  Abs:3                        -   66.7%\n"+
+		"total                      75.0%   75.0%   +0.0\n", buf.String())
+
+	buf.Reset()
+	r.WriteText(&buf, tools.WithColor(tools.ColorAlways))
+	assert.Contains(t, buf.String(), "  \x1b[32m+33.3\x1b[0m\n")
+
+	_, err = CompareFiles("testdata/missing.out", "testdata/after.out")
+	assert.Error(t, err)
+}
+
+func TestGate(t *testing.T) {
+	r, err := CompareFiles("testdata/before.out", "testdata/after.out", WithSourceDirs("testdata/before", "testdata/after"), WithMinCoverage(70))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, r.Failed())
+	assert.Equal(t, []string{"coverage of example.com/calc/util.go is 66.7%, below the minimum of 70.0%"}, r.Failures)
+	assert.Contains(t, r.String(), "FAIL: coverage of example.com/calc/util.go is 66.7%, below the minimum of 70.0%\n")
+
+	// reversed, calc.go's statements changed and its coverage dropped
+	r, err = CompareFiles("testdata/after.out", "testdata/before.out", WithSourceDirs("testdata/after", "testdata/before"), WithMaxDrop(0))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"coverage of example.com/calc/calc.go dropped by 5.0 points, from 80.0% to 75.0%"}, r.Failures)
+	assert.Equal(t, Func{Name: "Mul", Line: 14, Before: &Coverage{0, 1}}, r.Files[0].Funcs[2])
+
+	// unchanged statements aren't gated
+	r, _ = CompareFiles("testdata/before.out", "testdata/before.out", WithMinCoverage(100), WithMaxDrop(0))
+	assert.False(t, r.Failed())
+	assert.False(t, r.Files[0].Touched)
+	assert.Equal(t, "file   before  after  delta\ntotal   75.0%  75.0%   +0.0\n", r.String())
+}
@@ -0,0 +1,154 @@
+package coverdiff
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/cover"
+)
+
+// source finds the Go files of a module by their import path
+type source struct {
+	dir    string
+	module string
+}
+
+func newSource(dir string) *source {
+	if dir == "" {
+		return nil
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	return &source{dir: dir, module: modfile.ModulePath(bs)}
+}
+
+// moduleRoot returns the closest directory containing go.mod at or above
+// dir, or "" if there's none
+func moduleRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// path returns the file of the import path name, like
+// example.com/mod/pkg/file.go
+func (s *source) path(name string) (string, bool) {
+	if s.module == "" || !strings.HasPrefix(name, s.module+"/") {
+		return "", false
+	}
+	rel := strings.TrimPrefix(name, s.module+"/")
+	return filepath.Join(s.dir, filepath.FromSlash(rel)), true
+}
+
+type funcCoverage struct {
+	name string
+	line int
+	cov  Coverage
+}
+
+type funcExtent struct {
+	name                string
+	startLine, startCol int
+	endLine, endCol     int
+}
+
+// funcs returns the coverage of the functions of the file name in p, like
+// go tool cover -func, or nil if the file can't be parsed
+func (s *source) funcs(name string, p *cover.Profile) []funcCoverage {
+	if s == nil || p == nil {
+		return nil
+	}
+	file, ok := s.path(name)
+	if !ok {
+		return nil
+	}
+	extents, err := findFuncs(file)
+	if err != nil {
+		return nil
+	}
+
+	funcs := make([]funcCoverage, 0, len(extents))
+	for _, e := range extents {
+		fc := funcCoverage{name: e.name, line: e.startLine}
+		for _, b := range p.Blocks {
+			if e.contains(b) {
+				fc.cov.add(b)
+			}
+		}
+		funcs = append(funcs, fc)
+	}
+	return funcs
+}
+
+func (e funcExtent) contains(b cover.ProfileBlock) bool {
+	afterStart := b.StartLine > e.startLine || b.StartLine == e.startLine && b.StartCol >= e.startCol
+	beforeEnd := b.EndLine < e.endLine || b.EndLine == e.endLine && b.EndCol <= e.endCol
+	return afterStart && beforeEnd
+}
+
+func findFuncs(file string) ([]funcExtent, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var extents []funcExtent
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()), fset.Position(fn.End())
+		extents = append(extents, funcExtent{
+			name:      funcName(fn),
+			startLine: start.Line,
+			startCol:  start.Column,
+			endLine:   end.Line,
+			endCol:    end.Column,
+		})
+	}
+	return extents, nil
+}
+
+// funcName returns Name, or Type.Name for a method
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.IndexListExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
@@ -0,0 +1,42 @@
+package coverdiff
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+func TestFuncs(t *testing.T) {
+	src := newSource("testdata/after")
+	if assert.NotNil(t, src) {
+		assert.Equal(t, "example.com/calc", src.module)
+	}
+	assert.Nil(t, newSource("testdata"))
+
+	p := &cover.Profile{Blocks: []cover.ProfileBlock{
+		{StartLine: 3, StartCol: 21, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 4, StartCol: 11, EndLine: 6, EndCol: 3, NumStmt: 2},
+	}}
+	assert.Equal(t, []funcCoverage{{name: "Abs", line: 3, cov: Coverage{1, 3}}}, src.funcs("example.com/calc/util.go", p))
+	assert.Nil(t, src.funcs("example.com/other/util.go", p))
+	assert.Nil(t, src.funcs("example.com/calc/missing.go", p))
+
+	root, _ := filepath.Abs("..")
+	assert.Equal(t, root, moduleRoot("."))
+}
+
+func TestFuncName(t *testing.T) {
+	extents, err := findFuncs("funcs.go")
+	if !assert.NoError(t, err) {
+		return
+	}
+	names := map[string]bool{}
+	for _, e := range extents {
+		names[e.name] = true
+	}
+	assert.True(t, names["source.funcs"])
+	assert.True(t, names["funcExtent.contains"])
+	assert.True(t, names["moduleRoot"])
+}
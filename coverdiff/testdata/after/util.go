@@ -0,0 +1,8 @@
+package calc
+
+func Abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,12 @@
+package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Div(a, b int) (int, bool) {
+	if b == 0 {
+		return 0, false
+	}
+	return a / b, true
+}
@@ -0,0 +1,310 @@
+package dbassert
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+var _ Rows = (*sql.Rows)(nil)
+
+//Rows is the part of *sql.Rows that is read, so results of other drivers
+//can be compared too
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+//Null is how NULL values are shown and written in fixtures
+const Null = "NULL"
+
+//Table is a result set with every value formatted as a string
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+//Read reads all rows, formatting NULL as Null, []byte as text and times as
+//RFC 3339, and closes rows if it has a Close method
+func Read(rows Rows) (*Table, error) {
+	if c, ok := rows.(io.Closer); ok {
+		defer c.Close()
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Columns: cols}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(cols))
+		for i, v := range vals {
+			row[i] = format(v)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, rows.Err()
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return Null
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+//ReadCSV reads a table from CSV with a header row, an unquoted NULL is a
+//NULL value. Use a comma of '\t' for TSV.
+func ReadCSV(r io.Reader, comma rune) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return &Table{}, nil
+	}
+	return &Table{Columns: records[0], Rows: records[1:]}, nil
+}
+
+//ReadJSON reads a table from a JSON array of objects, with the keys of the
+//first object as columns in sorted order, followed by keys only later
+//objects have. null is a NULL value and nested values are compact JSON.
+func ReadJSON(r io.Reader) (*Table, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var objs []map[string]interface{}
+	if err := dec.Decode(&objs); err != nil {
+		return nil, err
+	}
+	t := &Table{}
+	if len(objs) == 0 {
+		return t, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, obj := range objs {
+		var keys []string
+		for k := range obj {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		t.Columns = append(t.Columns, keys...)
+	}
+	for _, obj := range objs {
+		row := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			switch v := obj[c].(type) {
+			case nil:
+				row[i] = Null
+			case string:
+				row[i] = v
+			case json.Number, bool:
+				row[i] = fmt.Sprint(v)
+			default:
+				bs, _ := json.Marshal(v)
+				row[i] = string(bs)
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+//String renders the table with aligned columns, one row per line
+func (t *Table) String() string {
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range t.Rows {
+		for i, v := range row {
+			if i < len(widths) && utf8.RuneCountInString(v) > widths[i] {
+				widths[i] = utf8.RuneCountInString(v)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	line := func(vals []string) {
+		for i, v := range vals {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			buf.WriteString(v)
+			if i < len(vals)-1 && i < len(widths) {
+				buf.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+			}
+		}
+		buf.WriteString("\n")
+	}
+	line(t.Columns)
+	for _, row := range t.Rows {
+		line(row)
+	}
+	return buf.String()
+}
+
+//Option configures how tables are compared
+type Option func(*options)
+
+type options struct {
+	ignoreColumns map[string]bool
+	ignoreOrder   bool
+}
+
+//IgnoreColumns leaves out columns that vary between runs, e.g. ids and
+//timestamps
+func IgnoreColumns(names ...string) Option {
+	return func(o *options) {
+		for _, n := range names {
+			o.ignoreColumns[n] = true
+		}
+	}
+}
+
+//IgnoreOrder compares rows in any order
+func IgnoreOrder() Option {
+	return func(o *options) {
+		o.ignoreOrder = true
+	}
+}
+
+// normalize drops ignored columns, orders the columns like cols and sorts
+// the rows if order is ignored
+func (o *options) normalize(t *Table, cols []string) *Table {
+	index := make(map[string]int)
+	for i, c := range t.Columns {
+		index[c] = i
+	}
+	n := &Table{}
+	var idx []int
+	for _, c := range cols {
+		if i, ok := index[c]; ok && !o.ignoreColumns[c] {
+			n.Columns = append(n.Columns, c)
+			idx = append(idx, i)
+		}
+	}
+	for _, c := range t.Columns {
+		if !o.ignoreColumns[c] && !contains(n.Columns, c) {
+			n.Columns = append(n.Columns, c)
+			idx = append(idx, index[c])
+		}
+	}
+	for _, row := range t.Rows {
+		r := make([]string, len(idx))
+		for j, i := range idx {
+			if i < len(row) {
+				r[j] = row[i]
+			}
+		}
+		n.Rows = append(n.Rows, r)
+	}
+	if o.ignoreOrder {
+		sort.SliceStable(n.Rows, func(i, j int) bool {
+			return strings.Join(n.Rows[i], "\x00") < strings.Join(n.Rows[j], "\x00")
+		})
+	}
+	return n
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+//Equal reads rows and verifies they equal want, failing with a diff of the
+//rendered tables if not. Columns are matched by name, in the order of want.
+func Equal(t TestingT, want *Table, rows Rows, opts ...Option) bool {
+	t.Helper()
+	got, err := Read(rows)
+	if err != nil {
+		t.Errorf("read rows: %v", err)
+		return false
+	}
+	return EqualTables(t, want, got, opts...)
+}
+
+//EqualTables verifies got equals want, like Equal
+func EqualTables(t TestingT, want, got *Table, opts ...Option) bool {
+	t.Helper()
+	o := &options{ignoreColumns: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	exp := o.normalize(want, want.Columns).String()
+	act := o.normalize(got, want.Columns).String()
+	if exp == act {
+		return true
+	}
+	t.Errorf("Rows Not Equal (-want +got)\n%s", tools.Diff(exp, act, tools.WithMode(tools.LineMode)))
+	return false
+}
+
+//EqualFixture reads rows and verifies they equal the fixture at path, a
+//JSON file if path ends in .json, TSV if it ends in .tsv and CSV otherwise
+func EqualFixture(t TestingT, path string, rows Rows, opts ...Option) bool {
+	t.Helper()
+	want, err := readFixture(path)
+	if err != nil {
+		t.Errorf("read fixture %s: %v", path, err)
+		return false
+	}
+	return Equal(t, want, rows, opts...)
+}
+
+func readFixture(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ReadJSON(f)
+	case ".tsv":
+		return ReadCSV(f, '\t')
+	default:
+		return ReadCSV(f, ',')
+	}
+}
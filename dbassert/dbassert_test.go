@@ -0,0 +1,99 @@
+package dbassert
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+// rows is a driver agnostic result set
+type rows struct {
+	cols   []string
+	vals   [][]interface{}
+	i      int
+	err    error
+	closed bool
+}
+
+func (r *rows) Columns() ([]string, error) { return r.cols, nil }
+func (r *rows) Next() bool                 { r.i++; return r.i <= len(r.vals) }
+func (r *rows) Err() error                 { return r.err }
+func (r *rows) Close() error               { r.closed = true; return nil }
+
+func (r *rows) Scan(dest ...interface{}) error {
+	for i, v := range r.vals[r.i-1] {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+var created = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func users() *rows {
+	return &rows{
+		cols: []string{"id", "name", "email", "created"},
+		vals: [][]interface{}{
+			{int64(1), "ann", []byte("ann@example.com"), created},
+			{int64(2), "bob", nil, created},
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	r := users()
+	tbl, err := Read(r)
+	assert.NoError(t, err)
+	assert.True(t, r.closed)
+	assert.Equal(t, `id | name | email           | created
+1  | ann  | ann@example.com | 2020-01-02T03:04:05Z
+2  | bob  | NULL            | 2020-01-02T03:04:05Z
+`, tbl.String())
+
+	_, err = Read(&rows{err: errors.New("broken")})
+	assert.EqualError(t, err, "broken")
+}
+
+func TestEqual(t *testing.T) {
+	want := &Table{
+		Columns: []string{"name", "email"},
+		Rows:    [][]string{{"bob", "NULL"}, {"ann", "ann@example.com"}},
+	}
+	assert.True(t, Equal(t, want, users(), IgnoreColumns("id", "created"), IgnoreOrder()))
+
+	m := tools.Mock()
+	assert.False(t, Equal(m, want, users(), IgnoreColumns("id", "created")))
+	assert.False(t, Equal(m, want, &rows{err: errors.New("broken")}))
+	res := m.Results()
+	assert.Contains(t, res.Err, "Rows Not Equal (-want +got)\n")
+	assert.Contains(t, res.Err, "-bob  | NULL")
+	assert.Contains(t, res.Err, "+bob  | NULL")
+	assert.Contains(t, res.Err, "read rows: broken")
+}
+
+func TestEqualFixture(t *testing.T) {
+	dir := fs.Dir(t, fs.Tree{
+		"users.csv":  "name,email\nann,ann@example.com\nbob,NULL\n",
+		"users.tsv":  "email\tname\nann@example.com\tann\nNULL\tbob\n",
+		"users.json": `[{"name": "ann", "email": "ann@example.com", "id": 1}, {"name": "bob", "email": null, "id": 2}]`,
+		"bad.json":   `{"name": "ann"}`,
+	})
+	for _, f := range []string{"users.csv", "users.tsv"} {
+		assert.True(t, EqualFixture(t, filepath.Join(dir, f), users(), IgnoreColumns("id", "created")), f)
+	}
+	assert.True(t, EqualFixture(t, filepath.Join(dir, "users.json"), users(), IgnoreColumns("created")))
+
+	m := tools.Mock()
+	assert.False(t, EqualFixture(m, filepath.Join(dir, "users.json"), users()))
+	assert.False(t, EqualFixture(m, filepath.Join(dir, "bad.json"), users()))
+	assert.False(t, EqualFixture(m, filepath.Join(dir, "missing.csv"), users()))
+	res := m.Results()
+	assert.Contains(t, res.Err, "Rows Not Equal")
+	assert.Contains(t, res.Err, "created")
+	assert.Equal(t, 2, strings.Count(res.Err, "read fixture "))
+}
@@ -0,0 +1,78 @@
+package env
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//Set sets the environment variable key to value and restores its original
+//value, or unsets it, when the test completes
+func Set(t TestingT, key, value string) {
+	t.Helper()
+	restore(t, key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Errorf("set %s: %v", key, err)
+		t.FailNow()
+	}
+}
+
+//Unset unsets the environment variable key and restores its original value
+//when the test completes
+func Unset(t TestingT, key string) {
+	t.Helper()
+	restore(t, key)
+	if err := os.Unsetenv(key); err != nil {
+		t.Errorf("unset %s: %v", key, err)
+		t.FailNow()
+	}
+}
+
+//Patch sets every environment variable in vars, in key order, and restores
+//the original values when the test completes
+func Patch(t TestingT, vars map[string]string) {
+	t.Helper()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		Set(t, k, vars[k])
+	}
+}
+
+//Snapshot records the whole environment and restores it exactly when the
+//test completes, so variables set or unset by the code under test, not just
+//with Set, don't leak into other tests
+func Snapshot(t TestingT) {
+	t.Helper()
+	env := os.Environ()
+	t.Cleanup(func() {
+		os.Clearenv()
+		for _, kv := range env {
+			if i := strings.Index(kv, "="); i > 0 {
+				os.Setenv(kv[:i], kv[i+1:])
+			}
+		}
+	})
+}
+
+func restore(t TestingT, key string) {
+	orig, ok := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, orig)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	os.Setenv("LOUPE_A", "orig")
+	defer os.Unsetenv("LOUPE_A")
+	os.Unsetenv("LOUPE_B")
+
+	t.Run("set", func(t *testing.T) {
+		Set(t, "LOUPE_A", "one")
+		Set(t, "LOUPE_A", "two")
+		Patch(t, map[string]string{"LOUPE_B": "b", "LOUPE_C": ""})
+		assert.Equal(t, "two", os.Getenv("LOUPE_A"))
+		assert.Equal(t, "b", os.Getenv("LOUPE_B"))
+		_, ok := os.LookupEnv("LOUPE_C")
+		assert.True(t, ok)
+	})
+	assert.Equal(t, "orig", os.Getenv("LOUPE_A"))
+	_, ok := os.LookupEnv("LOUPE_B")
+	assert.False(t, ok)
+	_, ok = os.LookupEnv("LOUPE_C")
+	assert.False(t, ok)
+
+	t.Run("unset", func(t *testing.T) {
+		Unset(t, "LOUPE_A")
+		_, ok := os.LookupEnv("LOUPE_A")
+		assert.False(t, ok)
+	})
+	assert.Equal(t, "orig", os.Getenv("LOUPE_A"))
+
+	m := tools.Mock()
+	Set(m, "", "x")
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.Contains(t, res.Err, "set : ")
+}
+
+func TestSnapshot(t *testing.T) {
+	os.Setenv("LOUPE_A", "orig")
+	defer os.Unsetenv("LOUPE_A")
+	before := os.Environ()
+
+	t.Run("snapshot", func(t *testing.T) {
+		Snapshot(t)
+		os.Setenv("LOUPE_A", "changed")
+		os.Setenv("LOUPE_NEW", "x=y")
+		os.Unsetenv("PATH")
+	})
+	assert.ElementsMatch(t, before, os.Environ())
+}
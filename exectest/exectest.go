@@ -0,0 +1,262 @@
+package exectest
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+	Log(args ...interface{})
+}
+
+//DefaultTimeout limits how long a command runs when ctx has no deadline
+var DefaultTimeout = time.Minute
+
+//Process is a command started by Start
+type Process struct {
+	cmd     *exec.Cmd
+	name    string
+	t       TestingT
+	out     *lineWriter
+	start   time.Time
+	timeout time.Duration
+
+	timedOut bool
+	done     chan struct{}
+	result   *Result
+	once     sync.Once
+}
+
+//Result is the outcome of a command, every check reports a failure with
+//t.Errorf and returns the Result so checks can be chained
+type Result struct {
+	//Output is the combined stdout and stderr
+	Output string
+
+	//ExitCode is -1 when the command was killed
+	ExitCode int
+
+	//TimedOut is set when the command was killed because ctx was done or the
+	//timeout passed
+	TimedOut bool
+
+	Duration time.Duration
+
+	t    TestingT
+	name string
+}
+
+//Run runs name with args, waits for it to exit and returns the result. See
+//Start.
+func Run(t TestingT, ctx context.Context, name string, args ...string) *Result {
+	t.Helper()
+	p := Start(t, ctx, name, args...)
+	if p == nil {
+		return &Result{ExitCode: -1, t: t, name: commandLine(name, args)}
+	}
+	return p.Wait()
+}
+
+//Start starts name with args in its own process group. Combined stdout and
+//stderr are logged line by line with t.Log while the command runs, and
+//captured for the Result. When ctx is done, or after DefaultTimeout if it
+//has no deadline, the process group is killed and the test fails. The
+//process group is also killed when the test ends, so servers and their
+//children don't outlive it. A command that can't be started stops the test.
+func Start(t TestingT, ctx context.Context, name string, args ...string) *Process {
+	t.Helper()
+	timeout := DefaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	p := &Process{
+		cmd:     exec.Command(name, args...),
+		name:    commandLine(name, args),
+		t:       t,
+		out:     &lineWriter{log: t.Log},
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+	p.cmd.Stdout = p.out
+	p.cmd.Stderr = p.out
+	setProcessGroup(p.cmd)
+
+	p.start = time.Now()
+	if err := p.cmd.Start(); err != nil {
+		cancel()
+		t.Errorf("exectest: start %s: %v", p.name, err)
+		t.FailNow()
+		return nil
+	}
+
+	exited, watched := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watched)
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			p.timedOut = true
+			killProcessGroup(p.cmd)
+		case <-exited:
+		}
+	}()
+	go func() {
+		err := p.cmd.Wait()
+		close(exited)
+		<-watched
+		p.finish(err)
+	}()
+
+	t.Cleanup(func() {
+		killProcessGroup(p.cmd)
+		<-p.done
+	})
+	return p
+}
+
+func (p *Process) finish(err error) {
+	p.out.flush()
+	r := &Result{
+		Output:   p.out.String(),
+		ExitCode: p.cmd.ProcessState.ExitCode(),
+		TimedOut: p.timedOut,
+		Duration: time.Since(p.start),
+		t:        p.t,
+		name:     p.name,
+	}
+	if err != nil && r.ExitCode == 0 {
+		r.ExitCode = -1
+	}
+	p.result = r
+	close(p.done)
+}
+
+//Wait waits for the command to exit and returns its result. If it timed out
+//the test fails.
+func (p *Process) Wait() *Result {
+	p.t.Helper()
+	<-p.done
+	p.once.Do(func() {
+		if p.result.TimedOut {
+			p.t.Errorf("%s: timed out after %v", p.name, p.timeout)
+		}
+	})
+	return p.result
+}
+
+//Output returns the combined output so far
+func (p *Process) Output() string {
+	return p.out.String()
+}
+
+//Kill kills the process group and waits for the command to exit
+func (p *Process) Kill() *Result {
+	killProcessGroup(p.cmd)
+	<-p.done
+	return p.result
+}
+
+//AssertExitCode verifies the exit code
+func (r *Result) AssertExitCode(code int) *Result {
+	r.t.Helper()
+	if r.ExitCode != code {
+		r.t.Errorf("%s: expected exit code %d, got %d\noutput:\n%s", r.name, code, r.ExitCode, r.Output)
+	}
+	return r
+}
+
+//AssertOutputContains verifies the output contains s
+func (r *Result) AssertOutputContains(s string) *Result {
+	r.t.Helper()
+	if !strings.Contains(r.Output, s) {
+		r.t.Errorf("%s: output doesn't contain %q\noutput:\n%s", r.name, s, r.Output)
+	}
+	return r
+}
+
+//AssertOutput verifies the output is want and fails with a diff if not.
+//opts configure the Differ.
+func (r *Result) AssertOutput(want string, opts ...tools.Option) *Result {
+	r.t.Helper()
+	opts = append([]tools.Option{tools.WithMode(tools.LineMode), tools.WithLabels("expected", "output")}, opts...)
+	d := tools.Diff(want, r.Output, opts...)
+	if !d.Equal() {
+		r.t.Errorf("%s: output Not Equal\n%s", r.name, d)
+	}
+	return r
+}
+
+//AssertGolden compares the output with the golden file at path, written
+//with -update like golden.Assert
+func (r *Result) AssertGolden(path string) *Result {
+	r.t.Helper()
+	golden.Assert(r.t, r.Output, path)
+	return r
+}
+
+// lineWriter captures output and logs each complete line
+type lineWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	pending []byte
+	log     func(args ...interface{})
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.pending[:i]))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush logs the last line if it doesn't end in a newline
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.log(string(w.pending))
+		w.pending = nil
+	}
+}
+
+func (w *lineWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func commandLine(name string, args []string) string {
+	parts := []string{filepath.Base(name)}
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\$") {
+			a = strconv.Quote(a)
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,90 @@
+package exectest
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func shell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+}
+
+func TestRun(t *testing.T) {
+	shell(t)
+	m := tools.Mock()
+	r := Run(m, context.Background(), "sh", "-c", "echo hello; echo err >&2; printf partial; exit 3")
+	r.AssertExitCode(3).AssertOutputContains("err").AssertOutput("hello\nerr\npartial")
+	res := m.Results()
+	assert.Equal(t, "", res.Err)
+	assert.Equal(t, "hello\nerr\npartial\n", res.Log)
+	assert.False(t, r.TimedOut)
+
+	Run(t, context.Background(), "sh", "-c", "echo hello; echo err >&2").AssertExitCode(0).AssertGolden("testdata/hello.golden")
+}
+
+func TestRunAssertions(t *testing.T) {
+	shell(t)
+	m := tools.Mock()
+	Run(m, context.Background(), "sh", "-c", "echo hi").
+		AssertExitCode(1).
+		AssertOutputContains("bye").
+		AssertOutput("bye\n", tools.WithNoColor())
+	assert.Equal(t, "sh -c \"echo hi\": expected exit code 1, got 0\noutput:\nhi\n"+
+		"sh -c \"echo hi\": output doesn't contain \"bye\"\noutput:\nhi\n"+
+		"sh -c \"echo hi\": output Not Equal\n--- expected\n+++ output\n@@ -1 +1 @@\n-bye\n+hi\n", m.Results().Err)
+}
+
+func TestTimeout(t *testing.T) {
+	shell(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// the background child keeps the output open, only killing the process
+	// group ends the command
+	m := tools.Mock()
+	r := Run(m, ctx, "sh", "-c", "(sleep 30; echo late) & echo started")
+	res := m.Results()
+	assert.True(t, r.TimedOut)
+	assert.Equal(t, 0, r.ExitCode, "sh itself exited")
+	assert.Equal(t, "started\n", r.Output)
+	assert.True(t, r.Duration < 10*time.Second, r.Duration.String())
+	assert.Contains(t, res.Err, "sh -c \"(sleep 30; echo late) & echo started\": timed out after ")
+}
+
+func TestStart(t *testing.T) {
+	shell(t)
+	m := tools.Mock()
+	p := Start(m, context.Background(), "sh", "-c", "echo ready; sleep 30")
+	for p.Output() == "" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "ready\n", p.Output())
+	m.Results()
+	select {
+	case <-p.done:
+	default:
+		t.Error("process still running after cleanup")
+	}
+
+	m = tools.Mock()
+	r := Start(m, context.Background(), "sleep", "30").Kill()
+	assert.Equal(t, -1, r.ExitCode)
+	assert.False(t, r.TimedOut)
+	assert.Equal(t, "", m.Results().Err)
+}
+
+func TestStartFails(t *testing.T) {
+	m := tools.Mock()
+	r := Run(m, context.Background(), "./testdata/missing")
+	res := m.Results()
+	assert.Equal(t, -1, r.ExitCode)
+	assert.True(t, res.FailNow)
+	assert.Contains(t, res.Err, "exectest: start missing: ")
+}
@@ -0,0 +1,215 @@
+package filecheck
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Error describes the first line of the output that doesn't match the
+//expected file
+type Error struct {
+	//Line is the line of the expected file whose directive failed
+	Line int
+
+	//Directive is the failed line of the expected file
+	Directive string
+
+	//Got is the output line it was checked against, empty at the end of
+	//the output
+	Got string
+
+	//Diff shows the output against the expected file, with the lines that
+	//matched a directive in place of it
+	Diff string
+}
+
+func (e *Error) Error() string {
+	got := strconv.Quote(e.Got)
+	if e.Got == "" {
+		got = "end of output"
+	}
+	return fmt.Sprintf("line %d: expected %s, got %s\n%s", e.Line, e.Directive, got, e.Diff)
+}
+
+// directive matches one output line
+type directive struct {
+	line int
+	text string
+	fn   func(string) bool
+}
+
+// parse turns the lines of an expected file into directives. count: N
+// repeats the directive after it N times.
+func parse(expected string) ([]directive, error) {
+	var ds []directive
+	for i, line := range splitLines(expected) {
+		n := 1
+		rest := line
+		if strings.HasPrefix(rest, "count:") {
+			f := strings.SplitN(strings.TrimSpace(rest[len("count:"):]), " ", 2)
+			c, err := strconv.Atoi(f[0])
+			if err != nil || c < 0 {
+				return nil, fmt.Errorf("line %d: invalid count in %q", i+1, line)
+			}
+			n, rest = c, ""
+			if len(f) > 1 {
+				rest = f[1]
+			}
+		}
+		fn, err := matcher(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		for j := 0; j < n; j++ {
+			ds = append(ds, directive{line: i + 1, text: line, fn: fn})
+		}
+	}
+	return ds, nil
+}
+
+// matcher returns the func checking a line against the directive text:
+// regexp: matches the whole line, contains: a part of it, literal: the rest
+// as is for lines starting with a directive, and other lines must equal it
+func matcher(text string) (func(string) bool, error) {
+	switch {
+	case strings.HasPrefix(text, "regexp:"):
+		re, err := regexp.Compile(`^(?:` + strings.TrimSpace(text[len("regexp:"):]) + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case strings.HasPrefix(text, "contains:"):
+		sub := strings.TrimSpace(text[len("contains:"):])
+		return func(l string) bool { return strings.Contains(l, sub) }, nil
+	case strings.HasPrefix(text, "literal:"):
+		text = strings.TrimPrefix(text[len("literal:"):], " ")
+	}
+	return func(l string) bool { return l == text }, nil
+}
+
+//Check checks got line by line against an expected file of directives,
+//like LLVM's FileCheck. Each line of expected matches one line of got:
+//
+//	regexp: <re>      the whole line matches re
+//	contains: <text>  the line contains text
+//	count: <n> <line> the next n lines each match line, itself plain or a directive
+//	literal: <text>   the line is text, for lines starting like a directive
+//	<text>            the line is text
+//
+//Line endings are normalized to \n. It returns an *Error for the first
+//mismatch, or a parse error for an invalid directive.
+func Check(got, expected string) error {
+	ds, err := parse(expected)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(got)
+	var exp []string
+	for i, d := range ds {
+		if i < len(lines) && d.fn(lines[i]) {
+			exp = append(exp, lines[i])
+			continue
+		}
+		e := &Error{Line: d.line, Directive: d.text}
+		if i < len(lines) {
+			e.Got = lines[i]
+		}
+		// the remaining directives once each, as written
+		last := 0
+		for _, r := range ds[i:] {
+			if r.line != last {
+				exp = append(exp, r.text)
+				last = r.line
+			}
+		}
+		e.Diff = tools.Diff(join(exp), join(lines), tools.WithMode(tools.LineMode), tools.WithLabels("expected", "got")).String()
+		return e
+	}
+	if len(lines) > len(ds) {
+		return &Error{
+			Line:      len(splitLines(expected)) + 1,
+			Directive: "end of output",
+			Got:       lines[len(ds)],
+			Diff:      tools.Diff(join(exp), join(lines), tools.WithMode(tools.LineMode), tools.WithLabels("expected", "got")).String(),
+		}
+	}
+	return nil
+}
+
+//Assert checks got against the expected file at path with Check and fails
+//the test with the diff on a mismatch. got can be a string, []byte or
+//fmt.Stringer.
+func Assert(t TestingT, got interface{}, path string) bool {
+	t.Helper()
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("read expected file %s: %v", path, err)
+		return false
+	}
+	return check(t, got, string(bs), path)
+}
+
+//AssertString is Assert with the directives in expected instead of a file
+func AssertString(t TestingT, got interface{}, expected string) bool {
+	t.Helper()
+	return check(t, got, expected, "")
+}
+
+func check(t TestingT, got interface{}, expected, path string) bool {
+	t.Helper()
+	out := output(got)
+	err := Check(out, expected)
+	if err == nil {
+		return true
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		t.Errorf("invalid expected file %s: %v", path, err)
+		return false
+	}
+	header := fmt.Sprintf("output does not match, line %d: expected %s", e.Line, e.Directive)
+	if path != "" {
+		header = fmt.Sprintf("output does not match %s:%d: expected %s", path, e.Line, e.Directive)
+	}
+	tools.ReportFailureValues(t, header, e.Diff, expected, out)
+	t.Errorf("%s\n%s", header, e.Diff)
+	return false
+}
+
+func output(got interface{}) string {
+	switch g := got.(type) {
+	case string:
+		return g
+	case []byte:
+		return string(g)
+	case fmt.Stringer:
+		return g.String()
+	}
+	panic(fmt.Sprintf("filecheck: got must be a string, []byte or fmt.Stringer, got %T", got))
+}
+
+func splitLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func join(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
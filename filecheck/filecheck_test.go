@@ -0,0 +1,83 @@
+package filecheck
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+const expected = `starting server
+regexp: listening on :\d+
+count: 2 regexp: worker \d ready
+literal: regexp: not a directive
+done
+`
+
+func TestCheck(t *testing.T) {
+	got := "starting server\r\nlistening on :8080\nworker 1 ready\nworker 2 ready\nregexp: not a directive\ndone\n"
+	assert.NoError(t, Check(got, expected))
+	assert.NoError(t, Check("", ""))
+	assert.NoError(t, Check("a\n", "count: 0 b\na"))
+
+	err := Check("starting server\nlistening on :x\n", expected)
+	if assert.IsType(t, &Error{}, err) {
+		e := err.(*Error)
+		assert.Equal(t, 2, e.Line)
+		assert.Equal(t, `regexp: listening on :\d+`, e.Directive)
+		assert.Equal(t, "listening on :x", e.Got)
+	}
+
+	// a count stops at the first line that doesn't match
+	err = Check("starting server\nlistening on :1\nworker 1 ready\nstopped\n", expected)
+	assert.EqualError(t, err, `line 3: expected count: 2 regexp: worker \d ready, got "stopped"
+--- expected
++++ got
+@@ -1,6 +1,4 @@
+ starting server
+ listening on :1
+ worker 1 ready
+-count: 2 regexp: worker \d ready
+-literal: regexp: not a directive
+-done
++stopped
+`)
+
+	err = Check("a\n", "a\nb\n")
+	assert.EqualError(t, err, "line 2: expected b, got end of output\n--- expected\n+++ got\n@@ -1,2 +1 @@\n a\n-b\n")
+
+	err = Check("a\nb\n", "a\n")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, 2, err.(*Error).Line)
+		assert.Equal(t, "end of output", err.(*Error).Directive)
+		assert.Equal(t, "b", err.(*Error).Got)
+	}
+
+	assert.EqualError(t, Check("", "regexp: ("), "line 1: error parsing regexp: missing closing ): `^(?:()$`")
+	assert.EqualError(t, Check("", "count: x a"), `line 1: invalid count in "count: x a"`)
+}
+
+func TestAssert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.check")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("hello\ncontains: wor\n"), 0644))
+	assert.True(t, Assert(t, []byte("hello\nworld\n"), path))
+
+	m := tools.Mock()
+	assert.False(t, Assert(m, "hello\nthere\n", path))
+	res := m.Results()
+	assert.Equal(t, "output does not match "+path+":2: expected contains: wor\n"+
+		"--- expected\n+++ got\n@@ -1,2 +1,2 @@\n hello\n-contains: wor\n+there\n", res.Err)
+
+	m = tools.Mock()
+	assert.False(t, AssertString(m, "x", "regexp: [a-w]"))
+	assert.Equal(t, "output does not match, line 1: expected regexp: [a-w]\n"+
+		"--- expected\n+++ got\n@@ -1 +1 @@\n-regexp: [a-w]\n+x\n", m.Results().Err)
+
+	m = tools.Mock()
+	assert.False(t, Assert(m, "x", filepath.Join(t.TempDir(), "missing")))
+	assert.Contains(t, m.Results().Err, "read expected file")
+
+	assert.Panics(t, func() { AssertString(t, 1, "") })
+}
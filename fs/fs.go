@@ -0,0 +1,80 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//Tree maps slash separated paths to file contents, a path ending in / is an
+//empty directory
+type Tree map[string]string
+
+//Dir creates a temporary directory containing tree, removed when the test
+//and its subtests complete, and returns its path. Parent directories are
+//created as needed.
+func Dir(t TestingT, tree Tree) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "loupe")
+	if err != nil {
+		t.Errorf("create temp dir: %v", err)
+		t.FailNow()
+		return ""
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(root)
+	})
+
+	if err := write(root, tree); err != nil {
+		t.Errorf("write tree: %v", err)
+		t.FailNow()
+	}
+	return root
+}
+
+func write(root string, tree Tree) error {
+	for p, content := range tree {
+		path := filepath.Join(root, filepath.FromSlash(p))
+		if strings.HasSuffix(p, "/") {
+			if err := os.MkdirAll(path, 0777); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//AssertTree compares the files below root against expected with
+//tools.DiffDirs and fails with the diff, labeled want and got, if they
+//differ. Empty directories are not compared.
+func AssertTree(t TestingT, root string, expected Tree, opts ...tools.Option) bool {
+	t.Helper()
+	d := tools.DiffDirs(Dir(t, expected), root, opts...)
+	if err := d.Error(); err != nil {
+		t.Errorf("compare %s: %v", root, err)
+		return false
+	}
+	if !d.Equal() {
+		t.Errorf("tree %s differs (-want +got):\n%s", root, d)
+		return false
+	}
+	return true
+}
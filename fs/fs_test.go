@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDir(t *testing.T) {
+	var root string
+	t.Run("build", func(t *testing.T) {
+		root = Dir(t, Tree{
+			"go.mod":          "module x\n",
+			"pkg/a/a.go":      "package a\n",
+			"testdata/empty/": "",
+		})
+		bs, err := ioutil.ReadFile(filepath.Join(root, "pkg", "a", "a.go"))
+		assert.NoError(t, err)
+		assert.Equal(t, "package a\n", string(bs))
+
+		info, err := os.Stat(filepath.Join(root, "testdata", "empty"))
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		assert.True(t, AssertTree(t, root, Tree{"go.mod": "module x\n", "pkg/a/a.go": "package a\n"}))
+	})
+
+	// the tree is removed with the test
+	_, err := os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAssertTree(t *testing.T) {
+	root := Dir(t, Tree{"a.txt": "one\n", "b.txt": "two\n"})
+
+	m := tools.Mock()
+	ok := AssertTree(m, root, Tree{"a.txt": "one\n", "c.txt": "three\n"}, tools.WithNoColor())
+	res := m.Results()
+	assert.False(t, ok)
+	assert.Contains(t, res.Err, "differs (-want +got)")
+	assert.Contains(t, res.Err, "--- /dev/null\n+++ b/b.txt\n@@ -0,0 +1 @@\n+two\n")
+	assert.Contains(t, res.Err, "--- a/c.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-three\n")
+}
@@ -0,0 +1,113 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/prasek/loupe/tools"
+	"golang.org/x/tools/txtar"
+)
+
+//WantPrefix starts the names of the files in the expected section of an
+//archive
+const WantPrefix = "want/"
+
+//ReadArchive reads the txtar archive at path and returns its input files
+//and its expected files below WantPrefix, with the prefix removed
+func ReadArchive(path string) (input, want Tree, err error) {
+	a, err := txtar.ParseFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	input, want = Tree{}, Tree{}
+	for _, f := range a.Files {
+		if strings.HasPrefix(f.Name, WantPrefix) {
+			want[strings.TrimPrefix(f.Name, WantPrefix)] = string(f.Data)
+		} else {
+			input[f.Name] = string(f.Data)
+		}
+	}
+	return input, want, nil
+}
+
+//LoadArchive creates a temporary directory containing the input files of
+//the txtar archive at path, like Dir, and returns its path
+func LoadArchive(t TestingT, path string) string {
+	t.Helper()
+	input, _, err := ReadArchive(path)
+	if err != nil {
+		t.Errorf("read archive: %v", err)
+		t.FailNow()
+		return ""
+	}
+	return Dir(t, input)
+}
+
+//AssertArchive compares the files below root with the expected section of
+//the txtar archive at path, like AssertTree. With -update the expected
+//section is replaced with the files below root instead, keeping the comment
+//and input files. txtar files end in a newline, so one is added to files
+//that don't.
+func AssertArchive(t TestingT, root, path string, opts ...tools.Option) bool {
+	t.Helper()
+	if golden.Update() {
+		if err := updateArchive(root, path); err != nil {
+			t.Errorf("update archive %s: %v", path, err)
+			return false
+		}
+		return true
+	}
+
+	_, want, err := ReadArchive(path)
+	if err != nil {
+		t.Errorf("read archive: %v", err)
+		return false
+	}
+	return AssertTree(t, root, want, opts...)
+}
+
+func updateArchive(root, path string) error {
+	a, err := txtar.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	var files []txtar.File
+	for _, f := range a.Files {
+		if !strings.HasPrefix(f.Name, WantPrefix) {
+			files = append(files, f)
+		}
+	}
+
+	var names []string
+	got := map[string][]byte{}
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		bs, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		names = append(names, name)
+		got[name] = bs
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		files = append(files, txtar.File{Name: WantPrefix + n, Data: got[n]})
+	}
+	a.Files = files
+	return ioutil.WriteFile(path, txtar.Format(a), 0666)
+}
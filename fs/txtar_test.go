@@ -0,0 +1,58 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+// rename renames the files below root to upper case
+func rename(t *testing.T, root string) {
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return os.Rename(p, filepath.Join(filepath.Dir(p), strings.ToUpper(info.Name())))
+	})
+	assert.NoError(t, err)
+}
+
+func TestArchive(t *testing.T) {
+	input, want, err := ReadArchive("testdata/rename.txtar")
+	assert.NoError(t, err)
+	assert.Equal(t, Tree{"a.txt": "one\n", "dir/b.txt": "two\n"}, input)
+	assert.Equal(t, Tree{"A.TXT": "one\n", "dir/B.TXT": "two\n"}, want)
+
+	root := LoadArchive(t, "testdata/rename.txtar")
+	rename(t, root)
+	assert.True(t, AssertArchive(t, root, "testdata/rename.txtar"))
+
+	m := tools.Mock()
+	root = LoadArchive(t, "testdata/rename.txtar")
+	assert.False(t, AssertArchive(m, root, "testdata/rename.txtar", tools.WithNoColor()))
+	res := m.Results()
+	assert.Contains(t, res.Err, "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+one\n")
+	assert.Contains(t, res.Err, "--- a/A.TXT\n+++ /dev/null\n")
+}
+
+func TestUpdateArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.txtar")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("comment\n-- in.txt --\nx\n-- want/old.txt --\nold\n"), 0666))
+	root := Dir(t, Tree{"in.txt": "x\n", "out/z.txt": "z", "out/a.txt": "a\n"})
+
+	assert.NoError(t, updateArchive(root, path))
+	bs, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "comment\n-- in.txt --\nx\n-- want/in.txt --\nx\n-- want/out/a.txt --\na\n-- want/out/z.txt --\nz\n", string(bs))
+
+	m := tools.Mock()
+	LoadArchive(m, filepath.Join(t.TempDir(), "missing.txtar"))
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.Contains(t, res.Err, "read archive: ")
+}
@@ -0,0 +1,359 @@
+package fstest
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	gofstest "testing/fstest"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Op is a file system operation that can be made to fail with FailOn
+type Op string
+
+const (
+	//OpOpen opens, stats or reads a file or directory
+	OpOpen Op = "open"
+
+	//OpWrite writes a file with WriteFile or a writer from Create
+	OpWrite Op = "write"
+
+	//OpMkdir creates a directory
+	OpMkdir Op = "mkdir"
+
+	//OpRemove removes a file or directory
+	OpRemove Op = "remove"
+
+	//OpRename renames a file, the pattern is matched against the old name
+	OpRename Op = "rename"
+)
+
+type failure struct {
+	op      Op
+	pattern string
+	err     error
+}
+
+//FS is an in-memory fs.FS that tests can also write to, with errors injected
+//on demand, e.g. syscall.EACCES or syscall.ENOSPC. It's safe for concurrent
+//use.
+type FS struct {
+	mu       sync.Mutex
+	files    gofstest.MapFS
+	failures []failure
+	quota    int
+}
+
+//New returns an FS with files, mapping slash separated paths to contents.
+//A path ending in / is an empty directory.
+func New(files map[string]string) *FS {
+	f := &FS{files: gofstest.MapFS{}, quota: -1}
+	now := time.Now()
+	for name, content := range files {
+		if strings.HasSuffix(name, "/") {
+			f.files[strings.TrimSuffix(name, "/")] = &gofstest.MapFile{Mode: fs.ModeDir | 0777, ModTime: now}
+			continue
+		}
+		f.files[name] = &gofstest.MapFile{Data: []byte(content), Mode: 0666, ModTime: now}
+	}
+	return f
+}
+
+//FailOn makes op fail with err for the names matching pattern, see
+//path.Match, until ClearFailures is called. The error is returned as an
+//*fs.PathError, so errors.Is(err, fs.ErrPermission) holds for
+//syscall.EACCES.
+func (f *FS) FailOn(op Op, pattern string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, failure{op: op, pattern: pattern, err: err})
+}
+
+//SetQuota limits the total size of the files to n bytes, writes that would
+//exceed it fail with syscall.ENOSPC. A negative n removes the limit.
+func (f *FS) SetQuota(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quota = n
+}
+
+//ClearFailures removes the failures added by FailOn and the quota
+func (f *FS) ClearFailures() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = nil
+	f.quota = -1
+}
+
+//Open implements fs.FS
+func (f *FS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpOpen, name); err != nil {
+		return nil, err
+	}
+	return f.files.Open(name)
+}
+
+//ReadFile implements fs.ReadFileFS
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpOpen, name); err != nil {
+		return nil, err
+	}
+	return f.files.ReadFile(name)
+}
+
+//Stat implements fs.StatFS
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpOpen, name); err != nil {
+		return nil, err
+	}
+	return f.files.Stat(name)
+}
+
+//ReadDir implements fs.ReadDirFS
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpOpen, name); err != nil {
+		return nil, err
+	}
+	return f.files.ReadDir(name)
+}
+
+//WriteFile writes data to the file name, creating it with perm if it
+//doesn't exist. Parent directories are created as needed, like a
+//filesystem with os.MkdirAll before every write.
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	size := len(data)
+	if old, ok := f.files[name]; ok {
+		size -= len(old.Data)
+	}
+	if err := f.writable(name, size); err != nil {
+		return err
+	}
+	f.files[name] = &gofstest.MapFile{Data: append([]byte{}, data...), Mode: perm, ModTime: time.Now()}
+	return nil
+}
+
+//Create creates or truncates the file name and returns a writer that
+//appends to it. Every Write is checked against the failures and the quota.
+func (f *FS) Create(name string) (*Writer, error) {
+	if err := f.WriteFile(name, nil, 0666); err != nil {
+		return nil, err
+	}
+	return &Writer{fs: f, name: name}, nil
+}
+
+//MkdirAll creates the directory name and any missing parents
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: string(OpMkdir), Path: name, Err: fs.ErrInvalid}
+	}
+	if err := f.fail(OpMkdir, name); err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	if err := f.parentsAreDirs(OpMkdir, name); err != nil {
+		return err
+	}
+	if file, ok := f.files[name]; ok {
+		if file.Mode.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: string(OpMkdir), Path: name, Err: syscall.ENOTDIR}
+	}
+	f.files[name] = &gofstest.MapFile{Mode: fs.ModeDir | perm, ModTime: time.Now()}
+	return nil
+}
+
+//Remove removes the file or empty directory name
+func (f *FS) Remove(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpRemove, name); err != nil {
+		return err
+	}
+	_, ok := f.files[name]
+	children := f.children(name)
+	switch {
+	case !ok && len(children) == 0:
+		return &fs.PathError{Op: string(OpRemove), Path: name, Err: fs.ErrNotExist}
+	case len(children) > 0:
+		return &fs.PathError{Op: string(OpRemove), Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(f.files, name)
+	return nil
+}
+
+//Rename renames the file oldname to newname, replacing it if it exists
+func (f *FS) Rename(oldname, newname string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(OpRename, oldname); err != nil {
+		return err
+	}
+	file, ok := f.files[oldname]
+	if !ok || file.Mode.IsDir() {
+		return &fs.PathError{Op: string(OpRename), Path: oldname, Err: fs.ErrNotExist}
+	}
+	if !fs.ValidPath(newname) {
+		return &fs.PathError{Op: string(OpRename), Path: newname, Err: fs.ErrInvalid}
+	}
+	if err := f.parentsAreDirs(OpRename, newname); err != nil {
+		return err
+	}
+	delete(f.files, oldname)
+	f.files[newname] = file
+	return nil
+}
+
+//Files returns the contents of the regular files by path
+func (f *FS) Files() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	files := make(map[string]string)
+	for name, file := range f.files {
+		if file.Mode.IsRegular() {
+			files[name] = string(file.Data)
+		}
+	}
+	return files
+}
+
+//Writer appends to a file of an FS, see Create
+type Writer struct {
+	fs   *FS
+	name string
+}
+
+//Write appends p to the file. When a failure or the quota stops the write
+//nothing is written.
+func (w *Writer) Write(p []byte) (int, error) {
+	f := w.fs
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.writable(w.name, len(p)); err != nil {
+		return 0, err
+	}
+	file := f.files[w.name]
+	if file == nil {
+		file = &gofstest.MapFile{Mode: 0666}
+	}
+	// copy, so files opened before the write keep reading the old contents
+	data := append(file.Data[:len(file.Data):len(file.Data)], p...)
+	f.files[w.name] = &gofstest.MapFile{Data: data, Mode: file.Mode, ModTime: time.Now()}
+	return len(p), nil
+}
+
+//Close implements io.Closer
+func (w *Writer) Close() error {
+	return nil
+}
+
+// writable checks that size more bytes can be written to the file name
+func (f *FS) writable(name string, size int) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: string(OpWrite), Path: name, Err: fs.ErrInvalid}
+	}
+	if err := f.fail(OpWrite, name); err != nil {
+		return err
+	}
+	if file, ok := f.files[name]; (ok && file.Mode.IsDir()) || len(f.children(name)) > 0 {
+		return &fs.PathError{Op: string(OpWrite), Path: name, Err: syscall.EISDIR}
+	}
+	if err := f.parentsAreDirs(OpWrite, name); err != nil {
+		return err
+	}
+	if f.quota >= 0 && f.size()+size > f.quota {
+		return &fs.PathError{Op: string(OpWrite), Path: name, Err: syscall.ENOSPC}
+	}
+	return nil
+}
+
+// fail returns the first injected error for op on name
+func (f *FS) fail(op Op, name string) error {
+	for _, fl := range f.failures {
+		if fl.op != op {
+			continue
+		}
+		if ok, _ := path.Match(fl.pattern, name); ok {
+			return &fs.PathError{Op: string(op), Path: name, Err: fl.err}
+		}
+	}
+	return nil
+}
+
+// parentsAreDirs checks that no parent of name is a regular file
+func (f *FS) parentsAreDirs(op Op, name string) error {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if file, ok := f.files[dir]; ok && !file.Mode.IsDir() {
+			return &fs.PathError{Op: string(op), Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+	return nil
+}
+
+// children returns the paths below the directory name
+func (f *FS) children(name string) []string {
+	var paths []string
+	for p := range f.files {
+		if strings.HasPrefix(p, name+"/") {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (f *FS) size() int {
+	n := 0
+	for _, file := range f.files {
+		n += len(file.Data)
+	}
+	return n
+}
+
+//AssertFS compares the regular files of fsys against expected, mapping
+//slash separated paths to contents, with tools.DiffFS and fails with the
+//diff if they differ. Directories are not compared.
+func AssertFS(t TestingT, fsys fs.FS, expected map[string]string, opts ...tools.Option) bool {
+	t.Helper()
+	want := gofstest.MapFS{}
+	for name, content := range expected {
+		if !strings.HasSuffix(name, "/") {
+			want[name] = &gofstest.MapFile{Data: []byte(content)}
+		}
+	}
+	d := tools.DiffFS(want, fsys, opts...)
+	if err := d.Error(); err != nil {
+		t.Errorf("compare fs: %v", err)
+		return false
+	}
+	if !d.Equal() {
+		t.Errorf("fs differs (-want +got):\n%s", d)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package fstest
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFS(t *testing.T) {
+	fsys := New(map[string]string{
+		"go.mod":      "module x\n",
+		"cmd/main.go": "package main\n",
+		"empty/":      "",
+	})
+	assert.NoError(t, fstest.TestFS(fsys, "go.mod", "cmd/main.go", "empty"))
+
+	assert.NoError(t, fsys.WriteFile("out/a.txt", []byte("a\n"), 0644))
+	assert.NoError(t, fsys.MkdirAll("out/sub", 0755))
+	assert.NoError(t, fsys.Rename("go.mod", "go.mod.bak"))
+	assert.NoError(t, fsys.Remove("empty"))
+
+	w, err := fsys.Create("log.txt")
+	if assert.NoError(t, err) {
+		f, err := fsys.Open("log.txt")
+		assert.NoError(t, err)
+		w.Write([]byte("one\n"))
+		w.Write([]byte("two\n"))
+		assert.NoError(t, w.Close())
+		old, _ := ioutil.ReadAll(f)
+		assert.Equal(t, "", string(old), "opened before the writes")
+	}
+
+	assert.Equal(t, map[string]string{
+		"go.mod.bak":  "module x\n",
+		"cmd/main.go": "package main\n",
+		"out/a.txt":   "a\n",
+		"log.txt":     "one\ntwo\n",
+	}, fsys.Files())
+	assert.NoError(t, fstest.TestFS(fsys, "out/a.txt", "out/sub", "log.txt"))
+
+	assert.True(t, errors.Is(fsys.Remove("out"), syscall.ENOTEMPTY))
+	assert.True(t, errors.Is(fsys.Remove("nope"), fs.ErrNotExist))
+	assert.True(t, errors.Is(fsys.WriteFile("log.txt/x", nil, 0644), syscall.ENOTDIR))
+	assert.True(t, errors.Is(fsys.WriteFile("out", nil, 0644), syscall.EISDIR))
+	assert.True(t, errors.Is(fsys.WriteFile("../x", nil, 0644), fs.ErrInvalid))
+}
+
+func TestFailOn(t *testing.T) {
+	fsys := New(map[string]string{"secret/key": "k", "public": "p"})
+	fsys.FailOn(OpOpen, "secret/*", syscall.EACCES)
+	fsys.FailOn(OpWrite, "*.lock", syscall.EROFS)
+
+	_, err := fs.ReadFile(fsys, "secret/key")
+	assert.EqualError(t, err, "open secret/key: permission denied")
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	_, err = fsys.Open("secret/key")
+	assert.Error(t, err)
+	_, err = fs.ReadFile(fsys, "public")
+	assert.NoError(t, err)
+
+	err = fsys.WriteFile("app.lock", nil, 0644)
+	assert.EqualError(t, err, "write app.lock: read-only file system")
+
+	fsys.ClearFailures()
+	_, err = fs.ReadFile(fsys, "secret/key")
+	assert.NoError(t, err)
+	assert.NoError(t, fsys.WriteFile("app.lock", nil, 0644))
+}
+
+func TestQuota(t *testing.T) {
+	fsys := New(map[string]string{"a": "1234"})
+	fsys.SetQuota(10)
+
+	assert.NoError(t, fsys.WriteFile("b", []byte("12345"), 0644))
+	err := fsys.WriteFile("c", []byte("12"), 0644)
+	assert.EqualError(t, err, "write c: no space left on device")
+	assert.True(t, errors.Is(err, syscall.ENOSPC))
+	assert.NoError(t, fsys.WriteFile("a", []byte("1"), 0644), "replacing frees space")
+
+	w, err := fsys.Create("log")
+	if assert.NoError(t, err) {
+		n, err := w.Write([]byte("1234"))
+		assert.Equal(t, 4, n)
+		assert.NoError(t, err)
+		n, err = w.Write([]byte("5"))
+		assert.Equal(t, 0, n)
+		assert.True(t, errors.Is(err, syscall.ENOSPC))
+	}
+	assert.Equal(t, "1234", fsys.Files()["log"])
+}
+
+func TestAssertFS(t *testing.T) {
+	fsys := New(map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+
+	m := tools.Mock()
+	assert.True(t, AssertFS(m, fsys, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "dir/": ""}))
+	assert.Equal(t, "", m.Results().Err)
+
+	m = tools.Mock()
+	assert.False(t, AssertFS(m, fsys, map[string]string{"a.txt": "x\n", "c.txt": "c\n"}, tools.WithNoColor()))
+	assert.Equal(t, "fs differs (-want +got):\n"+
+		"--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-x\n+a\n"+
+		"--- /dev/null\n+++ b/b.txt\n@@ -0,0 +1 @@\n+b\n"+
+		"--- a/c.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-c\n", m.Results().Err)
+}
@@ -23,6 +23,9 @@ func main() {
 	var d string
 	var tests = internal.Tests
 
+	// keep the golden files colored regardless of where gen runs
+	tools.ForceColor()
+
 	for _, t := range tests {
 		switch t.InputType {
 		case internal.ValueInput:
@@ -0,0 +1,350 @@
+package gen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+//SeedEnv is the environment variable that overrides the random seed, set
+//it to the seed logged by a failing test to replay it exactly
+const SeedEnv = "LOUPE_SEED"
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Failed() bool
+	Logf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//Rand is a source of random values that can be replayed from its seed
+type Rand struct {
+	*rand.Rand
+	seed int64
+}
+
+//New returns a Rand seeded from $LOUPE_SEED, or the current time when it's
+//unset, and logs the seed if the test fails
+func New(t TestingT) *Rand {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	if s := os.Getenv(SeedEnv); s != "" {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			t.Errorf("invalid %s %q: %v", SeedEnv, s, err)
+			t.FailNow()
+		}
+	}
+	r := NewSeed(seed)
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("random seed %d, rerun with %s=%d", seed, SeedEnv, seed)
+		}
+	})
+	return r
+}
+
+//NewSeed returns a Rand with the given seed
+func NewSeed(seed int64) *Rand {
+	return &Rand{Rand: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+//Seed returns the seed r was created with
+func (r *Rand) Seed() int64 {
+	return r.seed
+}
+
+//IntRange returns an int in [min, max]
+func (r *Rand) IntRange(min, max int) int {
+	if max <= min {
+		return min
+	}
+	span := uint64(max) - uint64(min) + 1
+	if span == 0 {
+		// the full range of int64
+		return int(r.Uint64())
+	}
+	return min + int(r.Uint64()%span)
+}
+
+//String returns a string of 0 to maxLen letters and digits
+func (r *Rand) String(maxLen int) string {
+	return r.StringOf(alphanumeric, 0, maxLen)
+}
+
+//StringOf returns a string of min to max runes from alphabet
+func (r *Rand) StringOf(alphabet string, min, max int) string {
+	runes := []rune(alphabet)
+	n := r.IntRange(min, max)
+	s := make([]rune, n)
+	for i := range s {
+		s[i] = runes[r.Intn(len(runes))]
+	}
+	return string(s)
+}
+
+//UUID returns a random version 4 UUID in its canonical form
+func (r *Rand) UUID() string {
+	var b [16]byte
+	r.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+//Time returns a UTC time between 1970 and 2100
+func (r *Rand) Time() time.Time {
+	return r.TimeRange(time.Unix(0, 0), maxTime)
+}
+
+//TimeRange returns a UTC time in [min, max)
+func (r *Rand) TimeRange(min, max time.Time) time.Time {
+	d := max.Sub(min)
+	if d <= 0 {
+		return min.UTC()
+	}
+	return min.Add(time.Duration(r.Int63n(int64(d)))).UTC()
+}
+
+//Fill populates the value ptr points to with random values: numbers,
+//strings, times, and slices and maps of up to maxElems
+//elements, recursing into structs, arrays and pointers. Unexported fields,
+//funcs, chans and interfaces are left alone, nesting stops at maxDepth.
+func (r *Rand) Fill(ptr interface{}) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("gen: Fill needs a non nil pointer, got %T", ptr))
+	}
+	r.fill(v.Elem(), 0)
+}
+
+const (
+	maxInt       = int(^uint(0) >> 1)
+	alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	maxElems     = 3
+	maxDepth     = 5
+	maxStringLen = 10
+)
+
+var (
+	maxTime  = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+func (r *Rand) fill(v reflect.Value, depth int) {
+	if v.Type() == timeType {
+		v.Set(reflect.ValueOf(r.Time()))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := uint(v.Type().Bits())
+		v.SetInt(int64(r.Uint64()<<(64-bits)) >> (64 - bits))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(r.Uint64() >> (64 - uint(v.Type().Bits())))
+	case reflect.Float32:
+		v.SetFloat(float64(r.Float32()*2-1) * math.MaxInt16)
+	case reflect.Float64:
+		v.SetFloat((r.Float64()*2 - 1) * math.MaxInt32)
+	case reflect.String:
+		v.SetString(r.String(maxStringLen))
+	case reflect.Ptr:
+		if depth >= maxDepth {
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		r.fill(p.Elem(), depth+1)
+		v.Set(p)
+	case reflect.Slice:
+		n := 0
+		if depth < maxDepth {
+			n = r.Intn(maxElems + 1)
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			r.fill(s.Index(i), depth+1)
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.fill(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		if depth < maxDepth {
+			for i := r.Intn(maxElems + 1); i > 0; i-- {
+				k := reflect.New(v.Type().Key()).Elem()
+				e := reflect.New(v.Type().Elem()).Elem()
+				r.fill(k, depth+1)
+				r.fill(e, depth+1)
+				m.SetMapIndex(k, e)
+			}
+		}
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				r.fill(f, depth+1)
+			}
+		}
+	}
+}
+
+//Generator produces random values of one type from a Rand, and smaller
+//values of it to shrink a failing input
+type Generator struct {
+	typ    reflect.Type
+	gen    func(r *Rand) interface{}
+	shrink func(v interface{}) []interface{}
+}
+
+//Generate returns a random value
+func (g Generator) Generate(r *Rand) interface{} {
+	return g.gen(r)
+}
+
+//Shrink returns values simpler than v, simplest first, or nil when v
+//can't be shrunk
+func (g Generator) Shrink(v interface{}) []interface{} {
+	if g.shrink == nil {
+		return nil
+	}
+	return g.shrink(v)
+}
+
+//Type returns the type of the generated values
+func (g Generator) Type() reflect.Type {
+	return g.typ
+}
+
+//Int generates ints across their full range, with 0, 1, -1 and the
+//extremes more likely than chance so edge cases come up. Ints shrink
+//towards 0, negative ones preferring their positive counterpart.
+func Int() Generator {
+	edges := []int{0, 1, -1, maxInt, -maxInt - 1}
+	return Generator{typ: reflect.TypeOf(0), gen: func(r *Rand) interface{} {
+		if r.Intn(10) == 0 {
+			return edges[r.Intn(len(edges))]
+		}
+		return int(r.Uint64())
+	}, shrink: func(v interface{}) []interface{} {
+		i := v.(int)
+		res := shrinkInt(i, 0)
+		if i < 0 && -i > 0 {
+			res = append([]interface{}{res[0], -i}, res[1:]...)
+		}
+		return res
+	}}
+}
+
+//IntRange generates ints in [min, max], they shrink towards the value in
+//the range closest to 0
+func IntRange(min, max int) Generator {
+	target := 0
+	if target < min {
+		target = min
+	}
+	if target > max {
+		target = max
+	}
+	return Generator{typ: reflect.TypeOf(0), gen: func(r *Rand) interface{} {
+		return r.IntRange(min, max)
+	}, shrink: func(v interface{}) []interface{} {
+		return shrinkInt(v.(int), target)
+	}}
+}
+
+// shrinkInt returns target and the values halfway and one step from v
+// towards it, halving the distance without overflowing
+func shrinkInt(v, target int) []interface{} {
+	if v == target {
+		return nil
+	}
+	res := []interface{}{target}
+	if mid := v - (v/2 - target/2); mid != v && mid != target {
+		res = append(res, mid)
+	}
+	step := v - 1
+	if v < target {
+		step = v + 1
+	}
+	if step != target {
+		res = append(res, step)
+	}
+	return res
+}
+
+//String generates strings of up to 20 letters and digits
+func String() Generator {
+	return StringOf(alphanumeric, 0, 20)
+}
+
+//StringOf generates strings of min to max runes from alphabet. Strings
+//shrink by dropping runes down to min and replacing runes with the first
+//rune of alphabet.
+func StringOf(alphabet string, min, max int) Generator {
+	first := []rune(alphabet)[0]
+	return Generator{typ: reflect.TypeOf(""), gen: func(r *Rand) interface{} {
+		return r.StringOf(alphabet, min, max)
+	}, shrink: func(v interface{}) []interface{} {
+		return shrinkString([]rune(v.(string)), min, first)
+	}}
+}
+
+// shrinkString returns s shortened to min runes, with halves and single
+// runes removed, and with runes replaced by first
+func shrinkString(s []rune, min int, first rune) []interface{} {
+	var res []interface{}
+	if len(s) > min {
+		res = append(res, string(s[:min]))
+		if half := len(s) / 2; half > min {
+			res = append(res, string(s[:half]), string(s[len(s)-half:]))
+		}
+		for i := range s {
+			res = append(res, string(s[:i])+string(s[i+1:]))
+		}
+	}
+	for i, c := range s {
+		if c != first {
+			t := append([]rune(nil), s...)
+			t[i] = first
+			res = append(res, string(t))
+		}
+	}
+	return res
+}
+
+//UUID generates version 4 UUID strings
+func UUID() Generator {
+	return Generator{typ: reflect.TypeOf(""), gen: func(r *Rand) interface{} {
+		return r.UUID()
+	}}
+}
+
+//Time generates UTC times between 1970 and 2100
+func Time() Generator {
+	return Generator{typ: timeType, gen: func(r *Rand) interface{} {
+		return r.Time()
+	}}
+}
+
+//Struct generates values of the type of example populated by Fill, e.g.
+//gen.Struct(User{}) or gen.Struct(&User{}) for pointers
+func Struct(example interface{}) Generator {
+	t := reflect.TypeOf(example)
+	return Generator{typ: t, gen: func(r *Rand) interface{} {
+		v := reflect.New(t)
+		r.fill(v.Elem(), 0)
+		return v.Elem().Interface()
+	}}
+}
@@ -0,0 +1,128 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeT struct {
+	failed   bool
+	logs     []string
+	errs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()          {}
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Failed() bool     { return t.failed }
+func (t *fakeT) FailNow()         { t.failed = true }
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) done() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+type address struct {
+	Street string
+	Zip    uint16
+}
+
+type user struct {
+	ID      int64
+	Name    string
+	Admin   bool
+	Score   float64
+	Created time.Time
+	Tags    []string
+	Attrs   map[string]int
+	Home    *address
+	Prev    [2]address
+	Next    *user
+	secret  string
+}
+
+func TestReproducible(t *testing.T) {
+	values := func(r *Rand) []interface{} {
+		var u user
+		r.Fill(&u)
+		return []interface{}{r.IntRange(-5, 5), r.String(8), r.UUID(), r.Time(), u, Struct(address{}).Generate(r)}
+	}
+	a, b := NewSeed(42), NewSeed(42)
+	assert.Equal(t, int64(42), a.Seed())
+	assert.Equal(t, values(a), values(b))
+	assert.NotEqual(t, values(a), values(NewSeed(43)))
+}
+
+func TestValues(t *testing.T) {
+	r := NewSeed(1)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for i := 0; i < 100; i++ {
+		n := r.IntRange(-3, 3)
+		assert.True(t, n >= -3 && n <= 3, "%d", n)
+		assert.Regexp(t, `^[a-zA-Z0-9]{0,8}$`, r.String(8))
+		assert.Regexp(t, `^[xy]{2,4}$`, r.StringOf("xy", 2, 4))
+		assert.Regexp(t, uuid, r.UUID())
+		tm := r.Time()
+		assert.True(t, tm.Year() >= 1970 && tm.Year() < 2100 && tm.Location() == time.UTC, "%v", tm)
+		assert.IsType(t, 0, Int().Generate(r))
+		assert.Regexp(t, uuid, UUID().Generate(r))
+	}
+
+	var u user
+	r.Fill(&u)
+	assert.Empty(t, u.secret)
+	assert.NotNil(t, u.Attrs)
+	assert.NotNil(t, u.Home)
+	assert.Panics(t, func() { r.Fill(u) })
+	assert.Equal(t, "gen.user", Struct(u).Type().String())
+}
+
+func TestShrink(t *testing.T) {
+	assert.Equal(t, []interface{}{0, 50, 99}, Int().Shrink(100))
+	assert.Equal(t, []interface{}{0, 7, -4, -6}, Int().Shrink(-7))
+	assert.Nil(t, Int().Shrink(0))
+	assert.Equal(t, []interface{}{5, 7, 8}, IntRange(5, 10).Shrink(9))
+	assert.Equal(t, []interface{}{"y", "yz", "yz", "yy", "xyz", "yxz"}, StringOf("xyz", 1, 3).Shrink("yyz")[:6])
+	assert.Nil(t, StringOf("xyz", 1, 3).Shrink("x"))
+	assert.Nil(t, UUID().Shrink("d0c4b6a2-0000-4000-8000-000000000000"))
+}
+
+func TestNew(t *testing.T) {
+	defer os.Setenv(SeedEnv, os.Getenv(SeedEnv))
+
+	os.Setenv(SeedEnv, "1234")
+	ft := &fakeT{}
+	r := New(ft)
+	assert.Equal(t, int64(1234), r.Seed())
+	assert.Equal(t, NewSeed(1234).Int63(), r.Int63())
+	ft.done()
+	assert.Empty(t, ft.logs, "passing tests don't log the seed")
+
+	ft = &fakeT{}
+	New(ft)
+	ft.failed = true
+	ft.done()
+	assert.Equal(t, []string{"random seed 1234, rerun with LOUPE_SEED=1234"}, ft.logs)
+
+	os.Setenv(SeedEnv, "x")
+	ft = &fakeT{}
+	New(ft)
+	assert.True(t, ft.failed)
+	assert.Contains(t, ft.errs[0], `invalid LOUPE_SEED "x"`)
+
+	os.Unsetenv(SeedEnv)
+	assert.NotEqual(t, New(&fakeT{}).Seed(), 0)
+}
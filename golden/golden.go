@@ -0,0 +1,140 @@
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the actual output")
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Update reports whether golden files are being rewritten, i.e. -update was
+//passed to go test
+func Update() bool {
+	return *update
+}
+
+//Assert compares got against the golden file at path and fails the test
+//with a diff on mismatch. Line endings are normalized to \n before
+//comparing. With -update the golden file and any missing directories are
+//written instead. got can be a string, []byte or fmt.Stringer.
+func Assert(t TestingT, got interface{}, path string) bool {
+	t.Helper()
+	act := normalize(text(got))
+
+	if *update {
+		if err := write(path, act); err != nil {
+			t.Errorf("update golden file %s: %v", path, err)
+			return false
+		}
+		return true
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Errorf("golden file %s does not exist, run go test with -update to create it", path)
+		return false
+	}
+	if err != nil {
+		t.Errorf("read golden file %s: %v", path, err)
+		return false
+	}
+
+	exp := normalize(string(bs))
+	if exp == act {
+		return true
+	}
+
+	d := tools.Diff(exp, act, tools.WithMode(tools.LineMode)).String()
+	tools.ReportFailureValues(t, "output does not match golden file "+path, d, exp, act)
+	t.Errorf("output does not match golden file %s, run go test with -update to accept it\n%s", path, d)
+	return false
+}
+
+//AssertImage compares got against the golden PNG at path with
+//tools.DiffImages and fails the test on mismatch, writing the diff image
+//next to it as <name>.diff.png. opts set the tolerance, e.g.
+//tools.WithMinSimilarity(0.99). With -update the golden file is written
+//instead.
+func AssertImage(t TestingT, got image.Image, path string, opts ...tools.Option) bool {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, got); err != nil {
+		t.Errorf("encode image: %v", err)
+		return false
+	}
+
+	if *update {
+		if err := write(path, buf.String()); err != nil {
+			t.Errorf("update golden file %s: %v", path, err)
+			return false
+		}
+		return true
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Errorf("golden file %s does not exist, run go test with -update to create it", path)
+		return false
+	}
+	if err != nil {
+		t.Errorf("read golden file %s: %v", path, err)
+		return false
+	}
+
+	d, err := tools.DiffPNG(bs, buf.Bytes(), opts...)
+	if err != nil {
+		t.Errorf("compare golden file %s: %v", path, err)
+		return false
+	}
+	if d.Equal() {
+		return true
+	}
+
+	diffPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".diff.png"
+	if err := d.WritePNG(diffPath); err != nil {
+		t.Errorf("image does not match golden file %s, run go test with -update to accept it: %s\nwrite diff image: %v", path, d, err)
+		return false
+	}
+	t.Errorf("image does not match golden file %s, run go test with -update to accept it: %s\ndiff image: %s", path, d, diffPath)
+	return false
+}
+
+func write(path, data string) error {
+	err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777))
+	if err != nil {
+		return fmt.Errorf("make dir failed: %v", err)
+	}
+	return ioutil.WriteFile(path, []byte(data), os.FileMode(0666))
+}
+
+func normalize(s string) string {
+	return strings.Replace(s, "\r\n", "\n", -1)
+}
+
+func text(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case fmt.Stringer:
+		return s.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
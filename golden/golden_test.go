@@ -0,0 +1,110 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestAssert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "testdata", "nested", "case1.golden")
+
+	// missing golden file
+	m := tools.Mock()
+	ok := Assert(m, "a\nb\n", path)
+	res := m.Results()
+	if ok || !strings.Contains(res.Err, "does not exist") {
+		t.Errorf("expected missing golden file failure, got %v %q", ok, res.Err)
+	}
+
+	// -update creates directories and the file
+	*update = true
+	m = tools.Mock()
+	ok = Assert(m, []byte("a\r\nb\r\n"), path)
+	m.Results()
+	*update = false
+	if !ok {
+		t.Fatal("expected update to succeed")
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "a\nb\n" {
+		t.Errorf("expected normalized golden file, got %q", bs)
+	}
+
+	// line endings are ignored
+	m = tools.Mock()
+	ok = Assert(m, "a\r\nb\r\n", path)
+	res = m.Results()
+	if !ok || res.Err != "" {
+		t.Errorf("expected match, got %v %q", ok, res.Err)
+	}
+
+	// mismatch shows a diff
+	m = tools.Mock()
+	ok = Assert(m, "a\nc\n", path)
+	res = m.Results()
+	if ok || !strings.Contains(res.Err, "-b") || !strings.Contains(res.Err, "+c") {
+		t.Errorf("expected diff, got %v %q", ok, res.Err)
+	}
+}
+
+func TestAssertImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testdata", "chart.png")
+
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	*update = true
+	m := tools.Mock()
+	ok := AssertImage(m, img, path)
+	m.Results()
+	*update = false
+	if !ok {
+		t.Fatal("expected update to succeed")
+	}
+
+	m = tools.Mock()
+	ok = AssertImage(m, img, path)
+	res := m.Results()
+	if !ok {
+		t.Errorf("expected match, got %q", res.Err)
+	}
+
+	changed := image.NewGray(image.Rect(0, 0, 4, 4))
+	changed.Set(1, 1, color.White)
+	m = tools.Mock()
+	ok = AssertImage(m, changed, path)
+	res = m.Results()
+	diffPath := filepath.Join(dir, "testdata", "chart.diff.png")
+	if ok || !strings.Contains(res.Err, "1 of 16 pixels differ") || !strings.Contains(res.Err, "diff image: "+diffPath) {
+		t.Errorf("expected image mismatch, got %v %q", ok, res.Err)
+	}
+	if _, err := os.Stat(diffPath); err != nil {
+		t.Error(err)
+	}
+
+	m = tools.Mock()
+	ok = AssertImage(m, changed, path, tools.WithMinSimilarity(0.9))
+	res = m.Results()
+	if !ok {
+		t.Errorf("expected match within the minimum similarity, got %q", res.Err)
+	}
+}
@@ -0,0 +1,121 @@
+package grpctest
+
+import (
+	"context"
+	"net"
+
+	"github.com/prasek/loupe/tools"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+const bufSize = 1 << 20
+
+//Server is a gRPC server listening on an in-memory bufconn listener with a
+//client connection to it
+type Server struct {
+	*grpc.Server
+
+	//Conn is the client connection to the server
+	Conn *grpc.ClientConn
+
+	lis *bufconn.Listener
+}
+
+//New starts a gRPC server on an in-memory listener, calls register to add
+//services to it and dials it. The connection and server are closed with
+//t.Cleanup. opts are passed to grpc.NewServer.
+func New(t TestingT, register func(*grpc.Server), opts ...grpc.ServerOption) *Server {
+	t.Helper()
+	s := &Server{
+		Server: grpc.NewServer(opts...),
+		lis:    bufconn.Listen(bufSize),
+	}
+	register(s.Server)
+	go s.Serve(s.lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := s.Dial()
+	if err != nil {
+		t.Errorf("dial bufconn: %v", err)
+		t.FailNow()
+		return s
+	}
+	s.Conn = conn
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return s
+}
+
+//Dial creates another client connection to the server, opts are added to
+//the ones needed to dial the in-memory listener. The caller closes it.
+func (s *Server) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+	return grpc.NewClient("passthrough:///bufconn", opts...)
+}
+
+//AssertResponse verifies a unary call returned no error and a response
+//equal to want, and fails with a DiffProto if not
+func AssertResponse(t TestingT, want, got proto.Message, err error, opts ...tools.Option) bool {
+	t.Helper()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return false
+	}
+	d := tools.DiffProto(want, got, opts...)
+	if !d.Equal() {
+		t.Errorf("Response Not Equal (-want +got)\n%s", d)
+		return false
+	}
+	return true
+}
+
+//AssertCode verifies err has the gRPC status code
+func AssertCode(t TestingT, err error, code codes.Code) bool {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Errorf("Code: expected %s, got %s: %v", code, got, err)
+		return false
+	}
+	return true
+}
+
+//AssertStream receives len(want) messages from stream and verifies each is
+//equal to the message at the same index of want, failing with a DiffProto
+//per mismatch. Messages after the last wanted one are not received, check
+//them with another call. Typed client streams can be passed as is.
+func AssertStream(t TestingT, stream grpc.ClientStream, want []proto.Message, opts ...tools.Option) bool {
+	t.Helper()
+	ok := true
+	for i, w := range want {
+		got := w.ProtoReflect().New().Interface()
+		if err := stream.RecvMsg(got); err != nil {
+			t.Errorf("Stream: expected %d messages, receive %d failed: %v", len(want), i, err)
+			return false
+		}
+		d := tools.DiffProto(w, got, opts...)
+		if !d.Equal() {
+			t.Errorf("Stream message %d Not Equal (-want +got)\n%s", i, d)
+			ok = false
+		}
+	}
+	return ok
+}
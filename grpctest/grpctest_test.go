@@ -0,0 +1,70 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func serving(s healthpb.HealthCheckResponse_ServingStatus) *healthpb.HealthCheckResponse {
+	return &healthpb.HealthCheckResponse{Status: s}
+}
+
+func TestServer(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	s := New(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, hs)
+	})
+	client := healthpb.NewHealthClient(s.Conn)
+	ctx := context.Background()
+
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	assert.True(t, AssertResponse(t, serving(healthpb.HealthCheckResponse_SERVING), resp, err))
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.True(t, AssertCode(t, err, codes.NotFound))
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	assert.NoError(t, err)
+	assert.True(t, AssertStream(t, stream, []proto.Message{serving(healthpb.HealthCheckResponse_SERVING)}))
+	hs.SetServingStatus("users", healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.True(t, AssertStream(t, stream, []proto.Message{serving(healthpb.HealthCheckResponse_NOT_SERVING)}))
+}
+
+func TestServerFail(t *testing.T) {
+	hs := health.NewServer()
+	s := New(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, hs)
+	})
+	client := healthpb.NewHealthClient(s.Conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := tools.Mock()
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.False(t, AssertResponse(m, serving(healthpb.HealthCheckResponse_NOT_SERVING), resp, err, tools.WithNoColor()))
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.False(t, AssertResponse(m, serving(healthpb.HealthCheckResponse_SERVING), nil, err))
+	assert.False(t, AssertCode(m, err, codes.OK))
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.False(t, AssertStream(m, stream, []proto.Message{serving(healthpb.HealthCheckResponse_UNKNOWN)}, tools.WithNoColor()))
+	cancel()
+	assert.False(t, AssertStream(m, stream, []proto.Message{serving(healthpb.HealthCheckResponse_SERVING)}))
+	res := m.Results()
+
+	assert.Contains(t, res.Err, "Response Not Equal (-want +got)\nstatus:\n-NOT_SERVING\n+SERVING\n")
+	assert.Contains(t, res.Err, "Unexpected error: rpc error: code = NotFound")
+	assert.Contains(t, res.Err, "Code: expected OK, got NotFound")
+	assert.Contains(t, res.Err, "Stream message 0 Not Equal (-want +got)\nstatus:\n+SERVING\n")
+	assert.Contains(t, res.Err, "Stream: expected 1 messages, receive 0 failed: rpc error: code = Canceled")
+}
@@ -0,0 +1,144 @@
+package httpassert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Assertion checks an HTTP response, every check reports a failure with
+//t.Errorf and returns the Assertion so checks can be chained
+type Assertion struct {
+	t    TestingT
+	resp *http.Response
+	body []byte
+	opts []tools.Option
+}
+
+//Response reads and closes the body of resp and returns an Assertion for
+//it. opts configure the Differ used for body mismatches.
+func Response(t TestingT, resp *http.Response, opts ...tools.Option) *Assertion {
+	t.Helper()
+	a := &Assertion{t: t, resp: resp, opts: opts}
+	if resp == nil {
+		t.Errorf("nil response")
+		return a
+	}
+	if resp.Body != nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		a.body = body
+	}
+	return a
+}
+
+//Body returns the response body
+func (a *Assertion) Body() []byte {
+	return a.body
+}
+
+//Status verifies the response status code
+func (a *Assertion) Status(code int) *Assertion {
+	a.t.Helper()
+	if a.resp != nil && a.resp.StatusCode != code {
+		a.t.Errorf("Status: expected %d %s, got %s\n%s", code, http.StatusText(code), a.resp.Status, a.body)
+	}
+	return a
+}
+
+//Header verifies the value of the header key is want
+func (a *Assertion) Header(key, want string) *Assertion {
+	a.t.Helper()
+	if a.resp == nil {
+		return a
+	}
+	if got := a.resp.Header.Get(key); got != want {
+		a.t.Errorf("Header %s: expected %q, got %q", key, want, got)
+	}
+	return a
+}
+
+//HeaderMatches verifies the value of the header key matches the regular
+//expression pattern, so "application/json" also matches
+//"application/json; charset=utf-8". It panics if pattern is invalid.
+func (a *Assertion) HeaderMatches(key, pattern string) *Assertion {
+	a.t.Helper()
+	re := regexp.MustCompile(pattern)
+	if a.resp == nil {
+		return a
+	}
+	if got := a.resp.Header.Get(key); !re.MatchString(got) {
+		a.t.Errorf("Header %s: expected match for %q, got %q", key, pattern, got)
+	}
+	return a
+}
+
+//BodyEquals verifies the body is want and fails with a text diff if not
+func (a *Assertion) BodyEquals(want string) *Assertion {
+	a.t.Helper()
+	if a.resp == nil {
+		return a
+	}
+	d := tools.Diff(want, string(a.body), a.opts...)
+	if !d.Equal() {
+		a.t.Errorf("Body Not Equal\n%s", d)
+	}
+	return a
+}
+
+//BodyJSONEquals verifies the body is semantically equal to want, ignoring
+//key order and whitespace, and fails with a JSON diff if not. want can be a
+//JSON string or []byte, any other value is marshaled with encoding/json.
+func (a *Assertion) BodyJSONEquals(want interface{}) *Assertion {
+	a.t.Helper()
+	if a.resp == nil {
+		return a
+	}
+	bs, err := jsonBytes(want)
+	if err != nil {
+		a.t.Errorf("Invalid want JSON: %v", err)
+		return a
+	}
+	if !json.Valid(a.body) {
+		a.t.Errorf("Invalid body JSON: %q", a.body)
+		return a
+	}
+	d := tools.DiffJSON(bs, a.body, a.opts...)
+	if err := d.Error(); err != nil {
+		a.t.Errorf("Body JSON: %v", err)
+		return a
+	}
+	if !d.Equal() {
+		a.t.Errorf("Body JSON Not Equal\n%s", d)
+	}
+	return a
+}
+
+func jsonBytes(v interface{}) ([]byte, error) {
+	var bs []byte
+	switch s := v.(type) {
+	case string:
+		bs = []byte(s)
+	case []byte:
+		bs = s
+	default:
+		return json.Marshal(v)
+	}
+	if !json.Valid(bs) {
+		return nil, fmt.Errorf("%q", bs)
+	}
+	return bs, nil
+}
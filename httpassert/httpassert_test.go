@@ -0,0 +1,61 @@
+package httpassert
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func response(status int, contentType, body string) *http.Response {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", contentType)
+	rec.WriteHeader(status)
+	rec.WriteString(body)
+	return rec.Result()
+}
+
+func TestResponse(t *testing.T) {
+	resp := response(200, "application/json; charset=utf-8", `{"id": 1, "name": "ann"}`)
+	Response(t, resp).
+		Status(200).
+		HeaderMatches("Content-Type", "application/json").
+		BodyJSONEquals(map[string]interface{}{"name": "ann", "id": 1}).
+		BodyJSONEquals(`{"name":"ann","id":1}`)
+
+	resp = response(201, "text/plain", "created\n")
+	a := Response(t, resp).Status(201).Header("Content-Type", "text/plain").BodyEquals("created\n")
+	assert.Equal(t, "created\n", string(a.Body()))
+}
+
+func TestResponseFail(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(*Assertion)
+		err   string
+	}{
+		{"status", func(a *Assertion) { a.Status(200) }, "Status: expected 200 OK, got 404 Not Found\n{\"id\": 2, \"name\": \"bob\"}"},
+		{"header", func(a *Assertion) { a.Header("Content-Type", "application/json") }, `Header Content-Type: expected "application/json", got "text/html"`},
+		{"header matches", func(a *Assertion) { a.HeaderMatches("Content-Type", "^application/") }, `Header Content-Type: expected match for "^application/", got "text/html"`},
+		{"body", func(a *Assertion) { a.BodyEquals(`{"id": 1, "name": "bob"}`) }, "Body Not Equal\n"},
+		{"json", func(a *Assertion) { a.BodyJSONEquals(`{"id": 1, "name": "bob"}`) }, "Body JSON Not Equal\n/id:\n-1\n+2\n"},
+		{"invalid want", func(a *Assertion) { a.BodyJSONEquals(`{"id":`) }, "Invalid want JSON"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := tools.Mock()
+			test.check(Response(m, response(404, "text/html", `{"id": 2, "name": "bob"}`), tools.WithNoColor()))
+			res := m.Results()
+			assert.Contains(t, res.Err, test.err)
+		})
+	}
+
+	m := tools.Mock()
+	Response(m, response(200, "text/plain", "not json")).BodyJSONEquals(`{}`)
+	res := m.Results()
+	assert.True(t, strings.HasPrefix(res.Err, `Invalid body JSON: "not json"`))
+}
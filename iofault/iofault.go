@@ -0,0 +1,275 @@
+package iofault
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/prasek/loupe/clock"
+)
+
+//ErrInjected is returned by FailReader and FailWriter when they're given a
+//nil error
+var ErrInjected = errors.New("iofault: injected error")
+
+//FailReader returns a Reader that reads the first n bytes of r and then
+//returns err, or ErrInjected if err is nil. The read that reaches n returns
+//the remaining bytes without an error, like a connection dropped between
+//two reads.
+func FailReader(r io.Reader, n int64, err error) io.Reader {
+	if err == nil {
+		err = ErrInjected
+	}
+	return &failReader{r: r, left: n, err: err}
+}
+
+type failReader struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func (f *failReader) Read(p []byte) (int, error) {
+	if f.left <= 0 {
+		return 0, f.err
+	}
+	if int64(len(p)) > f.left {
+		p = p[:f.left]
+	}
+	n, err := f.r.Read(p)
+	f.left -= int64(n)
+	return n, err
+}
+
+//FailWriter returns a Writer that writes the first n bytes to w and then
+//fails with err, or ErrInjected if err is nil. The write that crosses n
+//writes the bytes up to n and returns err with the short count.
+func FailWriter(w io.Writer, n int64, err error) io.Writer {
+	if err == nil {
+		err = ErrInjected
+	}
+	return &failWriter{w: w, left: n, err: err}
+}
+
+type failWriter struct {
+	w    io.Writer
+	left int64
+	err  error
+}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= f.left {
+		n, err := f.w.Write(p)
+		f.left -= int64(n)
+		return n, err
+	}
+	n, err := f.w.Write(p[:f.left])
+	f.left -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, f.err
+}
+
+//ShortReader returns a Reader that reads at most limit bytes, at least 1, in
+//each call, to test code that assumes Read fills the buffer
+func ShortReader(r io.Reader, limit int) io.Reader {
+	if limit < 1 {
+		limit = 1
+	}
+	return &shortReader{r: r, limit: limit}
+}
+
+type shortReader struct {
+	r     io.Reader
+	limit int
+}
+
+func (s *shortReader) Read(p []byte) (int, error) {
+	if len(p) > s.limit {
+		p = p[:s.limit]
+	}
+	return s.r.Read(p)
+}
+
+//ShortWriter returns a Writer that writes at most limit bytes, at least 1, in
+//each call and returns io.ErrShortWrite when that's less than asked for, to
+//test code that ignores the count returned by Write
+func ShortWriter(w io.Writer, limit int) io.Writer {
+	if limit < 1 {
+		limit = 1
+	}
+	return &shortWriter{w: w, limit: limit}
+}
+
+type shortWriter struct {
+	w     io.Writer
+	limit int
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) <= s.limit {
+		return s.w.Write(p)
+	}
+	n, err := s.w.Write(p[:s.limit])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
+
+//SlowReader returns a Reader that sleeps d before each read
+func SlowReader(r io.Reader, d time.Duration) io.Reader {
+	return &slowReader{r: r, d: d, clock: clock.Real()}
+}
+
+type slowReader struct {
+	r     io.Reader
+	d     time.Duration
+	clock clock.Clock
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	s.clock.Sleep(s.d)
+	return s.r.Read(p)
+}
+
+//SlowWriter returns a Writer that sleeps d before each write
+func SlowWriter(w io.Writer, d time.Duration) io.Writer {
+	return &slowWriter{w: w, d: d, clock: clock.Real()}
+}
+
+type slowWriter struct {
+	w     io.Writer
+	d     time.Duration
+	clock clock.Clock
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	s.clock.Sleep(s.d)
+	return s.w.Write(p)
+}
+
+//FlipReader returns a Reader that inverts the bits of the bytes at offsets
+//in the stream, to test checksums and decoders on corrupted input
+func FlipReader(r io.Reader, offsets ...int64) io.Reader {
+	return &flipReader{r: r, flips: flipSet(offsets)}
+}
+
+type flipReader struct {
+	r     io.Reader
+	flips map[int64]bool
+	off   int64
+}
+
+func (f *flipReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	for i := 0; i < n; i++ {
+		if f.flips[f.off+int64(i)] {
+			p[i] ^= 0xff
+		}
+	}
+	f.off += int64(n)
+	return n, err
+}
+
+//FlipWriter returns a Writer that inverts the bits of the bytes at offsets
+//in the stream before writing them to w. The buffers passed to Write are not
+//modified.
+func FlipWriter(w io.Writer, offsets ...int64) io.Writer {
+	return &flipWriter{w: w, flips: flipSet(offsets)}
+}
+
+type flipWriter struct {
+	w     io.Writer
+	flips map[int64]bool
+	off   int64
+}
+
+func (f *flipWriter) Write(p []byte) (int, error) {
+	var buf []byte
+	for i := range p {
+		if !f.flips[f.off+int64(i)] {
+			continue
+		}
+		if buf == nil {
+			buf = append([]byte{}, p...)
+		}
+		buf[i] ^= 0xff
+	}
+	if buf != nil {
+		p = buf
+	}
+	n, err := f.w.Write(p)
+	f.off += int64(n)
+	return n, err
+}
+
+func flipSet(offsets []int64) map[int64]bool {
+	flips := make(map[int64]bool, len(offsets))
+	for _, o := range offsets {
+		flips[o] = true
+	}
+	return flips
+}
+
+//Faults configures several faults at once. The zero value injects none.
+type Faults struct {
+	//Err is returned after FailAfter bytes, no error is injected when it's
+	//nil
+	Err       error
+	FailAfter int64
+
+	//MaxChunk limits the bytes read or written per call when it's > 0
+	MaxChunk int
+
+	//Latency is slept before each call on Clock, the real clock when it's
+	//nil, so a clock.Fake makes it deterministic
+	Latency time.Duration
+	Clock   clock.Clock
+
+	//Flip lists the offsets of the bytes whose bits are inverted
+	Flip []int64
+}
+
+//Reader wraps r with the faults. Bytes are flipped before they're counted
+//for FailAfter, and MaxChunk and Latency apply to every call.
+func (f Faults) Reader(r io.Reader) io.Reader {
+	if len(f.Flip) > 0 {
+		r = FlipReader(r, f.Flip...)
+	}
+	if f.Err != nil {
+		r = FailReader(r, f.FailAfter, f.Err)
+	}
+	if f.MaxChunk > 0 {
+		r = ShortReader(r, f.MaxChunk)
+	}
+	if f.Latency > 0 {
+		r = &slowReader{r: r, d: f.Latency, clock: f.clock()}
+	}
+	return r
+}
+
+//Writer wraps w with the faults, in the same order as Reader
+func (f Faults) Writer(w io.Writer) io.Writer {
+	if f.Err != nil {
+		w = FailWriter(w, f.FailAfter, f.Err)
+	}
+	if len(f.Flip) > 0 {
+		w = FlipWriter(w, f.Flip...)
+	}
+	if f.MaxChunk > 0 {
+		w = ShortWriter(w, f.MaxChunk)
+	}
+	if f.Latency > 0 {
+		w = &slowWriter{w: w, d: f.Latency, clock: f.clock()}
+	}
+	return w
+}
+
+func (f Faults) clock() clock.Clock {
+	if f.Clock == nil {
+		return clock.Real()
+	}
+	return f.Clock
+}
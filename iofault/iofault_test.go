@@ -0,0 +1,121 @@
+package iofault
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailReader(t *testing.T) {
+	r := FailReader(strings.NewReader("hello world"), 5, nil)
+	b, err := ioutil.ReadAll(r)
+	assert.Equal(t, "hello", string(b))
+	assert.Equal(t, ErrInjected, err)
+
+	reset := errors.New("connection reset")
+	_, err = io.ReadFull(FailReader(strings.NewReader("hello"), 2, reset), make([]byte, 5))
+	assert.Equal(t, reset, err)
+
+	b, err = ioutil.ReadAll(FailReader(strings.NewReader("hi"), 5, nil))
+	assert.Equal(t, "hi", string(b))
+	assert.NoError(t, err, "EOF before n")
+}
+
+func TestFailWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := FailWriter(&buf, 5, nil)
+	n, err := w.Write([]byte("abc"))
+	assert.Equal(t, 3, n)
+	assert.NoError(t, err)
+	n, err = w.Write([]byte("defg"))
+	assert.Equal(t, 2, n)
+	assert.Equal(t, ErrInjected, err)
+	n, err = w.Write([]byte("h"))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, ErrInjected, err)
+	assert.Equal(t, "abcde", buf.String())
+}
+
+func TestShort(t *testing.T) {
+	r := ShortReader(strings.NewReader("hello"), 2)
+	p := make([]byte, 10)
+	n, _ := r.Read(p)
+	assert.Equal(t, 2, n)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "llo", string(b))
+
+	var buf bytes.Buffer
+	n, err = ShortWriter(&buf, 0).Write([]byte("hello"))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, io.ErrShortWrite, err)
+	n, err = ShortWriter(&buf, 5).Write([]byte("ello"))
+	assert.Equal(t, 4, n)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", buf.String())
+}
+
+func TestFlip(t *testing.T) {
+	b, err := ioutil.ReadAll(ShortReader(FlipReader(strings.NewReader("abcd"), 1, 3), 1))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'a', ^byte('b'), 'c', ^byte('d')}, b)
+
+	var buf bytes.Buffer
+	w := FlipWriter(&buf, 2)
+	p := []byte("ab")
+	w.Write(p)
+	w.Write([]byte("cd"))
+	assert.Equal(t, []byte{'a', 'b', ^byte('c'), 'd'}, buf.Bytes())
+	assert.Equal(t, "ab", string(p))
+}
+
+func TestSlow(t *testing.T) {
+	start := time.Now()
+	var buf bytes.Buffer
+	SlowWriter(&buf, 20*time.Millisecond).Write([]byte("x"))
+	ioutil.ReadAll(SlowReader(strings.NewReader("x"), 20*time.Millisecond))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestFaults(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	f := Faults{Err: io.ErrUnexpectedEOF, FailAfter: 4, MaxChunk: 3, Latency: time.Second, Clock: fake, Flip: []int64{0}}
+
+	type result struct {
+		b   []byte
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		b, err := ioutil.ReadAll(f.Reader(strings.NewReader("abcdef")))
+		done <- result{b, err}
+	}()
+	for i := 0; i < 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Second)
+	}
+	res := <-done
+	assert.Equal(t, io.ErrUnexpectedEOF, res.err)
+	assert.Equal(t, []byte{^byte('a'), 'b', 'c', 'd'}, res.b)
+
+	var buf bytes.Buffer
+	w := Faults{Err: io.ErrClosedPipe, FailAfter: 4, MaxChunk: 3}.Writer(&buf)
+	n, err := w.Write([]byte("abcdef"))
+	assert.Equal(t, 3, n)
+	assert.Equal(t, io.ErrShortWrite, err)
+	n, err = w.Write([]byte("def"))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, io.ErrClosedPipe, err)
+	assert.Equal(t, "abcd", buf.String())
+
+	r := Faults{}.Reader(strings.NewReader("x"))
+	_, ok := r.(*strings.Reader)
+	assert.True(t, ok, "no faults")
+}
@@ -0,0 +1,235 @@
+package leak
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+}
+
+//Option configures Check
+type Option func(*options)
+
+type options struct {
+	timeout   time.Duration
+	topFuncs  []string
+	createdBy []string
+}
+
+//IgnoreTopFunction ignores goroutines currently running fn, e.g.
+//"net/http.(*persistConn).readLoop" or "go.opencensus.io/stats/view.(*worker).start"
+func IgnoreTopFunction(fn ...string) Option {
+	return func(o *options) {
+		o.topFuncs = append(o.topFuncs, fn...)
+	}
+}
+
+//IgnoreCreatedBy ignores goroutines started by fn, e.g.
+//"google.golang.org/grpc.(*Server).Serve"
+func IgnoreCreatedBy(fn ...string) Option {
+	return func(o *options) {
+		o.createdBy = append(o.createdBy, fn...)
+	}
+}
+
+//WithTimeout sets how long Check waits for goroutines to exit before
+//reporting them, the default is 1s
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// goroutines of the runtime and the testing package that come and go
+// outside of the control of a test
+var (
+	defaultTopFuncs  = []string{"runtime.goexit", "os/signal.signal_recv", "os/signal.loop", "runtime.ReadTrace"}
+	defaultCreatedBy = []string{"testing.(*T).Run", "testing.runTests", "testing.(*M).startAlarm", "testing.(*T).Parallel"}
+)
+
+//Check records the running goroutines and, when the test completes, fails
+//it if any others are still running after waiting for them to exit. The
+//report groups them by the function and location that started them. Call
+//it first so cleanups registered later, e.g. closing servers, run before
+//the check.
+func Check(t TestingT, opts ...Option) {
+	t.Helper()
+	o := &options{
+		timeout:   time.Second,
+		topFuncs:  append([]string(nil), defaultTopFuncs...),
+		createdBy: append([]string(nil), defaultCreatedBy...),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	before := make(map[int]bool)
+	for _, g := range goroutines() {
+		before[g.id] = true
+	}
+
+	t.Cleanup(func() {
+		t.Helper()
+		var leaked []goroutine
+		deadline := time.Now().Add(o.timeout)
+		for wait := time.Millisecond; ; wait *= 2 {
+			leaked = leaked[:0]
+			for _, g := range goroutines() {
+				if !before[g.id] && !o.ignored(g) {
+					leaked = append(leaked, g)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			if wait > 100*time.Millisecond {
+				wait = 100 * time.Millisecond
+			}
+			time.Sleep(wait)
+		}
+		if len(leaked) > 0 {
+			t.Errorf("%s", report(leaked))
+		}
+	})
+}
+
+func (o *options) ignored(g goroutine) bool {
+	for _, fn := range o.topFuncs {
+		if g.top == fn {
+			return true
+		}
+	}
+	for _, fn := range o.createdBy {
+		if g.createdBy == fn {
+			return true
+		}
+	}
+	return false
+}
+
+type goroutine struct {
+	id        int
+	state     string
+	top       string
+	createdBy string
+	createdAt string
+	stack     string
+}
+
+// goroutines parses the stacks of all goroutines except the current one
+func goroutines() []goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var gs []goroutine
+	for i, s := range strings.Split(string(buf), "\n\n") {
+		if g, ok := parse(s); ok && i > 0 {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// parse parses a stack in the runtime.Stack format:
+//
+//	goroutine 7 [chan receive]:
+//	main.worker(...)
+//		/src/main.go:12 +0x25
+//	created by main.main in goroutine 1
+//		/src/main.go:20 +0x4f
+func parse(s string) (goroutine, bool) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	var g goroutine
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "goroutine ") {
+		return g, false
+	}
+	header := strings.TrimSuffix(strings.TrimPrefix(lines[0], "goroutine "), ":")
+	i := strings.Index(header, " [")
+	if i < 0 {
+		return g, false
+	}
+	id, err := strconv.Atoi(header[:i])
+	if err != nil {
+		return g, false
+	}
+	g.id = id
+	g.state = strings.TrimSuffix(header[i+2:], "]")
+	g.top = funcName(lines[1])
+	g.stack = strings.Join(lines[1:], "\n")
+
+	for j, l := range lines {
+		if !strings.HasPrefix(l, "created by ") {
+			continue
+		}
+		fn := strings.TrimPrefix(l, "created by ")
+		if k := strings.Index(fn, " in goroutine "); k >= 0 {
+			fn = fn[:k]
+		}
+		g.createdBy = fn
+		if j+1 < len(lines) {
+			g.createdAt = location(lines[j+1])
+		}
+	}
+	return g, true
+}
+
+// funcName strips the arguments from a stack frame, e.g. main.f(0x1, ...)
+func funcName(frame string) string {
+	if i := strings.LastIndex(frame, "("); i > 0 && strings.HasSuffix(frame, ")") {
+		return frame[:i]
+	}
+	return frame
+}
+
+// location strips the program counter offset from a stack frame location
+func location(l string) string {
+	l = strings.TrimSpace(l)
+	if i := strings.LastIndex(l, " +0x"); i >= 0 {
+		l = l[:i]
+	}
+	return l
+}
+
+// report lists leaked goroutines grouped by where they were created
+func report(gs []goroutine) string {
+	groups := make(map[string][]goroutine)
+	var sites []string
+	for _, g := range gs {
+		site := "created by " + g.createdBy + " at " + g.createdAt
+		if g.createdBy == "" {
+			site = "created at an unknown location"
+		}
+		if _, ok := groups[site]; !ok {
+			sites = append(sites, site)
+		}
+		groups[site] = append(groups[site], g)
+	}
+	sort.Strings(sites)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "found %d leaked goroutines:\n", len(gs))
+	for _, site := range sites {
+		fmt.Fprintf(&buf, "\n%d %s\n", len(groups[site]), site)
+		for _, g := range groups[site] {
+			fmt.Fprintf(&buf, "\tgoroutine %d [%s]:\n\t\t%s\n", g.id, g.state, strings.Replace(g.stack, "\n", "\n\t\t", -1))
+		}
+	}
+	return buf.String()
+}
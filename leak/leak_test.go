@@ -0,0 +1,67 @@
+package leak
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func block(c chan struct{}) {
+	<-c
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("exits", func(t *testing.T) {
+		Check(t)
+		done := make(chan struct{})
+		go block(done)
+		t.Cleanup(func() { close(done) })
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	m := tools.Mock()
+	Check(m, WithTimeout(20*time.Millisecond))
+	go block(stop)
+	go func() { block(stop) }()
+	res := m.Results()
+	assert.Contains(t, res.Err, "found 2 leaked goroutines:\n")
+	assert.Contains(t, res.Err, "1 created by github.com/prasek/loupe/leak.TestCheck at ")
+	assert.Contains(t, res.Err, "leak_test.go:")
+	assert.Contains(t, res.Err, " [chan receive]:\n\t\tgithub.com/prasek/loupe/leak.block")
+	assert.Equal(t, 2, strings.Count(res.Err, "\tgoroutine "))
+
+	m = tools.Mock()
+	Check(m, WithTimeout(20*time.Millisecond), IgnoreTopFunction("github.com/prasek/loupe/leak.block"))
+	go block(stop)
+	assert.Empty(t, m.Results().Err)
+
+	m = tools.Mock()
+	Check(m, WithTimeout(20*time.Millisecond), IgnoreCreatedBy("github.com/prasek/loupe/leak.TestCheck"))
+	go block(stop)
+	assert.Empty(t, m.Results().Err)
+}
+
+func TestParse(t *testing.T) {
+	g, ok := parse(`goroutine 7 [chan receive, 2 minutes]:
+main.worker(0xc000010000)
+	/src/main.go:12 +0x25
+created by main.main in goroutine 1
+	/src/main.go:20 +0x4f`)
+	assert.True(t, ok)
+	assert.Equal(t, goroutine{
+		id:        7,
+		state:     "chan receive, 2 minutes",
+		top:       "main.worker",
+		createdBy: "main.main",
+		createdAt: "/src/main.go:20",
+		stack:     "main.worker(0xc000010000)\n\t/src/main.go:12 +0x25\ncreated by main.main in goroutine 1\n\t/src/main.go:20 +0x4f",
+	}, g)
+
+	_, ok = parse("not a stack")
+	assert.False(t, ok)
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Name() string
+	Log(args ...interface{})
+}
+
+//Level is the severity of a log line
+type Level int
+
+const (
+	//DebugLevel is for details only needed when debugging a test
+	DebugLevel Level = iota
+
+	//InfoLevel is the default level
+	InfoLevel
+
+	//WarnLevel is for unexpected but tolerated conditions
+	WarnLevel
+
+	//ErrorLevel is for failures, logging them doesn't fail the test
+	ErrorLevel
+)
+
+var levels = []struct {
+	name  string
+	attrs []color.Attribute
+}{
+	DebugLevel: {"DEBUG", []color.Attribute{color.Faint}},
+	InfoLevel:  {"INFO", []color.Attribute{color.FgCyan}},
+	WarnLevel:  {"WARN", []color.Attribute{color.FgYellow}},
+	ErrorLevel: {"ERROR", []color.Attribute{color.FgRed}},
+}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levels[l].name
+}
+
+//Option configures a Logger
+type Option func(*Logger)
+
+//WithLevel only writes lines at level l or above, the default is InfoLevel
+func WithLevel(l Level) Option {
+	return func(lg *Logger) {
+		lg.level = l
+	}
+}
+
+//WithColor sets when lines are colored. ColorAuto colors them unless
+//NO_COLOR or CI is set, TERM is dumb, or the test runs under go test -json.
+func WithColor(m tools.ColorMode) Option {
+	return func(lg *Logger) {
+		lg.color = m
+	}
+}
+
+//Logger writes leveled, colored lines prefixed with the test name to t.Log
+type Logger struct {
+	t     TestingT
+	level Level
+	color tools.ColorMode
+	on    bool
+}
+
+//New creates a Logger for t, e.g. log.New(t).Infof("took %v", d)
+func New(t TestingT, opts ...Option) *Logger {
+	lg := &Logger{t: t, level: InfoLevel}
+	for _, opt := range opts {
+		opt(lg)
+	}
+	lg.on = useColor(lg.color)
+	return lg
+}
+
+//Debug logs args at DebugLevel
+func (lg *Logger) Debug(args ...interface{}) {
+	lg.t.Helper()
+	lg.log(DebugLevel, fmt.Sprint(args...))
+}
+
+//Debugf logs a formatted line at DebugLevel
+func (lg *Logger) Debugf(format string, args ...interface{}) {
+	lg.t.Helper()
+	lg.log(DebugLevel, fmt.Sprintf(format, args...))
+}
+
+//Info logs args at InfoLevel
+func (lg *Logger) Info(args ...interface{}) {
+	lg.t.Helper()
+	lg.log(InfoLevel, fmt.Sprint(args...))
+}
+
+//Infof logs a formatted line at InfoLevel
+func (lg *Logger) Infof(format string, args ...interface{}) {
+	lg.t.Helper()
+	lg.log(InfoLevel, fmt.Sprintf(format, args...))
+}
+
+//Warn logs args at WarnLevel
+func (lg *Logger) Warn(args ...interface{}) {
+	lg.t.Helper()
+	lg.log(WarnLevel, fmt.Sprint(args...))
+}
+
+//Warnf logs a formatted line at WarnLevel
+func (lg *Logger) Warnf(format string, args ...interface{}) {
+	lg.t.Helper()
+	lg.log(WarnLevel, fmt.Sprintf(format, args...))
+}
+
+//Error logs args at ErrorLevel
+func (lg *Logger) Error(args ...interface{}) {
+	lg.t.Helper()
+	lg.log(ErrorLevel, fmt.Sprint(args...))
+}
+
+//Errorf logs a formatted line at ErrorLevel
+func (lg *Logger) Errorf(format string, args ...interface{}) {
+	lg.t.Helper()
+	lg.log(ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// log writes msg with one prefix per line, so multi line messages stay
+// attributed to the test when interleaved with others
+func (lg *Logger) log(l Level, msg string) {
+	lg.t.Helper()
+	if l < lg.level {
+		return
+	}
+
+	c := color.New(levels[l].attrs...)
+	if lg.on {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	prefix := fmt.Sprintf("%-5s [%s] ", l, lg.t.Name())
+	lines := strings.Split(strings.TrimSuffix(msg, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = c.Sprint(prefix + line)
+	}
+	lg.t.Log(strings.Join(lines, "\n"))
+}
+
+// useColor is like the tools color detection, except that test output is
+// never a terminal since go test reads it through a pipe
+func useColor(mode tools.ColorMode) bool {
+	switch mode {
+	case tools.ColorAlways:
+		return true
+	case tools.ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return !testJSON()
+}
+
+// testJSON reports whether the test binary was started by go test -json,
+// which runs it with -test.v=test2json
+func testJSON() bool {
+	f := flag.Lookup("test.v")
+	return f != nil && f.Value.String() == "test2json"
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	m := tools.Mock()
+	m.SetName("TestUser/parallel")
+	lg := New(m, WithColor(tools.ColorNever))
+	lg.Debug("hidden")
+	lg.Info("started ", 3, " workers")
+	lg.Warnf("retry %d", 2)
+	lg.Error("line one\nline two\n")
+	res := m.Results()
+	assert.Equal(t, "INFO  [TestUser/parallel] started 3 workers\n"+
+		"WARN  [TestUser/parallel] retry 2\n"+
+		"ERROR [TestUser/parallel] line one\n"+
+		"ERROR [TestUser/parallel] line two\n", res.Log)
+	assert.False(t, res.Fail)
+
+	m = tools.Mock()
+	m.SetName("TestUser")
+	lg = New(m, WithLevel(DebugLevel), WithColor(tools.ColorAlways))
+	lg.Debugf("x=%d", 1)
+	lg.Error("failed")
+	res = m.Results()
+	red := color.New(color.FgRed)
+	red.EnableColor()
+	faint := color.New(color.Faint)
+	faint.EnableColor()
+	assert.Equal(t, faint.Sprint("DEBUG [TestUser] x=1")+"\n"+red.Sprint("ERROR [TestUser] failed")+"\n", res.Log)
+
+	m = tools.Mock()
+	New(m, WithLevel(ErrorLevel)).Warn("hidden")
+	assert.Equal(t, "", m.Results().Log)
+
+	assert.Equal(t, "WARN", WarnLevel.String())
+	assert.Equal(t, "Level(7)", Level(7).String())
+}
+
+func TestUseColor(t *testing.T) {
+	assert.True(t, useColor(tools.ColorAlways))
+	assert.False(t, useColor(tools.ColorNever))
+
+	testV := flag.Lookup("test.v").Value
+	defer func(v string) { testV.Set(v) }(testV.String())
+	testV.Set("test2json")
+	assert.False(t, useColor(tools.ColorAuto))
+}
@@ -0,0 +1,185 @@
+package logassert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+//Contains asserts that the captured log has a line matching each of want,
+//in any order, so lines interleaved by concurrent goroutines still match.
+//A string matches lines containing it and a *regexp.Regexp lines it
+//matches, each line matches at most one of want. log can be a string,
+//[]byte or fmt.Stringer such as a *bytes.Buffer, and on failure the
+//missing lines are diffed against the log.
+func Contains(t TestingT, log interface{}, want ...interface{}) bool {
+	t.Helper()
+	return check(t, log, want, false)
+}
+
+//Exactly is Contains that also fails on lines of the log matching none of
+//want, which are reported as unexpected
+func Exactly(t TestingT, log interface{}, want ...interface{}) bool {
+	t.Helper()
+	return check(t, log, want, true)
+}
+
+// matcher is one expected line
+type matcher struct {
+	text string
+	re   *regexp.Regexp
+}
+
+func newMatcher(v interface{}) matcher {
+	switch m := v.(type) {
+	case string:
+		return matcher{text: m}
+	case *regexp.Regexp:
+		return matcher{text: "/" + m.String() + "/", re: m}
+	}
+	panic(fmt.Sprintf("logassert: want must be a string or *regexp.Regexp, got %T", v))
+}
+
+func (m matcher) match(line string) bool {
+	if m.re != nil {
+		return m.re.MatchString(line)
+	}
+	return strings.Contains(line, m.text)
+}
+
+func check(t TestingT, log interface{}, want []interface{}, exact bool) bool {
+	t.Helper()
+	ms := make([]matcher, len(want))
+	for i, w := range want {
+		ms[i] = newMatcher(w)
+	}
+	lines := Lines(log)
+	matched := match(ms, lines)
+
+	// the log reordered to follow want, then the unmatched lines
+	var exp, act []string
+	used := make([]bool, len(lines))
+	missing := 0
+	for i, m := range ms {
+		if j := matched[i]; j >= 0 {
+			used[j] = true
+			exp = append(exp, lines[j])
+			act = append(act, lines[j])
+			continue
+		}
+		missing++
+		exp = append(exp, m.text)
+	}
+	unexpected := 0
+	for j, l := range lines {
+		if used[j] {
+			continue
+		}
+		if exact {
+			unexpected++
+			act = append(act, l)
+		}
+	}
+	if missing == 0 && unexpected == 0 {
+		return true
+	}
+
+	var parts []string
+	if missing > 0 {
+		parts = append(parts, plural(missing, "missing line"))
+	}
+	if unexpected > 0 {
+		parts = append(parts, plural(unexpected, "unexpected line"))
+	}
+	header := fmt.Sprintf("log does not match, %s", strings.Join(parts, " and "))
+	d := tools.Diff(text(exp), text(act), tools.WithMode(tools.LineMode), tools.WithLabels("want", "log")).String()
+	if !exact {
+		d += fmt.Sprintf("log:\n%s", text(lines))
+	}
+	tools.ReportFailure(t, header, d)
+	t.Errorf("%s\n%s", header, d)
+	return false
+}
+
+// match pairs each matcher with a distinct line it matches, maximizing the
+// pairs so a broad pattern doesn't take the only line of a narrow one.
+// It returns the line index of each matcher, -1 when unmatched.
+func match(ms []matcher, lines []string) []int {
+	byLine := make([]int, len(lines))
+	for j := range byLine {
+		byLine[j] = -1
+	}
+	var try func(i int, seen []bool) bool
+	try = func(i int, seen []bool) bool {
+		for j, l := range lines {
+			if seen[j] || !ms[i].match(l) {
+				continue
+			}
+			seen[j] = true
+			if byLine[j] < 0 || try(byLine[j], seen) {
+				byLine[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range ms {
+		try(i, make([]bool, len(lines)))
+	}
+
+	res := make([]int, len(ms))
+	for i := range res {
+		res[i] = -1
+	}
+	for j, i := range byLine {
+		if i >= 0 {
+			res[i] = j
+		}
+	}
+	return res
+}
+
+//Lines splits a captured log into lines without their line endings,
+//leaving out empty lines
+func Lines(log interface{}) []string {
+	var s string
+	switch l := log.(type) {
+	case string:
+		s = l
+	case []byte:
+		s = string(l)
+	case fmt.Stringer:
+		s = l.String()
+	default:
+		panic(fmt.Sprintf("logassert: log must be a string, []byte or fmt.Stringer, got %T", log))
+	}
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSuffix(l, "\r"); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func text(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
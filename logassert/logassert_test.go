@@ -0,0 +1,60 @@
+package logassert
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContains(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			mu.Lock()
+			fmt.Fprintf(&buf, "worker %d started\n", i)
+			fmt.Fprintf(&buf, "worker %d done\n", i)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.True(t, Contains(t, &buf, "worker 4 done", "worker 0 started", regexp.MustCompile(`^worker \d done$`)))
+	assert.True(t, Exactly(t, buf.String(),
+		"worker 0 started", "worker 1 started", "worker 2 started", "worker 3 started", "worker 4 started",
+		regexp.MustCompile(`done$`), regexp.MustCompile(`done$`), regexp.MustCompile(`done$`), regexp.MustCompile(`done$`), regexp.MustCompile(`done$`)))
+
+	// a broad pattern doesn't take the only line a narrow one matches
+	assert.True(t, Exactly(t, []byte("a done\nb done\n"), regexp.MustCompile("done"), "a done"))
+}
+
+func TestContainsFail(t *testing.T) {
+	m := tools.Mock()
+	log := "connected\r\nretrying\n\nconnected\n"
+	ok := Contains(m, log, "connected", "closed", "connected", "connected")
+	res := m.Results()
+	assert.False(t, ok)
+	assert.Equal(t, "log does not match, 2 missing lines\n"+
+		"--- want\n+++ log\n@@ -1,4 +1,2 @@\n-connected\n-closed\n connected\n connected\n"+
+		"log:\nconnected\nretrying\nconnected\n", res.Err)
+}
+
+func TestExactlyFail(t *testing.T) {
+	m := tools.Mock()
+	ok := Exactly(m, "b\nunexpected\na\n", "a", regexp.MustCompile("^b$"), "closed")
+	res := m.Results()
+	assert.False(t, ok)
+	assert.Equal(t, "log does not match, 1 missing line and 1 unexpected line\n"+
+		"--- want\n+++ log\n@@ -1,3 +1,3 @@\n a\n b\n-closed\n+unexpected\n", res.Err)
+
+	assert.Panics(t, func() { Contains(t, "a", 1) })
+	assert.Panics(t, func() { Lines(1) })
+}
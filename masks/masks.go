@@ -0,0 +1,116 @@
+package masks
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Mask replaces the matches of a regular expression with a stable token, so
+//values that change between runs don't fail comparisons. A Mask is a
+//tools.Normalizer.
+type Mask struct {
+	re       *regexp.Regexp
+	token    string
+	numbered bool
+}
+
+//New returns a Mask replacing matches of pattern with token. If pattern has
+//a group named mask, e.g. `port=(?P<mask>\d+)`, only that part of the match
+//is replaced. It panics if pattern is invalid.
+func New(pattern, token string) *Mask {
+	return &Mask{re: regexp.MustCompile(pattern), token: token}
+}
+
+//Numbered returns a copy of m that numbers distinct matches in the order
+//they appear, e.g. <uuid-1>, <uuid-2>, <uuid-1>, so a diff still shows when
+//two values should have been the same
+func (m *Mask) Numbered() *Mask {
+	c := *m
+	c.numbered = true
+	return &c
+}
+
+//Normalize replaces the matches in s
+func (m *Mask) Normalize(s string) string {
+	group := 0
+	if i := m.re.SubexpIndex("mask"); i > 0 {
+		group = i
+	}
+	seen := map[string]int{}
+	var b strings.Builder
+	last := 0
+	for _, loc := range m.re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := loc[2*group], loc[2*group+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(m.replacement(s[start:end], seen))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (m *Mask) replacement(match string, seen map[string]int) string {
+	if !m.numbered {
+		return m.token
+	}
+	n, ok := seen[match]
+	if !ok {
+		n = len(seen) + 1
+		seen[match] = n
+	}
+	suffix := "-" + strconv.Itoa(n)
+	if strings.HasSuffix(m.token, ">") {
+		return strings.TrimSuffix(m.token, ">") + suffix + ">"
+	}
+	return m.token + suffix
+}
+
+var (
+	//Timestamps masks RFC 3339 timestamps, with a T or a space between the
+	//date and time and an optional fraction and zone, as <timestamp>
+	Timestamps = New(`\b\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:[Zz]|[+-]\d{2}:\d{2})?`, "<timestamp>")
+
+	//Durations masks durations formatted by time.Duration, e.g. 1.5s,
+	//350ms or 1h2m3s, as <duration>
+	Durations = New(`\b(?:\d+(?:\.\d+)?(?:ms|us|µs|ns|h|m|s))+\b`, "<duration>")
+
+	//UUIDs masks UUIDs as <uuid>
+	UUIDs = New(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`, "<uuid>")
+
+	//Addresses masks memory addresses like 0xc000012345 as <addr>
+	Addresses = New(`\b0x[0-9a-fA-F]{6,16}\b`, "<addr>")
+
+	//Ports masks the port of localhost, IPv4 and bracketed IPv6 addresses,
+	//e.g. 127.0.0.1:54321 becomes 127.0.0.1:<port>
+	Ports = New(`(?:\blocalhost|\b\d{1,3}(?:\.\d{1,3}){3}|\[[0-9a-fA-F:.]*\]):(?P<mask>\d{1,5})\b`, "<port>")
+
+	//TempPaths masks the directory created in os.TempDir(), e.g. by
+	//t.TempDir() or ioutil.TempDir, keeping the rest of the path, e.g.
+	//<tmp>/001/out.txt
+	TempPaths = New(tempPattern(), "<tmp>")
+
+	//Default applies all the masks above
+	Default = tools.Normalizers(TempPaths, Timestamps, UUIDs, Addresses, Ports, Durations)
+)
+
+func tempPattern() string {
+	dirs := []string{filepath.Clean(os.TempDir())}
+	if dirs[0] != "/tmp" {
+		dirs = append(dirs, "/tmp")
+	}
+	for i, d := range dirs {
+		dirs[i] = regexp.QuoteMeta(filepath.ToSlash(d))
+	}
+	return `(?:` + strings.Join(dirs, "|") + `)/[^/\s"'` + "`" + `]+`
+}
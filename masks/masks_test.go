@@ -0,0 +1,56 @@
+package masks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasks(t *testing.T) {
+	cases := []struct {
+		mask *Mask
+		in   string
+		want string
+	}{
+		{Timestamps, `at 2026-10-14T13:45:58.145Z and 2026-10-14 08:00:00+02:00`, `at <timestamp> and <timestamp>`},
+		{Durations, `took 1.5s, 350ms, 1h2m3s and 12µs, not 5min`, `took <duration>, <duration>, <duration> and <duration>, not 5min`},
+		{UUIDs, `id=0F8FAD5B-d9cb-469f-a165-70867728950e`, `id=<uuid>`},
+		{Addresses, `&{0xc000012345} 0x1f`, `&{<addr>} 0x1f`},
+		{Ports, `localhost:8080 127.0.0.1:54321 [::1]:443 12:30`, `localhost:<port> 127.0.0.1:<port> [::1]:<port> 12:30`},
+		{TempPaths, "open /tmp/TestFoo123/001/out.txt: denied", "open <tmp>/001/out.txt: denied"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.mask.Normalize(c.in))
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "out.txt"))
+	assert.Regexp(t, `^<tmp>/\d+/out\.txt$`, TempPaths.Normalize(path))
+	assert.Equal(t, "<tmp>", TempPaths.Normalize(filepath.ToSlash(filepath.Join(os.TempDir(), "x1"))))
+}
+
+func TestNew(t *testing.T) {
+	m := New(`session=(?P<mask>\w+)`, "<session>")
+	assert.Equal(t, "session=<session>; path=/", m.Normalize("session=abc123; path=/"))
+	assert.Equal(t, "none", m.Normalize("none"))
+	assert.Panics(t, func() { New(`(`, "x") })
+}
+
+func TestNumbered(t *testing.T) {
+	m := UUIDs.Numbered()
+	in := "0f8fad5b-d9cb-469f-a165-70867728950e 7c9e6679-7425-40de-944b-e07fc1f90ae7 0f8fad5b-d9cb-469f-a165-70867728950e"
+	assert.Equal(t, "<uuid-1> <uuid-2> <uuid-1>", m.Normalize(in))
+	assert.Equal(t, "<uuid> <uuid> <uuid>", UUIDs.Normalize(in))
+	assert.Equal(t, "port N-1 N-2", New(`\d+`, "N").Numbered().Normalize("port 80 443"))
+}
+
+func TestDefault(t *testing.T) {
+	a := "req 0f8fad5b-d9cb-469f-a165-70867728950e at 2026-10-14T13:45:58Z from 127.0.0.1:50312 took 12ms\n"
+	b := "req 7c9e6679-7425-40de-944b-e07fc1f90ae7 at 2026-10-15T09:00:01Z from 127.0.0.1:61003 took 9.1ms\n"
+	assert.True(t, tools.Diff(a, b, tools.WithNormalizers(Default)).Equal())
+	assert.Equal(t, "req <uuid> at <timestamp> from 127.0.0.1:<port> took <duration>\n", Default.Normalize(a))
+}
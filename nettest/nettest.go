@@ -0,0 +1,115 @@
+package nettest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//DefaultTimeout is how long WaitForHTTP waits for the expected status
+var DefaultTimeout = 30 * time.Second
+
+//PollInterval is how long the Wait functions sleep between attempts
+var PollInterval = 50 * time.Millisecond
+
+//GetFreePort returns a TCP port on 127.0.0.1 that's free to listen on,
+//stopping the test if none is. The port is released before it's returned,
+//so another process may take it, but the kernel hands out ports in a way
+//that makes this unlikely in a test run.
+func GetFreePort(t TestingT) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("GetFreePort: %v", err)
+		t.FailNow()
+		return 0
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+//WaitForTCP waits until addr accepts TCP connections, stopping the test
+//with the last connection error when it doesn't within timeout
+func WaitForTCP(t TestingT, addr string, timeout time.Duration) {
+	t.Helper()
+	err := poll(timeout, func(remaining time.Duration) error {
+		conn, err := net.DialTimeout("tcp", addr, remaining)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+	if err != nil {
+		t.Errorf("WaitForTCP %s: not reachable after %v: %v", addr, timeout, err)
+		t.FailNow()
+	}
+}
+
+//WaitForHTTP waits until a GET of url responds with the want status code,
+//stopping the test with the last error or response when it doesn't within
+//DefaultTimeout. Redirects are not followed, so want can be a 3xx status.
+func WaitForHTTP(t TestingT, url string, want int) {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	err := poll(DefaultTimeout, func(remaining time.Duration) error {
+		client.Timeout = remaining
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode != want {
+			return unexpectedStatus(resp.StatusCode, want, body)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("WaitForHTTP %s: not ready after %v: %v", url, DefaultTimeout, err)
+		t.FailNow()
+	}
+}
+
+func unexpectedStatus(got, want int, body []byte) error {
+	msg := fmt.Sprintf("got status %d %s, want %d %s", got, http.StatusText(got), want, http.StatusText(want))
+	if b := strings.TrimSpace(string(body)); b != "" {
+		msg += ": " + b
+	}
+	return errors.New(msg)
+}
+
+// poll calls try until it succeeds or timeout passes, returning the last
+// error. try is passed the time that's left so it can bound its own wait.
+func poll(timeout time.Duration, try func(remaining time.Duration) error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			remaining = PollInterval
+		}
+		err := try(remaining)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(PollInterval).After(deadline) {
+			return err
+		}
+		time.Sleep(PollInterval)
+	}
+}
@@ -0,0 +1,85 @@
+package nettest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFreePort(t *testing.T) {
+	port := GetFreePort(t)
+	l, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if assert.NoError(t, err) {
+		l.Close()
+	}
+}
+
+func TestWaitForTCP(t *testing.T) {
+	addr := "127.0.0.1:" + strconv.Itoa(GetFreePort(t))
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer l.Close()
+		conn, err := l.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	m := tools.Mock()
+	WaitForTCP(m, addr, 5*time.Second)
+	assert.Equal(t, "", m.Results().Err)
+}
+
+func TestWaitForTCPTimeout(t *testing.T) {
+	addr := "127.0.0.1:" + strconv.Itoa(GetFreePort(t))
+	m := tools.Mock()
+	WaitForTCP(m, addr, 200*time.Millisecond)
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.Contains(t, res.Err, "WaitForTCP "+addr+": not reachable after 200ms: dial tcp "+addr+": ")
+	assert.Contains(t, res.Err, "refused")
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "warming up", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	m := tools.Mock()
+	WaitForHTTP(m, srv.URL, http.StatusNoContent)
+	assert.Equal(t, "", m.Results().Err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestWaitForHTTPTimeout(t *testing.T) {
+	defer func(d time.Duration) { DefaultTimeout = d }(DefaultTimeout)
+	DefaultTimeout = 200 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "warming up", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	m := tools.Mock()
+	WaitForHTTP(m, srv.URL+"/health", http.StatusOK)
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.Equal(t, "WaitForHTTP "+srv.URL+"/health: not ready after 200ms: got status 503 Service Unavailable, want 200 OK: warming up", res.Err)
+}
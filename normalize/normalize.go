@@ -0,0 +1,216 @@
+package normalize
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSI sequences like colors and cursor movement, OSC sequences like window
+// titles and hyperlinks ended by BEL or ST, and the remaining two byte escapes
+var ansi = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-~]`)
+
+//StripANSI removes ANSI escape sequences from s: colors, cursor movement,
+//window titles and hyperlinks. Other control characters are kept, use
+//Terminal to apply them.
+func StripANSI(s string) string {
+	return ansi.ReplaceAllString(s, "")
+}
+
+//Terminal returns the text a terminal would show after printing s, so
+//output of progress bars and terminal UIs can be compared with golden
+//files. Carriage returns and backspaces overwrite what's on the line,
+//cursor movement and erase sequences are applied, tabs stop every 8
+//columns and colors and other escape sequences are removed. Lines don't
+//wrap and trailing spaces are trimmed.
+func Terminal(s string) string {
+	var sc screen
+	for i := 0; i < len(s); {
+		if s[i] != 0x1b {
+			r, n := utf8.DecodeRuneInString(s[i:])
+			sc.control(r)
+			i += n
+			continue
+		}
+		loc := ansi.FindStringIndex(s[i:])
+		if loc == nil || loc[0] != 0 {
+			// a lone escape
+			i++
+			continue
+		}
+		sc.escape(s[i : i+loc[1]])
+		i += loc[1]
+	}
+	return sc.String()
+}
+
+// screen is a terminal without a size limit
+type screen struct {
+	lines    [][]rune
+	row, col int
+
+	savedRow, savedCol int
+}
+
+func (sc *screen) control(r rune) {
+	switch r {
+	case '\n':
+		// like a tty with onlcr, which is how pipes get captured output
+		sc.row++
+		sc.col = 0
+	case '\r':
+		sc.col = 0
+	case '\b':
+		if sc.col > 0 {
+			sc.col--
+		}
+	case '\t':
+		sc.col = (sc.col/8 + 1) * 8
+	default:
+		if r < ' ' || r == 0x7f {
+			return
+		}
+		sc.put(r)
+	}
+	sc.grow()
+}
+
+func (sc *screen) put(r rune) {
+	sc.grow()
+	l := sc.lines[sc.row]
+	for len(l) <= sc.col {
+		l = append(l, ' ')
+	}
+	l[sc.col] = r
+	sc.lines[sc.row] = l
+	sc.col++
+}
+
+// grow adds the lines up to the cursor
+func (sc *screen) grow() {
+	for len(sc.lines) <= sc.row {
+		sc.lines = append(sc.lines, nil)
+	}
+}
+
+func (sc *screen) escape(seq string) {
+	switch {
+	case seq == "\x1b7":
+		sc.savedRow, sc.savedCol = sc.row, sc.col
+	case seq == "\x1b8":
+		sc.row, sc.col = sc.savedRow, sc.savedCol
+	case strings.HasPrefix(seq, "\x1b["):
+		sc.csi(seq[2:len(seq)-1], seq[len(seq)-1])
+	}
+	sc.grow()
+}
+
+// csi applies the cursor movement and erase sequences, the rest don't
+// change the text
+func (sc *screen) csi(params string, final byte) {
+	var ps []int
+	if params != "" {
+		for _, p := range strings.Split(params, ";") {
+			n, _ := strconv.Atoi(p)
+			ps = append(ps, n)
+		}
+	}
+	param := func(i, def int) int {
+		if i < len(ps) && ps[i] > 0 {
+			return ps[i]
+		}
+		return def
+	}
+	erase := 0
+	if len(ps) > 0 {
+		erase = ps[0]
+	}
+
+	switch final {
+	case 'A':
+		sc.row = clamp(sc.row - param(0, 1))
+	case 'B':
+		sc.row += param(0, 1)
+	case 'C':
+		sc.col += param(0, 1)
+	case 'D':
+		sc.col = clamp(sc.col - param(0, 1))
+	case 'E':
+		sc.row += param(0, 1)
+		sc.col = 0
+	case 'F':
+		sc.row = clamp(sc.row - param(0, 1))
+		sc.col = 0
+	case 'G':
+		sc.col = param(0, 1) - 1
+	case 'H', 'f':
+		sc.row, sc.col = param(0, 1)-1, param(1, 1)-1
+	case 'K':
+		sc.grow()
+		sc.eraseLine(sc.row, erase)
+	case 'J':
+		sc.grow()
+		switch erase {
+		case 0:
+			sc.eraseLine(sc.row, 0)
+			sc.lines = sc.lines[:sc.row+1]
+		case 1:
+			for i := 0; i < sc.row; i++ {
+				sc.lines[i] = nil
+			}
+			sc.eraseLine(sc.row, 1)
+		default:
+			sc.lines = nil
+		}
+	case 's':
+		sc.savedRow, sc.savedCol = sc.row, sc.col
+	case 'u':
+		sc.row, sc.col = sc.savedRow, sc.savedCol
+	}
+}
+
+// eraseLine erases from the cursor to the end of the line, from the start
+// to the cursor or the whole line for modes 0, 1 and 2
+func (sc *screen) eraseLine(row, mode int) {
+	l := sc.lines[row]
+	switch mode {
+	case 0:
+		if sc.col < len(l) {
+			sc.lines[row] = l[:sc.col]
+		}
+	case 1:
+		for i := 0; i <= sc.col && i < len(l); i++ {
+			l[i] = ' '
+		}
+	default:
+		sc.lines[row] = nil
+	}
+}
+
+func (sc *screen) String() string {
+	sc.grow()
+	end := sc.row
+	for i := len(sc.lines) - 1; i > end; i-- {
+		if strings.TrimRight(string(sc.lines[i]), " ") != "" {
+			end = i
+			break
+		}
+	}
+	lines := make([]string, end+1)
+	for i := range lines {
+		lines[i] = strings.TrimRight(string(sc.lines[i]), " ")
+	}
+	s := strings.Join(lines, "\n")
+	if len(sc.lines) > end+1 {
+		s += "\n"
+	}
+	return s
+}
+
+func clamp(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
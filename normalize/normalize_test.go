@@ -0,0 +1,40 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripANSI(t *testing.T) {
+	assert.Equal(t, "ok done", StripANSI("\x1b[1;32mok\x1b[0m done"))
+	assert.Equal(t, "title link", StripANSI("\x1b]0;my title\x07title \x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\"))
+	assert.Equal(t, "a\rb", StripANSI("a\x1b[2K\rb\x1b(B"))
+}
+
+func TestTerminal(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"plain\ntext\n", "plain\ntext\n"},
+		{"\x1b[31merror\x1b[0m\n", "error\n"},
+		{"downloading  10%\rdownloading  50%\rdownloading 100%\ndone\n", "downloading 100%\ndone\n"},
+		{"long progress\rshort\n", "shortprogress\n"},
+		{"long progress\x1b[2K\rshort\n", "short\n"},
+		{"abc\b\bX\n", "aXc\n"},
+		{"a\tb\n", "a       b\n"},
+		{"step 1\nstep 2\n\x1b[2A\x1b[Kstep 1 ok\n\x1b[Kstep 2 ok\n", "step 1 ok\nstep 2 ok\n"},
+		{"a\nb\nc\x1b[1F\x1b[0J", "a\n"},
+		{"one\ntwo\x1b[2J\x1b[Hnew\n", "new\n"},
+		{"hello\x1b[3Dy", "heylo"},
+		{"x\x1b[5Gy", "x   y"},
+		{"\x1b7abc\x1b8d", "dbc"},
+		{"a\x1b[1Kb", " b"},
+		{"a\nb\n\x1b[2A", "a\nb\n"},
+		{"bell\x07\x1b", "bell"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, Terminal(tt.in), "%q", tt.in)
+	}
+}
@@ -0,0 +1,336 @@
+package openapitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prasek/loupe/replay"
+	"github.com/prasek/loupe/tools"
+	"gopkg.in/yaml.v3"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+//Spec is an OpenAPI 3 document responses are checked against
+type Spec struct {
+	path    string
+	doc     map[string]interface{}
+	servers []string
+}
+
+//Load reads the OpenAPI 3 spec in YAML or JSON at path and stops the test
+//if it can't be read
+func Load(t TestingT, path string) *Spec {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("Load spec: %v", err)
+		t.FailNow()
+		return nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		t.Errorf("Load spec %s: %v", path, err)
+		t.FailNow()
+		return nil
+	}
+	doc, ok := jsonValue(v).(map[string]interface{})
+	if !ok || !strings.HasPrefix(fmt.Sprint(doc["openapi"]), "3.") {
+		t.Errorf("Load spec %s: not an OpenAPI 3 document", path)
+		t.FailNow()
+		return nil
+	}
+	nullable(doc)
+
+	s := &Spec{path: path, doc: doc}
+	servers, _ := doc["servers"].([]interface{})
+	for _, srv := range servers {
+		m, _ := srv.(map[string]interface{})
+		if u, err := url.Parse(fmt.Sprint(m["url"])); err == nil && strings.Trim(u.Path, "/") != "" {
+			s.servers = append(s.servers, "/"+strings.Trim(u.Path, "/"))
+		}
+	}
+	return s
+}
+
+//AssertResponse verifies resp is documented for the operation matching
+//method and the request path, which can include a server's base path: the
+//status code must be listed, required headers present and a JSON body must
+//match the response schema, reported with the violations as a diff. The
+//body is read and replaced so it can still be read afterwards.
+func (s *Spec) AssertResponse(t TestingT, method, path string, resp *http.Response) bool {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("nil response")
+		return false
+	}
+	var body []byte
+	if resp.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			return false
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return s.check(t, method, path, resp.StatusCode, resp.Header, body)
+}
+
+//AssertInteraction is AssertResponse for a response recorded by replay
+func (s *Spec) AssertInteraction(t TestingT, i replay.Interaction) bool {
+	t.Helper()
+	u, err := url.Parse(i.Request.URL)
+	if err != nil {
+		t.Errorf("Request URL: %v", err)
+		return false
+	}
+	return s.check(t, i.Request.Method, u.Path, i.Response.Status, i.Response.Header, []byte(i.Response.Body))
+}
+
+func (s *Spec) check(t TestingT, method, path string, status int, header http.Header, body []byte) bool {
+	t.Helper()
+	tmpl, op, opPtr := s.operation(method, path)
+	if op == nil {
+		t.Errorf("Operation: %s %s is not documented in %s", strings.ToUpper(method), path, s.path)
+		return false
+	}
+	name := strings.ToUpper(method) + " " + tmpl
+
+	responses, _ := op["responses"].(map[string]interface{})
+	code, res := response(responses, status)
+	if res == nil {
+		t.Errorf("Status: %d is not documented for %s, expected one of %s", status, name, strings.Join(sortedKeys(responses), ", "))
+		return false
+	}
+	res, resPtr := s.resolve(res, opPtr+"/responses/"+pointerEscaper.Replace(code))
+
+	ok := true
+	headers, _ := res["headers"].(map[string]interface{})
+	for _, h := range sortedKeys(headers) {
+		def, _ := s.resolve(headers[h], "")
+		if def["required"] == true && header.Get(h) == "" {
+			t.Errorf("Header %s: required for %s %s, missing", h, name, code)
+			ok = false
+		}
+	}
+
+	content, _ := res["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return ok
+	}
+	ct := header.Get("Content-Type")
+	media, mt := mediaType(content, ct)
+	if media == "" {
+		t.Errorf("Header Content-Type: expected one of %s for %s %s, got %q", strings.Join(sortedKeys(content), ", "), name, code, ct)
+		return false
+	}
+	def, _ := content[media].(map[string]interface{})
+	if _, has := def["schema"]; !has || !strings.Contains(mt, "json") {
+		return ok
+	}
+
+	d := tools.DiffJSONSchema(s.schema(resPtr+"/content/"+pointerEscaper.Replace(media)+"/schema"), body)
+	if err := d.Error(); err != nil {
+		t.Errorf("Body of %s %s: %v", name, code, err)
+		return false
+	}
+	if !d.Equal() {
+		t.Errorf("Body does not match the schema of %s %s in %s\n%s", name, code, s.path, d)
+		return false
+	}
+	return ok
+}
+
+// operation finds the path template, operation and its location for method
+// and path, preferring templates with more literal segments among the ones
+// that define method
+func (s *Spec) operation(method, path string) (string, map[string]interface{}, string) {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	candidates := []string{path}
+	for _, base := range s.servers {
+		if strings.HasPrefix(path, base+"/") {
+			candidates = append(candidates, strings.TrimPrefix(path, base))
+		}
+	}
+
+	m := strings.ToLower(method)
+	best, score := "", -1
+	var bestOp map[string]interface{}
+	var bestPtr string
+	for _, p := range candidates {
+		for tmpl := range paths {
+			n, ok := matchPath(tmpl, p)
+			if !ok || n < score || n == score && tmpl > best {
+				continue
+			}
+			item, ptr := s.resolve(paths[tmpl], "#/paths/"+pointerEscaper.Replace(tmpl))
+			if op, _ := item[m].(map[string]interface{}); op != nil {
+				best, score, bestOp, bestPtr = tmpl, n, op, ptr
+			}
+		}
+	}
+	if bestOp == nil {
+		return "", nil, ""
+	}
+	return best, bestOp, bestPtr + "/" + m
+}
+
+// matchPath matches path against the template tmpl, e.g. /users/{id}, and
+// returns the number of literal segments
+func matchPath(tmpl, path string) (int, bool) {
+	ts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ts) != len(ps) {
+		return 0, false
+	}
+	n := 0
+	for i, seg := range ts {
+		switch {
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			if ps[i] == "" {
+				return 0, false
+			}
+		case seg == ps[i]:
+			n++
+		default:
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+// response finds the response for status, by code, range like 2XX or the
+// default response
+func response(responses map[string]interface{}, status int) (string, map[string]interface{}) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if res, ok := responses[key].(map[string]interface{}); ok {
+			return key, res
+		}
+	}
+	return "", nil
+}
+
+// mediaType finds the documented media type for the Content-Type ct,
+// falling back to wildcards like application/* and */*, and returns it with
+// the media type of ct
+func mediaType(content map[string]interface{}, ct string) (string, string) {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		mt = ""
+	}
+	candidates := []string{mt}
+	if i := strings.Index(mt, "/"); i > 0 {
+		candidates = append(candidates, mt[:i]+"/*")
+	}
+	candidates = append(candidates, "*/*")
+	for _, c := range candidates {
+		if _, ok := content[c]; ok && c != "" {
+			return c, mt
+		}
+	}
+	return "", mt
+}
+
+// resolve follows the $refs from v, found at ptr, to other parts of the
+// document and returns the value and its location
+func (s *Spec) resolve(v interface{}, ptr string) (map[string]interface{}, string) {
+	for i := 0; i < 32; i++ {
+		m, _ := v.(map[string]interface{})
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return m, ptr
+		}
+		v, ptr = s.doc, ref
+		for _, tok := range strings.Split(ref[2:], "/") {
+			tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+			cur, _ := v.(map[string]interface{})
+			v = cur[tok]
+		}
+	}
+	return nil, ""
+}
+
+// schema returns the spec as a JSON Schema whose root refers to the schema
+// at ptr, so references to components resolve within the document
+func (s *Spec) schema(ptr string) []byte {
+	doc := make(map[string]interface{}, len(s.doc)+1)
+	for k, v := range s.doc {
+		doc[k] = v
+	}
+	doc["$ref"] = ptr
+	bs, _ := json.Marshal(doc)
+	return bs
+}
+
+// nullable rewrites the OpenAPI 3.0 nullable keyword as a null type
+func nullable(v interface{}) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if vv["nullable"] == true {
+			if typ, ok := vv["type"].(string); ok {
+				vv["type"] = []interface{}{typ, "null"}
+			}
+			delete(vv, "nullable")
+		}
+		for _, e := range vv {
+			nullable(e)
+		}
+	case []interface{}:
+		for _, e := range vv {
+			nullable(e)
+		}
+	}
+}
+
+// jsonValue converts YAML maps to maps with string keys, YAML reads status
+// codes like 200 as integers
+func jsonValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			vv[k] = jsonValue(e)
+		}
+		return vv
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = jsonValue(e)
+		}
+		return vv
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
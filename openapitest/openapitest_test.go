@@ -0,0 +1,103 @@
+package openapitest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prasek/loupe/replay"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func newResponse(status int, header map[string]string, body string) *http.Response {
+	rec := httptest.NewRecorder()
+	for k, v := range header {
+		rec.Header().Set(k, v)
+	}
+	rec.WriteHeader(status)
+	rec.WriteString(body)
+	return rec.Result()
+}
+
+var jsonHeader = map[string]string{"Content-Type": "application/json", "X-Request-Id": "r1"}
+
+func TestAssertResponse(t *testing.T) {
+	spec := Load(t, "testdata/api.yaml")
+
+	resp := newResponse(200, jsonHeader, `{"id": 1, "name": "ann", "email": null, "manager": {"id": 2, "name": "bob"}}`)
+	assert.True(t, spec.AssertResponse(t, "GET", "/users/1", resp))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `"ann"`)
+
+	assert.True(t, spec.AssertResponse(t, "get", "/v1/users/me", newResponse(200, map[string]string{"Content-Type": "application/json; charset=utf-8"}, `{"id": 1, "name": "me"}`)))
+	assert.True(t, spec.AssertResponse(t, "GET", "/users/7", newResponse(404, jsonHeader, `{"error": "not found"}`)))
+
+	// /users/me has no delete, /users/{id} does
+	assert.True(t, spec.AssertResponse(t, "DELETE", "/users/me", newResponse(204, nil, ``)))
+}
+
+func TestAssertResponseFail(t *testing.T) {
+	spec := Load(t, "testdata/api.yaml")
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		resp   *http.Response
+		err    string
+	}{
+		{"operation", "POST", "/users/1", newResponse(200, jsonHeader, `{}`), "Operation: POST /users/1 is not documented in testdata/api.yaml"},
+		{"path", "GET", "/teams/1", newResponse(200, jsonHeader, `{}`), "Operation: GET /teams/1 is not documented"},
+		{"status", "GET", "/users/1", newResponse(500, jsonHeader, `{}`), "Status: 500 is not documented for GET /users/{id}, expected one of 200, 404"},
+		{"header", "GET", "/users/1", newResponse(200, map[string]string{"Content-Type": "application/json"}, `{"id": 1, "name": "a"}`), "Header X-Request-Id: required for GET /users/{id} 200, missing"},
+		{"content type", "GET", "/users/1", newResponse(200, map[string]string{"Content-Type": "text/plain", "X-Request-Id": "r"}, `hi`), `Header Content-Type: expected one of application/json for GET /users/{id} 200, got "text/plain"`},
+		{"body", "GET", "/users/1", newResponse(200, jsonHeader, `{"id": "1", "manager": {"id": 2}}`), "Body does not match the schema of GET /users/{id} 200 in testdata/api.yaml\n" +
+			"(root):\n-missing properties: 'name' (/components/schemas/User/required)\n+{\"id\":\"1\",\"manager\":{\"id\":2}}\n" +
+			"/id:\n-expected integer, but got string (/components/schemas/User/properties/id/type)\n+\"1\"\n" +
+			"/manager:\n-missing properties: 'name' (/components/schemas/User/required)\n+{\"id\":2}\n"},
+		{"invalid body", "GET", "/users/1", newResponse(200, jsonHeader, `{"id":`), "Body of GET /users/{id} 200:"},
+	}
+
+	for _, test := range tests {
+		m := tools.Mock()
+		ok := spec.AssertResponse(m, test.method, test.path, test.resp)
+		res := m.Results()
+		assert.False(t, ok, test.name)
+		assert.Contains(t, res.Err, test.err, test.name)
+	}
+}
+
+func TestAssertInteraction(t *testing.T) {
+	spec := Load(t, "testdata/api.yaml")
+	i := replay.Interaction{
+		Request:  replay.Request{Method: "GET", URL: "https://api.example.com/v1/users/3?x=1"},
+		Response: replay.Response{Status: 200, Header: http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"r"}}, Body: `{"id": 3, "name": "cy"}`},
+	}
+	assert.True(t, spec.AssertInteraction(t, i))
+
+	i.Response.Body = `{"id": 3}`
+	m := tools.Mock()
+	assert.False(t, spec.AssertInteraction(m, i))
+	assert.Contains(t, m.Results().Err, "missing properties: 'name'")
+}
+
+func TestLoad(t *testing.T) {
+	m := tools.Mock()
+	assert.Nil(t, Load(m, "testdata/none.yaml"))
+	assert.True(t, m.Results().FailNow)
+
+	m = tools.Mock()
+	assert.Nil(t, Load(m, "openapitest.go"))
+	assert.Contains(t, m.Results().Err, "Load spec openapitest.go:")
+}
+
+func TestMatchPath(t *testing.T) {
+	n, ok := matchPath("/users/{id}/posts", "/users/7/posts")
+	assert.True(t, ok)
+	assert.Equal(t, 2, n)
+	_, ok = matchPath("/users/{id}", "/users/")
+	assert.False(t, ok)
+	_, ok = matchPath("/users/{id}", "/users/1/posts")
+	assert.False(t, ok)
+}
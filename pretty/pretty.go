@@ -0,0 +1,246 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//Config controls how values are printed, the zero value prints every
+//element on its own line with no depth limit
+type Config struct {
+	//MaxDepth elides values nested deeper than MaxDepth as Type{...},
+	//0 means no limit
+	MaxDepth int
+
+	//Width prints composite values on one line when they fit in Width
+	//columns, 0 always prints one element per line
+	Width int
+
+	//Indent is repeated once per level, the default is two spaces
+	Indent string
+}
+
+//Sprint renders v one field, element or map entry per line with type names
+//and sorted map keys, so the output is stable and diffs line by line
+func Sprint(v interface{}) string {
+	return (&Config{}).Sprint(v)
+}
+
+//Sprint renders v with the config c
+func (c *Config) Sprint(v interface{}) string {
+	p := &printer{Config: *c, seen: make(map[uintptr]bool)}
+	if p.Indent == "" {
+		p.Indent = "  "
+	}
+	p.print(reflect.ValueOf(v), 0)
+	return p.buf.String()
+}
+
+//Text returns the text of values that describe themselves: strings,
+//[]byte, errors, fmt.Stringers, encoding.TextMarshalers and
+//json.Marshalers. Values that are printed this way are not expanded, nil
+//pointers aren't since their methods may dereference them.
+func Text(v interface{}) (string, bool) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "", false
+	}
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case []byte:
+		return string(t), true
+	case error:
+		return t.Error(), true
+	case fmt.Stringer:
+		return t.String(), true
+	case encoding.TextMarshaler:
+		if bs, err := t.MarshalText(); err == nil {
+			return string(bs), true
+		}
+	case json.Marshaler:
+		if bs, err := t.MarshalJSON(); err == nil {
+			return string(bs), true
+		}
+	}
+	return "", false
+}
+
+type printer struct {
+	Config
+	buf  bytes.Buffer
+	seen map[uintptr]bool
+	flat bool
+}
+
+// column returns the length of the current line
+func (p *printer) column() int {
+	b := p.buf.Bytes()
+	return len(b) - bytes.LastIndexByte(b, '\n') - 1
+}
+
+func (p *printer) newline(depth int) {
+	if p.flat {
+		return
+	}
+	p.buf.WriteString("\n")
+	p.buf.WriteString(strings.Repeat(p.Indent, depth))
+}
+
+func (p *printer) print(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		p.buf.WriteString("nil")
+		return
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 && !v.IsNil() {
+		fmt.Fprintf(&p.buf, "%s(%q)", v.Type(), v.Bytes())
+		return
+	}
+
+	if v.CanInterface() && v.Kind() != reflect.String && !isNil(v) {
+		if s, ok := Text(v.Interface()); ok {
+			p.buf.WriteString(s)
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			fmt.Fprintf(&p.buf, "(%s)(nil)", v.Type())
+			return
+		}
+		if p.seen[v.Pointer()] {
+			fmt.Fprintf(&p.buf, "(%s)(<cycle>)", v.Type())
+			return
+		}
+		p.seen[v.Pointer()] = true
+		defer delete(p.seen, v.Pointer())
+		p.buf.WriteString("&")
+		p.print(v.Elem(), depth)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			p.buf.WriteString("nil")
+			return
+		}
+		p.print(v.Elem(), depth)
+
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		p.composite(v, depth)
+
+	case reflect.String:
+		fmt.Fprintf(&p.buf, "%q", v.String())
+	case reflect.Bool:
+		fmt.Fprintf(&p.buf, "%t", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(&p.buf, "%d", v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fmt.Fprintf(&p.buf, "%d", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(&p.buf, "%v", v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		fmt.Fprintf(&p.buf, "%v", v.Complex())
+	default:
+		// chans, funcs and unsafe pointers have no stable representation
+		fmt.Fprintf(&p.buf, "%s(...)", v.Type())
+	}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+type entry struct {
+	key string
+	val reflect.Value
+}
+
+// entries returns the labeled elements of a struct, map, slice or array,
+// map entries are sorted by their printed key
+func (p *printer) entries(v reflect.Value, depth int) []entry {
+	var entries []entry
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			entries = append(entries, entry{key: v.Type().Field(i).Name, val: v.Field(i)})
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			kp := &printer{Config: p.Config, seen: p.seen, flat: true}
+			kp.print(k, depth+1)
+			entries = append(entries, entry{key: kp.buf.String(), val: v.MapIndex(k)})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+	default:
+		for i := 0; i < v.Len(); i++ {
+			entries = append(entries, entry{val: v.Index(i)})
+		}
+	}
+	return entries
+}
+
+func (p *printer) composite(v reflect.Value, depth int) {
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		fmt.Fprintf(&p.buf, "%s(nil)", v.Type())
+		return
+	}
+
+	fmt.Fprintf(&p.buf, "%s{", v.Type())
+	var empty bool
+	switch v.Kind() {
+	case reflect.Struct:
+		empty = v.NumField() == 0
+	default:
+		empty = v.Len() == 0
+	}
+	if empty {
+		p.buf.WriteString("}")
+		return
+	}
+	if p.MaxDepth > 0 && depth >= p.MaxDepth {
+		p.buf.WriteString("...}")
+		return
+	}
+
+	entries := p.entries(v, depth)
+	if !p.flat && p.Width > 0 {
+		fp := &printer{Config: p.Config, seen: p.seen, flat: true}
+		fp.writeEntries(entries, depth)
+		if s := fp.buf.String(); p.column()+len(s) <= p.Width {
+			p.buf.WriteString(s)
+			return
+		}
+	}
+	p.writeEntries(entries, depth)
+}
+
+// writeEntries writes the elements and the closing brace
+func (p *printer) writeEntries(entries []entry, depth int) {
+	for i, e := range entries {
+		if p.flat && i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.newline(depth + 1)
+		if e.key != "" {
+			p.buf.WriteString(e.key + ": ")
+		}
+		p.print(e.val, depth+1)
+		if !p.flat {
+			p.buf.WriteString(",")
+		}
+	}
+	p.newline(depth)
+	p.buf.WriteString("}")
+}
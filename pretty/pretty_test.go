@@ -0,0 +1,88 @@
+package pretty
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inner struct {
+	Tags map[string]int
+	data []byte
+}
+
+type outer struct {
+	Name  string
+	When  time.Time
+	Err   error
+	Inner *inner
+	List  []inner
+	Empty []int
+	Fn    func()
+}
+
+func TestSprint(t *testing.T) {
+	v := outer{
+		Name:  "a",
+		When:  time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC),
+		Err:   errors.New("boom"),
+		Inner: &inner{Tags: map[string]int{"b": 2, "a": 1}, data: []byte("hi")},
+		List:  []inner{{}},
+		Empty: []int{},
+	}
+
+	assert.Equal(t, `pretty.outer{
+  Name: "a",
+  When: 2001-02-03 00:00:00 +0000 UTC,
+  Err: boom,
+  Inner: &pretty.inner{
+    Tags: map[string]int{
+      "a": 1,
+      "b": 2,
+    },
+    data: []uint8("hi"),
+  },
+  List: []pretty.inner{
+    pretty.inner{
+      Tags: map[string]int(nil),
+      data: []uint8(nil),
+    },
+  },
+  Empty: []int{},
+  Fn: func()(...),
+}`, Sprint(v))
+
+	c := &Config{Width: 40}
+	assert.Equal(t, `&pretty.inner{
+  Tags: map[string]int{"a": 1, "b": 2},
+  data: []uint8("hi"),
+}`, c.Sprint(v.Inner))
+	assert.Equal(t, `[]int{1, 2, 3}`, c.Sprint([]int{1, 2, 3}))
+
+	c = &Config{MaxDepth: 1, Indent: "\t"}
+	assert.Equal(t, "&pretty.inner{\n\tTags: map[string]int{...},\n\tdata: []uint8(\"hi\"),\n}", c.Sprint(v.Inner))
+
+	// map keys are sorted by their printed form, so output is stable
+	m := map[int]string{}
+	for i := 0; i < 20; i++ {
+		m[i] = "x"
+	}
+	exp := Sprint(m)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, exp, Sprint(m))
+	}
+}
+
+func TestText(t *testing.T) {
+	s, ok := Text(errors.New("boom"))
+	assert.True(t, ok)
+	assert.Equal(t, "boom", s)
+
+	_, ok = Text(1)
+	assert.False(t, ok)
+
+	_, ok = Text((*time.Time)(nil))
+	assert.False(t, ok)
+}
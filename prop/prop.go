@@ -0,0 +1,168 @@
+package prop
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/prasek/loupe/gen"
+	"github.com/prasek/loupe/pretty"
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	gen.TestingT
+}
+
+//Config controls how properties are checked, the zero value uses the
+//defaults
+type Config struct {
+	//Checks is the number of random inputs tried, 100 by default
+	Checks int
+
+	//MaxShrinks limits the simplification steps of a failing input, 1000
+	//by default
+	MaxShrinks int
+}
+
+//ForAll checks that fn returns true for random inputs from the
+//generators, e.g.
+//
+//	prop.ForAll(t, gen.Int(), gen.String(), func(i int, s string) bool {
+//		return len(strconv.Itoa(i)+s) >= len(s)
+//	})
+//
+//fn takes one argument per generator and returns a bool, a panic counts as
+//false. A failing input is shrunk to a simpler one that still fails, which
+//is reported with a diff from the original. The seed is logged on failure
+//and LOUPE_SEED replays it. ForAll panics if fn doesn't match the
+//generators.
+func ForAll(t TestingT, gensAndFn ...interface{}) bool {
+	t.Helper()
+	return (&Config{}).ForAll(t, gensAndFn...)
+}
+
+//ForAll checks the property with the config c
+func (c *Config) ForAll(t TestingT, gensAndFn ...interface{}) bool {
+	t.Helper()
+	gens, fn := parse(gensAndFn)
+	checks, maxShrinks := c.Checks, c.MaxShrinks
+	if checks <= 0 {
+		checks = 100
+	}
+	if maxShrinks <= 0 {
+		maxShrinks = 1000
+	}
+
+	r := gen.New(t)
+	for i := 1; i <= checks; i++ {
+		args := make([]interface{}, len(gens))
+		for j, g := range gens {
+			args[j] = g.Generate(r)
+		}
+		ok, msg := call(fn, args)
+		if ok {
+			continue
+		}
+
+		shrunk, steps := shrink(fn, gens, args, maxShrinks)
+		_, msg = call(fn, shrunk)
+		header := fmt.Sprintf("Property failed after %d checks, shrunk %d times", i, steps)
+		if msg != "" {
+			header += "\n" + msg
+		}
+		d := tools.Diff(format(args), format(shrunk), tools.WithLabels("original", "shrunk"), tools.WithMode(tools.LineMode))
+		t.Errorf("%s\ncounterexample:\n%s\n%s", header, format(shrunk), d)
+		return false
+	}
+	return true
+}
+
+// parse splits the arguments of ForAll and checks fn takes the generated
+// types and returns a bool
+func parse(gensAndFn []interface{}) ([]gen.Generator, reflect.Value) {
+	if len(gensAndFn) == 0 {
+		panic("prop: ForAll needs generators and a func")
+	}
+	var gens []gen.Generator
+	for i, a := range gensAndFn[:len(gensAndFn)-1] {
+		g, ok := a.(gen.Generator)
+		if !ok {
+			panic(fmt.Sprintf("prop: argument %d must be a gen.Generator, got %T", i, a))
+		}
+		gens = append(gens, g)
+	}
+
+	fn := reflect.ValueOf(gensAndFn[len(gensAndFn)-1])
+	ft := fn.Type()
+	ok := ft.Kind() == reflect.Func && ft.NumIn() == len(gens) && ft.NumOut() == 1 && ft.Out(0).Kind() == reflect.Bool
+	for i := 0; ok && i < len(gens); i++ {
+		ok = gens[i].Type().AssignableTo(ft.In(i))
+	}
+	if !ok {
+		types := make([]string, len(gens))
+		for i, g := range gens {
+			types[i] = g.Type().String()
+		}
+		panic(fmt.Sprintf("prop: fn must be a func(%s) bool, got %s", strings.Join(types, ", "), ft))
+	}
+	return gens, fn
+}
+
+// call calls fn with args and reports whether it returned true, a panic is
+// a failure described by msg
+func call(fn reflect.Value, args []interface{}) (ok bool, msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok, msg = false, fmt.Sprintf("panic: %v", r)
+		}
+	}()
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		if a == nil {
+			in[i] = reflect.Zero(fn.Type().In(i))
+		} else {
+			in[i] = reflect.ValueOf(a)
+		}
+	}
+	return fn.Call(in)[0].Bool(), ""
+}
+
+// shrink replaces one argument at a time with a simpler value that still
+// fails until none does or maxShrinks steps were taken
+func shrink(fn reflect.Value, gens []gen.Generator, args []interface{}, maxShrinks int) ([]interface{}, int) {
+	args = append([]interface{}(nil), args...)
+	steps := 0
+	for steps < maxShrinks {
+		shrunk := false
+		for i, g := range gens {
+			for _, c := range g.Shrink(args[i]) {
+				try := append([]interface{}(nil), args...)
+				try[i] = c
+				if ok, _ := call(fn, try); !ok {
+					args = try
+					shrunk = true
+					steps++
+					break
+				}
+			}
+			if shrunk || steps >= maxShrinks {
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return args, steps
+}
+
+// format renders each argument on its own lines with pretty.Sprint
+func format(args []interface{}) string {
+	var b strings.Builder
+	for i, a := range args {
+		fmt.Fprintf(&b, "[%d] %s\n", i, pretty.Sprint(a))
+	}
+	return b.String()
+}
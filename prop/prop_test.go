@@ -0,0 +1,94 @@
+package prop
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/gen"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeT struct {
+	failed   bool
+	errs     []string
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()          {}
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Failed() bool     { return t.failed }
+func (t *fakeT) FailNow()         { t.failed = true }
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) done() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestForAll(t *testing.T) {
+	calls := 0
+	ok := ForAll(t, gen.Int(), gen.String(), func(i int, s string) bool {
+		calls++
+		return len(fmt.Sprint(i)+s) >= len(s)
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 100, calls)
+
+	calls = 0
+	(&Config{Checks: 10}).ForAll(t, gen.UUID(), func(s string) bool {
+		calls++
+		return len(s) == 36
+	})
+	assert.Equal(t, 10, calls)
+}
+
+func TestForAllShrinks(t *testing.T) {
+	defer os.Setenv(gen.SeedEnv, os.Getenv(gen.SeedEnv))
+	os.Setenv(gen.SeedEnv, "7")
+
+	ft := &fakeT{}
+	ok := ForAll(ft, gen.IntRange(0, 1000), gen.String(), func(i int, s string) bool {
+		return i < 100 || len(s) < 3
+	})
+	ft.done()
+	assert.False(t, ok)
+	if assert.Len(t, ft.errs, 1) {
+		err := ft.errs[0]
+		assert.True(t, strings.HasPrefix(err, "Property failed after "), err)
+		assert.Contains(t, err, "counterexample:\n[0] 100\n[1] \"aaa\"\n")
+		assert.Contains(t, err, "--- original\n+++ shrunk\n")
+		assert.Contains(t, err, "+[0] 100\n")
+	}
+	assert.Equal(t, []string{"random seed 7, rerun with LOUPE_SEED=7"}, ft.logs)
+}
+
+func TestForAllPanics(t *testing.T) {
+	ft := &fakeT{}
+	ok := ForAll(ft, gen.Int(), func(i int) bool {
+		if i != 0 {
+			panic("not zero")
+		}
+		return true
+	})
+	assert.False(t, ok)
+	if assert.Len(t, ft.errs, 1) {
+		assert.Contains(t, ft.errs[0], "panic: not zero\ncounterexample:\n[0] 1\n")
+	}
+
+	assert.PanicsWithValue(t, "prop: fn must be a func(int, string) bool, got func(int) bool", func() {
+		ForAll(t, gen.Int(), gen.String(), func(i int) bool { return true })
+	})
+	assert.PanicsWithValue(t, "prop: argument 0 must be a gen.Generator, got int", func() {
+		ForAll(t, 1, func(i int) bool { return true })
+	})
+}
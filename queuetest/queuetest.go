@@ -0,0 +1,375 @@
+package queuetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+}
+
+//ErrClosed is returned by Receive after the broker or consumer is closed
+var ErrClosed = errors.New("queuetest: closed")
+
+//Message is a message published to a topic
+type Message struct {
+	Topic     string
+	Key       string
+	Value     []byte
+	Headers   map[string]string
+	Partition int
+	Offset    int64
+}
+
+//Option configures a Broker
+type Option func(*Broker)
+
+//WithPartitions sets the number of partitions of each topic, 1 by default.
+//Messages with the same key go to the same partition, and the partitions
+//of a topic are shared by the consumers of a group.
+func WithPartitions(n int) Option {
+	return func(b *Broker) {
+		if n > 0 {
+			b.partitions = n
+		}
+	}
+}
+
+//Broker is an in-memory message broker standing in for Kafka or another
+//queue: tests publish messages for the code under test to consume, and
+//assert on the messages it produced
+type Broker struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string]*topic
+	notify     chan struct{}
+	closed     bool
+}
+
+type topic struct {
+	log        []Message
+	partitions [][]Message
+	groups     map[string]*consumerGroup
+	next       int
+}
+
+// consumerGroup holds the offsets of a consumer group and its members,
+// partition p is assigned to members[p%len(members)]
+type consumerGroup struct {
+	offsets []int64
+	members []*Consumer
+}
+
+//New creates a Broker that is closed with t.Cleanup
+func New(t TestingT, opts ...Option) *Broker {
+	b := &Broker{
+		partitions: 1,
+		topics:     make(map[string]*topic),
+		notify:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	t.Cleanup(b.Close)
+	return b
+}
+
+//Close stops the broker, blocked consumers return ErrClosed
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		b.wake()
+	}
+}
+
+// wake notifies blocked consumers, b.mu is held
+func (b *Broker) wake() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// topic returns the topic name, creating it, b.mu is held
+func (b *Broker) topic(name string) *topic {
+	tp, ok := b.topics[name]
+	if !ok {
+		tp = &topic{
+			partitions: make([][]Message, b.partitions),
+			groups:     make(map[string]*consumerGroup),
+		}
+		b.topics[name] = tp
+	}
+	return tp
+}
+
+//Publish adds m to m.Topic and returns it with its partition and offset
+//set. The partition is chosen by a hash of the key, messages without a key
+//are spread round robin.
+func (b *Broker) Publish(m Message) Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tp := b.topic(m.Topic)
+	if m.Key == "" {
+		m.Partition = tp.next % b.partitions
+		tp.next++
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(m.Key))
+		m.Partition = int(h.Sum32() % uint32(b.partitions))
+	}
+	m.Offset = int64(len(tp.partitions[m.Partition]))
+	tp.partitions[m.Partition] = append(tp.partitions[m.Partition], m)
+	tp.log = append(tp.log, m)
+	b.wake()
+	return m
+}
+
+//PublishJSON publishes v encoded as JSON to topic with key, strings and
+//[]byte are published as is
+func (b *Broker) PublishJSON(t TestingT, topic, key string, v interface{}) Message {
+	t.Helper()
+	bs, err := jsonBytes(v)
+	if err != nil {
+		t.Errorf("PublishJSON: %v", err)
+		return Message{}
+	}
+	return b.Publish(Message{Topic: topic, Key: key, Value: bs})
+}
+
+//Messages returns the messages published to topic in the order they were
+//published
+func (b *Broker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tp := b.topics[topic]
+	if tp == nil {
+		return nil
+	}
+	return append([]Message(nil), tp.log...)
+}
+
+//Consumer is a member of a consumer group. Every group receives each
+//message of the topic once, from the start of the topic, and the members
+//of a group share its partitions.
+type Consumer struct {
+	b      *Broker
+	topic  string
+	group  string
+	next   int
+	closed bool
+}
+
+//Subscribe adds a consumer of topic to group, rebalancing the group's
+//partitions
+func (b *Broker) Subscribe(topic, group string) *Consumer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tp := b.topic(topic)
+	g, ok := tp.groups[group]
+	if !ok {
+		g = &consumerGroup{offsets: make([]int64, b.partitions)}
+		tp.groups[group] = g
+	}
+	c := &Consumer{b: b, topic: topic, group: group}
+	g.members = append(g.members, c)
+	b.wake()
+	return c
+}
+
+//Close leaves the group, its partitions are assigned to the remaining
+//members
+func (c *Consumer) Close() {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	g := c.b.topics[c.topic].groups[c.group]
+	for i, m := range g.members {
+		if m == c {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+	c.b.wake()
+}
+
+//Receive returns the next message from the partitions assigned to c,
+//waiting until one is published, ctx is done or the broker is closed
+func (c *Consumer) Receive(ctx context.Context) (Message, error) {
+	for {
+		c.b.mu.Lock()
+		if c.closed || c.b.closed {
+			c.b.mu.Unlock()
+			return Message{}, ErrClosed
+		}
+		m, ok := c.poll()
+		notify := c.b.notify
+		c.b.mu.Unlock()
+		if ok {
+			return m, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+//TryReceive is Receive without waiting
+func (c *Consumer) TryReceive() (Message, bool) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.closed || c.b.closed {
+		return Message{}, false
+	}
+	return c.poll()
+}
+
+// poll takes the next message from the assigned partitions, round robin so
+// no partition starves, b.mu is held
+func (c *Consumer) poll() (Message, bool) {
+	tp := c.b.topics[c.topic]
+	g := tp.groups[c.group]
+	index := -1
+	for i, m := range g.members {
+		if m == c {
+			index = i
+		}
+	}
+	for i := 0; i < c.b.partitions; i++ {
+		p := (c.next + i) % c.b.partitions
+		if p%len(g.members) != index || g.offsets[p] >= int64(len(tp.partitions[p])) {
+			continue
+		}
+		m := tp.partitions[p][g.offsets[p]]
+		g.offsets[p]++
+		c.next = p + 1
+		return m, true
+	}
+	return Message{}, false
+}
+
+//AssertMessages verifies the values of the messages published to topic
+//are equal to want as a JSON array and fails with a JSON diff if not. want
+//can be a JSON string or []byte, like a fixture file's contents, any other
+//value is marshaled with encoding/json. Messages are compared in order,
+//tools.WithListMode(tools.ListMultiset) ignores their order and
+//tools.WithListKeys matches them by fields.
+func (b *Broker) AssertMessages(t TestingT, topic string, want interface{}, opts ...tools.Option) bool {
+	t.Helper()
+	exp, err := jsonBytes(want)
+	if err != nil {
+		t.Errorf("Invalid want JSON: %v", err)
+		return false
+	}
+	act, err := b.valuesJSON(topic)
+	if err != nil {
+		t.Errorf("Messages of %s: %v", topic, err)
+		return false
+	}
+	d := tools.DiffJSON(exp, act, opts...)
+	if !d.Equal() {
+		tools.ReportFailureValues(t, "Messages of "+topic+" Not Equal", d.String(), exp, act)
+		t.Errorf("Messages of %s Not Equal (-want +got)\n%s", topic, d)
+		return false
+	}
+	return true
+}
+
+//AssertDecoded decodes the values of the messages published to topic as
+//JSON into values of the element type of the slice want and verifies they
+//are equal, failing with a DiffValues struct diff if not. opts work like
+//for AssertMessages.
+func (b *Broker) AssertDecoded(t TestingT, topic string, want interface{}, opts ...tools.Option) bool {
+	t.Helper()
+	wv := reflect.ValueOf(want)
+	if wv.Kind() != reflect.Slice {
+		t.Errorf("AssertDecoded: want must be a slice, got %T", want)
+		return false
+	}
+	got := reflect.MakeSlice(wv.Type(), 0, 0)
+	for _, m := range b.Messages(topic) {
+		v := reflect.New(wv.Type().Elem())
+		if err := json.Unmarshal(m.Value, v.Interface()); err != nil {
+			t.Errorf("Message %d of %s: %v", m.Offset, topic, err)
+			return false
+		}
+		got = reflect.Append(got, v.Elem())
+	}
+	d := tools.DiffValues(want, got.Interface(), opts...)
+	if !d.Equal() {
+		tools.ReportFailureValues(t, "Messages of "+topic+" Not Equal", d.String(), want, got.Interface())
+		t.Errorf("Messages of %s Not Equal (-want +got)\n%s", topic, d)
+		return false
+	}
+	return true
+}
+
+//AssertGolden compares the messages published to topic, one JSON line per
+//message with its key and value, with the golden file at path, written
+//with -update
+func (b *Broker) AssertGolden(t TestingT, topic, path string) bool {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, m := range b.Messages(topic) {
+		line := struct {
+			Key   string          `json:"key,omitempty"`
+			Value json.RawMessage `json:"value"`
+		}{Key: m.Key, Value: m.Value}
+		if !json.Valid(m.Value) {
+			line.Value, _ = json.Marshal(string(m.Value))
+		}
+		bs, _ := json.Marshal(line)
+		buf.Write(bs)
+		buf.WriteByte('\n')
+	}
+	return golden.Assert(t, buf.String(), path)
+}
+
+// valuesJSON returns the values of the messages of topic as a JSON array,
+// values that aren't JSON are strings
+func (b *Broker) valuesJSON(topic string) ([]byte, error) {
+	values := []json.RawMessage{}
+	for _, m := range b.Messages(topic) {
+		v := json.RawMessage(m.Value)
+		if !json.Valid(m.Value) {
+			v, _ = json.Marshal(string(m.Value))
+		}
+		values = append(values, v)
+	}
+	return json.Marshal(values)
+}
+
+func jsonBytes(v interface{}) ([]byte, error) {
+	var bs []byte
+	switch s := v.(type) {
+	case string:
+		bs = []byte(s)
+	case []byte:
+		bs = s
+	default:
+		return json.Marshal(v)
+	}
+	if !json.Valid(bs) {
+		return nil, fmt.Errorf("%q", bs)
+	}
+	return bs, nil
+}
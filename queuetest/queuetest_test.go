@@ -0,0 +1,134 @@
+package queuetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// pay is the code under test: it consumes orders and publishes them paid
+func pay(ctx context.Context, b *Broker, c *Consumer) error {
+	for {
+		m, err := c.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		var o order
+		if err := json.Unmarshal(m.Value, &o); err != nil {
+			return err
+		}
+		o.Status = "paid"
+		bs, _ := json.Marshal(o)
+		b.Publish(Message{Topic: "paid", Key: m.Key, Value: bs})
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := New(t)
+	b.PublishJSON(t, "orders", "o1", order{ID: 1, Status: "new"})
+	b.PublishJSON(t, "orders", "o2", `{"id": 2, "status": "new"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pay(ctx, b, b.Subscribe("orders", "payments")))
+
+	b.AssertMessages(t, "paid", `[{"id": 1, "status": "paid"}, {"id": 2, "status": "paid"}]`)
+	b.AssertMessages(t, "paid", []order{{2, "paid"}, {1, "paid"}}, tools.WithListMode(tools.ListMultiset))
+	b.AssertDecoded(t, "paid", []order{{1, "paid"}, {2, "paid"}})
+	b.AssertGolden(t, "paid", "testdata/orders.golden")
+
+	m := b.Messages("paid")[1]
+	assert.Equal(t, "o2", m.Key)
+	assert.Equal(t, int64(1), m.Offset)
+}
+
+func TestAssertFail(t *testing.T) {
+	b := New(t)
+	b.Publish(Message{Topic: "events", Value: []byte(`{"id":1,"status":"new"}`)})
+	b.Publish(Message{Topic: "events", Value: []byte(`not json`)})
+
+	m := tools.Mock()
+	assert.False(t, b.AssertMessages(m, "events", `[{"id":1,"status":"paid"},"not json"]`, tools.WithNoColor()))
+	assert.Equal(t, "Messages of events Not Equal (-want +got)\n/0/status:\n-\"paid\"\n+\"new\"\n", m.Results().Err)
+
+	m = tools.Mock()
+	b2 := New(m)
+	b2.Publish(Message{Topic: "orders", Value: []byte(`{"id":1,"status":"new"}`)})
+	assert.False(t, b2.AssertDecoded(m, "orders", []order{{1, "paid"}}, tools.WithNoColor()))
+	assert.Equal(t, "Messages of orders Not Equal (-want +got)\n[0].Status:\n-\"paid\"\n+\"new\"\n", m.Results().Err)
+
+	m = tools.Mock()
+	assert.False(t, b.AssertDecoded(m, "events", order{}))
+	assert.Equal(t, "AssertDecoded: want must be a slice, got queuetest.order", m.Results().Err)
+}
+
+func TestConsumerGroups(t *testing.T) {
+	b := New(t, WithPartitions(4))
+	a1 := b.Subscribe("t", "a")
+	a2 := b.Subscribe("t", "a")
+	other := b.Subscribe("t", "b")
+	for i := 0; i < 20; i++ {
+		b.Publish(Message{Topic: "t", Key: fmt.Sprint("k", i%5), Value: []byte(fmt.Sprint(i))})
+	}
+
+	drain := func(c *Consumer) []Message {
+		var ms []Message
+		for {
+			m, ok := c.TryReceive()
+			if !ok {
+				return ms
+			}
+			ms = append(ms, m)
+		}
+	}
+
+	got1, got2 := drain(a1), drain(a2)
+	assert.Equal(t, 20, len(got1)+len(got2))
+	for _, m := range got1 {
+		assert.Equal(t, 0, m.Partition%2, "a1 owns the even partitions")
+	}
+	assert.Len(t, drain(other), 20)
+
+	// a key's messages stay in order within its partition
+	last := map[string]int64{}
+	for _, m := range b.Messages("t") {
+		if off, ok := last[m.Key]; ok {
+			assert.True(t, m.Offset > off)
+		}
+		last[m.Key] = m.Offset
+	}
+
+	// after a2 leaves, a1 gets all partitions
+	a2.Close()
+	b.Publish(Message{Topic: "t", Key: "k1"})
+	b.Publish(Message{Topic: "t", Key: "k2"})
+	assert.Len(t, drain(a1), 2)
+	_, err := a2.Receive(context.Background())
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestReceiveWaits(t *testing.T) {
+	b := New(t)
+	c := b.Subscribe("t", "g")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Publish(Message{Topic: "t", Value: []byte("late")})
+	}()
+	m, err := c.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "late", string(m.Value))
+
+	go b.Close()
+	_, err = c.Receive(context.Background())
+	assert.Equal(t, ErrClosed, err)
+}
@@ -0,0 +1,364 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/prasek/loupe/tools"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update-cassettes", false, "record replay cassettes again instead of replaying them")
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+}
+
+//Mode selects whether a Recorder records or replays interactions
+type Mode int
+
+const (
+	//AutoMode replays the cassette if it exists and records it otherwise,
+	//this is the default
+	AutoMode Mode = iota
+
+	//RecordMode always sends requests and records a new cassette
+	RecordMode
+
+	//ReplayMode always replays the cassette and fails if it doesn't exist
+	ReplayMode
+)
+
+//Cassette is the file format of recorded interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+//Interaction is a recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request" yaml:"request"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+//Request is a recorded request
+type Request struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+//Response is a recorded response
+type Response struct {
+	Status int         `json:"status" yaml:"status"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+//Matcher reports whether the live request got matches the recorded
+//request want
+type Matcher func(want, got *Request) bool
+
+//MatchMethod matches requests with the same method
+func MatchMethod() Matcher {
+	return func(want, got *Request) bool {
+		return want.Method == got.Method
+	}
+}
+
+//MatchURL matches requests with the same URL, including the query
+func MatchURL() Matcher {
+	return func(want, got *Request) bool {
+		return want.URL == got.URL
+	}
+}
+
+//MatchBody matches requests with the same body, JSON bodies are compared
+//ignoring key order and whitespace
+func MatchBody() Matcher {
+	return func(want, got *Request) bool {
+		if want.Body == got.Body {
+			return true
+		}
+		a, b := []byte(want.Body), []byte(got.Body)
+		return json.Valid(a) && json.Valid(b) && tools.DiffJSON(a, b).Equal()
+	}
+}
+
+//MatchHeaders matches requests with the same values for the headers keys
+func MatchHeaders(keys ...string) Matcher {
+	return func(want, got *Request) bool {
+		for _, k := range keys {
+			if strings.Join(want.Header.Values(k), "\n") != strings.Join(got.Header.Values(k), "\n") {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+//Option configures a Recorder
+type Option func(*Recorder)
+
+//WithMode sets whether the Recorder records or replays, -update-cassettes
+//forces RecordMode
+func WithMode(m Mode) Option {
+	return func(r *Recorder) {
+		r.mode = m
+	}
+}
+
+//WithTransport sets the transport requests are sent with while recording,
+//the default is http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = rt
+	}
+}
+
+//WithMatchers sets how live requests are matched to recorded ones,
+//replacing the default MatchMethod, MatchURL and MatchBody
+func WithMatchers(m ...Matcher) Option {
+	return func(r *Recorder) {
+		r.matchers = m
+	}
+}
+
+//Recorder is an http.RoundTripper that records interactions to a cassette
+//or replays them from it
+type Recorder struct {
+	t         TestingT
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matchers  []Matcher
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+//New creates a Recorder for the cassette file at path, stored as JSON if
+//path ends in .json and as YAML otherwise. A recorded cassette, and any
+//missing directories, are written with t.Cleanup.
+func New(t TestingT, path string, opts ...Option) *Recorder {
+	t.Helper()
+	r := &Recorder{
+		t:         t,
+		path:      path,
+		transport: http.DefaultTransport,
+		matchers:  []Matcher{MatchMethod(), MatchURL(), MatchBody()},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	_, err := os.Stat(path)
+	switch {
+	case *update:
+		r.mode = RecordMode
+	case r.mode == AutoMode && os.IsNotExist(err):
+		r.mode = RecordMode
+	case r.mode == AutoMode:
+		r.mode = ReplayMode
+	}
+
+	if r.mode == RecordMode {
+		t.Cleanup(r.save)
+		return r
+	}
+	if err := r.load(); err != nil {
+		t.Errorf("load cassette %s: %v", path, err)
+	}
+	return r
+}
+
+//Client returns an http.Client that sends requests through the Recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+//Recording reports whether the Recorder records, instead of replays,
+//interactions
+func (r *Recorder) Recording() bool {
+	return r.mode == RecordMode
+}
+
+//RoundTrip records or replays req
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	live, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == RecordMode {
+		return r.record(req, live)
+	}
+	return r.replay(req, live)
+}
+
+func (r *Recorder) record(req *http.Request, live Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  live,
+		Response: Response{Status: resp.StatusCode, Header: resp.Header.Clone(), Body: string(body)},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, live Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := -1
+	for i := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		want := &r.cassette.Interactions[i].Request
+		if next < 0 || (sameRoute(want, &live) && !sameRoute(&r.cassette.Interactions[next].Request, &live)) {
+			next = i
+		}
+		if r.match(&r.cassette.Interactions[i].Request, &live) {
+			r.used[i] = true
+			return newResponse(req, r.cassette.Interactions[i].Response), nil
+		}
+	}
+
+	// diff against the first unused interaction for the same method and
+	// URL, or the next unused one, which is most likely the one the request
+	// was meant to match
+	err := fmt.Errorf("replay: no recorded interaction in %s matches %s %s", r.path, live.Method, live.URL)
+	if next >= 0 {
+		d := tools.DiffYAML(r.cassette.Interactions[next].Request, live)
+		err = fmt.Errorf("%v, closest recorded request (-want +got):\n%s", err, d)
+	}
+	r.t.Errorf("%v", err)
+	return nil, err
+}
+
+func sameRoute(want, got *Request) bool {
+	return want.Method == got.Method && want.URL == got.URL
+}
+
+func (r *Recorder) match(want, got *Request) bool {
+	for _, m := range r.matchers {
+		if !m(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+//AssertDone verifies every recorded interaction was replayed
+func (r *Recorder) AssertDone(t TestingT) bool {
+	t.Helper()
+	if r.mode == RecordMode {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []string
+	for i, used := range r.used {
+		if !used {
+			req := r.cassette.Interactions[i].Request
+			unused = append(unused, req.Method+" "+req.URL)
+		}
+	}
+	if len(unused) > 0 {
+		t.Errorf("%d recorded interactions in %s were not replayed:\n\t%s", len(unused), r.path, strings.Join(unused, "\n\t"))
+		return false
+	}
+	return true
+}
+
+func (r *Recorder) load() error {
+	bs, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	if isJSON(r.path) {
+		err = json.Unmarshal(bs, &r.cassette)
+	} else {
+		err = yaml.Unmarshal(bs, &r.cassette)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return err
+}
+
+func (r *Recorder) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var bs []byte
+	var err error
+	if isJSON(r.path) {
+		bs, err = json.MarshalIndent(r.cassette, "", "  ")
+	} else {
+		bs, err = yaml.Marshal(r.cassette)
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(r.path), os.FileMode(0777))
+	}
+	if err == nil {
+		err = ioutil.WriteFile(r.path, bs, os.FileMode(0666))
+	}
+	if err != nil {
+		r.t.Errorf("save cassette %s: %v", r.path, err)
+	}
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	live := Request{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone()}
+	if req.Body == nil {
+		return live, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return live, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	live.Body = string(body)
+	return live, nil
+}
+
+func newResponse(req *http.Request, r Response) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,100 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/prasek/loupe/testserver"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, c *http.Client, url string) string {
+	resp, err := c.Get(url)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	bs, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(bs)
+}
+
+func TestRecorder(t *testing.T) {
+	for _, name := range []string{"users.yaml", "users.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(fs.Dir(t, nil), "cassettes", name)
+
+			s := testserver.New(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+			}))
+
+			t.Run("record", func(t *testing.T) {
+				r := New(t, path)
+				assert.True(t, r.Recording())
+				assert.Equal(t, `{"path": "/users/1"}`, get(t, r.Client(), s.URL+"/users/1"))
+				resp, err := r.Client().Post(s.URL+"/users", "application/json", strings.NewReader(`{"name": "ann", "id": 2}`))
+				assert.NoError(t, err)
+				resp.Body.Close()
+			})
+			s.Close()
+
+			r := New(t, path)
+			assert.False(t, r.Recording())
+			c := r.Client()
+			resp, err := c.Post(s.URL+"/users", "application/json", strings.NewReader(`{"id": 2, "name": "ann"}`))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			resp.Body.Close()
+			assert.Equal(t, `{"path": "/users/1"}`, get(t, c, s.URL+"/users/1"))
+			assert.True(t, r.AssertDone(t))
+		})
+	}
+}
+
+func TestRecorderMismatch(t *testing.T) {
+	path := filepath.Join(fs.Dir(t, fs.Tree{"c.yaml": `interactions:
+  - request:
+      method: GET
+      url: http://example.com/users/1
+    response:
+      status: 200
+      body: ann
+  - request:
+      method: GET
+      url: http://example.com/users/2
+      header:
+        Accept: [text/plain]
+    response:
+      status: 200
+      body: bob
+`}), "c.yaml")
+
+	m := tools.Mock()
+	r := New(m, path, WithMatchers(MatchMethod(), MatchURL(), MatchHeaders("Accept")))
+	req, _ := http.NewRequest("GET", "http://example.com/users/3", nil)
+	_, err := r.RoundTrip(req)
+	assert.Error(t, err)
+
+	req, _ = http.NewRequest("GET", "http://example.com/users/2", nil)
+	req.Header.Set("Accept", "application/json")
+	_, err = r.RoundTrip(req)
+	assert.Error(t, err)
+
+	assert.False(t, r.AssertDone(m))
+	res := m.Results()
+	assert.Contains(t, res.Err, "replay: no recorded interaction in "+path+" matches GET http://example.com/users/3, closest recorded request (-want +got):\n")
+	assert.Contains(t, res.Err, "url:")
+	assert.Contains(t, res.Err, "closest recorded request (-want +got):\nheader.Accept[0]:\n-text/plain\n+application/json\n")
+	assert.Contains(t, res.Err, "2 recorded interactions in "+path+" were not replayed:\n\tGET http://example.com/users/1\n\tGET http://example.com/users/2")
+
+	m = tools.Mock()
+	New(m, filepath.Join(path, "missing.yaml"), WithMode(ReplayMode))
+	assert.Contains(t, m.Results().Err, "load cassette")
+}
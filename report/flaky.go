@@ -0,0 +1,237 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Status is how a failed test behaved when it was rerun
+type Status string
+
+const (
+	//Flaky tests passed on at least one rerun
+	Flaky Status = "flaky"
+
+	//Failing tests failed on every rerun
+	Failing Status = "failing"
+)
+
+//TestHistory is the record of a test that failed and was rerun
+type TestHistory struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Status  Status `json:"status"`
+
+	//Runs counts the failed runs and their reruns, Failures the ones that
+	//failed
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+
+	//LastFailure is when the test last failed
+	LastFailure time.Time `json:"last_failure"`
+}
+
+//History is the record of failed tests, kept as JSON between runs to tell
+//flaky tests from ones that fail consistently and quarantine them
+type History struct {
+	Tests []*TestHistory `json:"tests"`
+}
+
+//LoadHistory reads the history saved at path, a missing file is an empty
+//history
+func LoadHistory(path string) (*History, error) {
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := &History{}
+	if err := json.Unmarshal(bs, h); err != nil {
+		return nil, fmt.Errorf("parse history %s: %v", path, err)
+	}
+	return h, nil
+}
+
+//Save writes the history to path as JSON, sorted by package and test so it
+//can be checked in and reviewed
+func (h *History) Save(path string) error {
+	sort.Slice(h.Tests, func(i, j int) bool {
+		a, b := h.Tests[i], h.Tests[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Test < b.Test
+	})
+	bs, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("make dir failed: %v", err)
+		}
+	}
+	return ioutil.WriteFile(path, append(bs, '\n'), 0644)
+}
+
+//Lookup returns the history of a test, nil when it's not recorded
+func (h *History) Lookup(pkg, test string) *TestHistory {
+	for _, th := range h.Tests {
+		if th.Package == pkg && th.Test == test {
+			return th
+		}
+	}
+	return nil
+}
+
+//Record adds a run of a failed test and its reruns, runs counts both and
+//failures the ones that failed. The test is Flaky when one of them passed
+//and Failing when none did, unless it was flaky before: failing every
+//rerun once doesn't make a flaky test reliable.
+func (h *History) Record(pkg, test string, runs, failures int, now time.Time) *TestHistory {
+	th := h.Lookup(pkg, test)
+	if th == nil {
+		th = &TestHistory{Package: pkg, Test: test}
+		h.Tests = append(h.Tests, th)
+	}
+	th.Runs += runs
+	th.Failures += failures
+	th.LastFailure = now
+	if failures < runs || th.Status == Flaky {
+		th.Status = Flaky
+	} else {
+		th.Status = Failing
+	}
+	return th
+}
+
+//Quarantined returns the history of test, or of the test it's a subtest
+//of, when it's flaky and nil otherwise
+func (h *History) Quarantined(pkg, test string) *TestHistory {
+	for name := test; ; {
+		if th := h.Lookup(pkg, name); th != nil && th.Status == Flaky {
+			return th
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return nil
+		}
+		name = name[:i]
+	}
+}
+
+//Skip is a test skipped by Quarantine
+type Skip struct {
+	Test string
+
+	//File and Line are the location of the Quarantine call in the test
+	File string
+	Line int
+
+	Reason string
+}
+
+//TestingT is the subset of testing.T used by Quarantine
+type TestingT interface {
+	Helper()
+	Name() string
+	Errorf(format string, args ...interface{})
+	Skipf(format string, args ...interface{})
+}
+
+var (
+	historiesMu sync.Mutex
+	histories   = make(map[string]*History)
+)
+
+//Quarantine skips the calling test when the history at $LOUPE_QUARANTINE
+//lists it, or the test it's a subtest of, as flaky, e.g.
+//
+//	func TestUpload(t *testing.T) {
+//		report.Quarantine(t)
+//		...
+//	}
+//
+//so a known flaky test stops failing unrelated changes until it's fixed,
+//while the tests that fail consistently still do. The skip is annotated as
+//a GitHub Actions warning and written to the JUnit and TAP reports. It does
+//nothing when $LOUPE_QUARANTINE is unset, testfmt --quarantine sets it to
+//the --history file.
+func Quarantine(t TestingT) {
+	t.Helper()
+	path := os.Getenv("LOUPE_QUARANTINE")
+	if path == "" {
+		return
+	}
+	h, err := loadHistory(path)
+	if err != nil {
+		t.Errorf("quarantine: %v", err)
+		return
+	}
+	file, line, pkg := testCaller()
+	th := h.Quarantined(pkg, t.Name())
+	if th == nil {
+		return
+	}
+
+	reason := fmt.Sprintf("quarantined, %s is flaky: failed %d of %d runs", th.Test, th.Failures, th.Runs)
+	tools.Warn("Quarantined "+t.Name(), reason)
+	addSkip(Skip{Test: t.Name(), File: file, Line: line, Reason: reason})
+	t.Skipf("%s", reason)
+}
+
+// loadHistory loads path once, since Quarantine is called by every test
+func loadHistory(path string) (*History, error) {
+	historiesMu.Lock()
+	defer historiesMu.Unlock()
+	if h, ok := histories[path]; ok {
+		return h, nil
+	}
+	h, err := LoadHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	histories[path] = h
+	return h, nil
+}
+
+// testCaller finds the first frame in a _test.go file and the import path
+// of its package, which is how go test -json names it
+func testCaller() (string, int, string) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if strings.HasSuffix(f.File, "_test.go") {
+			return f.File, f.Line, funcPackage(f.Function)
+		}
+		if !more {
+			return "", 0, ""
+		}
+	}
+}
+
+// funcPackage returns the package of a function name like
+// github.com/a/b_test.TestX.func1, without the _test suffix of external
+// test packages. Dots in the last element of the path are escaped as %2e.
+func funcPackage(name string) string {
+	i := strings.LastIndex(name, "/")
+	if j := strings.Index(name[i+1:], "."); j >= 0 {
+		name = name[:i+1+j]
+	}
+	name = strings.Replace(name, "%2e", ".", -1)
+	return strings.TrimSuffix(name, "_test")
+}
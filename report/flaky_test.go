@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	tassert "github.com/stretchr/testify/assert"
+)
+
+const pkg = "github.com/prasek/loupe/report"
+
+func TestLoadHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	tassert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ci", "flaky.json")
+
+	h, err := LoadHistory(path)
+	tassert.NoError(t, err)
+	tassert.Empty(t, h.Tests)
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tassert.Equal(t, Flaky, h.Record("b", "TestUpload", 4, 2, now).Status)
+	tassert.Equal(t, Failing, h.Record("a", "TestParse/empty", 4, 4, now).Status)
+	th := h.Record("b", "TestUpload", 4, 4, now.Add(time.Hour))
+	tassert.Equal(t, &TestHistory{Package: "b", Test: "TestUpload", Status: Flaky, Runs: 8, Failures: 6, LastFailure: now.Add(time.Hour)}, th)
+
+	tassert.NoError(t, h.Save(path))
+	bs, err := ioutil.ReadFile(path)
+	tassert.NoError(t, err)
+	tassert.True(t, strings.HasPrefix(string(bs), "{\n  \"tests\": [\n    {\n      \"package\": \"a\",\n      \"test\": \"TestParse/empty\",\n      \"status\": \"failing\",\n"), string(bs))
+
+	loaded, err := LoadHistory(path)
+	tassert.NoError(t, err)
+	tassert.Equal(t, h, loaded)
+
+	tassert.NotNil(t, h.Quarantined("b", "TestUpload"))
+	tassert.NotNil(t, h.Quarantined("b", "TestUpload/large"))
+	tassert.Nil(t, h.Quarantined("a", "TestUpload"))
+	tassert.Nil(t, h.Quarantined("a", "TestParse/empty"))
+	tassert.Nil(t, h.Quarantined("b", "TestUploadAll"))
+
+	tassert.NoError(t, ioutil.WriteFile(path, []byte("{"), 0644))
+	_, err = LoadHistory(path)
+	tassert.Error(t, err)
+}
+
+func TestQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	tassert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "flaky.json")
+
+	h := &History{}
+	h.Record(pkg, "TestUpload", 3, 1, time.Now())
+	h.Record(pkg, "TestParse", 3, 3, time.Now())
+	tassert.NoError(t, h.Save(path))
+
+	defer os.Setenv("LOUPE_QUARANTINE", os.Getenv("LOUPE_QUARANTINE"))
+	defer tools.AutoAnnotations()
+	tools.EnableAnnotations()
+	c := Collect()
+	c.Suite = "pkg"
+	defer c.Stop()
+
+	quarantine := func(name string) *tools.TestResults {
+		m := tools.Mock()
+		m.SetName(name)
+		Quarantine(m)
+		return m.Results()
+	}
+
+	os.Setenv("LOUPE_QUARANTINE", "")
+	tassert.False(t, quarantine("TestUpload").Skipped)
+
+	os.Setenv("LOUPE_QUARANTINE", path)
+	res := quarantine("TestUpload/large")
+	tassert.True(t, res.Skipped)
+	tassert.Contains(t, res.Log, "quarantined, TestUpload is flaky: failed 1 of 3 runs")
+	tassert.Regexp(t, `^::warning file=.*flaky_test\.go,line=\d+,title=Quarantined TestUpload/large::quarantined`, res.Out)
+	tassert.False(t, quarantine("TestParse").Skipped)
+
+	skips := c.Skips()
+	if tassert.Len(t, skips, 1) {
+		tassert.Equal(t, "TestUpload/large", skips[0].Test)
+		tassert.Equal(t, "flaky_test.go", filepath.Base(skips[0].File))
+	}
+
+	var buf bytes.Buffer
+	tassert.NoError(t, c.WriteJUnit(&buf))
+	tassert.Contains(t, buf.String(), `<testsuite name="pkg" tests="1" failures="0" skipped="1">`)
+	tassert.Contains(t, buf.String(), `<skipped message="quarantined, TestUpload is flaky: failed 1 of 3 runs"></skipped>`)
+	buf.Reset()
+	tassert.NoError(t, c.WriteTAP(&buf))
+	tassert.Equal(t, "TAP version 13\n1..1\nok 1 - TestUpload/large # SKIP quarantined, TestUpload is flaky: failed 1 of 3 runs\n", buf.String())
+
+	os.Setenv("LOUPE_QUARANTINE", filepath.Join(dir, "invalid.json"))
+	tassert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte("["), 0644))
+	res = quarantine("TestUpload")
+	tassert.Contains(t, res.Err, "quarantine: parse history")
+}
+
+func TestFuncPackage(t *testing.T) {
+	tassert.Equal(t, "github.com/a/b", funcPackage("github.com/a/b.TestX.func1"))
+	tassert.Equal(t, "gopkg.in/b.v2", funcPackage("gopkg.in/b%2ev2.TestX"))
+	tassert.Equal(t, "github.com/a/b", funcPackage("github.com/a/b_test.TestX"))
+	tassert.Equal(t, "main", funcPackage("main.TestX"))
+}
@@ -0,0 +1,286 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Format is the format a report is written in
+type Format int
+
+const (
+	//JUnit writes JUnit XML
+	JUnit Format = iota
+
+	//TAP writes the Test Anything Protocol, version 13
+	TAP
+)
+
+//Collector collects assertion failures from assert, golden, snapshot and
+//anything else that calls tools.ReportFailure, and the tests skipped by
+//Quarantine
+type Collector struct {
+	//Suite names the test suite, the test binary name by default
+	Suite string
+
+	mu       sync.Mutex
+	failures []tools.Failure
+	skips    []Skip
+	remove   func()
+}
+
+var (
+	collectorsMu sync.Mutex
+	collectors   = make(map[*Collector]bool)
+)
+
+//Collect starts collecting failures until Stop is called
+func Collect() *Collector {
+	c := &Collector{Suite: strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")}
+	c.remove = tools.OnFailure(c.add)
+	collectorsMu.Lock()
+	collectors[c] = true
+	collectorsMu.Unlock()
+	return c
+}
+
+func (c *Collector) add(f tools.Failure) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = append(c.failures, f)
+}
+
+// addSkip passes a skip to the collecting Collectors
+func addSkip(s Skip) {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+	for c := range collectors {
+		c.mu.Lock()
+		c.skips = append(c.skips, s)
+		c.mu.Unlock()
+	}
+}
+
+//Stop stops collecting failures
+func (c *Collector) Stop() {
+	if c.remove != nil {
+		c.remove()
+		c.remove = nil
+	}
+	collectorsMu.Lock()
+	delete(collectors, c)
+	collectorsMu.Unlock()
+}
+
+//Failures returns the collected failures in the order they happened
+func (c *Collector) Failures() []tools.Failure {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]tools.Failure(nil), c.failures...)
+}
+
+//Skips returns the tests skipped by Quarantine in the order they were
+func (c *Collector) Skips() []Skip {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Skip(nil), c.skips...)
+}
+
+//Write writes the failures to w in format f
+func (c *Collector) Write(w io.Writer, f Format) error {
+	switch f {
+	case JUnit:
+		return c.WriteJUnit(w)
+	case TAP:
+		return c.WriteTAP(w)
+	}
+	return fmt.Errorf("unknown report format %d", f)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr,omitempty"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	Classname string         `xml:"classname,attr"`
+	File      string         `xml:"file,attr,omitempty"`
+	Failures  []junitFailure `xml:"failure"`
+	Skipped   *junitSkipped  `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+//WriteJUnit writes the failures as JUnit XML, one testcase per failing test
+//with a failure element per assertion holding its location and diff, and a
+//skipped testcase per quarantined test
+func (c *Collector) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{Name: c.Suite}
+	index := make(map[string]int)
+	for _, f := range c.Failures() {
+		i, ok := index[f.Test]
+		if !ok {
+			i = len(suite.Cases)
+			index[f.Test] = i
+			suite.Cases = append(suite.Cases, junitCase{Name: testName(f), Classname: c.Suite, File: f.File})
+		}
+		suite.Cases[i].Failures = append(suite.Cases[i].Failures, junitFailure{
+			Message: strings.SplitN(f.Title, "\n", 2)[0],
+			Type:    "assertion",
+			Text:    detail(f),
+		})
+		suite.Failures++
+	}
+	for _, s := range c.Skips() {
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      s.Test,
+			Classname: c.Suite,
+			File:      s.File,
+			Skipped:   &junitSkipped{Message: s.Reason},
+		})
+		suite.Skipped++
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+//WriteTAP writes the failures as TAP version 13, one test point per failure
+//with a YAML block holding its location and diff, followed by a SKIP test
+//point per quarantined test
+func (c *Collector) WriteTAP(w io.Writer) error {
+	failures, skips := c.Failures(), c.Skips()
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(failures)+len(skips))
+	for i, f := range failures {
+		fmt.Fprintf(&b, "not ok %d - %s: %s\n", i+1, testName(f), tapLine(f.Title))
+		b.WriteString("  ---\n")
+		fmt.Fprintf(&b, "  message: %q\n", f.Title)
+		if f.File != "" {
+			fmt.Fprintf(&b, "  at: %q\n", fmt.Sprintf("%s:%d", f.File, f.Line))
+		}
+		if f.Diff != "" {
+			b.WriteString("  diff: |\n")
+			for _, line := range strings.Split(strings.TrimRight(f.Diff, "\n"), "\n") {
+				b.WriteString("    " + line + "\n")
+			}
+		}
+		b.WriteString("  ...\n")
+	}
+	for i, s := range skips {
+		fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", len(failures)+i+1, s.Test, tapLine(s.Reason))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func testName(f tools.Failure) string {
+	if f.Test == "" {
+		return "unknown"
+	}
+	return f.Test
+}
+
+// tapLine keeps the description on one line, # starts a TAP directive
+func tapLine(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	return strings.Replace(s, "#", "\\#", -1)
+}
+
+func detail(f tools.Failure) string {
+	s := f.Title
+	if f.File != "" {
+		s = fmt.Sprintf("%s:%d: %s", f.File, f.Line, s)
+	}
+	if f.Diff != "" {
+		s += "\n" + f.Diff
+	}
+	return s
+}
+
+//Run is a TestMain hook that runs the tests and writes the failures to
+//path, as TAP when path ends in .tap and JUnit XML otherwise. It returns
+//the exit code for os.Exit, e.g.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(report.Run(m, "report.xml"))
+//	}
+//
+//An empty path uses $LOUPE_REPORT and runs the tests without a report when
+//that is unset too.
+func Run(m *testing.M, path string) int {
+	if path == "" {
+		path = os.Getenv("LOUPE_REPORT")
+	}
+	if path == "" {
+		return m.Run()
+	}
+
+	c := Collect()
+	code := m.Run()
+	c.Stop()
+
+	format := JUnit
+	if strings.EqualFold(filepath.Ext(path), ".tap") {
+		format = TAP
+	}
+	if err := writeFile(path, c, format); err != nil {
+		fmt.Fprintf(os.Stderr, "write report %s: %v\n", path, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+func writeFile(path string, c *Collector, format Format) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("make dir failed: %v", err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/assert"
+	"github.com/prasek/loupe/tools"
+	tassert "github.com/stretchr/testify/assert"
+)
+
+func collect(t *testing.T) *Collector {
+	c := Collect()
+	c.Suite = "pkg"
+
+	m := tools.Mock()
+	m.SetName("TestUsers")
+	assert.Equal(m, "alice\nbob\n", "alice\ncarol\n")
+	assert.Equal(m, 1, 2, "count")
+	m.SetName("TestEmpty")
+	assert.Equal(m, "", "x")
+	m.Results()
+
+	c.Stop()
+	return c
+}
+
+func TestCollect(t *testing.T) {
+	c := collect(t)
+	failures := c.Failures()
+	if tassert.Len(t, failures, 3) {
+		tassert.Equal(t, "TestUsers", failures[0].Test)
+		tassert.Equal(t, "report_test.go", filepath.Base(failures[0].File))
+		tassert.NotZero(t, failures[0].Line)
+		tassert.Equal(t, "Not Equal (string/string)", failures[0].Title)
+		tassert.Contains(t, failures[0].Diff, "-bob")
+		tassert.NotContains(t, failures[0].Diff, "\x1b[")
+		tassert.Equal(t, "Not Equal (int/int)\ncount", failures[1].Title)
+	}
+
+	m := tools.Mock()
+	assert.Equal(m, 1, 2)
+	m.Results()
+	tassert.Len(t, c.Failures(), 3, "failures after Stop")
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	tassert.NoError(t, collect(t).Write(&buf, JUnit))
+	out := buf.String()
+
+	tassert.True(t, strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`))
+	tassert.Contains(t, out, `<testsuite name="pkg" tests="2" failures="3">`)
+	tassert.Contains(t, out, `<testcase name="TestUsers" classname="pkg" file="`)
+	tassert.Contains(t, out, `<failure message="Not Equal (string/string)" type="assertion">`)
+	tassert.Contains(t, out, "<![CDATA[")
+	tassert.Contains(t, out, "-bob\n+carol")
+	tassert.Contains(t, out, `<failure message="Not Equal (int/int)" type="assertion">`)
+	tassert.Equal(t, 1, strings.Count(out, `<testcase name="TestUsers"`))
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	tassert.NoError(t, collect(t).Write(&buf, TAP))
+	out := buf.String()
+
+	tassert.True(t, strings.HasPrefix(out, "TAP version 13\n1..3\nnot ok 1 - TestUsers: Not Equal (string/string)\n  ---\n"))
+	tassert.Contains(t, out, "not ok 2 - TestUsers: Not Equal (int/int) count\n")
+	tassert.Contains(t, out, "  diff: |\n")
+	tassert.Contains(t, out, "    -bob\n    +carol\n")
+	tassert.Contains(t, out, "  at: \"")
+	tassert.True(t, strings.HasSuffix(out, "  ...\n"))
+}
+
+func TestWriteTAPEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	tassert.NoError(t, (&Collector{}).WriteTAP(&buf))
+	tassert.Equal(t, "TAP version 13\n1..0\n", buf.String())
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	tassert.NoError(t, err)
+	path := filepath.Join(dir, "out", "report.tap")
+
+	tassert.NoError(t, writeFile(path, collect(t), TAP))
+	bs, err := ioutil.ReadFile(path)
+	tassert.NoError(t, err)
+	tassert.Contains(t, string(bs), "1..3\n")
+}
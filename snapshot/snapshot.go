@@ -0,0 +1,135 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/prasek/loupe/tools"
+)
+
+var update = flag.Bool("update-snapshots", false, "rewrite snapshots with the actual values")
+
+//Dir is where snapshots are stored, relative to the package under test
+var Dir = filepath.Join("testdata", "__snapshots__")
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Name() string
+	Errorf(format string, args ...interface{})
+}
+
+// call identifies a test, by its t too when it can be a map key since a
+// rerun with -count has the same name
+type call struct {
+	t    interface{}
+	name string
+}
+
+var (
+	mu    sync.Mutex
+	calls = make(map[call]int)
+)
+
+//Match compares got against the snapshot stored for the current test and
+//fails with a diff on mismatch. Strings and []byte are stored as is, other
+//values as indented JSON. The first call in a test is stored in
+//Dir/<test name>.snap, later calls in <test name>_2.snap and so on. A missing
+//snapshot is written and the test passes, run go test with -update-snapshots
+//to rewrite existing snapshots.
+func Match(t TestingT, got interface{}) bool {
+	t.Helper()
+	path := next(t)
+
+	act, err := serialize(got)
+	if err != nil {
+		t.Errorf("serialize snapshot %s: %v", path, err)
+		return false
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if *update || os.IsNotExist(err) {
+		if err := write(path, act); err != nil {
+			t.Errorf("write snapshot %s: %v", path, err)
+			return false
+		}
+		return true
+	}
+	if err != nil {
+		t.Errorf("read snapshot %s: %v", path, err)
+		return false
+	}
+
+	exp := string(bs)
+	if exp == act {
+		return true
+	}
+
+	d := tools.Diff(exp, act).String()
+	tools.ReportFailureValues(t, "value does not match snapshot "+path, d, exp, act)
+	t.Errorf("value does not match snapshot %s, run go test with -update-snapshots to accept it\n%s", path, d)
+	return false
+}
+
+// count numbers the calls in test t, starting over when t has a Cleanup
+// method and ends
+func count(t TestingT) int {
+	k := call{name: t.Name()}
+	c, ok := t.(interface{ Cleanup(func()) })
+	if ok && reflect.TypeOf(t).Comparable() {
+		k.t = t
+	}
+	mu.Lock()
+	calls[k]++
+	n := calls[k]
+	mu.Unlock()
+	if n == 1 && ok {
+		c.Cleanup(func() {
+			mu.Lock()
+			delete(calls, k)
+			mu.Unlock()
+		})
+	}
+	return n
+}
+
+// next returns the snapshot path for the next Match call in test t
+func next(t TestingT) string {
+	name := t.Name()
+	n := count(t)
+
+	file := filepath.FromSlash(name)
+	if n > 1 {
+		file = fmt.Sprintf("%s_%d", file, n)
+	}
+	return filepath.Join(Dir, file+".snap")
+}
+
+func serialize(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	}
+
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(bs) + "\n", nil
+}
+
+func write(path, data string) error {
+	err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777))
+	if err != nil {
+		return fmt.Errorf("make dir failed: %v", err)
+	}
+	return ioutil.WriteFile(path, []byte(data), os.FileMode(0666))
+}
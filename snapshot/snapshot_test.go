@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestMatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orig := Dir
+	Dir = dir
+	defer func() { Dir = orig }()
+
+	type user struct {
+		Name string
+		Tags map[string]int
+	}
+	u := user{Name: "bob", Tags: map[string]int{"b": 2, "a": 1}}
+
+	// each call runs a test that matches the values in order
+	match := func(name string, values ...interface{}) (bool, *tools.TestResults) {
+		m := tools.Mock()
+		m.SetName(name)
+		ok := true
+		for _, v := range values {
+			ok = Match(m, v) && ok
+		}
+		return ok, m.Results()
+	}
+
+	// first run writes the snapshots, a second call in the same test gets
+	// its own snapshot
+	ok, _ := match("TestUser/sub", u, "second")
+	if !ok {
+		t.Fatal("expected new snapshot to pass")
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "TestUser", "sub.snap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "{\n  \"Name\": \"bob\",\n  \"Tags\": {\n    \"a\": 1,\n    \"b\": 2\n  }\n}\n"
+	if string(bs) != exp {
+		t.Errorf("expected %q, got %q", exp, bs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "TestUser", "sub_2.snap")); err != nil {
+		t.Error(err)
+	}
+
+	// a later run, like -count=2, compares against the stored snapshots
+	u.Name = "alice"
+	ok, res := match("TestUser/sub", u, "second")
+	if ok || !strings.Contains(res.Err, `-  "Name": "bob",`) || !strings.Contains(res.Err, `+  "Name": "alice",`) {
+		t.Errorf("expected mismatch with diff, got %v %q", ok, res.Err)
+	}
+	if strings.Contains(res.Err, "sub_2.snap") {
+		t.Errorf("expected second snapshot to match, got %q", res.Err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected calls to be reset when the test ends, got %v", calls)
+	}
+
+	// -update-snapshots rewrites them
+	*update = true
+	ok, _ = match("TestUser/sub", u)
+	*update = false
+	if !ok {
+		t.Fatal("expected update to pass")
+	}
+	if ok, res = match("TestUser/sub", u); !ok {
+		t.Errorf("expected match after update, got %q", res.Err)
+	}
+}
+
+func TestMatchCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orig := Dir
+	Dir = dir
+	defer func() { Dir = orig }()
+
+	// the calls of a test are forgotten when it ends, so a run with
+	// -count=2 uses the same snapshots
+	t.Run("sub", func(t *testing.T) {
+		Match(t, "first")
+		Match(t, "second")
+	})
+	if len(calls) != 0 {
+		t.Errorf("expected calls to be reset when the test ends, got %v", calls)
+	}
+}
@@ -0,0 +1,180 @@
+package suite
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/assert"
+)
+
+//Option configures Run
+type Option func(*options)
+
+type options struct {
+	parallel bool
+}
+
+//Parallel runs the test methods in parallel with each other, by default
+//they run one after another in name order. TeardownSuite still runs after
+//all of them.
+func Parallel() Option {
+	return func(o *options) {
+		o.parallel = true
+	}
+}
+
+//T is passed to each test method, it is the *testing.T of the subtest
+//with the assertions bound to it, e.g. t.Equal(want, got)
+type T struct {
+	*testing.T
+	*assert.Assert
+}
+
+func newT(t *testing.T) *T {
+	return &T{T: t, Assert: assert.New(t)}
+}
+
+//Suite can be embedded in a suite to get the suite level *testing.T and
+//assertions in SetupSuite and TeardownSuite
+type Suite struct {
+	t *T
+}
+
+//T returns the *testing.T of the test running the suite
+func (s *Suite) T() *testing.T {
+	return s.t.T
+}
+
+//Assert returns the assertions bound to the test running the suite
+func (s *Suite) Assert() *assert.Assert {
+	return s.t.Assert
+}
+
+func (s *Suite) setT(t *T) {
+	s.t = t
+}
+
+type suiteT interface {
+	setT(t *T)
+}
+
+//SetupSuite is implemented by suites that set up shared fixtures before
+//the first test method
+type SetupSuite interface {
+	SetupSuite()
+}
+
+//TeardownSuite is implemented by suites that clean up shared fixtures
+//after the last test method
+type TeardownSuite interface {
+	TeardownSuite()
+}
+
+//SetupTest is implemented by suites that prepare each test method, it
+//runs in the subtest before the method
+type SetupTest interface {
+	SetupTest(t *T)
+}
+
+//TeardownTest is implemented by suites that clean up after each test
+//method, it runs in the subtest after the method even if it failed
+type TeardownTest interface {
+	TeardownTest(t *T)
+}
+
+//Run runs the methods of s named Test* as subtests of t, e.g.
+//
+//	type UserSuite struct {
+//		suite.Suite
+//		db *sql.DB
+//	}
+//
+//	func (s *UserSuite) SetupSuite()   { s.db = openDB(s.T()) }
+//	func (s *UserSuite) TeardownSuite() { s.db.Close() }
+//
+//	func (s *UserSuite) TestCreate(t *suite.T) {
+//		t.Equal(want, create(s.db))
+//	}
+//
+//	func TestUsers(t *testing.T) {
+//		suite.Run(t, &UserSuite{})
+//	}
+//
+//Test methods take a *T, the hooks of the SetupSuite, TeardownSuite,
+//SetupTest and TeardownTest interfaces run around them. Fixtures set up in
+//SetupSuite are shared by all methods, so with Parallel they must be safe
+//to use concurrently. Run panics if a Test method has another signature.
+func Run(t *testing.T, s interface{}, opts ...Option) {
+	t.Helper()
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	tests := methods(s)
+	if st, ok := s.(suiteT); ok {
+		st.setT(newT(t))
+	}
+	if teardown, ok := s.(TeardownSuite); ok {
+		// cleanups run after parallel subtests finish, unlike defer
+		t.Cleanup(teardown.TeardownSuite)
+	}
+	if setup, ok := s.(SetupSuite); ok {
+		setup.SetupSuite()
+	}
+	if t.Failed() {
+		return
+	}
+
+	for _, m := range tests {
+		m := m
+		t.Run(m.name, func(t *testing.T) {
+			if o.parallel {
+				t.Parallel()
+			}
+			run(newT(t), s, m.fn)
+		})
+	}
+}
+
+// run calls fn with its hooks, TeardownTest runs even if fn stopped the
+// test with FailNow
+func run(t *T, s interface{}, fn reflect.Value) {
+	if teardown, ok := s.(TeardownTest); ok {
+		defer teardown.TeardownTest(t)
+	}
+	if setup, ok := s.(SetupTest); ok {
+		setup.SetupTest(t)
+		if t.Failed() {
+			return
+		}
+	}
+	fn.Call([]reflect.Value{reflect.ValueOf(t)})
+}
+
+type method struct {
+	name string
+	fn   reflect.Value
+}
+
+// methods returns the Test methods of s in name order
+func methods(s interface{}) []method {
+	v := reflect.ValueOf(s)
+	typ := v.Type()
+	want := reflect.TypeOf(func(*T) {})
+	var res []method
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		if !strings.HasPrefix(m.Name, "Test") {
+			continue
+		}
+		fn := v.Method(i)
+		if fn.Type() != want {
+			panic(fmt.Sprintf("suite: method %s of %T must be a func(*suite.T), got %s", m.Name, s, fn.Type()))
+		}
+		res = append(res, method{name: m.Name, fn: fn})
+	}
+	return res
+}
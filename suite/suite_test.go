@@ -0,0 +1,111 @@
+package suite
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderedSuite struct {
+	Suite
+	mu     sync.Mutex
+	events []string
+	shared string
+}
+
+func (s *orderedSuite) record(e string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+}
+
+func (s *orderedSuite) SetupSuite() {
+	s.shared = "fixture"
+	s.record("setup suite " + s.T().Name())
+}
+
+func (s *orderedSuite) TeardownSuite() {
+	s.record("teardown suite")
+}
+
+func (s *orderedSuite) SetupTest(t *T) {
+	s.record("setup " + t.Name())
+}
+
+func (s *orderedSuite) TeardownTest(t *T) {
+	s.record("teardown " + t.Name())
+}
+
+func (s *orderedSuite) TestB(t *T) {
+	t.Equal("fixture", s.shared)
+	s.record("b")
+}
+
+func (s *orderedSuite) TestA(t *T) {
+	s.record("a")
+}
+
+func (s *orderedSuite) Helper() string {
+	return "not a test"
+}
+
+func TestRunOrdered(t *testing.T) {
+	s := &orderedSuite{}
+	t.Run("suite", func(t *testing.T) {
+		Run(t, s)
+	})
+	assert.Equal(t, []string{
+		"setup suite TestRunOrdered/suite",
+		"setup TestRunOrdered/suite/TestA", "a", "teardown TestRunOrdered/suite/TestA",
+		"setup TestRunOrdered/suite/TestB", "b", "teardown TestRunOrdered/suite/TestB",
+		"teardown suite",
+	}, s.events)
+}
+
+type parallelSuite struct {
+	Suite
+	mu       sync.Mutex
+	finished int
+	done     bool
+}
+
+func (s *parallelSuite) step() {
+	time.Sleep(10 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished++
+}
+
+func (s *parallelSuite) TeardownSuite() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Assert().Equal(3, s.finished, "tests still running at teardown")
+	s.done = true
+}
+
+func (s *parallelSuite) TestA(t *T) { s.step() }
+func (s *parallelSuite) TestB(t *T) { s.step() }
+func (s *parallelSuite) TestC(t *T) { s.step() }
+
+func TestRunParallel(t *testing.T) {
+	s := &parallelSuite{}
+	t.Run("suite", func(t *testing.T) {
+		Run(t, s, Parallel())
+	})
+	assert.True(t, s.done)
+}
+
+type badSuite struct{}
+
+func (badSuite) TestX() {}
+
+func TestRunBadMethod(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.True(t, strings.Contains(r.(string), "method TestX of suite.badSuite must be a func(*suite.T), got func()"), "%v", r)
+	}()
+	Run(t, badSuite{})
+}
@@ -0,0 +1,106 @@
+package tabletest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/prasek/loupe/assert"
+)
+
+//Option configures Run
+type Option func(*options)
+
+type options struct {
+	parallel bool
+}
+
+//Parallel runs the subtests of the cases in parallel with each other
+func Parallel() Option {
+	return func(o *options) {
+		o.parallel = true
+	}
+}
+
+const maxName = 40
+
+//Run runs fn as a subtest for each case and fails it with a diff if the
+//result isn't deep equal to the Want field of the case. cases is a slice of
+//structs, or pointers to structs, with Input and Want fields and an optional
+//Name string field, e.g.
+//
+//	cases := []struct {
+//		Name  string
+//		Input string
+//		Want  int
+//	}{{"empty", "", 0}, {"word", "abc", 3}}
+//	tabletest.Run(t, cases, func(t *testing.T, in string) int {
+//		return len(in)
+//	})
+//
+//fn takes a *testing.T and a value of the Input type and returns a value
+//assignable to the Want type. Cases without a Name are named after their
+//Input. Run panics if cases or fn don't have that shape.
+func Run(t *testing.T, cases interface{}, fn interface{}, opts ...Option) {
+	t.Helper()
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cv := reflect.ValueOf(cases)
+	if cv.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("tabletest: cases must be a slice of structs, got %T", cases))
+	}
+	elem := cv.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	input, hasInput := elem.FieldByName("Input")
+	want, hasWant := elem.FieldByName("Want")
+	if elem.Kind() != reflect.Struct || !hasInput || !hasWant {
+		panic(fmt.Sprintf("tabletest: cases must be structs with Input and Want fields, got %s", cv.Type().Elem()))
+	}
+
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 2 || ft.In(0) != reflect.TypeOf(t) || !input.Type.AssignableTo(ft.In(1)) ||
+		ft.NumOut() != 1 || !ft.Out(0).AssignableTo(want.Type) {
+		panic(fmt.Sprintf("tabletest: fn must be a func(*testing.T, %s) %s, got %T", input.Type, want.Type, fn))
+	}
+
+	for i := 0; i < cv.Len(); i++ {
+		c := reflect.Indirect(cv.Index(i))
+		in := c.FieldByIndex(input.Index)
+		exp := c.FieldByIndex(want.Index).Interface()
+		t.Run(name(c, in, i), func(t *testing.T) {
+			t.Helper()
+			if o.parallel {
+				t.Parallel()
+			}
+			got := fv.Call([]reflect.Value{reflect.ValueOf(t), in})[0]
+			check(t, exp, got.Convert(want.Type).Interface())
+		})
+	}
+}
+
+func check(t assert.TestingT, want, got interface{}) bool {
+	t.Helper()
+	return assert.Equal(t, want, got)
+}
+
+// name returns the Name field of the case c, or a name derived from its
+// input
+func name(c, in reflect.Value, i int) string {
+	if f := c.FieldByName("Name"); f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+		return f.String()
+	}
+	s := fmt.Sprintf("%v", in.Interface())
+	if len(s) > maxName {
+		s = s[:maxName]
+	}
+	if s == "" {
+		s = fmt.Sprintf("case_%d", i)
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package tabletest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Input string
+		Want  int
+	}{
+		{"empty", "", 0},
+		{"word", "abc", 3},
+		{"", "auto named", 10},
+	}
+
+	var mu sync.Mutex
+	var names []string
+	// parallel subtests complete with their parent
+	t.Run("parallel", func(t *testing.T) {
+		Run(t, cases, func(t *testing.T, in string) int {
+			mu.Lock()
+			names = append(names, t.Name())
+			mu.Unlock()
+			return len(in)
+		}, Parallel())
+	})
+
+	type point struct{ X, Y int }
+	Run(t, []*struct {
+		Input []int
+		Want  point
+	}{{Input: []int{1, 2}, Want: point{1, 2}}}, func(t *testing.T, in []int) point {
+		return point{in[0], in[1]}
+	})
+
+	assert.ElementsMatch(t, []string{"TestRun/parallel/empty", "TestRun/parallel/word", "TestRun/parallel/auto_named"}, names)
+}
+
+func TestRunInvalid(t *testing.T) {
+	cases := []struct{ Input, Want string }{{"a", "a"}}
+	fn := func(t *testing.T, in string) string { return in }
+	assert.Panics(t, func() { Run(t, cases[0], fn) })
+	assert.Panics(t, func() { Run(t, []struct{ Input string }{}, fn) })
+	assert.PanicsWithValue(t, "tabletest: fn must be a func(*testing.T, string) string, got func(string) string", func() {
+		Run(t, cases, func(in string) string { return in })
+	})
+}
+
+func TestCheck(t *testing.T) {
+	m := tools.Mock()
+	assert.True(t, check(m, 1, 1))
+	assert.False(t, check(m, []string{"a", "b"}, []string{"a", "c"}))
+	res := m.Results()
+	assert.True(t, strings.HasPrefix(res.Err, "Not Equal ([]string/[]string)\n"))
+}
@@ -0,0 +1,68 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prasek/loupe/tools"
+)
+
+// logPrefix matches the file:line: prefix of t.Log and t.Error output
+var logPrefix = regexp.MustCompile(`^\s*[^\s:]+\.go:\d+: `)
+
+// colorDiffs colors the diffs in the output of a failed test: unified diffs
+// and the blocks after a (-want +got) header, like this package's and
+// go-cmp's failure messages
+func colorDiffs(out string, p tools.Palette) string {
+	lines := strings.SplitAfter(out, "\n")
+	indent := -1
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\n")
+		if indent >= 0 {
+			if colored, ok := colorDiffLine(text, indent, p); ok {
+				lines[i] = colored + line[len(text):]
+				continue
+			}
+			indent = -1
+		}
+
+		switch {
+		case strings.Contains(text, "(-want +got)") || strings.Contains(text, "(-expected +actual)"):
+			// the diff follows, indented like continuation lines of t.Error
+			// when the header has a file:line: prefix
+			indent = leadingSpace(text)
+			if logPrefix.MatchString(text) {
+				indent += 4
+			}
+		case strings.HasPrefix(strings.TrimSpace(text), "--- ") && i+1 < len(lines) &&
+			strings.HasPrefix(strings.TrimSpace(lines[i+1]), "+++ "):
+			indent = leadingSpace(text)
+			lines[i] = text[:indent] + p.Delete(text[indent:]) + line[len(text):]
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// colorDiffLine colors a line of a diff whose lines start after indent, it
+// returns false for a line that ends the diff
+func colorDiffLine(text string, indent int, p tools.Palette) (string, bool) {
+	if len(text) <= indent || strings.TrimSpace(text[:indent]) != "" {
+		return "", false
+	}
+	body := text[indent:]
+	switch body[0] {
+	case '-':
+		return text[:indent] + p.Delete(body), true
+	case '+':
+		return text[:indent] + p.Insert(body), true
+	case '@':
+		return text[:indent] + p.Dim(body), true
+	case ' ', '\t':
+		return text, true
+	}
+	return "", false
+}
+
+func leadingSpace(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " \t"))
+}
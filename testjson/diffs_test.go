@@ -0,0 +1,39 @@
+package testjson
+
+import (
+	"testing"
+
+	"github.com/prasek/loupe/tools"
+)
+
+func TestColorDiffs(t *testing.T) {
+	p := tools.NewPalette(nil, tools.WithColor(tools.ColorAlways))
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{
+			"want got header",
+			"    x_test.go:3: Not Equal (-want +got)\n        @@ -1 +1 @@\n         a\n        -b\n        +c\n    x_test.go:4: done\n",
+			"    x_test.go:3: Not Equal (-want +got)\n        " + p.Dim("@@ -1 +1 @@") + "\n         a\n        " + p.Delete("-b") + "\n        " + p.Insert("+c") + "\n    x_test.go:4: done\n",
+		},
+		{
+			"go-cmp",
+			"    x_test.go:3: mismatch (-want +got):\n          T{\n        - \tA: 1,\n        + \tA: 2,\n          }\n",
+			"    x_test.go:3: mismatch (-want +got):\n          T{\n        " + p.Delete("- \tA: 1,") + "\n        " + p.Insert("+ \tA: 2,") + "\n          }\n",
+		},
+		{
+			"unified diff",
+			"--- a\n+++ b\n-x\n+y\nafter\n-not a diff\n",
+			p.Delete("--- a") + "\n" + p.Insert("+++ b") + "\n" + p.Delete("-x") + "\n" + p.Insert("+y") + "\nafter\n-not a diff\n",
+		},
+		{
+			"no diff",
+			"    x_test.go:3: -1 is negative\n",
+			"    x_test.go:3: -1 is negative\n",
+		},
+	} {
+		if got := colorDiffs(tc.in, p); got != tc.want {
+			t.Errorf("%s: colorDiffs Not Equal\nwant %q\n got %q", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package testjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//Event is an event of go test -json, see go doc test2json
+type Event struct {
+	Time    time.Time `json:",omitempty"`
+	Action  string
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+
+	//ImportPath is set on build-output and build-fail events
+	ImportPath string `json:",omitempty"`
+}
+
+//Option configures a Formatter
+type Option func(*Formatter)
+
+//WithVerbose also prints each test as it finishes, not only the packages
+func WithVerbose() Option {
+	return func(f *Formatter) {
+		f.verbose = true
+	}
+}
+
+//WithColor sets when the output is colored, by default when it's written
+//to a terminal like diffs
+func WithColor(m tools.ColorMode) Option {
+	return func(f *Formatter) {
+		f.palette = tools.NewPalette(f.w, tools.WithColor(m))
+	}
+}
+
+//TestResult is a failed test and its output
+type TestResult struct {
+	Package string
+	Test    string
+	Elapsed time.Duration
+
+	//Output is what the test printed, without the === RUN and --- FAIL lines
+	Output string
+}
+
+//Summary counts the results of a run
+type Summary struct {
+	Tests   int
+	Passed  int
+	Failed  int
+	Skipped int
+
+	Packages       int
+	FailedPackages int
+
+	//Elapsed is the time between the first and last event
+	Elapsed time.Duration
+
+	//Failures are the failed tests, packages that failed without a failed
+	//test, like a panic in TestMain or a build failure, have an empty Test
+	Failures []TestResult
+}
+
+//OK reports whether no test or package failed
+func (s Summary) OK() bool {
+	return s.Failed == 0 && s.FailedPackages == 0
+}
+
+type testKey struct {
+	pkg, test string
+}
+
+type pkgState struct {
+	output   strings.Builder
+	coverage string
+	failed   bool
+}
+
+//Formatter renders go test -json events as they arrive, with colored
+//results, the elapsed time of each package, the failures grouped at the end
+//with their diffs colored and a summary
+type Formatter struct {
+	w       io.Writer
+	palette tools.Palette
+	verbose bool
+
+	summary     Summary
+	first, last time.Time
+	outputs     map[testKey]*strings.Builder
+	pkgs        map[string]*pkgState
+	failed      []testKey
+	elapsed     map[testKey]time.Duration
+}
+
+//New returns a Formatter writing to w
+func New(w io.Writer, opts ...Option) *Formatter {
+	f := &Formatter{
+		w:       w,
+		palette: tools.NewPalette(w),
+		outputs: map[testKey]*strings.Builder{},
+		pkgs:    map[string]*pkgState{},
+		elapsed: map[testKey]time.Duration{},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+//Format reads go test -json output from r and writes it to w until r ends,
+//returning the summary. Lines that aren't JSON, like build errors of older
+//Go versions, are written as they are.
+func Format(r io.Reader, w io.Writer, opts ...Option) (Summary, error) {
+	f := New(w, opts...)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		line := s.Bytes()
+		var e Event
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			fmt.Fprintf(w, "%s\n", line)
+			continue
+		}
+		f.Event(e)
+	}
+	if err := s.Err(); err != nil {
+		return f.summary, err
+	}
+	return f.Close(), nil
+}
+
+func (f *Formatter) pkg(name string) *pkgState {
+	p := f.pkgs[name]
+	if p == nil {
+		p = &pkgState{}
+		f.pkgs[name] = p
+	}
+	return p
+}
+
+//Event handles one event
+func (f *Formatter) Event(e Event) {
+	if !e.Time.IsZero() {
+		if f.first.IsZero() {
+			f.first = e.Time
+		}
+		f.last = e.Time
+	}
+	if e.Action == "build-output" || e.Action == "build-fail" {
+		f.buildEvent(e)
+		return
+	}
+	if e.Test == "" {
+		f.packageEvent(e)
+		return
+	}
+
+	k := testKey{e.Package, e.Test}
+	elapsed := time.Duration(e.Elapsed * float64(time.Second))
+	switch e.Action {
+	case "output":
+		if !isFraming(e.Output) {
+			out := f.outputs[k]
+			if out == nil {
+				out = &strings.Builder{}
+				f.outputs[k] = out
+			}
+			out.WriteString(e.Output)
+		}
+	case "pass":
+		f.summary.Tests++
+		f.summary.Passed++
+		f.testLine(f.palette.Insert("--- PASS"), e, elapsed)
+	case "skip":
+		f.summary.Tests++
+		f.summary.Skipped++
+		f.testLine(f.palette.Dim("--- SKIP"), e, elapsed)
+	case "fail":
+		f.summary.Tests++
+		f.summary.Failed++
+		f.failed = append(f.failed, k)
+		f.elapsed[k] = elapsed
+		f.testLine(f.palette.Delete("--- FAIL"), e, elapsed)
+	}
+}
+
+func (f *Formatter) testLine(status string, e Event, elapsed time.Duration) {
+	if f.verbose {
+		fmt.Fprintf(f.w, "%s: %s (%s)\n", status, e.Test, seconds(elapsed))
+	}
+}
+
+// buildEvent records build output by package, the import path of a test
+// binary is like "example.com/pkg [example.com/pkg.test]"
+func (f *Formatter) buildEvent(e Event) {
+	name := e.ImportPath
+	if i := strings.Index(name, " "); i > 0 {
+		name = name[:i]
+	}
+	p := f.pkg(name)
+	if e.Action == "build-output" {
+		p.output.WriteString(e.Output)
+		return
+	}
+	p.failed = true
+}
+
+func (f *Formatter) packageEvent(e Event) {
+	p := f.pkg(e.Package)
+	elapsed := seconds(time.Duration(e.Elapsed * float64(time.Second)))
+	switch e.Action {
+	case "output":
+		out := strings.TrimSpace(e.Output)
+		switch {
+		case strings.HasPrefix(out, "coverage: "):
+			p.coverage = out
+		case out == "PASS" || out == "FAIL" || strings.HasPrefix(out, "ok  \t") || strings.HasPrefix(out, "FAIL\t") ||
+			strings.HasPrefix(out, "?   \t") || out == "testing: warning: no tests to run":
+		default:
+			p.output.WriteString(e.Output)
+		}
+	case "pass":
+		f.summary.Packages++
+		f.pkgLine(f.palette.Insert("ok  "), e.Package, elapsed, p.coverage)
+	case "skip":
+		f.summary.Packages++
+		f.pkgLine(f.palette.Dim("?   "), e.Package, "[no test files]", "")
+	case "fail":
+		f.summary.Packages++
+		p.failed = true
+		f.pkgLine(f.palette.Delete("FAIL"), e.Package, elapsed, p.coverage)
+	}
+}
+
+func (f *Formatter) pkgLine(status, pkg, elapsed, coverage string) {
+	if coverage != "" {
+		fmt.Fprintf(f.w, "%s %s\t%s\t%s\n", status, pkg, elapsed, coverage)
+		return
+	}
+	fmt.Fprintf(f.w, "%s %s\t%s\n", status, pkg, elapsed)
+}
+
+// isFraming reports whether a line of test output is one of the lines go
+// test adds around the output of each test
+func isFraming(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS", "--- FAIL", "--- SKIP"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+//Close writes the failures and the summary and returns the summary
+func (f *Formatter) Close() Summary {
+	f.summary.Failures = f.failures()
+	f.summary.FailedPackages = 0
+	for _, p := range f.pkgs {
+		if p.failed {
+			f.summary.FailedPackages++
+		}
+	}
+	if !f.first.IsZero() {
+		f.summary.Elapsed = f.last.Sub(f.first)
+	}
+	if len(f.summary.Failures) > 0 {
+		fmt.Fprintf(f.w, "\n%s\n", f.palette.Delete("=== Failed"))
+	}
+	for i, r := range f.summary.Failures {
+		if i > 0 {
+			fmt.Fprintln(f.w)
+		}
+		name := r.Package
+		if r.Test != "" {
+			name += " " + r.Test
+			fmt.Fprintf(f.w, "%s (%s)\n", f.palette.Delete("=== FAIL: "+name), seconds(r.Elapsed))
+		} else {
+			fmt.Fprintf(f.w, "%s\n", f.palette.Delete("=== FAIL: "+name))
+		}
+		out := colorDiffs(r.Output, f.palette)
+		if out != "" && !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		io.WriteString(f.w, out)
+	}
+
+	s := f.summary
+	line := fmt.Sprintf("DONE %s", plural(s.Tests, "test"))
+	if s.Skipped > 0 {
+		line += fmt.Sprintf(", %d skipped", s.Skipped)
+	}
+	if s.Failed > 0 {
+		line += ", " + plural(s.Failed, "failure")
+	}
+	errs := 0
+	for _, r := range s.Failures {
+		if r.Test == "" {
+			errs++
+		}
+	}
+	if errs > 0 {
+		line += ", " + plural(errs, "package error")
+	}
+	line += fmt.Sprintf(" in %s", seconds(s.Elapsed))
+	if s.OK() {
+		line = f.palette.Insert(line)
+	} else {
+		line = f.palette.Delete(line)
+	}
+	fmt.Fprintf(f.w, "\n%s\n", line)
+	return f.summary
+}
+
+// failures returns the failed tests in the order they failed, leaving out
+// parents without output of their own when a subtest failed, followed by
+// failed packages that have no failed test
+func (f *Formatter) failures() []TestResult {
+	var results []TestResult
+	withFailures := map[string]bool{}
+	for i, k := range f.failed {
+		withFailures[k.pkg] = true
+		out := ""
+		if b := f.outputs[k]; b != nil {
+			out = b.String()
+		}
+		if out == "" && f.hasFailedSubtest(i) {
+			continue
+		}
+		results = append(results, TestResult{Package: k.pkg, Test: k.test, Elapsed: f.elapsed[k], Output: out})
+	}
+	for _, name := range f.failedPackages() {
+		if !withFailures[name] {
+			results = append(results, TestResult{Package: name, Output: f.pkgs[name].output.String()})
+		}
+	}
+	return results
+}
+
+func (f *Formatter) hasFailedSubtest(i int) bool {
+	k := f.failed[i]
+	for _, other := range f.failed {
+		if other.pkg == k.pkg && strings.HasPrefix(other.test, k.test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// failedPackages returns the names of the failed packages, sorted
+func (f *Formatter) failedPackages() []string {
+	var names []string
+	for name, p := range f.pkgs {
+		if p.failed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
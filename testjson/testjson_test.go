@@ -0,0 +1,102 @@
+package testjson
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func format(t *testing.T, opts ...Option) (Summary, string) {
+	f, err := os.Open("testdata/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	s, err := Format(f, &buf, append([]Option{WithColor(tools.ColorNever)}, opts...)...)
+	assert.NoError(t, err)
+	return s, buf.String()
+}
+
+func TestFormat(t *testing.T) {
+	s, out := format(t)
+	assert.Equal(t, "FAIL example.com/tj/broken\t0.000s\n"+
+		"FAIL example.com/tj/calc\t0.003s\tcoverage: [no statements]\n"+
+		"?    example.com/tj/empty\t[no test files]\n"+
+		"ok   example.com/tj/util\t0.003s\tcoverage: [no statements]\n"+
+		"\n"+
+		"=== Failed\n"+
+		"=== FAIL: example.com/tj/calc TestDiv/by_zero (0.000s)\n"+
+		"    calc_test.go:11: Div Not Equal (-want +got)\n"+
+		"        --- want\n"+
+		"        +++ got\n"+
+		"        @@ -1 +1 @@\n"+
+		"        -0\n"+
+		"        +1\n"+
+		"\n"+
+		"=== FAIL: example.com/tj/broken\n"+
+		"# example.com/tj/broken [example.com/tj/broken.test]\n"+
+		"broken/broken.go:3:163: undefined: undefined\n"+
+		"\n"+
+		"DONE 6 tests, 1 skipped, 2 failures, 1 package error in 0.424s\n", out)
+
+	assert.False(t, s.OK())
+	assert.Equal(t, 6, s.Tests)
+	assert.Equal(t, 3, s.Passed)
+	assert.Equal(t, 2, s.Failed)
+	assert.Equal(t, 1, s.Skipped)
+	assert.Equal(t, 4, s.Packages)
+	assert.Equal(t, 2, s.FailedPackages)
+	assert.Len(t, s.Failures, 2)
+	assert.Equal(t, "TestDiv/by_zero", s.Failures[0].Test)
+	assert.Equal(t, "", s.Failures[1].Test)
+}
+
+func TestFormatVerbose(t *testing.T) {
+	_, out := format(t, WithVerbose())
+	assert.True(t, strings.HasPrefix(out, "FAIL example.com/tj/broken\t0.000s\n"+
+		"--- PASS: TestAdd (0.000s)\n"+
+		"--- FAIL: TestDiv/by_zero (0.000s)\n"+
+		"--- PASS: TestDiv/ok (0.000s)\n"+
+		"--- FAIL: TestDiv (0.000s)\n"+
+		"--- SKIP: TestSkip (0.000s)\n"+
+		"FAIL example.com/tj/calc\t0.003s\tcoverage: [no statements]\n"), out)
+}
+
+func TestFormatColor(t *testing.T) {
+	p := tools.NewPalette(nil, tools.WithColor(tools.ColorAlways))
+	_, out := format(t, WithColor(tools.ColorAlways))
+	assert.Contains(t, out, p.Delete("FAIL")+" example.com/tj/calc\t0.003s")
+	assert.Contains(t, out, p.Insert("ok  ")+" example.com/tj/util")
+	assert.Contains(t, out, "        "+p.Delete("-0")+"\n        "+p.Insert("+1")+"\n")
+	assert.Contains(t, out, p.Delete("DONE 6 tests, 1 skipped, 2 failures, 1 package error in 0.424s")+"\n")
+}
+
+func TestFormatPassing(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	in := `{"Time":"2020-01-01T00:00:00Z","Action":"run","Package":"p","Test":"TestA"}
+{"Time":"2020-01-01T00:00:00Z","Action":"output","Package":"p","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2020-01-01T00:00:01Z","Action":"pass","Package":"p","Test":"TestA","Elapsed":1}
+not json
+{"Time":"2020-01-01T00:00:01.5Z","Action":"output","Package":"p","Output":"ok  \tp\t1.5s\n"}
+{"Time":"2020-01-01T00:00:01.5Z","Action":"pass","Package":"p","Elapsed":1.5}
+`
+	var buf bytes.Buffer
+	s, err := Format(strings.NewReader(in), &buf, WithColor(tools.ColorNever))
+	assert.NoError(t, err)
+	assert.True(t, s.OK())
+	assert.Equal(t, 1500*time.Millisecond, s.Elapsed)
+	assert.Equal(t, "not json\nok   p\t1.500s\n\nDONE 1 test in 1.500s\n", buf.String())
+
+	f := New(&buf)
+	f.Event(Event{Time: start, Action: "output", Package: "p", Test: "TestB", Output: "=== RUN   TestB\n"})
+	f.Event(Event{Time: start, Action: "output", Package: "p", Test: "TestB", Output: "panic: boom\n"})
+	f.Event(Event{Time: start, Action: "fail", Package: "p", Test: "TestB"})
+	s = f.Close()
+	assert.Equal(t, []TestResult{{Package: "p", Test: "TestB", Output: "panic: boom\n"}}, s.Failures)
+}
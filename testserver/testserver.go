@@ -0,0 +1,156 @@
+package testserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/prasek/loupe/tools"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+}
+
+//Request is a request received by a Server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+//Server is an httptest.Server that records every request it receives
+type Server struct {
+	*httptest.Server
+	handler http.Handler
+	mu      sync.Mutex
+	reqs    []Request
+}
+
+//New starts a Server that records requests and then serves them with
+//handler, a nil handler replies 200 with an empty body. The server is closed
+//with t.Cleanup.
+func New(t TestingT, handler http.Handler) *Server {
+	t.Helper()
+	s := &Server{handler: handler}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.reqs = append(s.reqs, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	s.mu.Unlock()
+
+	if s.handler != nil {
+		s.handler.ServeHTTP(w, r)
+	}
+}
+
+//Requests returns the requests received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.reqs...)
+}
+
+//Calls returns the requests received for method and path, in order
+func (s *Server) Calls(method, path string) []Request {
+	var calls []Request
+	for _, r := range s.Requests() {
+		if r.Method == method && r.Path == path {
+			calls = append(calls, r)
+		}
+	}
+	return calls
+}
+
+//Reset forgets the requests received so far
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.reqs = nil
+	s.mu.Unlock()
+}
+
+//AssertCalled verifies method and path were requested n times
+func (s *Server) AssertCalled(t TestingT, method, path string, n int) bool {
+	t.Helper()
+	if got := len(s.Calls(method, path)); got != n {
+		t.Errorf("%s %s: expected %d calls, got %d\n%s", method, path, n, got, s.summary())
+		return false
+	}
+	return true
+}
+
+//AssertBody verifies the body of the last request for method and path is
+//want. Bodies that are both JSON are compared with DiffJSON, ignoring key
+//order and whitespace, and other bodies with Diff.
+func (s *Server) AssertBody(t TestingT, method, path string, want []byte, opts ...tools.Option) bool {
+	t.Helper()
+	calls := s.Calls(method, path)
+	if len(calls) == 0 {
+		t.Errorf("%s %s: not called\n%s", method, path, s.summary())
+		return false
+	}
+	got := calls[len(calls)-1].Body
+
+	var d tools.Differ
+	if json.Valid(want) && json.Valid(got) {
+		d = tools.DiffJSON(want, got, opts...)
+	} else {
+		d = tools.Diff(string(want), string(got), opts...)
+	}
+	if !d.Equal() {
+		t.Errorf("%s %s: Body Not Equal\n%s", method, path, d)
+		return false
+	}
+	return true
+}
+
+//AssertBodyFile is like AssertBody but reads want from the fixture file
+//path
+func (s *Server) AssertBodyFile(t TestingT, method, path, fixture string, opts ...tools.Option) bool {
+	t.Helper()
+	want, err := ioutil.ReadFile(fixture)
+	if err != nil {
+		t.Errorf("read fixture: %v", err)
+		return false
+	}
+	return s.AssertBody(t, method, path, want, opts...)
+}
+
+// summary lists the requests received, for failure messages
+func (s *Server) summary() string {
+	reqs := s.Requests()
+	if len(reqs) == 0 {
+		return "no requests received"
+	}
+	var buf bytes.Buffer
+	buf.WriteString("requests received:")
+	for _, r := range reqs {
+		buf.WriteString("\n\t" + r.Method + " " + r.Path)
+		if r.Query != "" {
+			buf.WriteString("?" + r.Query)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,64 @@
+package testserver
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasek/loupe/fs"
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	s := New(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for _, body := range []string{`{"name": "ann"}`, `{"name": "bob", "admin": true}`} {
+		resp, err := http.Post(s.URL+"/v1/users?dry=1", "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+	resp, err := http.Get(s.URL + "/v1/users")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Len(t, s.Requests(), 3)
+	calls := s.Calls("POST", "/v1/users")
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "dry=1", calls[0].Query)
+	assert.Equal(t, "application/json", calls[0].Header.Get("Content-Type"))
+	assert.Equal(t, `{"name": "ann"}`, string(calls[0].Body))
+
+	assert.True(t, s.AssertCalled(t, "POST", "/v1/users", 2))
+	assert.True(t, s.AssertCalled(t, "DELETE", "/v1/users", 0))
+	assert.True(t, s.AssertBody(t, "POST", "/v1/users", []byte(`{"admin":true,"name":"bob"}`)))
+
+	dir := fs.Dir(t, fs.Tree{"user.json": `{"name": "bob", "admin": true}`})
+	assert.True(t, s.AssertBodyFile(t, "POST", "/v1/users", filepath.Join(dir, "user.json")))
+
+	s.Reset()
+	assert.Empty(t, s.Requests())
+}
+
+func TestServerFail(t *testing.T) {
+	s := New(t, nil)
+	resp, err := http.Post(s.URL+"/v1/users", "text/plain", strings.NewReader("name: ann\n"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	m := tools.Mock()
+	assert.False(t, s.AssertCalled(m, "POST", "/v1/users", 2))
+	assert.False(t, s.AssertBody(m, "GET", "/v1/users", nil))
+	assert.False(t, s.AssertBody(m, "POST", "/v1/users", []byte("name: bob\n"), tools.WithNoColor()))
+	assert.False(t, s.AssertBodyFile(m, "POST", "/v1/users", "missing.json"))
+	res := m.Results()
+
+	assert.Contains(t, res.Err, "POST /v1/users: expected 2 calls, got 1\nrequests received:\n\tPOST /v1/users")
+	assert.Contains(t, res.Err, "GET /v1/users: not called")
+	assert.Contains(t, res.Err, "POST /v1/users: Body Not Equal\n")
+	assert.Contains(t, res.Err, "read fixture: open missing.json")
+}
@@ -0,0 +1,216 @@
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+	TempDir() string
+}
+
+//Validity is how long generated certificates are valid, starting an hour
+//ago so a clock that's slightly behind still accepts them
+var Validity = 24 * time.Hour
+
+//CA is an ephemeral certificate authority that issues certificates for a
+//test. Its certificate and key are written as ca.pem and ca-key.pem to Dir.
+type CA struct {
+	Cert *Cert
+
+	//Dir is the temp directory the PEM files of the CA and the certificates
+	//it issues are written to
+	Dir string
+
+	pool *x509.CertPool
+}
+
+//Cert is a generated certificate and its private key
+type Cert struct {
+	//Certificate is the certificate chain and key to use in a tls.Config
+	Certificate tls.Certificate
+
+	//Leaf is the parsed certificate
+	Leaf *x509.Certificate
+
+	//CertPEM and KeyPEM are the PEM encoded certificate and key
+	CertPEM []byte
+	KeyPEM  []byte
+
+	//CertFile and KeyFile are the paths of the PEM files
+	CertFile string
+	KeyFile  string
+
+	key *ecdsa.PrivateKey
+}
+
+//NewCA generates a CA in a temp directory of the test, stopping the test if
+//it can't
+func NewCA(t TestingT) *CA {
+	t.Helper()
+	ca := &CA{Dir: t.TempDir(), pool: x509.NewCertPool()}
+	tmpl := template("loupe test CA")
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	c, err := ca.issue("ca", tmpl, nil)
+	if err != nil {
+		t.Errorf("tlstest: generate CA: %v", err)
+		t.FailNow()
+		return nil
+	}
+	ca.Cert = c
+	ca.pool.AddCert(c.Leaf)
+	return ca
+}
+
+//Pool returns a pool with the CA certificate
+func (ca *CA) Pool() *x509.CertPool {
+	return ca.pool
+}
+
+//Leaf issues a server certificate for hosts, DNS names or IP addresses, with
+//the first host as the common name. It's written to Dir as <host>.pem and
+//<host>-key.pem.
+func (ca *CA) Leaf(t TestingT, hosts ...string) *Cert {
+	t.Helper()
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+	tmpl := template(hosts[0])
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	c, err := ca.issue(hosts[0], tmpl, ca.Cert)
+	if err != nil {
+		t.Errorf("tlstest: issue certificate for %s: %v", strings.Join(hosts, ", "), err)
+		t.FailNow()
+		return nil
+	}
+	return c
+}
+
+//ClientCert issues a client certificate with name as the common name, for
+//mutual TLS. It's written to Dir as <name>.pem and <name>-key.pem.
+func (ca *CA) ClientCert(t TestingT, name string) *Cert {
+	t.Helper()
+	tmpl := template(name)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	c, err := ca.issue(name, tmpl, ca.Cert)
+	if err != nil {
+		t.Errorf("tlstest: issue client certificate for %s: %v", name, err)
+		t.FailNow()
+		return nil
+	}
+	return c
+}
+
+//ServerConfig returns a tls.Config for a server with a certificate for
+//hosts, see Leaf. Client certificates issued by the CA are verified when a
+//client sends one; set ClientAuth to tls.RequireAndVerifyClientCert to
+//require them.
+func (ca *CA) ServerConfig(t TestingT, hosts ...string) *tls.Config {
+	t.Helper()
+	c := ca.Leaf(t, hosts...)
+	if c == nil {
+		return nil
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{c.Certificate},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+}
+
+//ClientConfig returns a tls.Config for a client that trusts the CA, with
+//certs as its client certificates
+func (ca *CA) ClientConfig(certs ...*Cert) *tls.Config {
+	cfg := &tls.Config{RootCAs: ca.pool}
+	for _, c := range certs {
+		cfg.Certificates = append(cfg.Certificates, c.Certificate)
+	}
+	return cfg
+}
+
+// issue generates a key and a certificate signed by parent, or self-signed
+// when parent is nil, and writes both to the CA directory
+func (ca *CA) issue(name string, tmpl *x509.Certificate, parent *Cert) (*Cert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, signKey := tmpl, key
+	if parent != nil {
+		signer, signKey = parent.Leaf, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signKey)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cert{
+		Leaf:     leaf,
+		CertPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		CertFile: filepath.Join(ca.Dir, fileName(name)+".pem"),
+		KeyFile:  filepath.Join(ca.Dir, fileName(name)+"-key.pem"),
+		key:      key,
+	}
+	c.Certificate = tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	if parent != nil {
+		c.Certificate.Certificate = append(c.Certificate.Certificate, parent.Leaf.Raw)
+	}
+	if err := ioutil.WriteFile(c.CertFile, c.CertPEM, 0644); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(c.KeyFile, c.KeyPEM, 0600); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func template(cn string) *x509.Certificate {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"loupe tlstest"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(Validity),
+	}
+}
+
+// fileName makes a host usable as a file name, e.g. ::1 and *.example.com
+func fileName(name string) string {
+	return strings.NewReplacer(":", "_", "*", "_", "/", "_", "\\", "_").Replace(name)
+}
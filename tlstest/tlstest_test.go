@@ -0,0 +1,101 @@
+package tlstest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(t *testing.T, cfg *tls.Config) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+			return
+		}
+		w.Write([]byte("anonymous"))
+	}))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.TLS = cfg
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestServer(t *testing.T) {
+	ca := NewCA(t)
+	srv := newServer(t, ca.ServerConfig(t))
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: ca.ClientConfig()}}
+	body, err := get(client, srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "anonymous", body)
+
+	_, err = get(&http.Client{}, srv.URL)
+	assert.Error(t, err, "untrusted CA")
+}
+
+func TestWrongHost(t *testing.T) {
+	ca := NewCA(t)
+	srv := newServer(t, ca.ServerConfig(t, "example.com"))
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: ca.ClientConfig()}}
+	_, err := get(client, srv.URL)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "doesn't contain any IP SANs")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := NewCA(t)
+	cfg := ca.ServerConfig(t, "127.0.0.1")
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	srv := newServer(t, cfg)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: ca.ClientConfig(ca.ClientCert(t, "alice"))}}
+	body, err := get(client, srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", body)
+
+	other := NewCA(t)
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: ca.ClientConfig(other.ClientCert(t, "mallory"))}}
+	_, err = get(client, srv.URL)
+	assert.Error(t, err)
+}
+
+func TestFiles(t *testing.T) {
+	ca := NewCA(t)
+	leaf := ca.Leaf(t, "localhost", "::1")
+	assert.Equal(t, []string{"localhost"}, leaf.Leaf.DNSNames)
+	assert.Len(t, leaf.Leaf.IPAddresses, 1)
+
+	pair, err := tls.LoadX509KeyPair(leaf.CertFile, leaf.KeyFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, leaf.Leaf.Raw, pair.Certificate[0])
+	}
+	caPEM, err := ioutil.ReadFile(ca.Cert.CertFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ca.Cert.CertPEM, caPEM)
+		pool := x509.NewCertPool()
+		assert.True(t, pool.AppendCertsFromPEM(caPEM))
+		_, err = leaf.Leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: pool})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, ca.Dir+"/localhost.pem", leaf.CertFile)
+	assert.Equal(t, "__1", fileName("::1"))
+}
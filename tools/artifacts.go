@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	artifactsMu sync.Mutex
+	artifactsN  = make(map[string]int)
+
+	regExArtifactName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+//ArtifactsDir returns the directory failure artifacts are written to,
+//$LOUPE_ARTIFACTS, empty when they aren't written
+func ArtifactsDir() string {
+	return os.Getenv("LOUPE_ARTIFACTS")
+}
+
+// writeArtifacts writes the sides and diff of f below ArtifactsDir, in a
+// directory per test named after the failing file and line, e.g.
+// TestFoo/case_1/foo_test.go-42.diff, with -2, -3 appended when the line
+// fails again. It returns the path of the files without their extension.
+func writeArtifacts(f Failure, sides bool) (string, error) {
+	dir := ArtifactsDir()
+	if dir == "" {
+		return "", nil
+	}
+	var parts []string
+	for _, p := range strings.Split(f.Test, "/") {
+		if p = regExArtifactName.ReplaceAllString(p, "_"); p != "" && p != "." && p != ".." {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"unknown"}
+	}
+	name := "failure"
+	if f.File != "" {
+		name = fmt.Sprintf("%s-%d", regExArtifactName.ReplaceAllString(filepath.Base(f.File), "_"), f.Line)
+	}
+	base := filepath.Join(append([]string{dir}, append(parts, name)...)...)
+
+	artifactsMu.Lock()
+	artifactsN[base]++
+	if n := artifactsN[base]; n > 1 {
+		base = fmt.Sprintf("%s-%d", base, n)
+	}
+	artifactsMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(base), 0777); err != nil {
+		return "", err
+	}
+	files := map[string]string{".diff": f.Title + nl + f.Diff}
+	if sides {
+		files[".want"] = f.Want
+		files[".got"] = f.Got
+	}
+	for ext, data := range files {
+		if err := ioutil.WriteFile(base+ext, []byte(data), 0666); err != nil {
+			return "", err
+		}
+	}
+	return base, nil
+}
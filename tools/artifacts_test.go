@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// logT is a TestingT with a name and a log
+type logT struct {
+	name string
+	logs []string
+}
+
+func (t *logT) Name() string {
+	return t.name
+}
+
+func (t *logT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func TestArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("LOUPE_ARTIFACTS", dir)
+	defer os.Unsetenv("LOUPE_ARTIFACTS")
+
+	lt := &logT{name: "TestX/case 1"}
+	for i := 0; i < 2; i++ {
+		// the same line failing twice
+		ReportFailureValues(lt, "Not Equal", "-a\n+b\n", "a", []byte("b"))
+	}
+	ReportFailure(lt, "\x1b[31mfailed\x1b[0m", "")
+
+	wants, err := filepath.Glob(filepath.Join(dir, "TestX", "case_1", "artifacts_test.go-*.want"))
+	assert.NoError(t, err)
+	if !assert.Len(t, wants, 2) {
+		return
+	}
+	// the first failure has no -2 suffix
+	base := strings.TrimSuffix(wants[0], ".want")
+	if len(wants[1]) < len(wants[0]) {
+		base = strings.TrimSuffix(wants[1], ".want")
+	}
+	read := func(path string) string {
+		bs, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		return string(bs)
+	}
+	assert.Equal(t, "a", read(base+".want"))
+	assert.Equal(t, "b", read(base+".got"))
+	assert.Equal(t, "Not Equal\n-a\n+b\n", read(base+".diff"))
+	assert.Equal(t, "b", read(base+"-2.got"))
+	if assert.Len(t, lt.logs, 3) {
+		assert.Equal(t, "failure artifacts: "+base+".{want,got,diff}", lt.logs[0])
+		assert.Equal(t, "failure artifacts: "+base+"-2.{want,got,diff}", lt.logs[1])
+		assert.Regexp(t, `^failure artifact: .*artifacts_test.go-\d+.diff$`, lt.logs[2])
+	}
+
+	os.Unsetenv("LOUPE_ARTIFACTS")
+	lt = &logT{name: "TestY"}
+	ReportFailureValues(lt, "Not Equal", "", "a", "b")
+	assert.Empty(t, lt.logs)
+	_, err = os.Stat(filepath.Join(dir, "TestY"))
+	assert.True(t, os.IsNotExist(err))
+}
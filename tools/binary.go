@@ -0,0 +1,393 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"sync"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const hexRowSize = 16
+
+//DiffBinary creates a Differ that compares a and b byte by byte and renders
+//the changed rows as a hex dump with offsets and an ASCII gutter, like
+//hexdump -C, highlighting the changed bytes. Diff uses it automatically when
+//either side is not valid UTF-8.
+func DiffBinary(a, b []byte, opts ...Option) Differ {
+	return &binaryDiff{a: a, b: b, opts: newOptions(opts)}
+}
+
+type binaryDiff struct {
+	a    []byte
+	b    []byte
+	opts *options
+
+	once  sync.Once
+	cache []dmp.Diff
+}
+
+// hexRow is one row of a hex dump, changed marks the bytes that differ
+type hexRow struct {
+	op      dmp.Operation
+	offset  int
+	data    []byte
+	changed []bool
+}
+
+// hexGroup is a run of changed rows on both sides
+type hexGroup struct {
+	old []hexRow
+	new []hexRow
+}
+
+func (d *binaryDiff) Print() {
+	printDiff(d.opts, d.diff)
+}
+
+func (d *binaryDiff) String() string {
+	var buf bytes.Buffer
+	d.diff(&buf, d.opts.palette(nil))
+	return buf.String()
+}
+
+func (d *binaryDiff) WriteTo(w io.Writer) (int64, error) {
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *binaryDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *binaryDiff) HTML(w io.Writer, layout Layout) error {
+	return writeHTML(w, func(w io.Writer) {
+		d.rows(w, layout)
+	})
+}
+
+// rows writes the table rows of HTML
+func (d *binaryDiff) rows(w io.Writer, layout Layout) {
+	for _, g := range d.groups() {
+		switch layout {
+		case SideBySide:
+			for i := 0; i < len(g.old) || i < len(g.new); i++ {
+				left, right := "<td></td>", "<td></td>"
+				if i < len(g.old) {
+					left = fmt.Sprintf("<td class=\"del\">%s</td>", htmlHexRow(g.old[i]))
+				}
+				if i < len(g.new) {
+					right = fmt.Sprintf("<td class=\"ins\">%s</td>", htmlHexRow(g.new[i]))
+				}
+				fmt.Fprintf(w, "<tr>%s%s</tr>\n", left, right)
+			}
+		default:
+			for _, r := range g.old {
+				fmt.Fprintf(w, "<tr class=\"del\"><td>-%s</td></tr>\n", htmlHexRow(r))
+			}
+			for _, r := range g.new {
+				fmt.Fprintf(w, "<tr class=\"ins\"><td>+%s</td></tr>\n", htmlHexRow(r))
+			}
+		}
+	}
+}
+
+//Hunks returns a single hunk with byte ranges, the edit texts are hex
+//encoded
+func (d *binaryDiff) Hunks() []Hunk {
+	hunks := textHunks(d.diffs())
+	for i := range hunks {
+		for j := range hunks[i].Edits {
+			e := &hunks[i].Edits[j]
+			e.Text = hex.EncodeToString([]byte(e.Text))
+		}
+	}
+	return hunks
+}
+
+func (d *binaryDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *binaryDiff) Equal() bool {
+	return d.Stats().changed() == 0
+}
+
+func (d *binaryDiff) Error() error {
+	return nil
+}
+
+//Stats counts bytes
+func (d *binaryDiff) Stats() Stats {
+	var s Stats
+	for _, diff := range d.diffs() {
+		switch diff.Type {
+		case dmp.DiffDelete:
+			s.Deletes += len(diff.Text)
+		case dmp.DiffInsert:
+			s.Inserts += len(diff.Text)
+		default:
+			s.Equal += len(diff.Text)
+		}
+	}
+	return s
+}
+
+// diffs computes a byte diff once, each diff.Text holds the raw bytes
+func (d *binaryDiff) diffs() []dmp.Diff {
+	d.compute()
+	return d.cache
+}
+
+func (d *binaryDiff) compute() {
+	d.once.Do(func() {
+		d.cache = d.run(d.opts.budget())
+	})
+}
+
+func (d *binaryDiff) coarse() Differ {
+	c := &binaryDiff{a: d.a, b: d.b, opts: d.opts}
+	c.once.Do(func() {
+		c.cache = coarseDiffs(string(d.a), string(d.b), false)
+	})
+	return c
+}
+
+func (d *binaryDiff) run(o *options) []dmp.Diff {
+	toRunes := func(data []byte) []rune {
+		rs := make([]rune, len(data))
+		for i, c := range data {
+			rs[i] = rune(c)
+		}
+		return rs
+	}
+
+	gd := o.dmp()
+	diffs := gd.DiffMainRunes(toRunes(d.a), toRunes(d.b), false)
+	diffs = o.cleanupDiffs(gd, diffs)
+	for i, diff := range diffs {
+		rs := []rune(diff.Text)
+		data := make([]byte, len(rs))
+		for j, r := range rs {
+			data[j] = byte(r)
+		}
+		diffs[i].Text = string(data)
+	}
+	return diffs
+}
+
+// groups marks the changed bytes of both sides and collects the rows that
+// contain them, merging changes that touch the same or adjacent rows
+func (d *binaryDiff) groups() []hexGroup {
+	delA := make([]bool, len(d.a))
+	insB := make([]bool, len(d.b))
+
+	type span struct{ oldStart, oldEnd, newStart, newEnd int }
+	var spans []span
+	inChange := false
+	oldNo, newNo := 0, 0
+	for _, diff := range d.diffs() {
+		n := len(diff.Text)
+		if diff.Type == dmp.DiffEqual {
+			oldNo += n
+			newNo += n
+			inChange = false
+			continue
+		}
+		if !inChange {
+			spans = append(spans, span{oldNo, oldNo, newNo, newNo})
+			inChange = true
+		}
+		if diff.Type == dmp.DiffDelete {
+			for i := oldNo; i < oldNo+n; i++ {
+				delA[i] = true
+			}
+			oldNo += n
+		} else {
+			for i := newNo; i < newNo+n; i++ {
+				insB[i] = true
+			}
+			newNo += n
+		}
+		last := &spans[len(spans)-1]
+		last.oldEnd, last.newEnd = oldNo, newNo
+	}
+
+	var groups []hexGroup
+	lastOld, lastNew := -2, -2
+	for _, s := range spans {
+		oldFirst, oldLast, okOld := hexRows(s.oldStart, s.oldEnd, len(d.a))
+		newFirst, newLast, okNew := hexRows(s.newStart, s.newEnd, len(d.b))
+
+		merge := len(groups) > 0 && ((okOld && oldFirst <= lastOld+1) || (okNew && newFirst <= lastNew+1))
+		if !merge {
+			groups = append(groups, hexGroup{})
+		}
+		g := &groups[len(groups)-1]
+		if okOld {
+			for r := maxInt(oldFirst, lastOld+1); r <= oldLast; r++ {
+				g.old = append(g.old, newHexRow(dmp.DiffDelete, d.a, delA, r))
+			}
+			lastOld = maxInt(lastOld, oldLast)
+		}
+		if okNew {
+			for r := maxInt(newFirst, lastNew+1); r <= newLast; r++ {
+				g.new = append(g.new, newHexRow(dmp.DiffInsert, d.b, insB, r))
+			}
+			lastNew = maxInt(lastNew, newLast)
+		}
+	}
+	return groups
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// hexRows returns the first and last row touched by the byte range
+// [start, end) of a side with size bytes. An empty range touches the row it
+// is positioned in.
+func hexRows(start, end, size int) (int, int, bool) {
+	if size == 0 {
+		return 0, 0, false
+	}
+	if end > start {
+		return start / hexRowSize, (end - 1) / hexRowSize, true
+	}
+	if start >= size {
+		start = size - 1
+	}
+	return start / hexRowSize, start / hexRowSize, true
+}
+
+func newHexRow(op dmp.Operation, data []byte, changed []bool, row int) hexRow {
+	start := row * hexRowSize
+	end := start + hexRowSize
+	if end > len(data) {
+		end = len(data)
+	}
+	return hexRow{op: op, offset: start, data: data[start:end], changed: changed[start:end]}
+}
+
+func (d *binaryDiff) diff(w io.Writer, p *palette) {
+	groups := d.groups()
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", d.opts.labelA, d.opts.labelB)
+	lim := d.opts.limits()
+	for gi, g := range groups {
+		if !lim.hunk() {
+			p.dim.Fprintln(w, summary(len(groups)-gi, "hunk", hexLines(groups[gi:])))
+			return
+		}
+		oldOff, newOff := 0, 0
+		if len(g.old) > 0 {
+			oldOff = g.old[0].offset
+		}
+		if len(g.new) > 0 {
+			newOff = g.new[0].offset
+		}
+		fmt.Fprintf(w, "@@ -%08x +%08x @@\n", oldOff, newOff)
+
+		rows := append(append([]hexRow{}, g.old...), g.new...)
+		for i, r := range rows {
+			if !lim.line() {
+				rest := len(rows) - i + hexLines(groups[gi+1:])
+				p.dim.Fprintln(w, summary(len(groups)-gi-1, "hunk", rest))
+				return
+			}
+			line, span := p.del, p.delSpan
+			if r.op == dmp.DiffInsert {
+				line, span = p.ins, p.insSpan
+			}
+			writeHexRow(w, r, line, span)
+		}
+	}
+}
+
+func hexLines(groups []hexGroup) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g.old) + len(g.new)
+	}
+	return n
+}
+
+// writeHexRow writes a row like hexdump -C prefixed with - or +
+func writeHexRow(w io.Writer, r hexRow, line, span printer) {
+	line.Fprintf(w, "%c%08x ", linePrefix(r.op), r.offset)
+	for i := 0; i < hexRowSize; i++ {
+		sep := " "
+		if i == hexRowSize/2 {
+			sep = "  "
+		}
+		line.Fprint(w, sep)
+		switch {
+		case i >= len(r.data):
+			line.Fprint(w, "  ")
+		case r.changed[i]:
+			span.Fprintf(w, "%02x", r.data[i])
+		default:
+			line.Fprintf(w, "%02x", r.data[i])
+		}
+	}
+	line.Fprint(w, "  |")
+	for i, c := range r.data {
+		if r.changed[i] {
+			span.Fprint(w, string(hexASCII(c)))
+		} else {
+			line.Fprint(w, string(hexASCII(c)))
+		}
+	}
+	line.Fprint(w, "|")
+	fmt.Fprintln(w)
+}
+
+func hexASCII(c byte) byte {
+	if c < 0x20 || c > 0x7e {
+		return '.'
+	}
+	return c
+}
+
+func htmlHexRow(r hexRow) string {
+	tag := "del"
+	if r.op == dmp.DiffInsert {
+		tag = "ins"
+	}
+	mark := func(s string, changed bool) string {
+		s = html.EscapeString(s)
+		if changed {
+			return fmt.Sprintf("<%s>%s</%s>", tag, s, tag)
+		}
+		return s
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%08x ", r.offset)
+	for i := 0; i < hexRowSize; i++ {
+		if i == hexRowSize/2 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(" ")
+		if i < len(r.data) {
+			buf.WriteString(mark(fmt.Sprintf("%02x", r.data[i]), r.changed[i]))
+		} else {
+			buf.WriteString("  ")
+		}
+	}
+	buf.WriteString("  |")
+	for i, c := range r.data {
+		buf.WriteString(mark(string(hexASCII(c)), r.changed[i]))
+	}
+	buf.WriteString("|")
+	return buf.String()
+}
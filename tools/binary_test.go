@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffBinary(t *testing.T) {
+	a := []byte("Hello world\n\x00\x01\x02\x03 plus some trailing data")
+	b := []byte("Hello World\n\x00\x01\x02\x03 plus some trailing data")
+
+	d := DiffBinary(a, b, WithNoColor())
+	assert.Equal(t, "--- a\n+++ b\n@@ -00000000 +00000000 @@\n"+
+		"-00000000  48 65 6c 6c 6f 20 77 6f  72 6c 64 0a 00 01 02 03  |Hello world.....|\n"+
+		"+00000000  48 65 6c 6c 6f 20 57 6f  72 6c 64 0a 00 01 02 03  |Hello World.....|\n", d.String())
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Equal: 39}, d.Stats())
+
+	// changed bytes are highlighted
+	out := DiffBinary(a, b, WithColor(ColorAlways)).String()
+	p := newPalette(true, Theme{})
+	assert.Contains(t, out, p.delSpan.Sprint("77"))
+	assert.Contains(t, out, p.insSpan.Sprint("W"))
+
+	// non UTF-8 input switches Diff to a hex dump
+	d = Diff([]byte{0xff, 0x00, 0x01}, []byte{0xff, 0x00, 0x02}, WithNoColor())
+	assert.Equal(t, "--- a\n+++ b\n@@ -00000000 +00000000 @@\n"+
+		"-00000000  ff 00 01                                          |...|\n"+
+		"+00000000  ff 00 02                                          |...|\n", d.String())
+	assert.Equal(t, "0102", d.Hunks()[0].Edits[1].Text+d.Hunks()[0].Edits[2].Text)
+
+	// an insertion only shows the row it is in
+	a = bytes.Repeat([]byte{0xaa}, 64)
+	b = append(append(append([]byte{}, a[:40]...), 0xbb), a[40:]...)
+	d = DiffBinary(a, b, WithNoColor())
+	assert.Equal(t, "--- a\n+++ b\n@@ -00000020 +00000020 @@\n"+
+		"-00000020  aa aa aa aa aa aa aa aa  aa aa aa aa aa aa aa aa  |................|\n"+
+		"+00000020  aa aa aa aa aa aa aa aa  bb aa aa aa aa aa aa aa  |................|\n", d.String())
+
+	assert.Equal(t, "", DiffBinary(a, a).String())
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+	isatty "github.com/mattn/go-isatty"
+)
+
+//ColorMode controls when diffs are rendered with ANSI colors
+type ColorMode int32
+
+const (
+	//ColorAuto colors output unless NO_COLOR or CI is set, TERM is dumb,
+	//or the destination is not a terminal. Writers that are not an
+	//*os.File, e.g. the buffer behind String(), follow os.Stdout.
+	ColorAuto ColorMode = iota
+
+	//ColorAlways always colors output
+	ColorAlways
+
+	//ColorNever never colors output
+	ColorNever
+)
+
+var colorMode int32
+
+//ForceColor colors all output regardless of the environment, unless a
+//Differ is created with its own color option
+func ForceColor() {
+	atomic.StoreInt32(&colorMode, int32(ColorAlways))
+}
+
+//DisableColor disables colored output, unless a Differ is created with its
+//own color option
+func DisableColor() {
+	atomic.StoreInt32(&colorMode, int32(ColorNever))
+}
+
+//AutoColor restores automatic color detection
+func AutoColor() {
+	atomic.StoreInt32(&colorMode, int32(ColorAuto))
+}
+
+//Theme sets the colors diffs are rendered with, nil fields use the default
+//theme. Any attribute can be used, e.g. Theme{Insert: color.New(color.FgBlue),
+//Delete: color.New(color.FgYellow)}, and leaving out color.Bold or
+//color.Underline keeps them off.
+type Theme struct {
+	//Insert colors inserted lines, green by default
+	Insert *color.Color
+
+	//Delete colors deleted lines, red by default
+	Delete *color.Color
+
+	//InsertSpan highlights the changed part of an inserted line, green in
+	//reverse video by default
+	InsertSpan *color.Color
+
+	//DeleteSpan highlights the changed part of a deleted line, red in
+	//reverse video by default
+	DeleteSpan *color.Color
+
+	//Context colors unchanged lines, uncolored by default
+	Context *color.Color
+
+	//Dim colors ignored lines and truncation summaries, faint by default
+	Dim *color.Color
+
+	//Moved colors lines and values found by DetectMoves, cyan by default
+	Moved *color.Color
+}
+
+//BlueYellow is a theme for red/green color blindness
+var BlueYellow = Theme{
+	Insert:     color.New(color.FgBlue),
+	Delete:     color.New(color.FgYellow),
+	InsertSpan: color.New(color.FgBlue, color.ReverseVideo),
+	DeleteSpan: color.New(color.FgYellow, color.ReverseVideo),
+	Moved:      color.New(color.FgMagenta),
+}
+
+var theme atomic.Value
+
+//SetTheme sets the theme of all output, unless a Differ is created with its
+//own theme
+func SetTheme(t Theme) {
+	theme.Store(t)
+}
+
+func globalTheme() Theme {
+	t, _ := theme.Load().(Theme)
+	return t
+}
+
+// palette holds the printers for one render
+type palette struct {
+	color   bool
+	del     printer
+	ins     printer
+	delSpan printer
+	insSpan printer
+	ctx     printer
+	dim     printer
+	mov     printer
+}
+
+func newPalette(enabled bool, t Theme) *palette {
+	return &palette{
+		color:   enabled,
+		del:     newPrinter(enabled, t.Delete, color.FgRed),
+		ins:     newPrinter(enabled, t.Insert, color.FgGreen),
+		delSpan: newPrinter(enabled, t.DeleteSpan, color.FgRed, color.ReverseVideo),
+		insSpan: newPrinter(enabled, t.InsertSpan, color.FgGreen, color.ReverseVideo),
+		ctx:     newPrinter(enabled, t.Context),
+		dim:     newPrinter(enabled, t.Dim, color.Faint),
+		mov:     newPrinter(enabled, t.Moved, color.FgCyan),
+	}
+}
+
+// printer writes each call as one complete colored string so the escape
+// codes don't depend on the global color state, a nil color writes plain text
+type printer struct {
+	c *color.Color
+}
+
+// newPrinter copies c, or creates a color from the default attrs when c is
+// nil, so enabling it doesn't change the caller's color
+func newPrinter(enabled bool, c *color.Color, attrs ...color.Attribute) printer {
+	switch {
+	case c != nil:
+		cc := *c
+		c = &cc
+	case len(attrs) > 0:
+		c = color.New(attrs...)
+	default:
+		return printer{}
+	}
+	if enabled {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return printer{c: c}
+}
+
+func (p printer) Sprint(a ...interface{}) string {
+	if p.c == nil {
+		return fmt.Sprint(a...)
+	}
+	return p.c.Sprint(a...)
+}
+
+func (p printer) Fprint(w io.Writer, a ...interface{}) {
+	io.WriteString(w, p.Sprint(a...))
+}
+
+func (p printer) Fprintf(w io.Writer, format string, a ...interface{}) {
+	io.WriteString(w, p.Sprint(fmt.Sprintf(format, a...)))
+}
+
+func (p printer) Fprintln(w io.Writer, a ...interface{}) {
+	io.WriteString(w, p.Sprint(fmt.Sprintln(a...)))
+}
+
+// useColor decides whether output written to w is colored, a nil w means
+// os.Stdout
+func useColor(mode ColorMode, w io.Writer) bool {
+	if mode == ColorAuto {
+		mode = ColorMode(atomic.LoadInt32(&colorMode))
+	}
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	if s, ok := w.(*SyncWriter); ok {
+		w = s.w
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		f = os.Stdout
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+//Palette colors text like diffs are colored, for output that isn't a diff,
+//e.g. a table of benchmark changes
+type Palette struct {
+	p *palette
+}
+
+//NewPalette returns the Palette for output written to w, a nil w means
+//os.Stdout. WithColor, WithNoColor and WithTheme apply, other options are
+//ignored.
+func NewPalette(w io.Writer, opts ...Option) Palette {
+	return Palette{p: newOptions(opts).palette(w)}
+}
+
+//Enabled reports whether the Palette adds color codes
+func (p Palette) Enabled() bool {
+	return p.p.color
+}
+
+//Insert colors s like inserted lines, green by default
+func (p Palette) Insert(s string) string {
+	return p.p.ins.Sprint(s)
+}
+
+//Delete colors s like deleted lines, red by default
+func (p Palette) Delete(s string) string {
+	return p.p.del.Sprint(s)
+}
+
+//Dim colors s like ignored lines, faint by default
+func (p Palette) Dim(s string) string {
+	return p.p.dim.Sprint(s)
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPalette(t *testing.T) {
+	// only the codes that start a color are checked, how it's reset
+	// depends on the version of fatih/color
+	colored := func(code, text, got string) {
+		t.Helper()
+		assert.True(t, strings.HasPrefix(got, code), "%q starts with %q", got, code)
+		assert.NotEqual(t, text, got)
+		assert.Equal(t, text, stripANSI(got))
+	}
+	p := NewPalette(nil, WithColor(ColorAlways))
+	assert.True(t, p.Enabled())
+	colored("\x1b[32m", "+1", p.Insert("+1"))
+	colored("\x1b[31m", "-1", p.Delete("-1"))
+	colored("\x1b[2m", "~", p.Dim("~"))
+
+	p = NewPalette(nil, WithColor(ColorAlways), WithTheme(BlueYellow))
+	colored("\x1b[34m", "+1", p.Insert("+1"))
+
+	p = NewPalette(&bytes.Buffer{}, WithColor(ColorAlways), WithNoColor())
+	assert.False(t, p.Enabled())
+	assert.Equal(t, "-1", p.Delete("-1"))
+}
@@ -1,28 +1,33 @@
 package tools
 
 import (
-	"fmt"
 	"reflect"
 	"regexp"
 
-	"github.com/fatih/color"
+	"github.com/prasek/loupe/pretty"
 )
 
-var regExColor = regexp.MustCompile(`\x1b\[[0-9]*m`)
-
-var red = color.New(color.FgRed)
-var green = color.New(color.FgGreen)
+var regExColor = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 //Value returns the value of v
 func Value(v interface{}) interface{} {
 	vt := reflect.TypeOf(v)
-	if vt.Kind() == reflect.Ptr {
+	if vt != nil && vt.Kind() == reflect.Ptr {
 		vv := reflect.ValueOf(v)
-		v = vv.Elem().Interface()
+		if !vv.IsNil() {
+			v = vv.Elem().Interface()
+		}
 	}
 	return v
 }
 
+// getText renders v for text diffs. Values that describe themselves are
+// used as is, pointers are checked before they are dereferenced. Structs,
+// maps, slices and arrays are formatted one element per line with sorted
+// map keys so diffs of them are stable.
 func getText(v interface{}) string {
-	return fmt.Sprintf("%s", Value(v))
+	if s, ok := pretty.Text(v); ok {
+		return s
+	}
+	return pretty.Sprint(Value(v))
 }
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ptrStringer struct{ name string }
+
+func (p *ptrStringer) String() string { return "stringer " + p.name }
+
+type jsonOnly struct{}
+
+func (jsonOnly) MarshalJSON() ([]byte, error) { return []byte(`{"json":true}`), nil }
+
+func TestGetText(t *testing.T) {
+	assert.Equal(t, "abc", getText("abc"))
+	assert.Equal(t, "abc", getText([]byte("abc")))
+	assert.Equal(t, "5", getText(5))
+	assert.Equal(t, "boom", getText(errors.New("boom")))
+	assert.Equal(t, "stringer a", getText(&ptrStringer{name: "a"}))
+	assert.Equal(t, "10.0.0.1", getText(net.ParseIP("10.0.0.1")))
+	assert.Equal(t, `{"json":true}`, getText(jsonOnly{}))
+	assert.Equal(t, "2001-02-03 00:00:00 +0000 UTC", getText(time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)))
+
+	m := map[string]interface{}{"b": []int{1, 2}, "a": nil, "c": map[int]bool{2: true, 1: false}}
+	assert.Equal(t, `map[string]interface {}{
+  "a": nil,
+  "b": []int{
+    1,
+    2,
+  },
+  "c": map[int]bool{
+    1: false,
+    2: true,
+  },
+}`, getText(m))
+
+	n := &node{Name: "a"}
+	n.Next = n
+	// the top level pointer is dereferenced like the other inputs of Diff
+	assert.Equal(t, `tools.node{
+  Name: "a",
+  Next: &tools.node{
+    Name: "a",
+    Next: (*tools.node)(<cycle>),
+    tag: "",
+  },
+  tag: "",
+}`, getText(n))
+
+	assert.Equal(t, `[]error{
+  boom,
+}`, getText([]error{errors.New("boom")}))
+}
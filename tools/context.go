@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"unicode/utf8"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// computer is a Differ that computes its diff on first use, DiffContext
+// computes it up front so it can give up on it
+type computer interface {
+	Differ
+	compute()
+
+	//coarse returns the same Differ with everything between the common
+	//prefix and suffix replaced, which is cheap to compute
+	coarse() Differ
+}
+
+//DiffContext is Diff computed under ctx. The diff is computed before
+//returning and its time budget ends at the context deadline if that is
+//earlier than WithTimeout. When ctx is done first a coarse diff is returned
+//that replaces everything between the common prefix and suffix, with
+//ctx.Err() as its Error.
+func DiffContext(ctx context.Context, a, b interface{}, opts ...Option) Differ {
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.ctx = ctx
+	})
+	d := Diff(a, b, opts...)
+	c, ok := d.(computer)
+	if !ok {
+		return d
+	}
+	if err := ctx.Err(); err != nil {
+		return &fallbackDiff{Differ: c.coarse(), err: err}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.compute()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return d
+	case <-ctx.Done():
+		return &fallbackDiff{Differ: c.coarse(), err: ctx.Err()}
+	}
+}
+
+// coarseDiffs keeps the common prefix and suffix of a and b and replaces
+// the rest, whole runes when runes is set
+func coarseDiffs(a, b string, runes bool) []dmp.Diff {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	for runes && n > 0 && n < len(a) && !utf8.RuneStart(a[n]) {
+		n--
+	}
+	m := 0
+	for m < len(a)-n && m < len(b)-n && a[len(a)-1-m] == b[len(b)-1-m] {
+		m++
+	}
+	for runes && m > 0 && !utf8.RuneStart(a[len(a)-m]) {
+		m--
+	}
+
+	var diffs []dmp.Diff
+	add := func(op dmp.Operation, text string) {
+		if text != "" {
+			diffs = append(diffs, dmp.Diff{Type: op, Text: text})
+		}
+	}
+	add(dmp.DiffEqual, a[:n])
+	add(dmp.DiffDelete, a[n:len(a)-m])
+	add(dmp.DiffInsert, b[n:len(b)-m])
+	add(dmp.DiffEqual, a[len(a)-m:])
+	return diffs
+}
+
+// coarseLines keeps the common leading and trailing lines of a and b and
+// replaces the rest
+func coarseLines(a, b []string) []lineOp {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	m := 0
+	for m < len(a)-n && m < len(b)-n && a[len(a)-1-m] == b[len(b)-1-m] {
+		m++
+	}
+
+	ops := make([]lineOp, 0, len(a)+len(b)-n-m)
+	for _, l := range a[:n] {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: l})
+	}
+	for _, l := range a[n : len(a)-m] {
+		ops = append(ops, lineOp{op: dmp.DiffDelete, text: l})
+	}
+	for _, l := range b[n : len(b)-m] {
+		ops = append(ops, lineOp{op: dmp.DiffInsert, text: l})
+	}
+	for _, l := range a[len(a)-m:] {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: l})
+	}
+	return ops
+}
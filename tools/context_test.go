@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffContext(t *testing.T) {
+	a := "a\nb\nc\nd\ne\n"
+	b := "a\nx\nc\ny\ne\n"
+
+	d := DiffContext(context.Background(), a, b, WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.Equal(t, Diff(a, b, WithNoColor()).String(), d.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = DiffContext(ctx, a, b, WithNoColor())
+	assert.Equal(t, context.Canceled, d.Error())
+	assert.False(t, d.Equal())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,5 +1,5 @@\n a\n-b\n-c\n-d\n+x\n+c\n+y\n e\n", d.String())
+
+	d = DiffContext(ctx, "the quick fox", "the slow fox", WithNoColor())
+	assert.Equal(t, context.Canceled, d.Error())
+	assert.Equal(t, []dmp.Diff{
+		{Type: dmp.DiffEqual, Text: "the "},
+		{Type: dmp.DiffDelete, Text: "quick"},
+		{Type: dmp.DiffInsert, Text: "slow"},
+		{Type: dmp.DiffEqual, Text: " fox"},
+	}, d.(*fallbackDiff).Differ.(*wordDiff).diffs())
+
+	d = DiffContext(ctx, []byte{0xff, 1, 2}, []byte{0xff, 3, 2})
+	assert.Equal(t, context.Canceled, d.Error())
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Equal: 2}, d.Stats())
+}
+
+func TestDiffContextDeadline(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() string {
+		bs := make([]byte, 200000)
+		for i := range bs {
+			bs[i] = 'a' + byte(r.Intn(4))
+		}
+		return string(bs)
+	}
+	a, b := random(), random()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	d := DiffContext(ctx, a, b, WithTimeout(0), WithMode(WordMode))
+	assert.True(t, time.Since(start) < time.Second, "took %v", time.Since(start))
+	assert.False(t, d.Equal())
+}
+
+func TestCoarseDiffs(t *testing.T) {
+	assert.Equal(t, []dmp.Diff{
+		{Type: dmp.DiffEqual, Text: "gr"},
+		{Type: dmp.DiffDelete, Text: "ö"},
+		{Type: dmp.DiffInsert, Text: "ü"},
+		{Type: dmp.DiffEqual, Text: "n"},
+	}, coarseDiffs("grön", "grün", true))
+	assert.Equal(t, []dmp.Diff{{Type: dmp.DiffEqual, Text: "same"}}, coarseDiffs("same", "same", true))
+	assert.Equal(t, []dmp.Diff{{Type: dmp.DiffEqual, Text: "aa"}, {Type: dmp.DiffInsert, Text: "a"}}, coarseDiffs("aa", "aaa", false))
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+//DiffCSV creates a Differ that parses a and b as CSV, with a header row, and
+//compares them row by row and cell by cell. Rows are aligned by position
+//so an inserted row is reported as added instead of shifting every row after
+//it, or by key with WithKeyColumns. Changed cells are reported by row and
+//column, e.g. [3].email or [id=7].email, and added and removed rows as CSV.
+//If either side is not valid CSV it falls back to a text Diff and Error
+//returns the parse error.
+func DiffCSV(a, b io.Reader, opts ...Option) Differ {
+	o := newOptions(opts)
+	textA, errA := ioutil.ReadAll(a)
+	textB, errB := ioutil.ReadAll(b)
+	if err := firstError(errA, errB); err != nil {
+		return &fallbackDiff{Diff(string(textA), string(textB), opts...), err}
+	}
+
+	ta, errA := parseCSV(textA, o.comma)
+	tb, errB := parseCSV(textB, o.comma)
+	if err := firstError(errA, errB); err != nil {
+		return &fallbackDiff{Diff(string(textA), string(textB), opts...), err}
+	}
+
+	w := &csvWalker{opts: o, a: ta, b: tb}
+	w.walk()
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+func parseCSV(data []byte, comma rune) (*csvTable, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	t := &csvTable{}
+	if len(records) > 0 {
+		t.header, t.rows = records[0], records[1:]
+	}
+	return t, nil
+}
+
+// cell returns the value of the named column of row, and whether the
+// table has that column
+func (t *csvTable) cell(row []string, col string) (string, bool) {
+	for i, h := range t.header {
+		if h == col {
+			if i < len(row) {
+				return row[i], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+type csvWalker struct {
+	opts    *options
+	a, b    *csvTable
+	changes []change
+}
+
+func (w *csvWalker) walk() {
+	if ha, hb := formatCSV(w.a.header, w.opts.comma), formatCSV(w.b.header, w.opts.comma); ha != hb {
+		w.add(change{typ: changed, path: "(header)", exp: ha, act: hb})
+	}
+	if len(w.opts.keyColumns) > 0 {
+		w.walkKeyed()
+		return
+	}
+
+	keys := func(t *csvTable) []string {
+		ks := make([]string, len(t.rows))
+		for i, row := range t.rows {
+			ks[i] = w.rowKey(t, row)
+		}
+		return ks
+	}
+	index := func(i int) string {
+		return "[" + strconv.Itoa(i) + "]"
+	}
+	for _, s := range alignSeq(keys(w.a), keys(w.b), w.opts) {
+		switch {
+		case s.moved:
+			w.add(change{typ: moved, path: index(s.j), exp: index(s.i), act: formatCSV(w.b.rows[s.j], w.opts.comma)})
+		case s.j < 0:
+			w.add(change{typ: removed, path: index(s.i), exp: formatCSV(w.a.rows[s.i], w.opts.comma)})
+		case s.i < 0:
+			w.add(change{typ: added, path: index(s.j), act: formatCSV(w.b.rows[s.j], w.opts.comma)})
+		default:
+			w.walkRow(index(s.j), w.a.rows[s.i], w.b.rows[s.j])
+		}
+	}
+}
+
+// rowKey identifies a row by its cells in header order, so rows with
+// reordered columns are still equal
+func (w *csvWalker) rowKey(t *csvTable, row []string) string {
+	var buf strings.Builder
+	for _, col := range w.columns() {
+		v, ok := t.cell(row, col)
+		if ok {
+			buf.WriteString(strconv.Quote(v))
+		}
+		buf.WriteByte(',')
+	}
+	return buf.String()
+}
+
+// walkKeyed matches rows by their key columns, duplicate keys are matched
+// in order. Rows are reported in the order of b, followed by removed rows.
+func (w *csvWalker) walkKeyed() {
+	path := func(t *csvTable, row []string) string {
+		parts := make([]string, len(w.opts.keyColumns))
+		for i, col := range w.opts.keyColumns {
+			v, _ := t.cell(row, col)
+			parts[i] = col + "=" + v
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+
+	rowsA := make(map[string][]int)
+	for i, row := range w.a.rows {
+		p := path(w.a, row)
+		rowsA[p] = append(rowsA[p], i)
+	}
+	matched := make(map[int]bool)
+	for _, row := range w.b.rows {
+		p := path(w.b, row)
+		if is := rowsA[p]; len(is) > 0 {
+			rowsA[p] = is[1:]
+			matched[is[0]] = true
+			w.walkRow(p, w.a.rows[is[0]], row)
+			continue
+		}
+		w.add(change{typ: added, path: p, act: formatCSV(row, w.opts.comma)})
+	}
+	for i, row := range w.a.rows {
+		if !matched[i] {
+			w.add(change{typ: removed, path: path(w.a, row), exp: formatCSV(row, w.opts.comma)})
+		}
+	}
+}
+
+// walkRow compares the cells of two rows by column name
+func (w *csvWalker) walkRow(path string, a, b []string) {
+	for _, col := range w.columns() {
+		va, okA := w.a.cell(a, col)
+		vb, okB := w.b.cell(b, col)
+		cp := path + "." + col
+		switch {
+		case !okB:
+			w.add(change{typ: removed, path: cp, exp: va})
+		case !okA:
+			w.add(change{typ: added, path: cp, act: vb})
+		case va != vb:
+			w.add(change{typ: changed, path: cp, exp: va, act: vb})
+		}
+	}
+}
+
+// columns returns the columns of a followed by the columns only b has
+func (w *csvWalker) columns() []string {
+	cols := append([]string(nil), w.a.header...)
+	for _, h := range w.b.header {
+		if _, ok := w.a.cell(nil, h); !ok {
+			cols = append(cols, h)
+		}
+	}
+	return cols
+}
+
+func (w *csvWalker) add(c change) {
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func formatCSV(row []string, comma rune) string {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Comma = comma
+	cw.Write(row)
+	cw.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCSV(t *testing.T) {
+	a := "id,name,email\n1,ann,ann@example.com\n2,bob,bob@example.com\n3,cy,cy@example.com\n"
+
+	tests := []struct {
+		name string
+		b    string
+		opts []Option
+		exp  string
+	}{
+		{"equal", a, nil, ""},
+		{"inserted row", "id,name,email\n1,ann,ann@example.com\n9,new,new@example.com\n2,bob,bob@example.com\n3,cy,cy@example.com\n", nil,
+			"[1]:\n+9,new,new@example.com\n"},
+		{"changed cell", "id,name,email\n1,ann,ann@example.com\n2,bob,robert@example.com\n3,cy,cy@example.com\n", nil,
+			"[1].email:\n-bob@example.com\n+robert@example.com\n"},
+		{"columns", "name,id,phone\nann,1,555\nbob,2,\ncy,3,\n", nil,
+			"(header):\n-id,name,email\n+name,id,phone\n" +
+				"[0].email:\n-ann@example.com\n[0].phone:\n+555\n" +
+				"[1].email:\n-bob@example.com\n[1].phone:\n+\n" +
+				"[2].email:\n-cy@example.com\n[2].phone:\n+\n"},
+		{"keyed", "id,name,email\n3,cy,cy@example.com\n1,ann,ann@example.org\n4,dee,\"dee, d@example.com\"\n", []Option{WithKeyColumns("id")},
+			"[id=1].email:\n-ann@example.com\n+ann@example.org\n" +
+				"[id=4]:\n+4,dee,\"dee, d@example.com\"\n" +
+				"[id=2]:\n-2,bob,bob@example.com\n"},
+		{"ignored", "id,name,email\n1,ann,x\n2,bob,bob@example.com\n3,cy,cy@example.com\n", []Option{IgnorePath("[*].email")},
+			"[0].email:\n-ann@example.com\n+x\n"},
+		{"moved", "id,name,email\n3,cy,cy@example.com\n1,ann,ann@example.com\n2,bob,bob@example.com\n", []Option{DetectMoves()},
+			"[0]:\n~moved from [2]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := DiffCSV(strings.NewReader(a), strings.NewReader(test.b), append(test.opts, WithNoColor())...)
+			assert.NoError(t, d.Error())
+			assert.Equal(t, test.exp, d.String())
+			assert.Equal(t, test.exp == "" || test.name == "ignored", d.Equal())
+		})
+	}
+
+	tsv := DiffCSV(strings.NewReader("id\tname\n1\tann\n"), strings.NewReader("id\tname\n1\tanne\n"), WithComma('\t'), WithNoColor())
+	assert.Equal(t, "[0].name:\n-ann\n+anne\n", tsv.String())
+
+	bad := DiffCSV(strings.NewReader(a), strings.NewReader("id,name\n\"1,ann\n"), WithNoColor())
+	assert.Error(t, bad.Error())
+	assert.False(t, bad.Equal())
+}
@@ -47,16 +47,18 @@ func testDeepEqual(t TestingT, exp, act interface{}, format string, args ...inte
 	const line = "================================================================="
 
 	var buf bytes.Buffer
+	p := newPalette(useColor(ColorAuto, os.Stdout), globalTheme())
 
-	red.Fprintln(&buf, line)
-	red.Fprintf(&buf, "%s:%d: Not Equal (%T/%T)\n%s\n", base, ln, exp, act, msg)
-	red.Fprintln(&buf, line)
+	p.del.Fprintln(&buf, line)
+	p.del.Fprintf(&buf, "%s:%d: Not Equal (%T/%T)\n%s\n", base, ln, exp, act, msg)
+	p.del.Fprintln(&buf, line)
 
 	Diff(exp, act).WriteTo(&buf)
 	fmt.Fprintln(&buf)
 	fmt.Fprintln(&buf)
 
 	buf.WriteTo(os.Stdout)
+	ReportFailureValues(t, fmt.Sprintf("Not Equal (%T/%T)", exp, act), Diff(exp, act).String(), exp, act)
 	t.Errorf("%s:%d: Not Equal (%T/%T)\n%s\n", base, ln, exp, act, msg)
 
 	return false
@@ -1,48 +1,114 @@
 package tools
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"html"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	dmp "github.com/sergi/go-diff/diffmatchpatch"
 )
 
 const (
-	nle = "%0A"
-	nl  = "\n"
+	nl        = "\n"
+	noNewline = "\\ No newline at end of file"
 )
 
-var unescaper = strings.NewReplacer(
-	"%21", "!", "%7E", "~", "%27", "'",
-	"%28", "(", "%29", ")", "%3B", ";",
-	"%2F", "/", "%3F", "?", "%3A", ":",
-	"%40", "@", "%26", "&", "%3D", "=",
-	"%2B", "+", "%24", "$", "%2C", ",",
-	"%23", "#", "%2A", "*", "%0A", "",
-	"%5B", "[", "%5D", "]", "%09", "^I",
-	"%0D", "^M", "%7B", "{", "%7D", "}",
-	"%25", "%")
+// visible shows tabs and carriage returns in word diff patches
+var visible = strings.NewReplacer("\t", "^I", "\r", "^M")
 
 //Differ allows different diff strategies to be returned
 type Differ interface {
 	Print()
 	String() string
 	WriteTo(w io.Writer) (int64, error)
+
+	//HTML writes a standalone HTML page showing the diff
+	HTML(w io.Writer, layout Layout) error
+
+	//Markdown writes the diff without color in ```diff code blocks, e.g.
+	//for a comment on a pull request
+	Markdown(w io.Writer, style MarkdownStyle) error
+
+	//Hunks returns the computed edits as structured data
+	Hunks() []Hunk
+
+	//MarshalJSON encodes the hunks as {"hunks": [...]}
+	MarshalJSON() ([]byte, error)
+
+	//Stats counts the inserted, deleted and equal parts of the diff
+	Stats() Stats
+
+	//Equal reports whether no differences were found, differences excluded
+	//by IgnoreLines or IgnorePath don't count
+	Equal() bool
+
+	//Error returns the error, if any, that kept the inputs from being
+	//compared as requested, e.g. invalid JSON or a failed read
+	Error() error
+}
+
+// fallbackDiff is a Differ used in place of the requested one, it reports
+// why through Error
+type fallbackDiff struct {
+	Differ
+	err error
+}
+
+func (d *fallbackDiff) Error() error {
+	return d.err
 }
 
-//Diff creates a Differ for comparing a and b
-func Diff(a, b interface{}) Differ {
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Diff creates a Differ for comparing a and b. By default a line diff is
+//used if either side has more than one line and a word diff otherwise.
+//Line diffs are rendered in unified format, and with WithNoColor and
+//WithLabels can be applied with patch(1). WithNormalizers rewrite both sides
+//first. Input that is not valid UTF-8 is compared with DiffBinary.
+func Diff(a, b interface{}, opts ...Option) Differ {
+	o := newOptions(opts)
 	textA := getText(a)
 	textB := getText(b)
+	for _, n := range o.normalizers {
+		textA, textB = n.Normalize(textA), n.Normalize(textB)
+	}
+	if !utf8.ValidString(textA) || !utf8.ValidString(textB) {
+		return DiffBinary([]byte(textA), []byte(textB), opts...)
+	}
+	switch o.lineEndings {
+	case LineEndingsNormalize:
+		textA, textB = normalizeEndings(textA), normalizeEndings(textB)
+	case LineEndingsFail:
+		if textA != textB && normalizeEndings(textA) == normalizeEndings(textB) {
+			return &endingDiff{
+				Differ: Diff(textA, textB, append(opts, WithLineEndings(LineEndingsShow))...),
+				err:    &LineEndingError{LabelA: o.labelA, LabelB: o.labelB, A: describeEndings(textA), B: describeEndings(textB)},
+				opts:   o,
+			}
+		}
+	}
 
 	hasLines := false
-	if strings.Contains(textA, nl) {
-		hasLines = true
-	} else if strings.Contains(textB, nl) {
+	switch o.mode {
+	case WordMode:
+	case LineMode:
 		hasLines = true
+	default:
+		hasLines = strings.Contains(textA, nl) || strings.Contains(textB, nl)
 	}
 
 	var diff Differ
@@ -50,13 +116,15 @@ func Diff(a, b interface{}) Differ {
 	switch hasLines {
 	case false:
 		diff = &wordDiff{
-			a: textA,
-			b: textB,
+			a:    textA,
+			b:    textB,
+			opts: o,
 		}
 	default:
 		diff = &unifiedDiff{
-			a: textA,
-			b: textB,
+			a:    textA,
+			b:    textB,
+			opts: o,
 		}
 	}
 
@@ -65,50 +133,126 @@ func Diff(a, b interface{}) Differ {
 }
 
 type wordDiff struct {
-	a string
-	b string
+	a    string
+	b    string
+	opts *options
+
+	once  sync.Once
+	cache []dmp.Diff
 }
 
 func (d *wordDiff) Print() {
-	d.diff(os.Stdout)
-	fmt.Println()
+	printDiff(d.opts, d.diff)
 }
 
 func (d *wordDiff) String() string {
 	var buf bytes.Buffer
-	d.diff(&buf)
+	d.diff(&buf, d.opts.palette(nil))
 	return buf.String()
 }
 
 func (d *wordDiff) WriteTo(w io.Writer) (int64, error) {
-	if b, ok := w.(*bytes.Buffer); ok {
-		d.diff(b)
-		return int64(b.Len()), nil
-	}
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
 
-	var buf bytes.Buffer
-	d.diff(&buf)
-	return buf.WriteTo(w)
+func (d *wordDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *wordDiff) HTML(w io.Writer, layout Layout) error {
+	diffs := d.diffs()
+	return writeHTML(w, func(w io.Writer) {
+		switch layout {
+		case SideBySide:
+			fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td></tr>\n", htmlSpans(diffs, dmp.DiffDelete), htmlSpans(diffs, dmp.DiffInsert))
+		default:
+			var buf bytes.Buffer
+			for _, diff := range diffs {
+				text := html.EscapeString(diff.Text)
+				switch diff.Type {
+				case dmp.DiffDelete:
+					fmt.Fprintf(&buf, "<del>%s</del>", text)
+				case dmp.DiffInsert:
+					fmt.Fprintf(&buf, "<ins>%s</ins>", text)
+				default:
+					buf.WriteString(text)
+				}
+			}
+			fmt.Fprintf(w, "<tr><td>%s</td></tr>\n", buf.String())
+		}
+	})
+}
+
+func (d *wordDiff) Hunks() []Hunk {
+	return textHunks(d.diffs())
+}
+
+func (d *wordDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *wordDiff) Equal() bool {
+	return d.Stats().changed() == 0
+}
+
+func (d *wordDiff) Error() error {
+	return nil
+}
+
+func (d *wordDiff) Stats() Stats {
+	return textStats(d.diffs())
+}
+
+// diffs computes the diff once, every rendering shares it
+func (d *wordDiff) diffs() []dmp.Diff {
+	d.compute()
+	return d.cache
 }
 
-func (d *wordDiff) diff(w io.Writer) {
-	gd := dmp.New()
+func (d *wordDiff) compute() {
+	d.once.Do(func() {
+		d.cache = d.run(d.opts.budget())
+	})
+}
+
+func (d *wordDiff) run(o *options) []dmp.Diff {
+	if o.folds() && o.foldText(d.a) == o.foldText(d.b) {
+		return []dmp.Diff{{Type: dmp.DiffEqual, Text: d.a}}
+	}
+
+	gd := o.dmp()
 	diffs := gd.DiffMain(d.a, d.b, false)
-	diffs = gd.DiffCleanupSemanticLossless(diffs)
+	if o.cleanup == SemanticCleanup {
+		diffs = gd.DiffCleanupSemanticLossless(diffs)
+	}
 
-	diffs = gd.DiffCleanupSemantic(diffs)
+	return o.cleanupDiffs(gd, diffs)
+}
+
+func (d *wordDiff) coarse() Differ {
+	c := &wordDiff{a: d.a, b: d.b, opts: d.opts}
+	c.once.Do(func() {
+		c.cache = coarseDiffs(d.a, d.b, true)
+	})
+	return c
+}
+
+func (d *wordDiff) diff(w io.Writer, p *palette) {
+	gd := d.opts.dmp()
+	diffs := d.diffs()
 
 	//do whole word diff first
 	for _, diff := range diffs {
 		switch diff.Type {
 		case dmp.DiffDelete:
-			red.Fprint(w, diff.Text)
+			p.del.Fprint(w, diff.Text)
 
 		case dmp.DiffInsert:
-			green.Fprintf(w, diff.Text)
+			p.ins.Fprint(w, diff.Text)
 
 		case dmp.DiffEqual:
-			fmt.Fprint(w, diff.Text)
+			p.ctx.Fprint(w, diff.Text)
 
 		default:
 			fmt.Fprintf(w, "ERROR: Unknown diff type: %v", diff.Type)
@@ -121,103 +265,429 @@ func (d *wordDiff) diff(w io.Writer) {
 
 	//then individual patches
 	patches := gd.PatchMake(diffs)
+	if d.opts.escapedPatches {
+		fmt.Fprint(w, gd.PatchToText(patches))
+		return
+	}
+	for _, wp := range wordPatches(patches, diffs, d.b) {
+		fmt.Fprint(w, wp.header)
+		for _, diff := range wp.diffs {
+			switch diff.Type {
+			case dmp.DiffDelete:
+				for _, line := range strings.Split(strings.TrimSuffix(diff.Text, nl), nl) {
+					p.del.Fprintf(w, "-%s\n", visible.Replace(line))
+				}
+			case dmp.DiffInsert:
+				for _, line := range strings.Split(strings.TrimSuffix(diff.Text, nl), nl) {
+					p.ins.Fprintf(w, "+%s\n", visible.Replace(line))
+				}
+			default:
+				p.ctx.Fprintf(w, " %s\n", visible.Replace(strings.Replace(diff.Text, nl, "", -1)))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// wordPatch is one hunk of a word diff
+type wordPatch struct {
+	header string
+	diffs  []dmp.Diff
+}
+
+// wordPatches slices diffs into the hunks of patches by their range in the
+// new text b, so the text is rendered as is instead of being parsed back out
+// of the escaped patch format. Ranges are widened to whole runes.
+func wordPatches(patches []dmp.Patch, diffs []dmp.Diff, b string) []wordPatch {
+	var res []wordPatch
+	claimed := make(map[int]bool)
 	for _, patch := range patches {
-		lines := strings.Split(patch.String(), nl)
-		for _, line := range lines {
-			if len(line) == 0 {
-				fmt.Fprintln(w)
+		start, end := patch.Start2, patch.Start2+patch.Length2
+		for start > 0 && start < len(b) && !utf8.RuneStart(b[start]) {
+			start--
+		}
+		for end < len(b) && !utf8.RuneStart(b[end]) {
+			end++
+		}
+
+		wp := wordPatch{header: patchHeader(patch)}
+		pos := 0
+		for i, diff := range diffs {
+			if diff.Type == dmp.DiffDelete {
+				if pos >= start && pos <= end && !claimed[i] {
+					wp.diffs = append(wp.diffs, diff)
+					claimed[i] = true
+				}
 				continue
 			}
-			prefix := line[0]
-			switch prefix {
-			case '+', '-':
-				line = strings.TrimSuffix(line, nle)
-				difflines := strings.Split(string(line[1:]), nle)
-				for _, diffline := range difflines {
-					diffline = unescaper.Replace(diffline)
-					switch prefix {
-					case '-':
-						red.Fprintf(w, "-%s\n", diffline)
-					case '+':
-						green.Fprintf(w, "+%s\n", diffline)
-					default:
-						fmt.Fprintf(w, "ERROR: unknown prefix %v", prefix)
-						return
-					}
-				}
-
-			default:
-				line = unescaper.Replace(line)
-				fmt.Fprintln(w, line)
+			s, e := pos, pos+len(diff.Text)
+			if s < start {
+				s = start
 			}
+			if e > end {
+				e = end
+			}
+			if s < e {
+				wp.diffs = append(wp.diffs, dmp.Diff{Type: diff.Type, Text: diff.Text[s-pos : e-pos]})
+			}
+			pos += len(diff.Text)
+		}
+		res = append(res, wp)
+	}
+	return res
+}
+
+// patchHeader formats the @@ line of a patch the way diffmatchpatch does
+func patchHeader(p dmp.Patch) string {
+	coords := func(start, length int) string {
+		switch length {
+		case 0:
+			return fmt.Sprintf("%d,0", start)
+		case 1:
+			return fmt.Sprintf("%d", start+1)
+		default:
+			return fmt.Sprintf("%d,%d", start+1, length)
 		}
 	}
+	return fmt.Sprintf("@@ -%s +%s @@\n", coords(p.Start1, p.Length1), coords(p.Start2, p.Length2))
 }
 
 type unifiedDiff struct {
-	a string
-	b string
+	a    string
+	b    string
+	opts *options
+
+	once  sync.Once
+	ops   []lineOp
+	cache []hunk
 }
 
 func (d *unifiedDiff) Print() {
-	d.diff(os.Stdout)
-	fmt.Println()
+	printDiff(d.opts, d.diff)
 }
 
 func (d *unifiedDiff) String() string {
 	var buf bytes.Buffer
-	d.diff(&buf)
+	d.diff(&buf, d.opts.palette(nil))
 	return buf.String()
 }
 
 func (d *unifiedDiff) WriteTo(w io.Writer) (int64, error) {
-	if b, ok := w.(*bytes.Buffer); ok {
-		d.diff(b)
-		return int64(b.Len()), nil
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *unifiedDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *unifiedDiff) HTML(w io.Writer, layout Layout) error {
+	return writeHTML(w, func(w io.Writer) {
+		d.rows(w, layout)
+	})
+}
+
+// rows writes the table rows of HTML
+func (d *unifiedDiff) rows(w io.Writer, layout Layout) {
+	htmlHunks(w, d.hunks(), layout, d.opts, d.opts.syntax(d.a, d.b))
+}
+
+func (d *unifiedDiff) Hunks() []Hunk {
+	return lineHunks(d.hunks())
+}
+
+func (d *unifiedDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *unifiedDiff) Equal() bool {
+	return d.Stats().changed() == 0
+}
+
+func (d *unifiedDiff) Error() error {
+	return nil
+}
+
+func (d *unifiedDiff) Stats() Stats {
+	d.compute()
+	return lineStats(d.ops)
+}
+
+func (d *unifiedDiff) hunks() []hunk {
+	d.compute()
+	return d.cache
+}
+
+// compute diffs the lines once, every rendering shares the result
+func (d *unifiedDiff) compute() {
+	d.once.Do(func() {
+		d.ops = diffLines(d.a, d.b, d.opts.budget())
+		d.cache = labelHunks(d.ops, makeHunks(d.ops, d.opts.contextLines), d.opts)
+	})
+}
+
+func (d *unifiedDiff) coarse() Differ {
+	c := &unifiedDiff{a: d.a, b: d.b, opts: d.opts}
+	c.once.Do(func() {
+		c.ops = coarseLines(splitLines(d.a), splitLines(d.b))
+		c.cache = labelHunks(c.ops, makeHunks(c.ops, d.opts.contextLines), d.opts)
+	})
+	return c
+}
+
+func (d *unifiedDiff) diff(w io.Writer, p *palette) {
+	var s *syntax
+	if p.color {
+		s = d.opts.syntax(d.a, d.b)
 	}
+	writeHunks(w, p, d.hunks(), d.opts, s)
+}
 
+// printDiff renders a diff and a newline for os.Stdout and writes them in
+// one write, so diffs printed by parallel tests don't interleave. Diffs
+// taller than the terminal are shown in the pager.
+func printDiff(o *options, render func(w io.Writer, p *palette)) {
 	var buf bytes.Buffer
-	d.diff(&buf)
-	return buf.WriteTo(w)
+	render(&buf, o.palette(os.Stdout))
+	buf.WriteString(nl)
+	if o.page(buf.Bytes()) {
+		return
+	}
+	os.Stdout.Write(buf.Bytes())
+}
+
+// writeTo streams what render writes to w, buffered so the many small
+// writes of a render don't each reach w, and counts the bytes written. A
+// SyncWriter gets the whole render in one write.
+func writeTo(w io.Writer, render func(w io.Writer)) (int64, error) {
+	cw := &countWriter{w: w}
+	switch w.(type) {
+	case *bytes.Buffer:
+		render(cw)
+		return cw.n, cw.err
+	case *SyncWriter:
+		var buf bytes.Buffer
+		render(&buf)
+		cw.Write(buf.Bytes())
+		return cw.n, cw.err
+	}
+	bw := bufio.NewWriter(cw)
+	render(bw)
+	err := bw.Flush()
+	return cw.n, firstError(cw.err, err)
 }
 
-func (d *unifiedDiff) diff(w io.Writer) {
-	gd := dmp.New()
-	a, b, lineArray := gd.DiffLinesToRunes(d.a, d.b)
-	diffs := gd.DiffMainRunes(a, b, false)
-	diffs = gd.DiffCharsToLines(diffs, lineArray)
-	diffs = gd.DiffCleanupSemantic(diffs)
+// countWriter counts the bytes written to w and keeps the first error,
+// later writes are dropped
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
 
-	patches := gd.PatchMake(diffs)
-	for _, patch := range patches {
-		lines := strings.Split(patch.String(), nl)
-		for _, line := range lines {
-			if len(line) == 0 {
-				fmt.Fprintln(w)
-				continue
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// hunkHeader formats the @@ line of a hunk, followed by its label if any
+func hunkHeader(h hunk) string {
+	header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.oldStart, h.oldLines), hunkRange(h.newStart, h.newLines))
+	if h.label != "" {
+		header += " " + h.label
+	}
+	return header
+}
+
+// writeHunks renders line hunks in unified format, highlighting the lines
+// with s if it isn't nil
+func writeHunks(w io.Writer, p *palette, hunks []hunk, o *options, s *syntax) {
+	if len(hunks) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", o.labelA, o.labelB)
+	lim := o.limits()
+	width := 0
+	if o.lineNumbers {
+		width = gutterWidth(hunks)
+	}
+	fit := o.lineFit()
+	show := o.lineEndings == LineEndingsShow
+	buffered := fit != nil || show || o.showWhitespace
+	var lb bytes.Buffer
+	indent := 0
+	if width > 0 {
+		indent = 2*width + 4
+	}
+	if fit != nil {
+		defer fit.note(w, p)
+	}
+	for hi, h := range hunks {
+		if !lim.hunk() {
+			p.dim.Fprintln(w, summary(len(hunks)-hi, "hunk", changedLines(hunks[hi:])))
+			return
+		}
+		fmt.Fprintln(w, hunkHeader(h))
+		inline := inlineDiffs(h.ops, o)
+		oldNo, newNo := h.oldStart, h.newStart
+		for i, l := range h.ops {
+			if !lim.line() {
+				rest := append([]hunk{{ops: h.ops[i:]}}, hunks[hi+1:]...)
+				p.dim.Fprintln(w, summary(len(hunks)-hi-1, "hunk", changedLines(rest)))
+				return
+			}
+			out := w
+			if buffered {
+				lb.Reset()
+				out = &lb
+			}
+			if width > 0 {
+				writeGutter(out, p, l.op, oldNo, newNo, width)
+			}
+			n := oldNo
+			if l.op == dmp.DiffInsert {
+				n = newNo
 			}
-			prefix := line[0]
-			switch prefix {
-			case '+', '-':
-				line = strings.TrimSuffix(line, nle)
-				difflines := strings.Split(string(line[1:]), nle)
-				for _, diffline := range difflines {
-					diffline = unescaper.Replace(diffline)
-					switch prefix {
-					case '-':
-						red.Fprintf(w, "-%s\n", diffline)
-					case '+':
-						green.Fprintf(w, "+%s\n", diffline)
-					default:
-						fmt.Fprintf(w, "ERROR: unknown prefix %v", prefix)
-						return
-					}
+			if l.op != dmp.DiffInsert {
+				oldNo++
+			}
+			if l.op != dmp.DiffDelete {
+				newNo++
+			}
+			line := strings.TrimSuffix(l.text, nl)
+			switch {
+			case l.ignored:
+				p.dim.Fprintf(out, "%c%s\n", linePrefix(l.op), line)
+			case l.moved:
+				p.mov.Fprintf(out, "%c%s\n", linePrefix(l.op), line)
+			case s != nil && l.op == dmp.DiffEqual:
+				io.WriteString(out, " ")
+				writeSyntax(out, s.segments(l, n, nil), l.op)
+				fmt.Fprintln(out)
+			case s != nil && l.op == dmp.DiffDelete:
+				p.del.Fprint(out, "-")
+				writeSyntax(out, s.segments(l, n, inline[i]), l.op)
+				fmt.Fprintln(out)
+			case s != nil:
+				p.ins.Fprint(out, "+")
+				writeSyntax(out, s.segments(l, n, inline[i]), l.op)
+				fmt.Fprintln(out)
+			case inline[i] != nil && l.op == dmp.DiffDelete:
+				p.del.Fprint(out, "-")
+				writeInline(out, inline[i], dmp.DiffDelete, p.del, p.delSpan)
+				fmt.Fprintln(out)
+			case inline[i] != nil && l.op == dmp.DiffInsert:
+				p.ins.Fprint(out, "+")
+				writeInline(out, inline[i], dmp.DiffInsert, p.ins, p.insSpan)
+				fmt.Fprintln(out)
+			case l.op == dmp.DiffDelete:
+				p.del.Fprintf(out, "-%s\n", line)
+			case l.op == dmp.DiffInsert:
+				p.ins.Fprintf(out, "+%s\n", line)
+			default:
+				p.ctx.Fprintf(out, " %s\n", line)
+			}
+			if buffered {
+				text := lb.String()
+				if show {
+					text = showEndings.Replace(text)
+				}
+				if o.showWhitespace && l.op != dmp.DiffEqual {
+					text = showWhitespace(text)
 				}
+				if fit != nil {
+					fit.write(w, text, strings.Repeat(" ", indent)+string(linePrefix(l.op)))
+				} else {
+					io.WriteString(w, text)
+				}
+			}
+			if !strings.HasSuffix(l.text, nl) {
+				if width > 0 {
+					p.dim.Fprint(w, strings.Repeat(" ", 2*width+1)+" | ")
+				}
+				fmt.Fprintln(w, noNewline)
+			}
+		}
+	}
+}
 
-			default:
-				line = unescaper.Replace(line)
-				fmt.Fprintln(w, line)
+// gutterWidth returns the digits needed for the largest line number in
+// hunks
+func gutterWidth(hunks []hunk) int {
+	last := 0
+	for _, h := range hunks {
+		last = maxInt(last, maxInt(h.oldStart+h.oldLines, h.newStart+h.newLines))
+	}
+	return len(strconv.Itoa(last))
+}
+
+// writeGutter writes the old and new line numbers of a line, left blank for
+// the side the line isn't on
+func writeGutter(w io.Writer, p *palette, op dmp.Operation, oldNo, newNo, width int) {
+	a, b := strconv.Itoa(oldNo), strconv.Itoa(newNo)
+	switch op {
+	case dmp.DiffDelete:
+		b = ""
+	case dmp.DiffInsert:
+		a = ""
+	}
+	p.dim.Fprintf(w, "%*s %*s | ", width, a, width, b)
+}
+
+func changedLines(hunks []hunk) int {
+	n := 0
+	for _, h := range hunks {
+		for _, l := range h.ops {
+			if l.isChange() {
+				n++
 			}
 		}
 	}
+	return n
+}
+
+// writeInline writes one side of a replaced line, highlighting the spans
+// that were changed by op
+func writeInline(w io.Writer, diffs []dmp.Diff, op dmp.Operation, line, span printer) {
+	for _, diff := range diffs {
+		if diff.Text == "" {
+			continue
+		}
+		switch diff.Type {
+		case dmp.DiffEqual:
+			line.Fprint(w, diff.Text)
+		case op:
+			span.Fprint(w, diff.Text)
+		}
+	}
+}
+
+func linePrefix(op dmp.Operation) byte {
+	switch op {
+	case dmp.DiffDelete:
+		return '-'
+	case dmp.DiffInsert:
+		return '+'
+	default:
+		return ' '
+	}
+}
+
+// hunkRange formats a hunk range like GNU diff: the count is omitted when
+// it is 1, and an empty range starts at the line before it
+func hunkRange(start, lines int) string {
+	switch lines {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, lines)
+	}
 }
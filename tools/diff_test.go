@@ -1,11 +1,19 @@
 package tools
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/prasek/loupe/internal"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/unicode/norm"
 )
 
 func TestDiff(t *testing.T) {
@@ -36,3 +44,387 @@ func TestDiff(t *testing.T) {
 		}
 	}
 }
+
+func TestDiffOptions(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\n"
+	b := "a\nb\nc\nD\ne\nf\ng\n"
+
+	d := Diff(a, b, WithNoColor(), WithContextLines(1)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -3,3 +3,3 @@\n c\n-d\n+D\n e\n", d)
+
+	d = Diff(a, b, WithNoColor(), WithContextLines(0)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -4 +4 @@\n-d\n+D\n", d)
+
+	d = Diff("abc", "abd", WithNoColor(), WithMode(LineMode), WithLabels("x.txt", "y.txt")).String()
+	assert.Equal(t, "--- x.txt\n+++ y.txt\n@@ -1 +1 @@\n-abc\n\\ No newline at end of file\n+abd\n\\ No newline at end of file\n", d)
+
+	d = Diff("", "a\nb\n", WithNoColor(), WithMode(LineMode)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n", d)
+
+	// patience anchors on the unique func and return lines
+	a = "func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 2\n}\n"
+	b = "func a() {\n\treturn 1\n}\n\nfunc c() {\n\treturn 3\n}\n\nfunc b() {\n\treturn 2\n}\n"
+	d = Diff(a, b, WithNoColor(), WithAlgorithm(Patience), WithContextLines(0)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -4,0 +5,4 @@\n+func c() {\n+\treturn 3\n+}\n+\n", d)
+
+	d = Diff(a, b, WithNoColor(), WithAlgorithm(Histogram), WithContextLines(0)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -4,0 +5,4 @@\n+func c() {\n+\treturn 3\n+}\n+\n", d)
+
+	// histogram still anchors on lines that occur more than once
+	a = "if x {\n\treturn\n}\nif y {\n\treturn\n}\n"
+	b = "if y {\n\treturn\n}\nif x {\n\treturn\n}\nif y {\n\treturn\n}\n"
+	d = Diff(a, b, WithNoColor(), WithAlgorithm(Histogram), WithContextLines(0)).String()
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,3 @@\n+if y {\n+\treturn\n+}\n", d)
+}
+
+func TestDiffIgnore(t *testing.T) {
+	a := "Subject: hi\nDate: Mon\nbody\n"
+	b := "Subject: hi\nDate: Tue\nbody\n"
+	d := Diff(a, b, WithNoColor(), IgnoreLines(`^Date:`))
+	assert.Equal(t, "", d.String())
+
+	b = "Subject: hello\nDate: Tue\nbody\n"
+	d = Diff(a, b, WithNoColor(), IgnoreLines(`^Date:`))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n-Subject: hi\n+Subject: hello\n-Date: Mon\n+Date: Tue\n body\n", d.String())
+	edits := d.Hunks()[0].Edits
+	assert.Len(t, edits, 5)
+	assert.False(t, edits[0].Ignored)
+	assert.True(t, edits[2].Ignored)
+	assert.True(t, edits[3].Ignored)
+
+	x := "metadata:\n  name: a\n  uid: 1\nitems:\n- uid: 2\n"
+	y := "metadata:\n  name: b\n  uid: 3\nitems:\n- uid: 4\n"
+	d = DiffYAML(x, y, WithNoColor(), IgnorePath("metadata.uid", "items[*].uid"))
+	assert.Equal(t, "metadata.name:\n-a\n+b\nmetadata.uid:\n-1\n+3\nitems[0].uid:\n-2\n+4\n", d.String())
+	var ignored []string
+	for _, h := range d.Hunks() {
+		if h.Edits[0].Ignored {
+			ignored = append(ignored, h.Path)
+		}
+	}
+	assert.Equal(t, []string{"metadata.uid", "items[0].uid"}, ignored)
+
+	d = DiffJSON([]byte(`{"id":1,"name":"a"}`), []byte(`{"id":2,"name":"a"}`), IgnorePath("/id"))
+	assert.True(t, d.Hunks()[0].Edits[0].Ignored)
+
+	// a prefix only matches whole path elements
+	d = DiffJSON([]byte(`{"idx":1}`), []byte(`{"idx":2}`), IgnorePath("/id"))
+	assert.False(t, d.Hunks()[0].Edits[0].Ignored)
+}
+
+func TestDiffWhitespace(t *testing.T) {
+	a := "if x {\n\treturn  1\n}\n"
+	b := "if x {\n    return 1 \n}\n"
+
+	tests := []struct {
+		opt   Option
+		a, b  string
+		equal bool
+	}{
+		{IgnoreAllSpace(), a, b, true},
+		{IgnoreAllSpace(), "a b\n", "ab\n", true},
+		{IgnoreSpaceChange(), a, b, true},
+		{IgnoreSpaceChange(), "a  b \n", "a\tb\n", true},
+		{IgnoreSpaceChange(), "a b\n", "ab\n", false},
+		{IgnoreTrailingWhitespace(), "a \t\nb\n", "a\nb\n", true},
+		{IgnoreTrailingWhitespace(), "a\n", " a\n", false},
+		{IgnoreBlankLines(), "a\nb\n", "a\n\n  \nb\n", true},
+		{IgnoreBlankLines(), "a\nb\n", "a\n\nc\n", false},
+		{IgnoreLineEndings(), "a\r\nb\r\n", "a\nb\n", true},
+		{IgnoreLineEndings(), "a \r\n", "a\n", false},
+	}
+	for _, tt := range tests {
+		d := Diff(tt.a, tt.b, tt.opt, WithMode(LineMode))
+		assert.Equal(t, tt.equal, d.Equal(), "%q %q", tt.a, tt.b)
+	}
+
+	// whitespace only differences are rendered as context with the old text
+	d := Diff(a, "if y {\n    return 1 \n}\n", WithNoColor(), IgnoreAllSpace())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n-if x {\n+if y {\n \treturn  1\n }\n", d.String())
+
+	// ignored blank lines are dimmed
+	d = Diff("a\nb\n", "a\n\nb\n", WithNoColor(), IgnoreBlankLines())
+	assert.Equal(t, Stats{Equal: 2, Ignored: 1}, d.Stats())
+
+	d = DiffReaders(strings.NewReader(a), strings.NewReader(b), IgnoreAllSpace())
+	assert.True(t, d.Equal())
+}
+
+func TestDiffFold(t *testing.T) {
+	nfc, nfd := "caf\u00e9.txt", "cafe\u0301.txt"
+	assert.False(t, Diff(nfc, nfd).Equal())
+	assert.True(t, Diff(nfc, nfd, WithNormalization(norm.NFC)).Equal())
+	assert.True(t, Diff("\ufb01le", "file", WithNormalization(norm.NFKC)).Equal())
+	assert.False(t, Diff("\ufb01le", "file", WithNormalization(norm.NFC)).Equal())
+
+	assert.True(t, Diff("Hello World", "hello world", IgnoreCase()).Equal())
+	assert.True(t, Diff("Stra\u00dfe", "STRASSE", IgnoreCase()).Equal())
+	assert.False(t, Diff("Hello", "Help", IgnoreCase()).Equal())
+
+	// line diffs render the old text of lines that only differ by case
+	d := Diff("A\nb\n", "a\nc\n", WithNoColor(), IgnoreCase())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n A\n-b\n+c\n", d.String())
+}
+
+func TestDiffWordPatches(t *testing.T) {
+	a := "path a%2Fb is 100% \"done\" for caf\u00e9"
+	b := "path a%2Fc is 50% \"done\" for cafe"
+
+	// patches are rendered from the diffs, escapes in the text are kept as is
+	d := Diff(a, b, WithNoColor())
+	assert.True(t, strings.HasSuffix(d.String(), "@@ -6,15 +6,14 @@\n a%2F\n-b\n+c\n  is \n-10\n+5\n 0% \"\n\n"+
+		"@@ -29,6 +29,5 @@\n  caf\n-\u00e9\n+e\n\n"))
+
+	d = Diff(a, b, WithNoColor(), WithEscapedPatches())
+	assert.True(t, strings.HasSuffix(d.String(), "@@ -6,15 +6,14 @@\n a%252F\n-b\n+c\n  is \n-10\n+5\n 0%25 %22\n"+
+		"@@ -29,6 +29,5 @@\n  caf\n-%C3%A9\n+e\n"))
+
+	d = Diff("a\tb", "a\tc", WithNoColor())
+	assert.Contains(t, d.String(), " a^I\n-b\n+c\n")
+}
+
+func TestDiffInline(t *testing.T) {
+	a := "a\nthe quick brown fox\nc\n"
+	b := "a\nthe quick red fox\nc\n"
+
+	// highlighting only adds color, the text stays a valid patch
+	d := Diff(a, b, WithNoColor())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-the quick brown fox\n+the quick red fox\n c\n", d.String())
+
+	out := Diff(a, b, WithColor(ColorAlways)).String()
+	p := newPalette(true, Theme{})
+	assert.Contains(t, out, p.del.Sprint("the quick ")+p.delSpan.Sprint("brown")+p.del.Sprint(" fox"))
+	assert.Contains(t, out, p.ins.Sprint("the quick ")+p.insSpan.Sprint("red")+p.ins.Sprint(" fox"))
+
+	// unequal runs are not paired
+	out = Diff(a, "a\nthe quick red fox\nb\nc\n", WithColor(ColorAlways)).String()
+	assert.Contains(t, out, p.del.Sprint("-the quick brown fox\n"))
+}
+
+func TestDiffEqual(t *testing.T) {
+	tests := []struct {
+		d     Differ
+		equal bool
+	}{
+		{Diff("abc", "abc"), true},
+		{Diff("abc", "abd"), false},
+		{Diff("a\nb\n", "a\nb\n"), true},
+		{Diff("a\nb\n", "a\nc\n"), false},
+		{Diff("Date: 1\n", "Date: 2\n", IgnoreLines(`^Date:`)), true},
+		{DiffValues([]int{1}, []int{1}), true},
+		{DiffValues([]int{1}, []int{2}), false},
+		{DiffJSON([]byte(`{"id":1}`), []byte(`{"id":2}`), IgnorePath("/id")), true},
+		{DiffBinary([]byte{0}, []byte{1}), false},
+		{DiffReaders(strings.NewReader("a\n"), strings.NewReader("a\n")), true},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.equal, test.d.Equal(), "test %d", i)
+		assert.NoError(t, test.d.Error(), "test %d", i)
+	}
+
+	d := DiffJSON([]byte(`{`), []byte(`{}`))
+	assert.False(t, d.Equal())
+	assert.Error(t, d.Error())
+
+	d = DiffYAML("a: [", "a: 1")
+	assert.Error(t, d.Error())
+
+	d = DiffReaders(strings.NewReader("a\n"), errReader{})
+	assert.False(t, d.Equal())
+	assert.EqualError(t, d.Error(), "boom")
+}
+
+func TestDiffColor(t *testing.T) {
+	colored := func(d Differ) bool {
+		return regExColor.MatchString(d.String())
+	}
+
+	assert.True(t, colored(Diff("aaabbb", "aaaccc", WithColor(ColorAlways))))
+	assert.False(t, colored(Diff("aaabbb", "aaaccc", WithNoColor())))
+
+	ForceColor()
+	assert.True(t, colored(Diff("aaabbb", "aaaccc")))
+	assert.False(t, colored(Diff("aaabbb", "aaaccc", WithNoColor())))
+
+	DisableColor()
+	assert.False(t, colored(Diff("aaabbb", "aaaccc")))
+	assert.True(t, colored(DiffValues(1, 2, WithColor(ColorAlways))))
+
+	AutoColor()
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	assert.False(t, colored(Diff("aaabbb", "aaaccc")))
+}
+
+func TestDiffTheme(t *testing.T) {
+	// unequal runs of lines are not highlighted inline
+	a, b := "a\nb\n", "a\nc\nd\n"
+	blue := color.New(color.FgBlue)
+	blue.EnableColor()
+	yellow := color.New(color.FgYellow)
+	yellow.EnableColor()
+
+	out := Diff(a, b, WithColor(ColorAlways), WithTheme(Theme{Insert: blue, Delete: yellow})).String()
+	assert.Contains(t, out, yellow.Sprint("-b\n"))
+	assert.Contains(t, out, blue.Sprint("+c\n"))
+	assert.Contains(t, out, "\n a\n")
+
+	// the theme doesn't enable color on its own
+	out = Diff(a, b, WithNoColor(), WithTheme(Theme{Insert: blue, Delete: yellow})).String()
+	assert.False(t, regExColor.MatchString(out))
+
+	SetTheme(Theme{Context: blue})
+	defer SetTheme(Theme{})
+	p := newPalette(true, Theme{})
+	out = Diff(a, b, WithColor(ColorAlways)).String()
+	assert.Contains(t, out, blue.Sprint(" a\n"))
+	assert.Contains(t, out, p.del.Sprint("-b\n"))
+
+	// a Differ's own theme replaces the global one
+	out = Diff(a, b, WithColor(ColorAlways), WithTheme(BlueYellow)).String()
+	assert.Contains(t, out, "\n a\n")
+	assert.Contains(t, out, newPalette(true, BlueYellow).ins.Sprint("+c\n"))
+}
+
+func TestDiffHunkLabels(t *testing.T) {
+	a := `package main
+
+func first() {
+	a := 1
+	b := 2
+	c := 3
+	d := 4
+	return a + b + c + d
+}
+
+func second() {
+	x := 1
+	y := 2
+	z := 3
+	return x
+}
+`
+	b := strings.Replace(strings.Replace(a, "d := 4", "d := 5", 1), "return x", "return x + y + z", 1)
+
+	d := Diff(a, b, WithNoColor(), WithContextLines(1), WithHunkLabels(`^func `))
+	assert.Equal(t, "--- a\n+++ b\n"+
+		"@@ -6,3 +6,3 @@ func first() {\n \tc := 3\n-\td := 4\n+\td := 5\n \treturn a + b + c + d\n"+
+		"@@ -14,3 +14,3 @@ func second() {\n \tz := 3\n-\treturn x\n+\treturn x + y + z\n }\n", d.String())
+
+	hunks := d.Hunks()
+	assert.Equal(t, "func first() {", hunks[0].Label)
+	assert.Equal(t, "func second() {", hunks[1].Label)
+
+	var buf bytes.Buffer
+	assert.NoError(t, d.HTML(&buf, Inline))
+	assert.Contains(t, buf.String(), "@@ -14,3 +14,3 @@ func second() {</td>")
+
+	// the label must come before the hunk, not from its context
+	src := "func a() {\n\treturn\n}\nfunc b() {\n\tx()\n}\n"
+	d = Diff(src, strings.Replace(src, "x()", "y()", 1), WithNoColor(), WithContextLines(1), WithHunkLabels(`^func `))
+	assert.Equal(t, "func a() {", d.Hunks()[0].Label)
+
+	// ini sections, labels are truncated to 80 bytes
+	ini := "[" + strings.Repeat("x", 100) + "]\nkey = 1\n"
+	d = Diff(ini, strings.Replace(ini, "1", "2", 1), WithNoColor(), WithContextLines(0), WithHunkLabels(`^\[.*\]`))
+	assert.Equal(t, "["+strings.Repeat("x", 79), d.Hunks()[0].Label)
+
+	d = DiffReaders(strings.NewReader(a), strings.NewReader(b), WithNoColor(), WithContextLines(1), WithHunkLabels(`^func `))
+	assert.Equal(t, Diff(a, b, WithNoColor(), WithContextLines(1), WithHunkLabels(`^func `)).String(), d.String())
+}
+
+type errWriter struct {
+	n int
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.n+len(p) > 10 {
+		return 0, errors.New("disk full")
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestDiffWriteTo(t *testing.T) {
+	d := Diff("a\nb\nc\n", "a\nx\nc\n", WithNoColor())
+	want := d.String()
+
+	buf := bytes.NewBufferString("prefix\n")
+	n, err := d.WriteTo(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(want)), n)
+	assert.Equal(t, "prefix\n"+want, buf.String())
+
+	var sb strings.Builder
+	n, err = d.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(want)), n)
+	assert.Equal(t, want, sb.String())
+
+	n, err = d.WriteTo(&errWriter{})
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, int64(0), n)
+}
+
+func largeInputs(lines int) (string, string) {
+	var a, b strings.Builder
+	for i := 0; i < lines; i++ {
+		line := fmt.Sprintf("line %d of a large input with some padding to make it longer\n", i)
+		a.WriteString(line)
+		if i%100 == 0 {
+			line = fmt.Sprintf("line %d changed\n", i)
+		}
+		b.WriteString(line)
+	}
+	return a.String(), b.String()
+}
+
+// about 6MB per side
+func BenchmarkDiffWriteTo(b *testing.B) {
+	x, y := largeInputs(100000)
+	d := Diff(x, y, WithNoColor())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.WriteTo(ioutil.Discard)
+	}
+}
+
+func BenchmarkDiffStringTwice(b *testing.B) {
+	x, y := largeInputs(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := Diff(x, y, WithNoColor())
+		_ = d.String()
+		_ = d.String()
+	}
+}
+
+func TestWithLineNumbers(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nb\nc\nd\ne\nf\ng\nh\nI\nj"
+	assert.Equal(t, "--- a\n+++ b\n@@ -6,5 +6,5 @@\n"+
+		" 6  6 |  f\n"+
+		" 7  7 |  g\n"+
+		" 8  8 |  h\n"+
+		" 9    | -i\n"+
+		"10    | -j\n"+
+		"    9 | +I\n"+
+		"   10 | +j\n"+
+		"      | \\ No newline at end of file\n",
+		Diff(a, b, WithNoColor(), WithLineNumbers()).String())
+
+	p := newPalette(true, globalTheme())
+	d := Diff(a, b, WithColor(ColorAlways), WithLineNumbers()).String()
+	assert.Contains(t, d, p.dim.Sprint(" 9    | ")+p.del.Sprint("-"))
+}
+
+func TestDiffNilStringer(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := Diff((*time.Time)(nil), &now, WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.False(t, d.Equal())
+	assert.Contains(t, d.String(), "-(*time.Time)(nil)")
+	assert.True(t, Diff((*time.Time)(nil), (*time.Time)(nil)).Equal())
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"unicode/utf8"
+)
+
+// fileDiffer is a Differ that can be rendered as part of a directory diff
+type fileDiffer interface {
+	Differ
+	diff(w io.Writer, p *palette)
+	rows(w io.Writer, layout Layout)
+}
+
+type dirFile struct {
+	path string
+	d    fileDiffer
+}
+
+//DiffDirs creates a Differ that compares the regular files below the
+//directories a and b by their slash separated relative path, like git diff
+//--no-index. Each changed file is line diffed with a/<path> and b/<path>
+//labels, files only present on one side are diffed against /dev/null and
+//binary files are compared with DiffBinary. Hunks are reported with the
+//file path, and read errors are returned by Error.
+func DiffDirs(a, b string, opts ...Option) Differ {
+	return DiffFS(os.DirFS(a), os.DirFS(b), opts...)
+}
+
+//DiffFS creates a Differ that compares the regular files of the file
+//systems a and b like DiffDirs, e.g. an embed.FS or an in-memory fs.FS
+func DiffFS(a, b fs.FS, opts ...Option) Differ {
+	d := &dirDiff{opts: newOptions(opts)}
+	filesA, errA := dirFiles(a)
+	filesB, errB := dirFiles(b)
+	if d.err = firstError(errA, errB); d.err != nil {
+		return d
+	}
+
+	paths := make([]string, 0, len(filesA)+len(filesB))
+	for p := range filesA {
+		paths = append(paths, p)
+	}
+	for p := range filesB {
+		if !filesA[p] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		textA, labelA, errA := readDirFile(a, p, filesA[p], "a/")
+		textB, labelB, errB := readDirFile(b, p, filesB[p], "b/")
+		if err := firstError(errA, errB); err != nil {
+			d.err = err
+			return d
+		}
+		if bytes.Equal(textA, textB) {
+			continue
+		}
+
+		fo := newOptions(append(append([]Option{}, opts...), WithLabels(labelA, labelB)))
+		var fd fileDiffer
+		if utf8.Valid(textA) && utf8.Valid(textB) {
+			fd = &unifiedDiff{a: string(textA), b: string(textB), opts: fo}
+		} else {
+			fd = &binaryDiff{a: textA, b: textB, opts: fo}
+		}
+		d.files = append(d.files, dirFile{path: p, d: fd})
+	}
+	return d
+}
+
+// readDirFile returns the contents and label of the file p in fsys, or an
+// empty file labeled /dev/null if it only exists on the other side
+func readDirFile(fsys fs.FS, p string, exists bool, prefix string) ([]byte, string, error) {
+	if !exists {
+		return nil, "/dev/null", nil
+	}
+	bs, err := fs.ReadFile(fsys, p)
+	return bs, prefix + p, err
+}
+
+// dirFiles returns the slash separated paths of the regular files in fsys
+func dirFiles(fsys fs.FS) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		files[path] = true
+		return nil
+	})
+	return files, err
+}
+
+type dirDiff struct {
+	files []dirFile
+	opts  *options
+	err   error
+}
+
+func (d *dirDiff) Print() {
+	printDiff(d.opts, d.diff)
+}
+
+func (d *dirDiff) String() string {
+	var buf bytes.Buffer
+	d.diff(&buf, d.opts.palette(nil))
+	return buf.String()
+}
+
+func (d *dirDiff) WriteTo(w io.Writer) (int64, error) {
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *dirDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *dirDiff) HTML(w io.Writer, layout Layout) error {
+	cols := 3
+	if layout == SideBySide {
+		cols = 4
+	}
+	return writeHTML(w, func(w io.Writer) {
+		for _, f := range d.files {
+			fmt.Fprintf(w, "<tr class=\"path\"><td colspan=\"%d\">%s</td></tr>\n", cols, html.EscapeString(f.path))
+			f.d.rows(w, layout)
+		}
+	})
+}
+
+func (d *dirDiff) Hunks() []Hunk {
+	var hunks []Hunk
+	for _, f := range d.files {
+		for _, h := range f.d.Hunks() {
+			h.Path = f.path
+			hunks = append(hunks, h)
+		}
+	}
+	return hunks
+}
+
+func (d *dirDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *dirDiff) Equal() bool {
+	return d.err == nil && d.Stats().changed() == 0
+}
+
+func (d *dirDiff) Error() error {
+	return d.err
+}
+
+//Stats sums the line stats of the changed files, binary files count bytes
+func (d *dirDiff) Stats() Stats {
+	var s Stats
+	for _, f := range d.files {
+		fs := f.d.Stats()
+		s.Inserts += fs.Inserts
+		s.Deletes += fs.Deletes
+		s.Equal += fs.Equal
+		s.Ignored += fs.Ignored
+		s.Moved += fs.Moved
+	}
+	return s
+}
+
+func (d *dirDiff) diff(w io.Writer, p *palette) {
+	if d.err != nil {
+		fmt.Fprintf(w, "ERROR: read failed: %v\n", d.err)
+		return
+	}
+	for _, f := range d.files {
+		f.d.diff(w, p)
+	}
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "dirs")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(root)
+
+	write := func(path, content string) {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0666))
+	}
+	write("a/same.txt", "x\n")
+	write("b/same.txt", "x\n")
+	write("a/sub/changed.txt", "a\nb\n")
+	write("b/sub/changed.txt", "a\nc\n")
+	write("a/removed.txt", "gone\n")
+	write("b/added.txt", "new\n")
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+
+	d := DiffDirs(a, b, WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.False(t, d.Equal())
+	assert.Equal(t, "--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1 @@\n+new\n"+
+		"--- a/removed.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-gone\n"+
+		"--- a/sub/changed.txt\n+++ b/sub/changed.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n", d.String())
+	assert.Equal(t, Stats{Inserts: 2, Deletes: 2, Equal: 1}, d.Stats())
+
+	hunks := d.Hunks()
+	assert.Len(t, hunks, 3)
+	assert.Equal(t, "sub/changed.txt", hunks[2].Path)
+
+	assert.True(t, DiffDirs(a, a).Equal())
+	assert.True(t, DiffDirs(a, b, IgnoreLines(`.*`)).Equal())
+
+	d = DiffDirs(a, filepath.Join(root, "missing"))
+	assert.Error(t, d.Error())
+	assert.False(t, d.Equal())
+}
+
+func TestDiffFS(t *testing.T) {
+	a := fstest.MapFS{
+		"go.mod":      {Data: []byte("module x\n")},
+		"cmd/main.go": {Data: []byte("package main\n")},
+	}
+	b := fstest.MapFS{
+		"go.mod":      {Data: []byte("module y\n")},
+		"cmd/main.go": {Data: []byte("package main\n")},
+		"bin":         {Data: []byte{0xff, 0}},
+	}
+
+	d := DiffFS(a, b, WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.Equal(t, "--- /dev/null\n+++ b/bin\n@@ -00000000 +00000000 @@\n+00000000  ff 00                                             |..|\n"+
+		"--- a/go.mod\n+++ b/go.mod\n@@ -1 +1 @@\n-module x\n+module y\n", d.String())
+	assert.True(t, DiffFS(a, a).Equal())
+}
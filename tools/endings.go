@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const bom = "\ufeff"
+
+// showEndings makes carriage returns and byte order marks visible for
+// LineEndingsShow
+var showEndings = strings.NewReplacer("\r", "^M", bom, "<BOM>")
+
+//LineEndingError is the Error of a diff with LineEndingsFail whose sides
+//only differ in line endings or byte order marks
+type LineEndingError struct {
+	LabelA string
+	LabelB string
+
+	//A and B describe the line endings of each side, e.g. "CRLF" or
+	//"LF with BOM"
+	A string
+	B string
+}
+
+func (e *LineEndingError) Error() string {
+	return fmt.Sprintf("%s and %s only differ in line endings: %s has %s, %s has %s", e.LabelA, e.LabelB, e.LabelA, e.A, e.LabelB, e.B)
+}
+
+// endingDiff is the Differ for LineEndingsFail when only the line endings
+// differ, it renders the error instead of every line changing. Hunks and
+// Stats are those of the diff with LineEndingsShow.
+type endingDiff struct {
+	Differ
+	err  *LineEndingError
+	opts *options
+}
+
+func (d *endingDiff) Print() {
+	printDiff(d.opts, d.diff)
+}
+
+func (d *endingDiff) String() string {
+	var buf bytes.Buffer
+	d.diff(&buf, d.opts.palette(nil))
+	return buf.String()
+}
+
+func (d *endingDiff) WriteTo(w io.Writer) (int64, error) {
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *endingDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *endingDiff) HTML(w io.Writer, layout Layout) error {
+	return writeHTML(w, func(w io.Writer) {
+		fmt.Fprintf(w, "<tr><td>%s</td></tr>\n", html.EscapeString(d.err.Error()))
+	})
+}
+
+func (d *endingDiff) Error() error {
+	return d.err
+}
+
+func (d *endingDiff) diff(w io.Writer, p *palette) {
+	p.mov.Fprintln(w, d.err.Error())
+}
+
+// normalizeEndings removes a leading byte order mark and converts CRLF and
+// CR line endings to LF
+func normalizeEndings(s string) string {
+	s = strings.TrimPrefix(s, bom)
+	return strings.Replace(strings.Replace(s, "\r\n", nl, -1), "\r", nl, -1)
+}
+
+// describeEndings names the line endings used in s, e.g. "CRLF", "mixed
+// CRLF and LF" or "no line endings", with a byte order mark
+func describeEndings(s string) string {
+	crlf := strings.Count(s, "\r\n")
+	lf := strings.Count(s, nl) - crlf
+	cr := strings.Count(s, "\r") - crlf
+	var kinds []string
+	for _, k := range []struct {
+		name string
+		n    int
+	}{{"CRLF", crlf}, {"LF", lf}, {"CR", cr}} {
+		if k.n > 0 {
+			kinds = append(kinds, k.name)
+		}
+	}
+	desc := "no line endings"
+	switch len(kinds) {
+	case 0:
+	case 1:
+		desc = kinds[0]
+	default:
+		desc = "mixed " + strings.Join(kinds[:len(kinds)-1], ", ") + " and " + kinds[len(kinds)-1]
+	}
+	if strings.HasPrefix(s, bom) {
+		desc += " with BOM"
+	}
+	return desc
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLineEndings(t *testing.T) {
+	a := "\ufeffone\r\ntwo\r\n"
+	b := "one\ntwo\n"
+
+	d := Diff(a, b, WithNoColor())
+	assert.False(t, d.Equal())
+
+	d = Diff(a, b, WithNoColor(), WithLineEndings(LineEndingsNormalize))
+	assert.True(t, d.Equal())
+	assert.True(t, Diff("a\rb\r", "a\nb\n", WithLineEndings(LineEndingsNormalize)).Equal())
+
+	d = Diff(a, b, WithNoColor(), WithLineEndings(LineEndingsShow))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-<BOM>one^M\n-two^M\n+one\n+two\n", d.String())
+
+	d = Diff(a, b, WithNoColor(), WithLineEndings(LineEndingsFail), WithLabels("want", "got"))
+	assert.False(t, d.Equal())
+	assert.Equal(t, "want and got only differ in line endings: want has CRLF with BOM, got has LF\n", d.String())
+	if assert.IsType(t, &LineEndingError{}, d.Error()) {
+		assert.Equal(t, "CRLF with BOM", d.Error().(*LineEndingError).A)
+	}
+	assert.Equal(t, 4, d.Stats().Deletes+d.Stats().Inserts)
+
+	// other changes get a normal diff
+	d = Diff(a, "one\nthree\n", WithNoColor(), WithLineEndings(LineEndingsFail))
+	assert.NoError(t, d.Error())
+	assert.Contains(t, d.String(), "+three\n")
+}
+
+func TestDescribeEndings(t *testing.T) {
+	assert.Equal(t, "no line endings", describeEndings("x"))
+	assert.Equal(t, "LF", describeEndings("x\ny\n"))
+	assert.Equal(t, "mixed CRLF, LF and CR", describeEndings("a\r\nb\nc\rd"))
+	assert.Equal(t, "CR with BOM", describeEndings("\ufeffa\r"))
+}
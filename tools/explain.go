@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explainLimit is how many differences Explain describes by default
+const explainLimit = 5
+
+//Explain walks a and b like DiffValues and describes the first differences
+//in a sentence each, e.g. field User.Addresses[2].Zip: "02134" != "02139",
+//for quick triage in structures too big to diff. It returns "" if they're
+//equal. At most 5 differences are described, WithMaxHunks(n) changes it.
+func Explain(a, b interface{}, opts ...Option) string {
+	d := DiffValues(a, b, opts...).(*valueDiff)
+	limit := d.opts.maxHunks
+	if limit <= 0 {
+		limit = explainLimit
+	}
+
+	var lines []string
+	more := 0
+	for _, c := range d.changes {
+		if c.ignored {
+			continue
+		}
+		if len(lines) == limit {
+			more++
+			continue
+		}
+		lines = append(lines, c.explain(d.root))
+	}
+	if more > 0 {
+		lines = append(lines, "and "+plural(more, "more difference"))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, nl) + nl
+}
+
+// explain describes c in a sentence, naming the field unless the values
+// differ at the root
+func (c change) explain(root string) string {
+	subject := c.path
+	if c.path != root {
+		subject = "field " + c.path
+	}
+	switch c.typ {
+	case removed:
+		return fmt.Sprintf("%s: %s is missing", subject, c.exp)
+	case added:
+		return fmt.Sprintf("%s: unexpected %s", subject, c.act)
+	case moved:
+		return fmt.Sprintf("%s: moved from %s", subject, c.exp)
+	}
+	return fmt.Sprintf("%s: %s != %s", subject, c.exp, c.act)
+}
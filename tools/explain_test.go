@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type explainAddress struct {
+	Zip string
+}
+
+type explainUser struct {
+	Name      string
+	Addresses []explainAddress
+	Tags      map[string]int
+}
+
+func TestExplain(t *testing.T) {
+	a := explainUser{
+		Name:      "ann",
+		Addresses: []explainAddress{{"10001"}, {"60601"}, {"02134"}},
+		Tags:      map[string]int{"a": 1, "b": 2},
+	}
+	b := explainUser{
+		Name:      "ann",
+		Addresses: []explainAddress{{"10001"}, {"60601"}, {"02139"}, {"94105"}},
+		Tags:      map[string]int{"a": 1, "c": 3},
+	}
+	assert.Equal(t, `field explainUser.Addresses[2].Zip: "02134" != "02139"
+field explainUser.Addresses[3]: unexpected tools.explainAddress{Zip:"94105"}
+field explainUser.Tags["b"]: 2 is missing
+field explainUser.Tags["c"]: unexpected 3
+`, Explain(a, b))
+
+	assert.Equal(t, "field explainUser.Addresses[2].Zip: \"02134\" != \"02139\"\nand 3 more differences\n", Explain(a, b, WithMaxHunks(1)))
+	assert.Equal(t, "field explainUser.Addresses[3]: unexpected tools.explainAddress{Zip:\"94105\"}\n",
+		Explain(a, b, IgnorePath("explainUser.Tags"), IgnorePath("explainUser.Addresses[2].Zip")))
+	assert.Equal(t, "", Explain(a, a))
+	assert.Equal(t, "string: \"a\" != \"b\"\n", Explain("a", "b"))
+}
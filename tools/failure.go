@@ -0,0 +1,98 @@
+package tools
+
+import "sync"
+
+//Failure is a failed assertion passed to the hooks registered with OnFailure
+type Failure struct {
+	//Test is the name of the failing test, when the TestingT has a Name method
+	Test string
+
+	//File and Line are the location of the failing call in the test
+	File string
+	Line int
+
+	//Title is the one line summary, e.g. Not Equal (string/string)
+	Title string
+
+	//Diff is the uncolored diff, empty when there is none
+	Diff string
+
+	//Want and Got are the compared values as text, empty when the failure
+	//wasn't reported with ReportFailureValues
+	Want string
+	Got  string
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   = make(map[int]func(Failure))
+	hookID  int
+)
+
+//OnFailure registers fn to be called for every failure reported with
+//ReportFailure, e.g. by assert, golden and snapshot. It returns a function
+//that removes the hook.
+func OnFailure(fn func(Failure)) func() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hookID++
+	id := hookID
+	hooks[id] = fn
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		delete(hooks, id)
+	}
+}
+
+//ReportFailure reports a failure of test t with Annotate and passes it to
+//the OnFailure hooks. When ArtifactsDir is set the diff is written there and
+//logged if t has a Logf method. t is only used for its name and logging and
+//can be nil.
+func ReportFailure(t interface{}, title, diff string) {
+	report(t, Failure{Title: title, Diff: diff}, false)
+}
+
+//ReportFailureValues is ReportFailure for a comparison of want and got,
+//the hooks get both sides as text and both are written as artifacts with
+//the diff, so payloads cut short in the log can be inspected in full
+func ReportFailureValues(t interface{}, title, diff string, want, got interface{}) {
+	report(t, Failure{Title: title, Diff: diff, Want: getText(want), Got: getText(got)}, true)
+}
+
+func report(t interface{}, f Failure, sides bool) {
+	Annotate(f.Title, f.Diff)
+
+	hooksMu.Lock()
+	fns := make([]func(Failure), 0, len(hooks))
+	for _, fn := range hooks {
+		fns = append(fns, fn)
+	}
+	hooksMu.Unlock()
+	if len(fns) == 0 && ArtifactsDir() == "" {
+		return
+	}
+
+	f.Title, f.Diff = stripANSI(f.Title), stripANSI(f.Diff)
+	if n, ok := t.(interface{ Name() string }); ok {
+		f.Test = n.Name()
+	}
+	f.File, f.Line = testCaller()
+	for _, fn := range fns {
+		fn(f)
+	}
+
+	path, err := writeArtifacts(f, sides)
+	if l, ok := t.(interface {
+		Logf(format string, args ...interface{})
+	}); ok {
+		switch {
+		case err != nil:
+			l.Logf("write failure artifacts: %v", err)
+		case path != "" && sides:
+			l.Logf("failure artifacts: %s.{want,got,diff}", path)
+		case path != "":
+			l.Logf("failure artifact: %s.diff", path)
+		}
+	}
+}
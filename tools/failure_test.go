@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnFailure(t *testing.T) {
+	var got []Failure
+	remove := OnFailure(func(f Failure) { got = append(got, f) })
+
+	mock := Mock()
+	mock.SetName("TestX")
+	AssertDeepEqual(mock, "a", "b", "")
+	ReportFailure(nil, "\x1b[31mfailed\x1b[0m", "")
+	mock.Results()
+	remove()
+	ReportFailure(nil, "after remove", "")
+
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "TestX", got[0].Test)
+		assert.Equal(t, "failure_test.go", filepath.Base(got[0].File))
+		assert.NotZero(t, got[0].Line)
+		assert.Equal(t, "Not Equal (string/string)", got[0].Title)
+		assert.NotContains(t, got[0].Diff, "\x1b[")
+		assert.Equal(t, Failure{Title: "failed", File: got[1].File, Line: got[1].Line}, got[1])
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//DiffGit creates a Differ that compares the file at path as committed at
+//the git revision rev, e.g. HEAD, with the working copy, so tests can check
+//generated files match what is committed. The labels default to
+//<rev>:<path> and path, and a file missing on either side is diffed
+//against /dev/null. It runs git show in the directory of path, errors from
+//git or reading the file are returned by Error.
+func DiffGit(path, rev string, opts ...Option) Differ {
+	committed, errA := gitShow(path, rev)
+	labelA := rev + ":" + filepath.ToSlash(path)
+	if committed == nil && errA == nil {
+		labelA = "/dev/null"
+	}
+
+	working, errB := ioutil.ReadFile(path)
+	labelB := filepath.ToSlash(path)
+	if os.IsNotExist(errB) {
+		labelB, errB = "/dev/null", nil
+	}
+
+	opts = append([]Option{WithLabels(labelA, labelB)}, opts...)
+	d := Diff(string(committed), string(working), opts...)
+	if err := firstError(errA, errB); err != nil {
+		return &fallbackDiff{d, err}
+	}
+	return d
+}
+
+// gitShow returns the content of path at rev, or nil if it doesn't exist
+// there
+func gitShow(path, rev string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "show", rev+":./"+filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "exists on disk, but not in") || strings.Contains(msg, "does not exist in") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git show %s:%s: %v: %s", rev, path, err, msg)
+	}
+	return stdout.Bytes(), nil
+}
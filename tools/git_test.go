@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir, err := ioutil.TempDir("", "diffgit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	os.MkdirAll(filepath.Join(dir, "gen"), 0777)
+	ioutil.WriteFile(filepath.Join(dir, "gen", "out.go"), []byte("package gen\n\nconst A = 1\n"), 0666)
+	ioutil.WriteFile(filepath.Join(dir, "gone.txt"), []byte("old\n"), 0666)
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestDiffGit(t *testing.T) {
+	dir := gitRepo(t)
+	out := filepath.Join(dir, "gen", "out.go")
+
+	d := DiffGit(out, "HEAD", WithNoColor())
+	assert.True(t, d.Equal())
+	assert.NoError(t, d.Error())
+
+	ioutil.WriteFile(out, []byte("package gen\n\nconst A = 2\n"), 0666)
+	d = DiffGit(out, "HEAD", WithNoColor())
+	assert.False(t, d.Equal())
+	assert.NoError(t, d.Error())
+	label := filepath.ToSlash(out)
+	assert.Equal(t, "--- HEAD:"+label+"\n+++ "+label+"\n@@ -1,3 +1,3 @@\n package gen\n \n-const A = 1\n+const A = 2\n", d.String())
+
+	d = DiffGit(out, "HEAD", WithNoColor(), WithLabels("committed", "generated"))
+	assert.Contains(t, d.String(), "--- committed\n+++ generated\n")
+
+	added := filepath.Join(dir, "gen", "new.go")
+	ioutil.WriteFile(added, []byte("package gen\n"), 0666)
+	d = DiffGit(added, "HEAD", WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.Equal(t, "--- /dev/null\n+++ "+filepath.ToSlash(added)+"\n@@ -0,0 +1 @@\n+package gen\n", d.String())
+
+	gone := filepath.Join(dir, "gone.txt")
+	os.Remove(gone)
+	d = DiffGit(gone, "HEAD", WithNoColor())
+	assert.NoError(t, d.Error())
+	assert.Contains(t, d.String(), "+++ /dev/null\n@@ -1 +0,0 @@\n-old\n")
+
+	d = DiffGit(out, "nosuchrev", WithNoColor())
+	assert.Error(t, d.Error())
+	assert.False(t, d.Equal())
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+//AnnotationMode controls when failures are reported as GitHub Actions
+//workflow commands
+type AnnotationMode int32
+
+const (
+	//AnnotateAuto annotates failures when GITHUB_ACTIONS=true
+	AnnotateAuto AnnotationMode = iota
+
+	//AnnotateAlways always annotates failures
+	AnnotateAlways
+
+	//AnnotateNever never annotates failures
+	AnnotateNever
+)
+
+var annotationMode int32
+
+//EnableAnnotations reports failures as GitHub Actions annotations regardless
+//of the environment
+func EnableAnnotations() {
+	atomic.StoreInt32(&annotationMode, int32(AnnotateAlways))
+}
+
+//DisableAnnotations stops reporting failures as GitHub Actions annotations,
+//even when running in GitHub Actions
+func DisableAnnotations() {
+	atomic.StoreInt32(&annotationMode, int32(AnnotateNever))
+}
+
+//AutoAnnotations restores detection of GitHub Actions from GITHUB_ACTIONS
+func AutoAnnotations() {
+	atomic.StoreInt32(&annotationMode, int32(AnnotateAuto))
+}
+
+//Annotations reports whether failures are annotated
+func Annotations() bool {
+	switch AnnotationMode(atomic.LoadInt32(&annotationMode)) {
+	case AnnotateAlways:
+		return true
+	case AnnotateNever:
+		return false
+	}
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+//Annotation is a GitHub Actions ::error or ::warning workflow command, shown
+//inline on the file and line in pull requests
+type Annotation struct {
+	File    string
+	Line    int
+	Title   string
+	Message string
+
+	//Level is the command, error when empty, warning or notice
+	Level string
+}
+
+//String returns the workflow command, e.g.
+//::error file=foo_test.go,line=12,title=Not Equal::-a%0A+b
+func (a Annotation) String() string {
+	var props []string
+	if a.File != "" {
+		props = append(props, "file="+escapeProperty(a.File))
+		if a.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", a.Line))
+		}
+	}
+	if a.Title != "" {
+		props = append(props, "title="+escapeProperty(a.Title))
+	}
+	cmd := "::error"
+	if a.Level != "" {
+		cmd = "::" + a.Level
+	}
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + escapeData(a.Message)
+}
+
+//WriteGroup writes body between ::group:: and ::endgroup:: commands so it's
+//shown as a collapsible section in the job log
+func WriteGroup(w io.Writer, title, body string) {
+	fmt.Fprintf(w, "::group::%s\n", escapeData(title))
+	io.WriteString(w, body)
+	if !strings.HasSuffix(body, "\n") {
+		io.WriteString(w, "\n")
+	}
+	io.WriteString(w, "::endgroup::\n")
+}
+
+//Annotate writes an annotation for the failing line in the calling test,
+//and the diff in a group, to os.Stdout when Annotations is true. The first
+//caller in a _test.go file is used as the location, relative to
+//GITHUB_WORKSPACE when it's set. Without a diff the title is the message.
+func Annotate(title, diff string) {
+	if !Annotations() {
+		return
+	}
+	title = stripANSI(title)
+	diff = stripANSI(diff)
+	file, line := testCaller()
+	a := Annotation{File: file, Line: line, Title: title, Message: diff}
+	if diff == "" {
+		a.Message = title
+	}
+	io.WriteString(os.Stdout, a.String()+"\n")
+	if diff != "" {
+		WriteGroup(os.Stdout, title, diff)
+	}
+}
+
+//Warn writes a warning annotation for the calling test to os.Stdout when
+//Annotations is true, for something worth a look that doesn't fail the test
+func Warn(title, message string) {
+	if !Annotations() {
+		return
+	}
+	file, line := testCaller()
+	a := Annotation{File: file, Line: line, Title: stripANSI(title), Message: stripANSI(message), Level: "warning"}
+	io.WriteString(os.Stdout, a.String()+"\n")
+}
+
+// testCaller finds the first frame in a _test.go file
+func testCaller() (string, int) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if strings.HasSuffix(f.File, "_test.go") {
+			return workspacePath(f.File), f.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
+
+// workspacePath makes file relative to GITHUB_WORKSPACE, which is how
+// annotations are matched to files in the pull request
+func workspacePath(file string) string {
+	ws := os.Getenv("GITHUB_WORKSPACE")
+	if ws == "" {
+		return file
+	}
+	rel, err := filepath.Rel(ws, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+var ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripANSI(s string) string {
+	return ansi.ReplaceAllString(s, "")
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
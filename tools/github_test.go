@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationString(t *testing.T) {
+	a := Annotation{File: "pkg/foo_test.go", Line: 12, Title: "Not Equal: a,b", Message: "-a\n+b 100%"}
+	assert.Equal(t, "::error file=pkg/foo_test.go,line=12,title=Not Equal%3A a%2Cb::-a%0A+b 100%25", a.String())
+	assert.Equal(t, "::error::failed", Annotation{Message: "failed"}.String())
+	assert.Equal(t, "::warning title=Flaky::failed", Annotation{Title: "Flaky", Message: "failed", Level: "warning"}.String())
+}
+
+func TestWriteGroup(t *testing.T) {
+	var buf bytes.Buffer
+	WriteGroup(&buf, "diff", "-a\n+b")
+	assert.Equal(t, "::group::diff\n-a\n+b\n::endgroup::\n", buf.String())
+}
+
+func TestAnnotations(t *testing.T) {
+	defer AutoAnnotations()
+	defer os.Setenv("GITHUB_ACTIONS", os.Getenv("GITHUB_ACTIONS"))
+
+	os.Setenv("GITHUB_ACTIONS", "true")
+	assert.True(t, Annotations())
+	DisableAnnotations()
+	assert.False(t, Annotations())
+
+	AutoAnnotations()
+	os.Setenv("GITHUB_ACTIONS", "")
+	assert.False(t, Annotations())
+	EnableAnnotations()
+	assert.True(t, Annotations())
+}
+
+func TestAnnotate(t *testing.T) {
+	defer AutoAnnotations()
+	defer os.Setenv("GITHUB_WORKSPACE", os.Getenv("GITHUB_WORKSPACE"))
+	wd, _ := os.Getwd()
+	os.Setenv("GITHUB_WORKSPACE", wd)
+
+	EnableAnnotations()
+	mock := Mock()
+	Annotate("Not Equal", "\x1b[31m-a\x1b[0m\n+b\n")
+	res := mock.Results()
+
+	lines := strings.Split(res.Out, "\n")
+	assert.Regexp(t, `^::error file=github_test\.go,line=\d+,title=Not Equal::-a%0A\+b%0A$`, lines[0])
+	assert.Equal(t, "::group::Not Equal\n-a\n+b\n::endgroup::\n", strings.Join(lines[1:], "\n"))
+
+	DisableAnnotations()
+	mock = Mock()
+	Annotate("Not Equal", "-a\n+b\n")
+	res = mock.Results()
+	assert.Empty(t, res.Out)
+}
+
+func TestAssertDeepEqualAnnotates(t *testing.T) {
+	defer AutoAnnotations()
+	EnableAnnotations()
+
+	mock := Mock()
+	AssertDeepEqual(mock, "a", "b", "")
+	res := mock.Results()
+	assert.Contains(t, res.Out, "::error file=")
+	assert.Contains(t, res.Out, "github_test.go,line=")
+	assert.Contains(t, res.Out, "::group::Not Equal (string/string)\n")
+}
+
+func TestWarn(t *testing.T) {
+	defer AutoAnnotations()
+	defer os.Setenv("GITHUB_WORKSPACE", os.Getenv("GITHUB_WORKSPACE"))
+	wd, _ := os.Getwd()
+	os.Setenv("GITHUB_WORKSPACE", wd)
+
+	EnableAnnotations()
+	mock := Mock()
+	Warn("Quarantined", "flaky:\nfailed 2 of 3 runs")
+	res := mock.Results()
+	assert.Regexp(t, `^::warning file=github_test\.go,line=\d+,title=Quarantined::flaky:%0Afailed 2 of 3 runs\n$`, res.Out)
+
+	DisableAnnotations()
+	mock = Mock()
+	Warn("Quarantined", "flaky")
+	res = mock.Results()
+	assert.Empty(t, res.Out)
+}
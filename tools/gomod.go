@@ -0,0 +1,324 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+//ModChangeKind is how an entry of a go.mod or go.sum file changed
+type ModChangeKind int
+
+const (
+	//ModAdded is an entry only in the new file
+	ModAdded ModChangeKind = iota
+
+	//ModRemoved is an entry only in the old file
+	ModRemoved
+
+	//ModUpgraded is a version raised to a higher semantic version
+	ModUpgraded
+
+	//ModDowngraded is a version lowered to a lower semantic version
+	ModDowngraded
+
+	//ModChanged is any other change, e.g. a go.sum hash or a replacement
+	//with another module
+	ModChanged
+)
+
+func (k ModChangeKind) String() string {
+	switch k {
+	case ModAdded:
+		return "added"
+	case ModRemoved:
+		return "removed"
+	case ModUpgraded:
+		return "upgraded"
+	case ModDowngraded:
+		return "downgraded"
+	}
+	return "changed"
+}
+
+//ModChange is a difference between two go.mod or go.sum files
+type ModChange struct {
+	Kind ModChangeKind
+
+	//Path names the entry like the file does, e.g. "go",
+	//"require golang.org/x/net" or "replace example.com/a v1.0.0" for
+	//go.mod and the module path, or the module path and version for hash
+	//changes, for go.sum
+	Path string
+
+	//Old and New are the version or value before and after, Old is empty
+	//when added and New when removed
+	Old string
+	New string
+}
+
+// sections orders the changes of a go.mod like the file
+var sections = []string{"module", "go", "toolchain", "require", "replace", "exclude", "retract"}
+
+//GoModChanges compares the go.mod files a and b semantically: the module
+//path, go and toolchain versions, and the requirements, replacements,
+//exclusions and retractions, regardless of formatting, order and comments.
+//Changed versions are classified by semantic version.
+func GoModChanges(a, b []byte) ([]ModChange, error) {
+	ea, errA := modEntries(a)
+	eb, errB := modEntries(b)
+	if err := firstError(errA, errB); err != nil {
+		return nil, err
+	}
+	changes := compareEntries(ea, eb)
+	sort.SliceStable(changes, func(i, j int) bool {
+		si, sj := sectionRank(changes[i].Path), sectionRank(changes[j].Path)
+		if si != sj {
+			return si < sj
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+func sectionRank(path string) int {
+	word := strings.SplitN(path, " ", 2)[0]
+	for i, s := range sections {
+		if s == word {
+			return i
+		}
+	}
+	return len(sections)
+}
+
+// modEntries maps each directive of a go.mod to its value
+func modEntries(data []byte) (map[string]string, error) {
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	e := make(map[string]string)
+	if f.Module != nil {
+		e["module"] = f.Module.Mod.Path
+	}
+	if f.Go != nil {
+		e["go"] = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		e["toolchain"] = f.Toolchain.Name
+	}
+	for _, r := range f.Require {
+		v := r.Mod.Version
+		if r.Indirect {
+			v += " // indirect"
+		}
+		e["require "+r.Mod.Path] = v
+	}
+	for _, r := range f.Replace {
+		key := strings.TrimSpace("replace " + r.Old.Path + " " + r.Old.Version)
+		e[key] = strings.TrimSpace(r.New.Path + " " + r.New.Version)
+	}
+	for _, x := range f.Exclude {
+		e["exclude "+x.Mod.Path+" "+x.Mod.Version] = x.Mod.Version
+	}
+	for _, r := range f.Retract {
+		v := r.Low
+		if r.High != r.Low {
+			v = "[" + r.Low + ", " + r.High + "]"
+		}
+		e["retract "+v] = v
+	}
+	return e, nil
+}
+
+// compareEntries reports the entries added, removed or changed from a to b
+func compareEntries(a, b map[string]string) []ModChange {
+	var changes []ModChange
+	for path, old := range a {
+		v, ok := b[path]
+		switch {
+		case !ok:
+			changes = append(changes, ModChange{Kind: ModRemoved, Path: path, Old: old})
+		case v != old:
+			changes = append(changes, ModChange{Kind: versionChange(old, v), Path: path, Old: old, New: v})
+		}
+	}
+	for path, v := range b {
+		if _, ok := a[path]; !ok {
+			changes = append(changes, ModChange{Kind: ModAdded, Path: path, New: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// versionChange classifies a change from old to v by the last word of
+// each that is a semantic version, or a go version like 1.21 or go1.21.0
+func versionChange(old, v string) ModChangeKind {
+	va, pa := modVersion(old)
+	vb, pb := modVersion(v)
+	if va == "" || vb == "" || pa != pb {
+		return ModChanged
+	}
+	switch c := semver.Compare(va, vb); {
+	case c < 0:
+		return ModUpgraded
+	case c > 0:
+		return ModDowngraded
+	}
+	return ModChanged
+}
+
+// modVersion returns the semantic version in a value like
+// "v1.2.0 // indirect", "example.com/fork v1.2.0" or "go1.21.0", and the
+// module path before it if any
+func modVersion(s string) (version, path string) {
+	s = strings.TrimSuffix(s, " // indirect")
+	if i := strings.LastIndex(s, " "); i >= 0 {
+		path, s = s[:i], s[i+1:]
+	}
+	if !strings.HasPrefix(s, "v") {
+		s = "v" + strings.TrimPrefix(s, "go")
+	}
+	if !semver.IsValid(s) {
+		return "", path
+	}
+	return s, path
+}
+
+//GoSumChanges compares the go.sum files a and b by module. A module whose
+//only version changed is reported as upgraded or downgraded, versions added
+//or removed next to others one by one, and a different hash for the same
+//version as changed.
+func GoSumChanges(a, b []byte) ([]ModChange, error) {
+	ha, errA := sumHashes(a)
+	hb, errB := sumHashes(b)
+	if err := firstError(errA, errB); err != nil {
+		return nil, err
+	}
+
+	var changes []ModChange
+	for key, h := range ha {
+		if hb[key] != "" && hb[key] != h {
+			changes = append(changes, ModChange{Kind: ModChanged, Path: key, Old: h, New: hb[key]})
+		}
+	}
+
+	va, vb := sumVersions(ha), sumVersions(hb)
+	mods := make(map[string]bool)
+	for m := range va {
+		mods[m] = true
+	}
+	for m := range vb {
+		mods[m] = true
+	}
+	for m := range mods {
+		olds, news := minus(va[m], vb[m]), minus(vb[m], va[m])
+		if len(olds) == 1 && len(news) == 1 {
+			changes = append(changes, ModChange{Kind: versionChange(olds[0], news[0]), Path: m, Old: olds[0], New: news[0]})
+			continue
+		}
+		for _, v := range olds {
+			changes = append(changes, ModChange{Kind: ModRemoved, Path: m, Old: v})
+		}
+		for _, v := range news {
+			changes = append(changes, ModChange{Kind: ModAdded, Path: m, New: v})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Old+changes[i].New < changes[j].Old+changes[j].New
+	})
+	return changes, nil
+}
+
+// sumHashes maps the "<module> <version>" of each go.sum line to its hash
+func sumHashes(data []byte) (map[string]string, error) {
+	hashes := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return nil, fmt.Errorf("go.sum line %d: expected module, version and hash, got %q", n, sc.Text())
+		}
+		hashes[f[0]+" "+f[1]] = f[2]
+	}
+	return hashes, sc.Err()
+}
+
+// sumVersions maps each module to its versions, without /go.mod suffixes
+func sumVersions(hashes map[string]string) map[string]map[string]bool {
+	versions := make(map[string]map[string]bool)
+	for key := range hashes {
+		f := strings.SplitN(key, " ", 2)
+		if versions[f[0]] == nil {
+			versions[f[0]] = make(map[string]bool)
+		}
+		versions[f[0]][strings.TrimSuffix(f[1], "/go.mod")] = true
+	}
+	return versions
+}
+
+// minus returns the versions in a and not in b in semver order
+func minus(a, b map[string]bool) []string {
+	var res []string
+	for v := range a {
+		if !b[v] {
+			res = append(res, v)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return semver.Compare(res[i], res[j]) < 0 })
+	return res
+}
+
+//DiffGoMod creates a Differ that compares go.mod files with GoModChanges,
+//reporting each changed directive by path and how it changed, e.g.
+//"require golang.org/x/net (upgraded)", with the old and new value.
+//IgnorePath takes the path without the kind, e.g. "require golang.org"
+//ignores every module below golang.org. If
+//either can't be parsed it falls back to a text Diff and Error returns the
+//parse error.
+func DiffGoMod(a, b []byte, opts ...Option) Differ {
+	changes, err := GoModChanges(a, b)
+	return modDiff(a, b, changes, err, opts)
+}
+
+//DiffGoSum creates a Differ that compares go.sum files with GoSumChanges,
+//falling back to a text Diff for malformed lines like DiffGoMod
+func DiffGoSum(a, b []byte, opts ...Option) Differ {
+	changes, err := GoSumChanges(a, b)
+	return modDiff(a, b, changes, err, opts)
+}
+
+func modDiff(a, b []byte, mcs []ModChange, err error, opts []Option) Differ {
+	if err != nil {
+		return &fallbackDiff{Diff(string(a), string(b), opts...), err}
+	}
+	o := newOptions(opts)
+	d := &valueDiff{opts: o}
+	for _, mc := range mcs {
+		c := change{typ: changed, path: mc.Path, exp: mc.Old, act: mc.New}
+		if mc.Kind != ModChanged {
+			c.path += " (" + mc.Kind.String() + ")"
+		}
+		switch mc.Kind {
+		case ModAdded:
+			c.typ = added
+		case ModRemoved:
+			c.typ = removed
+		}
+		c.ignored = o.ignoredPath(mc.Path)
+		d.changes = append(d.changes, c)
+	}
+	return d
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const modA = `module example.com/app
+
+go 1.20
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/net v0.10.0
+	golang.org/x/text v0.9.0 // indirect
+)
+
+replace example.com/lib => ../lib
+
+exclude golang.org/x/net v0.9.0
+`
+
+const modB = `// reordered, with comments
+module example.com/app
+
+go 1.21
+
+toolchain go1.21.3
+
+require golang.org/x/text v0.9.0
+
+require (
+	golang.org/x/net v0.8.0 // pinned
+	github.com/stretchr/testify v1.9.0
+)
+
+replace example.com/lib => example.com/lib v1.2.0
+
+exclude golang.org/x/net v0.9.0
+`
+
+func TestGoModChanges(t *testing.T) {
+	changes, err := GoModChanges([]byte(modA), []byte(modB))
+	assert.NoError(t, err)
+	assert.Equal(t, []ModChange{
+		{Kind: ModUpgraded, Path: "go", Old: "1.20", New: "1.21"},
+		{Kind: ModAdded, Path: "toolchain", New: "go1.21.3"},
+		{Kind: ModRemoved, Path: "require github.com/pkg/errors", Old: "v0.9.1"},
+		{Kind: ModAdded, Path: "require github.com/stretchr/testify", New: "v1.9.0"},
+		{Kind: ModDowngraded, Path: "require golang.org/x/net", Old: "v0.10.0", New: "v0.8.0"},
+		{Kind: ModChanged, Path: "require golang.org/x/text", Old: "v0.9.0 // indirect", New: "v0.9.0"},
+		{Kind: ModChanged, Path: "replace example.com/lib", Old: "../lib", New: "example.com/lib v1.2.0"},
+	}, changes)
+
+	changes, err = GoModChanges([]byte(modA), []byte(modA))
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = GoModChanges([]byte(modA), []byte("require (\n"))
+	assert.Error(t, err)
+}
+
+func TestDiffGoMod(t *testing.T) {
+	a := "module m\n\nrequire golang.org/x/net v0.1.0\n"
+	b := "module m\n\nrequire (\n\tgolang.org/x/net v0.2.0\n\tgolang.org/x/sys v0.3.0\n)\n"
+	d := DiffGoMod([]byte(a), []byte(b), WithNoColor())
+	assert.False(t, d.Equal())
+	assert.NoError(t, d.Error())
+	assert.Equal(t, "require golang.org/x/net (upgraded):\n-v0.1.0\n+v0.2.0\nrequire golang.org/x/sys (added):\n+v0.3.0\n", d.String())
+
+	d = DiffGoMod([]byte(a), []byte(b), WithNoColor(), IgnorePath("require golang.org/x/sys"))
+	assert.Equal(t, 1, d.Stats().Ignored)
+
+	assert.True(t, DiffGoMod([]byte(a), []byte("// same\nmodule m\nrequire golang.org/x/net v0.1.0\n")).Equal())
+
+	d = DiffGoMod([]byte(a), []byte("require (\n"), WithNoColor())
+	assert.Error(t, d.Error())
+	assert.Contains(t, d.String(), "-module m\n")
+}
+
+const sumA = `github.com/pkg/errors v0.9.1 h1:a=
+github.com/pkg/errors v0.9.1/go.mod h1:b=
+golang.org/x/net v0.1.0 h1:c=
+golang.org/x/net v0.1.0/go.mod h1:d=
+golang.org/x/text v0.3.0/go.mod h1:e=
+`
+
+const sumB = `golang.org/x/net v0.2.0 h1:f=
+golang.org/x/net v0.2.0/go.mod h1:g=
+golang.org/x/text v0.3.0/go.mod h1:changed=
+golang.org/x/text v0.4.0/go.mod h1:h=
+`
+
+func TestGoSumChanges(t *testing.T) {
+	changes, err := GoSumChanges([]byte(sumA), []byte(sumB))
+	assert.NoError(t, err)
+	assert.Equal(t, []ModChange{
+		{Kind: ModRemoved, Path: "github.com/pkg/errors", Old: "v0.9.1"},
+		{Kind: ModUpgraded, Path: "golang.org/x/net", Old: "v0.1.0", New: "v0.2.0"},
+		{Kind: ModAdded, Path: "golang.org/x/text", New: "v0.4.0"},
+		{Kind: ModChanged, Path: "golang.org/x/text v0.3.0/go.mod", Old: "h1:e=", New: "h1:changed="},
+	}, changes)
+
+	_, err = GoSumChanges([]byte("golang.org/x/net v0.1.0\n"), nil)
+	assert.EqualError(t, err, `go.sum line 1: expected module, version and hash, got "golang.org/x/net v0.1.0"`)
+
+	d := DiffGoSum([]byte(sumA), []byte(sumA))
+	assert.True(t, d.Equal())
+	d = DiffGoSum([]byte(sumA), []byte(sumB), WithNoColor())
+	assert.Contains(t, d.String(), "golang.org/x/net (upgraded):\n-v0.1.0\n+v0.2.0\n")
+}
+
+func TestModChangeKind(t *testing.T) {
+	assert.Equal(t, "added", ModAdded.String())
+	assert.Equal(t, "downgraded", ModDowngraded.String())
+	assert.Equal(t, "changed", ModChanged.String())
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+//Layout selects how an HTML diff is arranged
+type Layout int
+
+const (
+	//Inline shows deletions and insertions interleaved in one column
+	Inline Layout = iota
+
+	//SideBySide shows the old text on the left and the new text on the right
+	SideBySide
+)
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>diff</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table.diff { border-collapse: collapse; font-family: monospace; font-size: 13px; width: 100%; }
+table.diff td { padding: 0 6px; white-space: pre-wrap; vertical-align: top; }
+table.diff td.num { color: #6e7781; text-align: right; width: 1%; user-select: none; }
+table.diff tr.hunk td { background: #ddf4ff; color: #57606a; }
+table.diff tr.path td { background: #f6f8fa; font-weight: bold; }
+.del { background: #ffebe9; }
+.ins { background: #e6ffec; }
+.ign { color: #8c959f; }
+.mov { background: #ddf4ff; }
+del { background: #ffc1c0; text-decoration: none; }
+ins { background: #abf2bc; text-decoration: none; }
+</style>
+</head>
+<body>
+`
+
+const htmlFoot = `</body>
+</html>
+`
+
+// writeHTML writes a standalone page around the table rendered by body
+func writeHTML(w io.Writer, body func(w io.Writer)) error {
+	var buf bytes.Buffer
+	buf.WriteString(htmlHead)
+	buf.WriteString("<table class=\"diff\">\n")
+	body(&buf)
+	buf.WriteString("</table>\n")
+	buf.WriteString(htmlFoot)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func htmlText(s string) string {
+	return html.EscapeString(strings.TrimSuffix(s, nl))
+}
+
+// htmlLine renders the text of a line, marking the changed spans when it
+// was paired with another line
+func htmlLine(l lineOp, inline []dmp.Diff) string {
+	if inline == nil || l.ignored || l.moved {
+		return htmlText(l.text)
+	}
+	return htmlSpans(inline, l.op)
+}
+
+// htmlCode renders line n of its side highlighted with s, or like htmlLine
+// when s is nil
+func htmlCode(s *syntax, l lineOp, n int, inline []dmp.Diff) string {
+	if s == nil || l.ignored || l.moved {
+		return htmlLine(l, inline)
+	}
+	return htmlSyntax(s.segments(l, n, inline), l.op)
+}
+
+func htmlClass(l lineOp) string {
+	switch {
+	case l.ignored:
+		return "ign"
+	case l.moved:
+		return "mov"
+	case l.op == dmp.DiffInsert:
+		return "ins"
+	default:
+		return "del"
+	}
+}
+
+// htmlSpans renders the diffs for one side, marking the operation op
+func htmlSpans(diffs []dmp.Diff, op dmp.Operation) string {
+	var buf bytes.Buffer
+	for _, diff := range diffs {
+		text := html.EscapeString(diff.Text)
+		switch diff.Type {
+		case dmp.DiffEqual:
+			buf.WriteString(text)
+		case op:
+			tag := "del"
+			if op == dmp.DiffInsert {
+				tag = "ins"
+			}
+			fmt.Fprintf(&buf, "<%s>%s</%s>", tag, text, tag)
+		}
+	}
+	return buf.String()
+}
+
+// htmlHunks renders line hunks in either layout
+func htmlHunks(w io.Writer, hunks []hunk, layout Layout, o *options, s *syntax) {
+	cols := 3
+	if layout == SideBySide {
+		cols = 4
+	}
+
+	for _, h := range hunks {
+		fmt.Fprintf(w, "<tr class=\"hunk\"><td colspan=\"%d\">%s</td></tr>\n", cols, html.EscapeString(hunkHeader(h)))
+
+		inline := inlineDiffs(h.ops, o)
+		oldNo, newNo := h.oldStart, h.newStart
+		if layout == Inline {
+			for i, l := range h.ops {
+				switch l.op {
+				case dmp.DiffDelete:
+					fmt.Fprintf(w, "<tr class=\"%s\"><td class=\"num\">%d</td><td class=\"num\"></td><td>-%s</td></tr>\n", htmlClass(l), oldNo, htmlCode(s, l, oldNo, inline[i]))
+					oldNo++
+				case dmp.DiffInsert:
+					fmt.Fprintf(w, "<tr class=\"%s\"><td class=\"num\"></td><td class=\"num\">%d</td><td>+%s</td></tr>\n", htmlClass(l), newNo, htmlCode(s, l, newNo, inline[i]))
+					newNo++
+				default:
+					fmt.Fprintf(w, "<tr><td class=\"num\">%d</td><td class=\"num\">%d</td><td> %s</td></tr>\n", oldNo, newNo, htmlCode(s, l, oldNo, nil))
+					oldNo++
+					newNo++
+				}
+			}
+			continue
+		}
+
+		// side by side pairs each run of deletions with the insertions after it
+		for i := 0; i < len(h.ops); {
+			if h.ops[i].op == dmp.DiffEqual {
+				text := htmlCode(s, h.ops[i], oldNo, nil)
+				fmt.Fprintf(w, "<tr><td class=\"num\">%d</td><td>%s</td><td class=\"num\">%d</td><td>%s</td></tr>\n", oldNo, text, newNo, text)
+				oldNo++
+				newNo++
+				i++
+				continue
+			}
+
+			var dels, inss []int
+			for ; i < len(h.ops) && h.ops[i].op == dmp.DiffDelete; i++ {
+				dels = append(dels, i)
+			}
+			for ; i < len(h.ops) && h.ops[i].op == dmp.DiffInsert; i++ {
+				inss = append(inss, i)
+			}
+			for j := 0; j < len(dels) || j < len(inss); j++ {
+				left, right := "<td class=\"num\"></td><td></td>", "<td class=\"num\"></td><td></td>"
+				if j < len(dels) {
+					l := h.ops[dels[j]]
+					left = fmt.Sprintf("<td class=\"num\">%d</td><td class=\"%s\">%s</td>", oldNo, htmlClass(l), htmlCode(s, l, oldNo, inline[dels[j]]))
+					oldNo++
+				}
+				if j < len(inss) {
+					l := h.ops[inss[j]]
+					right = fmt.Sprintf("<td class=\"num\">%d</td><td class=\"%s\">%s</td>", newNo, htmlClass(l), htmlCode(s, l, newNo, inline[inss[j]]))
+					newNo++
+				}
+				fmt.Fprintf(w, "<tr>%s%s</tr>\n", left, right)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      Differ
+		layout Layout
+		exp    []string
+	}{
+		{
+			name:   "unified inline",
+			d:      Diff("a\nb<\nc\n", "a\nB<\nc\n"),
+			layout: Inline,
+			exp: []string{
+				"<tr class=\"hunk\"><td colspan=\"3\">@@ -1,3 +1,3 @@</td></tr>",
+				"<tr class=\"del\"><td class=\"num\">2</td><td class=\"num\"></td><td>-<del>b</del>&lt;</td></tr>",
+				"<tr class=\"ins\"><td class=\"num\"></td><td class=\"num\">2</td><td>+<ins>B</ins>&lt;</td></tr>",
+			},
+		},
+		{
+			name:   "unified side by side",
+			d:      Diff("a\nb\nc\n", "a\nB\nc\n"),
+			layout: SideBySide,
+			exp: []string{
+				"<tr><td class=\"num\">1</td><td>a</td><td class=\"num\">1</td><td>a</td></tr>",
+				"<tr><td class=\"num\">2</td><td class=\"del\"><del>b</del></td><td class=\"num\">2</td><td class=\"ins\"><ins>B</ins></td></tr>",
+			},
+		},
+		{
+			name:   "word inline",
+			d:      Diff("aaabbb", "aaaccc"),
+			layout: Inline,
+			exp:    []string{"<tr><td>aaa<del>bbb</del><ins>ccc</ins></td></tr>"},
+		},
+		{
+			name:   "word side by side",
+			d:      Diff("aaabbb", "aaaccc"),
+			layout: SideBySide,
+			exp:    []string{"<tr><td>aaa<del>bbb</del></td><td>aaa<ins>ccc</ins></td></tr>"},
+		},
+		{
+			name:   "values",
+			d:      DiffValues(map[string]string{"a": "x"}, map[string]string{"a": "y"}),
+			layout: SideBySide,
+			exp: []string{
+				"<tr class=\"path\"><td colspan=\"2\">[&#34;a&#34;]</td></tr>",
+				"<tr><td class=\"del\">&#34;x&#34;</td><td class=\"ins\">&#34;y&#34;</td></tr>",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := test.d.HTML(&buf, test.layout)
+		assert.NoError(t, err, test.name)
+
+		out := buf.String()
+		assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"), test.name)
+		for _, exp := range test.exp {
+			assert.Contains(t, out, exp, test.name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"encoding/json"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+//Op is the kind of change an Edit makes
+type Op string
+
+const (
+	//OpEqual is text present on both sides
+	OpEqual Op = "equal"
+
+	//OpDelete is text only present in the old side
+	OpDelete Op = "delete"
+
+	//OpInsert is text only present in the new side
+	OpInsert Op = "insert"
+)
+
+//Range is a half open, 0 based span [Start, End). It counts lines for line
+//diffs and bytes for word diffs.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+//Edit is a single operation of a diff. Ignored edits are differences
+//excluded by an option such as IgnoreLines or IgnorePath. Moved edits were
+//found by DetectMoves, structural diffs report them as one insert with the
+//old path in From.
+type Edit struct {
+	Op      Op     `json:"op"`
+	Old     Range  `json:"old"`
+	New     Range  `json:"new"`
+	Text    string `json:"text"`
+	Ignored bool   `json:"ignored,omitempty"`
+	Moved   bool   `json:"moved,omitempty"`
+	From    string `json:"from,omitempty"`
+}
+
+//Hunk is a group of edits. Structural diffs such as DiffValues and
+//DiffJSON report one hunk per Path with empty ranges. Label is the line
+//found by WithHunkLabels.
+type Hunk struct {
+	Path  string `json:"path,omitempty"`
+	Label string `json:"label,omitempty"`
+	Old   Range  `json:"old"`
+	New   Range  `json:"new"`
+	Edits []Edit `json:"edits"`
+}
+
+func toOp(op dmp.Operation) Op {
+	switch op {
+	case dmp.DiffDelete:
+		return OpDelete
+	case dmp.DiffInsert:
+		return OpInsert
+	default:
+		return OpEqual
+	}
+}
+
+// lineHunks converts line hunks, joining consecutive lines with the same op
+// into one edit
+func lineHunks(hunks []hunk) []Hunk {
+	var res []Hunk
+	for _, h := range hunks {
+		oldNo, newNo := h.oldStart-1, h.newStart-1
+		out := Hunk{
+			Label: h.label,
+			Old:   Range{Start: oldNo, End: oldNo + h.oldLines},
+			New:   Range{Start: newNo, End: newNo + h.newLines},
+		}
+		for i := 0; i < len(h.ops); {
+			e := Edit{
+				Op:      toOp(h.ops[i].op),
+				Old:     Range{Start: oldNo, End: oldNo},
+				New:     Range{Start: newNo, End: newNo},
+				Ignored: h.ops[i].ignored,
+				Moved:   h.ops[i].moved,
+			}
+			for ; i < len(h.ops) && toOp(h.ops[i].op) == e.Op && h.ops[i].ignored == e.Ignored && h.ops[i].moved == e.Moved; i++ {
+				e.Text += h.ops[i].text
+				if e.Op != OpInsert {
+					oldNo++
+				}
+				if e.Op != OpDelete {
+					newNo++
+				}
+			}
+			e.Old.End, e.New.End = oldNo, newNo
+			out.Edits = append(out.Edits, e)
+		}
+		res = append(res, out)
+	}
+	return res
+}
+
+// textHunks converts character diffs into a single hunk spanning both texts
+func textHunks(diffs []dmp.Diff) []Hunk {
+	equal := true
+	for _, diff := range diffs {
+		if diff.Type != dmp.DiffEqual {
+			equal = false
+			break
+		}
+	}
+	if equal {
+		return nil
+	}
+
+	var h Hunk
+	oldNo, newNo := 0, 0
+	for _, diff := range diffs {
+		e := Edit{
+			Op:   toOp(diff.Type),
+			Old:  Range{Start: oldNo, End: oldNo},
+			New:  Range{Start: newNo, End: newNo},
+			Text: diff.Text,
+		}
+		if diff.Type != dmp.DiffInsert {
+			oldNo += len(diff.Text)
+		}
+		if diff.Type != dmp.DiffDelete {
+			newNo += len(diff.Text)
+		}
+		e.Old.End, e.New.End = oldNo, newNo
+		h.Edits = append(h.Edits, e)
+	}
+	h.Old.End, h.New.End = oldNo, newNo
+	return []Hunk{h}
+}
+
+// changeHunks converts structural changes into one hunk per path
+func changeHunks(changes []change) []Hunk {
+	var res []Hunk
+	for _, c := range changes {
+		h := Hunk{Path: c.path}
+		if c.typ == moved {
+			h.Edits = append(h.Edits, Edit{Op: OpInsert, Text: c.act, Ignored: c.ignored, Moved: true, From: c.exp})
+			res = append(res, h)
+			continue
+		}
+		if c.typ != added {
+			h.Edits = append(h.Edits, Edit{Op: OpDelete, Text: c.exp, Ignored: c.ignored})
+		}
+		if c.typ != removed {
+			h.Edits = append(h.Edits, Edit{Op: OpInsert, Text: c.act, Ignored: c.ignored})
+		}
+		res = append(res, h)
+	}
+	return res
+}
+
+func marshalHunks(hunks []Hunk) ([]byte, error) {
+	if hunks == nil {
+		hunks = []Hunk{}
+	}
+	return json.Marshal(struct {
+		Hunks []Hunk `json:"hunks"`
+	}{hunks})
+}
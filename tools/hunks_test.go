@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHunks(t *testing.T) {
+	d := Diff("a\nb\nc\nd\n", "a\nB\nC\nd\ne\n", WithContextLines(1), WithCleanup(NoCleanup))
+	assert.Equal(t, []Hunk{
+		{
+			Old: Range{0, 4},
+			New: Range{0, 5},
+			Edits: []Edit{
+				{Op: OpEqual, Old: Range{0, 1}, New: Range{0, 1}, Text: "a\n"},
+				{Op: OpDelete, Old: Range{1, 3}, New: Range{1, 1}, Text: "b\nc\n"},
+				{Op: OpInsert, Old: Range{3, 3}, New: Range{1, 3}, Text: "B\nC\n"},
+				{Op: OpEqual, Old: Range{3, 4}, New: Range{3, 4}, Text: "d\n"},
+				{Op: OpInsert, Old: Range{4, 4}, New: Range{4, 5}, Text: "e\n"},
+			},
+		},
+	}, d.Hunks())
+
+	d = Diff("aaabbb", "aaaccc")
+	assert.Equal(t, []Hunk{
+		{
+			Old: Range{0, 6},
+			New: Range{0, 6},
+			Edits: []Edit{
+				{Op: OpEqual, Old: Range{0, 3}, New: Range{0, 3}, Text: "aaa"},
+				{Op: OpDelete, Old: Range{3, 6}, New: Range{3, 3}, Text: "bbb"},
+				{Op: OpInsert, Old: Range{6, 6}, New: Range{3, 6}, Text: "ccc"},
+			},
+		},
+	}, d.Hunks())
+
+	assert.Nil(t, Diff("aaa", "aaa").Hunks())
+
+	bs, err := json.Marshal(DiffJSON([]byte(`{"a":1}`), []byte(`{"a":2}`)))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hunks":[{"path":"/a","old":{"start":0,"end":0},"new":{"start":0,"end":0},"edits":[
+		{"op":"delete","old":{"start":0,"end":0},"new":{"start":0,"end":0},"text":"1"},
+		{"op":"insert","old":{"start":0,"end":0},"new":{"start":0,"end":0},"text":"2"}]}]}`, string(bs))
+
+	bs, err = json.Marshal(Diff("a", "a"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hunks":[]}`, string(bs))
+}
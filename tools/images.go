@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// maxYIQDelta is the weighted squared YIQ distance between black and white,
+// strongly colored pairs can be further apart and are capped at 1
+const maxYIQDelta = 0.5053 * 255 * 255
+
+//ImageDiff is the result of comparing two images pixel by pixel
+type ImageDiff struct {
+	//Pixels is the number of pixels that differ by more than the pixel
+	//tolerance, every pixel if the sizes differ
+	Pixels int
+
+	//Total is the number of pixels compared
+	Total int
+
+	//Similarity is 1 for identical images, falling towards 0 the more and
+	//the stronger pixels differ, perceptually weighted like the YIQ color
+	//space so changes in brightness count more than changes in hue
+	Similarity float64
+
+	a, b image.Image
+	o    *options
+}
+
+//DiffImages compares a and b pixel by pixel. Images of different sizes have
+//a Similarity of 0.
+func DiffImages(a, b image.Image, opts ...Option) *ImageDiff {
+	d := &ImageDiff{a: a, b: b, o: newOptions(opts)}
+	ra, rb := a.Bounds(), b.Bounds()
+	if ra.Dx() != rb.Dx() || ra.Dy() != rb.Dy() {
+		d.Total = maxInt(ra.Dx()*ra.Dy(), rb.Dx()*rb.Dy())
+		d.Pixels = d.Total
+		return d
+	}
+
+	d.Total = ra.Dx() * ra.Dy()
+	if d.Total == 0 {
+		d.Similarity = 1
+		return d
+	}
+	var sum float64
+	for y := 0; y < ra.Dy(); y++ {
+		for x := 0; x < ra.Dx(); x++ {
+			delta := d.delta(x, y)
+			sum += delta
+			if delta > d.o.pixelTolerance {
+				d.Pixels++
+			}
+		}
+	}
+	d.Similarity = 1 - sum/float64(d.Total)
+	return d
+}
+
+//DiffPNG decodes a and b as images, e.g. PNG, and compares them like
+//DiffImages
+func DiffPNG(a, b []byte, opts ...Option) (*ImageDiff, error) {
+	ia, _, err := image.Decode(bytes.NewReader(a))
+	if err != nil {
+		return nil, fmt.Errorf("decode a: %v", err)
+	}
+	ib, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("decode b: %v", err)
+	}
+	return DiffImages(ia, ib, opts...), nil
+}
+
+// delta returns the perceptual distance of the pixels at x, y relative to
+// the bounds of each image, from 0 to 1
+func (d *ImageDiff) delta(x, y int) float64 {
+	ra, rb := d.a.Bounds(), d.b.Bounds()
+	y1, i1, q1 := yiq(d.a.At(ra.Min.X+x, ra.Min.Y+y))
+	y2, i2, q2 := yiq(d.b.At(rb.Min.X+x, rb.Min.Y+y))
+	dy, di, dq := y1-y2, i1-i2, q1-q2
+	if delta := (0.5053*dy*dy + 0.299*di*di + 0.1957*dq*dq) / maxYIQDelta; delta < 1 {
+		return delta
+	}
+	return 1
+}
+
+// yiq converts c, blended onto white, to the YIQ color space with 8 bit
+// channels
+func yiq(c color.Color) (y, i, q float64) {
+	r, g, b, a := c.RGBA()
+	blend := func(v uint32) float64 {
+		return (float64(v) + float64(0xffff-a)) / 0x101
+	}
+	fr, fg, fb := blend(r), blend(g), blend(b)
+	y = 0.29889531*fr + 0.58662247*fg + 0.11448223*fb
+	i = 0.59597799*fr - 0.27417610*fg - 0.32180189*fb
+	q = 0.21147017*fr - 0.52261711*fg + 0.31114694*fb
+	return y, i, q
+}
+
+//Equal reports whether the images are at least as similar as the minimum
+//similarity, see WithMinSimilarity
+func (d *ImageDiff) Equal() bool {
+	if d.Total > 0 && !d.sameSize() {
+		return false
+	}
+	if d.o.minSimilarity >= 1 {
+		return d.Pixels == 0
+	}
+	return d.Similarity >= d.o.minSimilarity
+}
+
+func (d *ImageDiff) sameSize() bool {
+	return d.a.Bounds().Size() == d.b.Bounds().Size()
+}
+
+//String summarizes the difference, e.g. "12 of 10000 pixels differ,
+//similarity 99.88%"
+func (d *ImageDiff) String() string {
+	if !d.sameSize() {
+		return fmt.Sprintf("image sizes differ: %v vs %v", d.a.Bounds().Size(), d.b.Bounds().Size())
+	}
+	s := fmt.Sprintf("%d of %d pixels differ, similarity %.2f%%", d.Pixels, d.Total, 100*d.Similarity)
+	if d.o.minSimilarity < 1 {
+		s += fmt.Sprintf(" (minimum %.2f%%)", 100*d.o.minSimilarity)
+	}
+	return s
+}
+
+//Image renders the difference: b faded to gray with the pixels that differ
+//in red. Images of different sizes are drawn over each other, with the
+//area only one of them covers in red.
+func (d *ImageDiff) Image() image.Image {
+	ra, rb := d.a.Bounds(), d.b.Bounds()
+	w, h := maxInt(ra.Dx(), rb.Dx()), maxInt(ra.Dy(), rb.Dy())
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			inA, inB := x < ra.Dx() && y < ra.Dy(), x < rb.Dx() && y < rb.Dy()
+			if !inA || !inB || d.delta(x, y) > d.o.pixelTolerance {
+				out.Set(x, y, red)
+				continue
+			}
+			// fade to a light gray so the red marks stand out
+			l, _, _ := yiq(d.b.At(rb.Min.X+x, rb.Min.Y+y))
+			v := uint8(0xff - (0xff-l)*0.25)
+			out.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 0xff})
+		}
+	}
+	return out
+}
+
+//WritePNG writes Image to path as a PNG, creating missing directories
+func (d *ImageDiff) WritePNG(path string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, d.Image()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), os.FileMode(0666))
+}
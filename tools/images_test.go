@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chart(w, h int, bars map[int]color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c, ok := bars[x]
+			if !ok {
+				c = color.White
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDiffImages(t *testing.T) {
+	a := chart(10, 10, map[int]color.Color{2: color.Black})
+	assert.True(t, DiffImages(a, chart(10, 10, map[int]color.Color{2: color.Black})).Equal())
+
+	// a slightly different shade is within the pixel tolerance
+	d := DiffImages(a, chart(10, 10, map[int]color.Color{2: color.Gray{Y: 20}}))
+	assert.True(t, d.Equal())
+	assert.Equal(t, 0, d.Pixels)
+	assert.True(t, d.Similarity < 1)
+
+	b := chart(10, 10, map[int]color.Color{2: color.Black, 7: color.Black})
+	d = DiffImages(a, b)
+	assert.False(t, d.Equal())
+	assert.Equal(t, 10, d.Pixels)
+	assert.Equal(t, 100, d.Total)
+	assert.InDelta(t, 0.9, d.Similarity, 0.001)
+	assert.Equal(t, "10 of 100 pixels differ, similarity 90.00%", d.String())
+
+	d = DiffImages(a, b, WithMinSimilarity(0.85))
+	assert.True(t, d.Equal())
+	assert.Equal(t, "10 of 100 pixels differ, similarity 90.00% (minimum 85.00%)", d.String())
+	assert.False(t, DiffImages(a, b, WithPixelTolerance(1)).Pixels > 0)
+
+	diff := d.Image()
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, diff.At(7, 3))
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, diff.At(0, 0))
+	assert.Equal(t, color.RGBA{R: 0xbf, G: 0xbf, B: 0xbf, A: 0xff}, diff.At(2, 0))
+
+	d = DiffImages(a, chart(12, 10, nil))
+	assert.False(t, d.Equal())
+	assert.Equal(t, 0.0, d.Similarity)
+	assert.Equal(t, "image sizes differ: (10,10) vs (12,10)", d.String())
+	assert.Equal(t, image.Rect(0, 0, 12, 10), d.Image().Bounds())
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, d.Image().At(11, 0))
+}
+
+func TestDiffPNG(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, img))
+		return buf.Bytes()
+	}
+	a := encode(chart(4, 4, nil))
+	d, err := DiffPNG(a, encode(chart(4, 4, map[int]color.Color{1: color.Black})))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, d.Pixels)
+
+	_, err = DiffPNG(a, []byte("not a png"))
+	assert.EqualError(t, err, "decode b: image: unknown format")
+
+	dir, err := ioutil.TempDir("", "images")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testdata", "chart.diff.png")
+	assert.NoError(t, d.WritePNG(path))
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 4), img.Bounds())
+}
@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+//DiffJSON creates a Differ that parses a and b as JSON and compares the
+//resulting documents, so key order and whitespace are ignored. Changes are
+//reported by JSON pointer. If either side is not valid JSON it falls back
+//to a text Diff of the raw input and Error returns the parse error.
+func DiffJSON(a, b []byte, opts ...Option) Differ {
+	ja, errA := parseJSON(a)
+	jb, errB := parseJSON(b)
+	if errA != nil || errB != nil {
+		return &fallbackDiff{Diff(string(a), string(b), opts...), firstError(errA, errB)}
+	}
+
+	o := newOptions(opts)
+	w := &jsonWalker{opts: o}
+	w.walk("", ja, jb)
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+func parseJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+type jsonWalker struct {
+	opts    *options
+	changes []change
+}
+
+func (w *jsonWalker) walk(path string, a, b interface{}) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			w.changed(path, a, b)
+			return
+		}
+		keys := make([]string, 0, len(va)+len(vb))
+		for k := range va {
+			keys = append(keys, k)
+		}
+		for k := range vb {
+			if _, ok := va[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kp := path + "/" + pointerEscaper.Replace(k)
+			ea, okA := va[k]
+			eb, okB := vb[k]
+			switch {
+			case !okB:
+				w.add(change{typ: removed, path: kp, exp: formatJSON(ea), key: true})
+			case !okA:
+				if !w.opts.ignoreExtraKeys {
+					w.add(change{typ: added, path: kp, act: formatJSON(eb), key: true})
+				}
+			default:
+				w.walk(kp, ea, eb)
+			}
+		}
+
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			w.changed(path, a, b)
+			return
+		}
+		if w.opts.unordered() {
+			w.walkList(path, va, vb)
+			return
+		}
+		if w.opts.detectMoves {
+			w.walkMoves(path, va, vb)
+			return
+		}
+		n := len(va)
+		if len(vb) > n {
+			n = len(vb)
+		}
+		for i := 0; i < n; i++ {
+			ip := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(vb):
+				w.add(change{typ: removed, path: ip, exp: formatJSON(va[i])})
+			case i >= len(va):
+				w.add(change{typ: added, path: ip, act: formatJSON(vb[i])})
+			default:
+				w.walk(ip, va[i], vb[i])
+			}
+		}
+
+	case json.Number:
+		vb, ok := b.(json.Number)
+		if !ok || (!numberEqual(va, vb) && !w.withinTolerance(va, vb)) {
+			w.changed(path, a, b)
+		}
+
+	default:
+		// string, bool and null compare directly
+		if a != b {
+			w.changed(path, a, b)
+		}
+	}
+}
+
+// walkMoves aligns array elements by value so reordered elements are
+// reported as moved
+func (w *jsonWalker) walkMoves(path string, a, b []interface{}) {
+	keys := func(vals []interface{}) []string {
+		ks := make([]string, len(vals))
+		for i, v := range vals {
+			ks[i] = formatJSON(v)
+		}
+		return ks
+	}
+	index := func(i int) string {
+		return path + "/" + strconv.Itoa(i)
+	}
+
+	for _, s := range alignSeq(keys(a), keys(b), w.opts) {
+		switch {
+		case s.moved:
+			w.add(change{typ: moved, path: index(s.j), exp: index(s.i), act: formatJSON(b[s.j])})
+		case s.j < 0:
+			w.add(change{typ: removed, path: index(s.i), exp: formatJSON(a[s.i])})
+		case s.i < 0:
+			w.add(change{typ: added, path: index(s.j), act: formatJSON(b[s.j])})
+		default:
+			w.walk(index(s.j), a[s.i], b[s.j])
+		}
+	}
+}
+
+func (w *jsonWalker) add(c change) {
+	if c.path == "" {
+		c.path = "(root)"
+	}
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func (w *jsonWalker) changed(path string, a, b interface{}) {
+	w.add(change{typ: changed, path: path, exp: formatJSON(a), act: formatJSON(b)})
+}
+
+func (w *jsonWalker) withinTolerance(a, b json.Number) bool {
+	fa, errA := a.Float64()
+	fb, errB := b.Float64()
+	return errA == nil && errB == nil && w.opts.withinTolerance(fa, fb)
+}
+
+// numberEqual treats numbers as equal if they have the same value,
+// e.g. 1, 1.0 and 1e0. They are compared exactly so large IDs that round to
+// the same float are still different.
+func numberEqual(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	ra, okA := new(big.Rat).SetString(string(a))
+	rb, okB := new(big.Rat).SetString(string(b))
+	return okA && okB && ra.Cmp(rb) == 0
+}
+
+func formatJSON(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
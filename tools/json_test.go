@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		exp  string
+	}{
+		{
+			name: "equal",
+			a:    `{"a": 1, "b": [1, 2, {"c": null}]}`,
+			b:    `{"b":[1,2.0,{"c":null}],"a":1e0}`,
+			exp:  "",
+		},
+		{
+			name: "changes",
+			a:    `{"a": 1, "b": [1, 2], "c/d": "x", "e": true}`,
+			b:    `{"a": "1", "b": [1], "c/d": "y", "f": {"g": 1}}`,
+			exp: "/a:\n-1\n+\"1\"\n" +
+				"/b/1:\n-2\n" +
+				"/c~1d:\n-\"x\"\n+\"y\"\n" +
+				"/e:\n-true\n" +
+				"/f:\n+{\"g\":1}\n",
+		},
+		{
+			name: "large numbers",
+			a:    `{"id": 123456789012345678901, "n": 1.50}`,
+			b:    `{"id": 123456789012345678902, "n": 15e-1}`,
+			exp:  "/id:\n-123456789012345678901\n+123456789012345678902\n",
+		},
+		{
+			name: "root",
+			a:    `[1]`,
+			b:    `{}`,
+			exp:  "(root):\n-[1]\n+{}\n",
+		},
+		{
+			name: "invalid",
+			a:    `{"a":`,
+			b:    `{"a":`,
+			exp:  "{\"a\":\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		d := DiffJSON([]byte(test.a), []byte(test.b)).String()
+		d = regExColor.ReplaceAllString(d, "")
+		assert.Equal(t, test.exp, d, test.name)
+	}
+}
+
+func TestDiffJSONTolerance(t *testing.T) {
+	a := []byte(`{"x": 0.3, "y": [1, 2.5], "z": "a"}`)
+	b := []byte(`{"x": 0.30000000000000004, "y": [1.0000001, 2.6], "z": "a"}`)
+
+	d := DiffJSON(a, b, WithTolerance(1e-6, 0), WithNoColor())
+	assert.Equal(t, "/y/1:\n-2.5\n+2.6\n", d.String())
+	assert.True(t, DiffJSON(a, b, WithTolerance(0, 0.05)).Equal())
+	assert.False(t, DiffJSON(a, b).Equal())
+}
+
+func TestDiffJSONKeys(t *testing.T) {
+	a := []byte(`{"id": 1, "name": "ann", "address": {"zip": "02134"}}`)
+	b := []byte(`{"id": 1, "name": "bob", "address": {"zip": "02134", "city": "Boston"}, "created": "2026"}`)
+
+	d := DiffJSON(a, b, WithKeySummary(), WithNoColor())
+	assert.Equal(t, "extra keys: /address/city, /created\n/address/city:\n+\"Boston\"\n/created:\n+\"2026\"\n/name:\n-\"ann\"\n+\"bob\"\n", d.String())
+
+	d = DiffJSON(a, b, IgnoreExtraKeys(), WithNoColor())
+	assert.Equal(t, "/name:\n-\"ann\"\n+\"bob\"\n", d.String())
+	assert.True(t, DiffJSON([]byte(`{"id": 1}`), b, IgnoreExtraKeys()).Equal())
+
+	d = DiffJSON(b, a, IgnoreExtraKeys(), WithKeySummary(), IgnorePath("/created"), WithNoColor())
+	assert.Equal(t, "missing keys: /address/city\n/address/city:\n-\"Boston\"\n/created:\n-\"2026\"\n/name:\n-\"bob\"\n+\"ann\"\n", d.String())
+}
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+//DiffJSONL creates a Differ that compares newline delimited JSON streams,
+//such as structured logs, record by record. Records are aligned by
+//position, or by the values of the top level fields named with
+//WithKeyColumns, e.g. "msg" and "trace_id", so they can be reordered.
+//Fields named with IgnoreFields, e.g. timestamps and durations, are left
+//out. Each matched record is compared like DiffJSON and changes are
+//reported by record and JSON pointer, e.g. [2]/level or
+//[msg=done,trace_id=7]/status, added and removed records as JSON. Blank
+//lines are skipped. If a line is not valid JSON it falls back to a text
+//Diff and Error returns the parse error.
+func DiffJSONL(a, b io.Reader, opts ...Option) Differ {
+	o := newOptions(opts)
+	textA, errA := ioutil.ReadAll(a)
+	textB, errB := ioutil.ReadAll(b)
+	if err := firstError(errA, errB); err != nil {
+		return &fallbackDiff{Diff(string(textA), string(textB), opts...), err}
+	}
+
+	ra, errA := parseJSONL(string(textA), o)
+	rb, errB := parseJSONL(string(textB), o)
+	if err := firstError(errA, errB); err != nil {
+		return &fallbackDiff{Diff(string(textA), string(textB), opts...), err}
+	}
+
+	w := &jsonWalker{opts: o}
+	if len(o.keyColumns) > 0 {
+		walkKeyedRecords(w, ra, rb)
+	} else {
+		walkRecords(w, ra, rb)
+	}
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+// parseJSONL parses each non-blank line and removes the ignored fields
+func parseJSONL(text string, o *options) ([]interface{}, error) {
+	var records []interface{}
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(nil, 64<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		v, err := parseJSON([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		for _, f := range o.ignoreFields {
+			removeField(v, f)
+		}
+		records = append(records, v)
+	}
+	return records, sc.Err()
+}
+
+// removeField deletes a top level field, or the field at a JSON pointer
+func removeField(v interface{}, field string) {
+	parts := []string{field}
+	if strings.HasPrefix(field, "/") {
+		parts = strings.Split(field[1:], "/")
+		for i, p := range parts {
+			parts[i] = pointerUnescaper.Replace(p)
+		}
+	}
+	for i, p := range parts {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if i == len(parts)-1 {
+			delete(m, p)
+			return
+		}
+		v = m[p]
+	}
+}
+
+// walkRecords aligns records by position like DiffCSV aligns rows
+func walkRecords(w *jsonWalker, a, b []interface{}) {
+	keys := func(records []interface{}) []string {
+		ks := make([]string, len(records))
+		for i, r := range records {
+			ks[i] = formatJSON(r)
+		}
+		return ks
+	}
+	index := func(i int) string {
+		return "[" + strconv.Itoa(i) + "]"
+	}
+	for _, s := range alignSeq(keys(a), keys(b), w.opts) {
+		switch {
+		case s.moved:
+			w.add(change{typ: moved, path: index(s.j), exp: index(s.i), act: formatJSON(b[s.j])})
+		case s.j < 0:
+			w.add(change{typ: removed, path: index(s.i), exp: formatJSON(a[s.i])})
+		case s.i < 0:
+			w.add(change{typ: added, path: index(s.j), act: formatJSON(b[s.j])})
+		default:
+			w.walk(index(s.j), a[s.i], b[s.j])
+		}
+	}
+}
+
+// walkKeyedRecords matches records by their key fields, duplicate keys are
+// matched in order. Records are reported in the order of b, followed by
+// removed records.
+func walkKeyedRecords(w *jsonWalker, a, b []interface{}) {
+	path := func(r interface{}) string {
+		m, _ := r.(map[string]interface{})
+		parts := make([]string, len(w.opts.keyColumns))
+		for i, k := range w.opts.keyColumns {
+			v := ""
+			switch f := m[k].(type) {
+			case nil:
+			case string:
+				v = f
+			default:
+				v = formatJSON(f)
+			}
+			parts[i] = k + "=" + v
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+
+	recordsA := make(map[string][]int)
+	for i, r := range a {
+		p := path(r)
+		recordsA[p] = append(recordsA[p], i)
+	}
+	matched := make(map[int]bool)
+	for _, r := range b {
+		p := path(r)
+		if is := recordsA[p]; len(is) > 0 {
+			recordsA[p] = is[1:]
+			matched[is[0]] = true
+			w.walk(p, a[is[0]], r)
+			continue
+		}
+		w.add(change{typ: added, path: p, act: formatJSON(r)})
+	}
+	for i, r := range a {
+		if !matched[i] {
+			w.add(change{typ: removed, path: path(r), exp: formatJSON(r)})
+		}
+	}
+}
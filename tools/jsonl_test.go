@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const logA = `{"ts":"2024-01-01T00:00:00Z","level":"info","msg":"start","trace_id":"1"}
+{"ts":"2024-01-01T00:00:01Z","level":"info","msg":"request","trace_id":"1","http":{"status":200,"duration":0.12}}
+
+{"ts":"2024-01-01T00:00:02Z","level":"info","msg":"request","trace_id":"2","http":{"status":200,"duration":0.3}}
+{"ts":"2024-01-01T00:00:03Z","level":"info","msg":"stop","trace_id":"1"}
+`
+
+const logB = `{"ts":"2024-06-01T10:00:00Z","level":"info","msg":"start","trace_id":"1"}
+{"ts":"2024-06-01T10:00:01Z","level":"info","msg":"request","trace_id":"2","http":{"status":200,"duration":0.1}}
+{"ts":"2024-06-01T10:00:02Z","level":"warn","msg":"request","trace_id":"1","http":{"status":500,"duration":0.5}}
+{"ts":"2024-06-01T10:00:03Z","level":"info","msg":"retry","trace_id":"1"}
+`
+
+func TestDiffJSONL(t *testing.T) {
+	d := DiffJSONL(strings.NewReader(logA), strings.NewReader(logB), WithNoColor(),
+		WithKeyColumns("msg", "trace_id"), IgnoreFields("ts", "/http/duration"))
+	assert.NoError(t, d.Error())
+	assert.Equal(t, `[msg=request,trace_id=1]/http/status:
+-200
++500
+[msg=request,trace_id=1]/level:
+-"info"
++"warn"
+[msg=retry,trace_id=1]:
++{"level":"info","msg":"retry","trace_id":"1"}
+[msg=stop,trace_id=1]:
+-{"level":"info","msg":"stop","trace_id":"1"}
+`, d.String())
+
+	same := "{\"b\":1,\"a\":[1,2]}\n{\"ts\":3}"
+	assert.True(t, DiffJSONL(strings.NewReader(same), strings.NewReader("{\"a\":[1,2],\"b\":1}\n\n{\"ts\":4}\n"), IgnoreFields("ts")).Equal())
+}
+
+func TestDiffJSONLByPosition(t *testing.T) {
+	a := "{\"msg\":\"a\"}\n{\"msg\":\"b\"}\n{\"msg\":\"c\"}\n"
+	b := "{\"msg\":\"a\"}\n{\"msg\":\"x\"}\n{\"msg\":\"b\"}\n{\"msg\":\"C\"}\n"
+	d := DiffJSONL(strings.NewReader(a), strings.NewReader(b), WithNoColor())
+	assert.Equal(t, "[1]:\n+{\"msg\":\"x\"}\n[3]/msg:\n-\"c\"\n+\"C\"\n", d.String())
+}
+
+func TestDiffJSONLInvalid(t *testing.T) {
+	d := DiffJSONL(strings.NewReader("{\"a\":1}\n{oops\n"), strings.NewReader("{\"a\":1}\n"), WithNoColor())
+	assert.EqualError(t, d.Error(), "line 2: invalid character 'o' looking for beginning of object key string")
+	assert.False(t, d.Equal())
+	assert.Contains(t, d.String(), "-{oops\n")
+}
@@ -0,0 +1,503 @@
+package tools
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// lineOp is a single line of a line diff including its newline, if any.
+// Ignored ops are differences that were ignored by an option, they are
+// rendered but don't count as changes. Moved ops are part of a block that
+// was deleted in one place and inserted in another.
+type lineOp struct {
+	op      dmp.Operation
+	text    string
+	ignored bool
+	moved   bool
+}
+
+func (l lineOp) isChange() bool {
+	return l.op != dmp.DiffEqual && !l.ignored
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, nl)
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a line diff with the configured algorithm. Lines are
+// compared by their key so options can make different lines compare equal,
+// and the original text is restored afterwards.
+func diffLines(a, b string, o *options) []lineOp {
+	la := splitLines(a)
+	lb := splitLines(b)
+	return diffKeyed(la, lb, o.lineKeys(la), o.lineKeys(lb), o)
+}
+
+// diffKeyed diffs lines la and lb by their keys ka and kb. Lines with equal
+// keys but different text are equal when only whitespace options made them
+// so, rendered with the old text, an ignored delete and insert when the key
+// is ignored, and a delete and insert when hashed keys only collided.
+func diffKeyed(la, lb, ka, kb []string, o *options) []lineOp {
+	var keyed []lineOp
+	switch o.algorithm {
+	case Patience:
+		keyed = patienceDiff(ka, kb, o)
+	case Histogram:
+		keyed = histogramDiff(ka, kb, o)
+	default:
+		keyed = myersDiff(ka, kb, o, true)
+	}
+
+	ops := make([]lineOp, 0, len(keyed))
+	i, j := 0, 0
+	for _, k := range keyed {
+		switch k.op {
+		case dmp.DiffDelete:
+			ops = append(ops, lineOp{op: dmp.DiffDelete, text: la[i], ignored: isIgnoredKey(ka[i])})
+			i++
+		case dmp.DiffInsert:
+			ops = append(ops, lineOp{op: dmp.DiffInsert, text: lb[j], ignored: isIgnoredKey(kb[j])})
+			j++
+		default:
+			if la[i] == lb[j] || (!isIgnoredKey(ka[i]) && o.lineKey(la[i]) == o.lineKey(lb[j])) {
+				ops = append(ops, lineOp{op: dmp.DiffEqual, text: la[i]})
+			} else {
+				ignored := isIgnoredKey(ka[i])
+				ops = append(ops,
+					lineOp{op: dmp.DiffDelete, text: la[i], ignored: ignored},
+					lineOp{op: dmp.DiffInsert, text: lb[j], ignored: ignored})
+			}
+			i++
+			j++
+		}
+	}
+	if o.detectMoves {
+		markMoves(ops)
+	}
+	return ops
+}
+
+// inlineDiffs pairs each run of changed lines with the run of inserted lines
+// after it and returns the character diff of every pair, indexed like ops.
+// Runs are only paired when they have the same number of lines, otherwise
+// the lines are rendered whole.
+func inlineDiffs(ops []lineOp, o *options) [][]dmp.Diff {
+	inline := make([][]dmp.Diff, len(ops))
+	for i := 0; i < len(ops); {
+		if !ops[i].isChange() || ops[i].moved || ops[i].op != dmp.DiffDelete {
+			i++
+			continue
+		}
+
+		del := i
+		for i < len(ops) && ops[i].isChange() && !ops[i].moved && ops[i].op == dmp.DiffDelete {
+			i++
+		}
+		ins := i
+		for i < len(ops) && ops[i].isChange() && !ops[i].moved && ops[i].op == dmp.DiffInsert {
+			i++
+		}
+		if i-ins != ins-del {
+			continue
+		}
+
+		gd := o.dmp()
+		for j := 0; j < ins-del; j++ {
+			a := strings.TrimSuffix(ops[del+j].text, nl)
+			b := strings.TrimSuffix(ops[ins+j].text, nl)
+			diffs := gd.DiffCleanupSemantic(gd.DiffMain(a, b, false))
+			inline[del+j], inline[ins+j] = diffs, diffs
+		}
+	}
+	return inline
+}
+
+// myersDiff runs diffmatchpatch over lines by encoding each unique line as
+// a single rune
+func myersDiff(a, b []string, o *options, cleanup bool) []lineOp {
+	index := make(map[string]rune)
+	var lines []string
+	encode := func(in []string) []rune {
+		rs := make([]rune, len(in))
+		for i, l := range in {
+			r, ok := index[l]
+			if !ok {
+				r = lineRune(len(lines))
+				index[l] = r
+				lines = append(lines, l)
+			}
+			rs[i] = r
+		}
+		return rs
+	}
+	ra := encode(a)
+	rb := encode(b)
+
+	gd := o.dmp()
+	diffs := gd.DiffMainRunes(ra, rb, false)
+	if cleanup {
+		diffs = o.cleanupDiffs(gd, diffs)
+	}
+
+	var ops []lineOp
+	for _, diff := range diffs {
+		for _, r := range diff.Text {
+			ops = append(ops, lineOp{op: diff.Type, text: lines[runeLine(r)]})
+		}
+	}
+	return ops
+}
+
+// lineRune maps a line index to a rune, skipping the surrogate range which
+// does not survive conversion to a string
+func lineRune(i int) rune {
+	if i >= 0xD800 {
+		return rune(i + 0x800)
+	}
+	return rune(i)
+}
+
+func runeLine(r rune) int {
+	if r >= 0xE000 {
+		return int(r) - 0x800
+	}
+	return int(r)
+}
+
+// patienceDiff anchors the diff on lines that are unique on both sides,
+// recursing between anchors and falling back to myersDiff when there are
+// none
+func patienceDiff(a, b []string, o *options) []lineOp {
+	var ops []lineOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	tail := a[len(a)-n:]
+	a, b = a[:len(a)-n], b[:len(b)-n]
+
+	anchors := uniqueLCS(a, b)
+	if len(anchors) == 0 {
+		ops = append(ops, myersDiff(a, b, o, false)...)
+	} else {
+		ai, bi := 0, 0
+		for _, m := range anchors {
+			ops = append(ops, patienceDiff(a[ai:m.a], b[bi:m.b], o)...)
+			ops = append(ops, lineOp{op: dmp.DiffEqual, text: a[m.a]})
+			ai, bi = m.a+1, m.b+1
+		}
+		ops = append(ops, patienceDiff(a[ai:], b[bi:], o)...)
+	}
+
+	for _, l := range tail {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: l})
+	}
+	return ops
+}
+
+type lineMatch struct {
+	a int
+	b int
+}
+
+// uniqueLCS returns the longest common subsequence of the lines that occur
+// exactly once in both a and b, found with patience sorting
+func uniqueLCS(a, b []string) []lineMatch {
+	type count struct {
+		a, b   int
+		bIndex int
+	}
+	counts := make(map[string]*count)
+	for _, l := range a {
+		c, ok := counts[l]
+		if !ok {
+			c = &count{}
+			counts[l] = c
+		}
+		c.a++
+	}
+	for i, l := range b {
+		if c, ok := counts[l]; ok {
+			c.b++
+			c.bIndex = i
+		}
+	}
+
+	var unique []lineMatch
+	for i, l := range a {
+		if c := counts[l]; c.a == 1 && c.b == 1 {
+			unique = append(unique, lineMatch{a: i, b: c.bIndex})
+		}
+	}
+
+	var piles []int
+	prev := make([]int, len(unique))
+	for i, m := range unique {
+		k := sort.Search(len(piles), func(j int) bool {
+			return unique[piles[j]].b > m.b
+		})
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = piles[k-1]
+		}
+		if k == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[k] = i
+		}
+	}
+	if len(piles) == 0 {
+		return nil
+	}
+
+	lcs := make([]lineMatch, len(piles))
+	for i, k := len(piles)-1, piles[len(piles)-1]; k >= 0; i, k = i-1, prev[k] {
+		lcs[i] = unique[k]
+	}
+	return lcs
+}
+
+// histogramMaxChain is the most times a line may occur in a before
+// histogramDiff stops considering it as an anchor
+const histogramMaxChain = 64
+
+// histogramDiff anchors the diff on the longest run of common lines that
+// contains the rarest line, recursing on both sides of it and falling back
+// to myersDiff when no line is rare enough
+func histogramDiff(a, b []string, o *options) []lineOp {
+	var ops []lineOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	tail := a[len(a)-n:]
+	a, b = a[:len(a)-n], b[:len(b)-n]
+
+	if m, ok := histogramMatch(a, b); !ok {
+		ops = append(ops, myersDiff(a, b, o, false)...)
+	} else {
+		ops = append(ops, histogramDiff(a[:m.a], b[:m.b], o)...)
+		for _, l := range a[m.a : m.a+m.n] {
+			ops = append(ops, lineOp{op: dmp.DiffEqual, text: l})
+		}
+		ops = append(ops, histogramDiff(a[m.a+m.n:], b[m.b+m.n:], o)...)
+	}
+
+	for _, l := range tail {
+		ops = append(ops, lineOp{op: dmp.DiffEqual, text: l})
+	}
+	return ops
+}
+
+// histogramRegion is a run of n equal lines starting at a and b
+type histogramRegion struct {
+	a, b, n int
+}
+
+// histogramMatch finds the common run of lines whose rarest line occurs the
+// fewest times in a, preferring the longest run on ties
+func histogramMatch(a, b []string) (histogramRegion, bool) {
+	positions := make(map[string][]int)
+	for i, l := range a {
+		positions[l] = append(positions[l], i)
+	}
+
+	var best histogramRegion
+	bestCount := histogramMaxChain + 1
+	for j := 0; j < len(b); j++ {
+		if len(positions[b[j]]) > histogramMaxChain {
+			continue
+		}
+		for _, i := range positions[b[j]] {
+			s, t := i, j
+			for s > 0 && t > 0 && a[s-1] == b[t-1] {
+				s, t = s-1, t-1
+			}
+			e := i + 1
+			for e < len(a) && t+e-s < len(b) && a[e] == b[t+e-s] {
+				e++
+			}
+
+			count := len(positions[b[j]])
+			for _, l := range a[s:e] {
+				if c := len(positions[l]); c < count {
+					count = c
+				}
+			}
+			if count < bestCount || (count == bestCount && e-s > best.n) {
+				best, bestCount = histogramRegion{a: s, b: t, n: e - s}, count
+			}
+		}
+	}
+	return best, best.n > 0
+}
+
+// hunk is a group of changed lines and their surrounding context
+type hunk struct {
+	oldStart int
+	oldLines int
+	newStart int
+	newLines int
+	ops      []lineOp
+	label    string
+}
+
+// maxLabel is how long a hunk label can be, like git diff
+const maxLabel = 80
+
+// hunkLabeler tracks the old lines matching WithHunkLabels, keeping only the
+// ones a hunk starting in the last n lines could still need
+type hunkLabeler struct {
+	re      *regexp.Regexp
+	n       int
+	matches []labelLine
+}
+
+type labelLine struct {
+	no   int
+	text string
+}
+
+// add records line no of the old text
+func (l *hunkLabeler) add(no int, text string) {
+	if l == nil {
+		return
+	}
+	text = strings.TrimRightFunc(strings.TrimSuffix(text, nl), unicode.IsSpace)
+	if l.re.MatchString(text) {
+		if len(text) > maxLabel {
+			text = text[:maxLabel]
+			for !utf8.ValidString(text) {
+				text = text[:len(text)-1]
+			}
+		}
+		l.matches = append(l.matches, labelLine{no, text})
+	}
+	for len(l.matches) > 1 && l.matches[1].no < no-l.n {
+		l.matches = l.matches[1:]
+	}
+}
+
+// label returns the last matching line before old line start
+func (l *hunkLabeler) label(start int) string {
+	if l == nil {
+		return ""
+	}
+	for i := len(l.matches) - 1; i >= 0; i-- {
+		if l.matches[i].no < start {
+			return l.matches[i].text
+		}
+	}
+	return ""
+}
+
+func newHunkLabeler(o *options) *hunkLabeler {
+	if o.hunkLabel == nil {
+		return nil
+	}
+	return &hunkLabeler{re: o.hunkLabel, n: o.contextLines}
+}
+
+// labelHunks sets the labels of hunks made from ops
+func labelHunks(ops []lineOp, hunks []hunk, o *options) []hunk {
+	l := newHunkLabeler(o)
+	if l == nil {
+		return hunks
+	}
+	no, hi := 1, 0
+	for _, op := range ops {
+		for ; hi < len(hunks) && hunks[hi].oldStart <= no; hi++ {
+			hunks[hi].label = l.label(hunks[hi].oldStart)
+		}
+		if op.op != dmp.DiffInsert {
+			l.add(no, op.text)
+			no++
+		}
+	}
+	for ; hi < len(hunks); hi++ {
+		hunks[hi].label = l.label(hunks[hi].oldStart)
+	}
+	return hunks
+}
+
+// makeHunks groups line ops into hunks with n lines of context around each
+// change, merging changes that are less than 2n lines apart
+func makeHunks(ops []lineOp, n int) []hunk {
+	oldNo := make([]int, len(ops)+1)
+	newNo := make([]int, len(ops)+1)
+	oldNo[0], newNo[0] = 1, 1
+	for i, l := range ops {
+		oldNo[i+1], newNo[i+1] = oldNo[i], newNo[i]
+		if l.op != dmp.DiffInsert {
+			oldNo[i+1]++
+		}
+		if l.op != dmp.DiffDelete {
+			newNo[i+1]++
+		}
+	}
+
+	var hunks []hunk
+	for i := 0; i < len(ops); {
+		if !ops[i].isChange() {
+			i++
+			continue
+		}
+
+		start := i - n
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(ops) {
+			if ops[end].isChange() {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && !ops[run].isChange() {
+				run++
+			}
+			if run == len(ops) || run-end > 2*n {
+				end += n
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, hunk{
+			oldStart: oldNo[start],
+			oldLines: oldNo[end] - oldNo[start],
+			newStart: newNo[start],
+			newLines: newNo[end] - newNo[start],
+			ops:      ops[start:end],
+		})
+		i = end
+	}
+	return hunks
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// listPair is an element of list a matched with one of list b, i or j is
+// -1 if the element has no match
+type listPair struct {
+	i, j int
+}
+
+// unordered reports whether lists are matched ignoring order
+func (o *options) unordered() bool {
+	return o.listMode != ListOrdered || len(o.listKeys) > 0
+}
+
+// matchList pairs the elements of lists of length n and m. With keys the
+// elements are matched by key, otherwise equal elements are matched
+// according to mode, and with ListSet duplicates are matched too. The pairs
+// are ordered like b, followed by the unmatched elements of a.
+func matchList(n, m int, mode ListMode, keysA, keysB []string, equal func(i, j int) bool) []listPair {
+	ja := make([]int, n)
+	ib := make([]int, m)
+	dup := make([]bool, n)
+	for i := range ja {
+		ja[i] = -1
+	}
+	for j := range ib {
+		ib[j] = -1
+	}
+
+	switch {
+	case keysA != nil:
+		byKey := make(map[string][]int)
+		for i, k := range keysA {
+			byKey[k] = append(byKey[k], i)
+		}
+		for j, k := range keysB {
+			if is := byKey[k]; len(is) > 0 {
+				ib[j], ja[is[0]] = is[0], j
+				byKey[k] = is[1:]
+			}
+		}
+
+	case mode != ListOrdered:
+		for j := 0; j < m; j++ {
+			dupOf := -1
+			for i := 0; i < n; i++ {
+				if !equal(i, j) {
+					continue
+				}
+				if ja[i] < 0 {
+					ib[j], ja[i] = i, j
+					break
+				}
+				if dupOf < 0 {
+					dupOf = i
+				}
+			}
+			if ib[j] < 0 && mode == ListSet {
+				ib[j] = dupOf
+			}
+		}
+		if mode == ListSet {
+			for i := 0; i < n; i++ {
+				for j := 0; j < m && ja[i] < 0 && !dup[i]; j++ {
+					dup[i] = equal(i, j)
+				}
+			}
+		}
+	}
+
+	// without keys the remaining elements are compared in order
+	for i, j := 0, 0; keysA == nil && j < m; j++ {
+		if ib[j] >= 0 {
+			continue
+		}
+		for i < n && (ja[i] >= 0 || dup[i]) {
+			i++
+		}
+		if i == n {
+			break
+		}
+		ib[j], ja[i] = i, j
+	}
+
+	pairs := make([]listPair, 0, n+m)
+	for j := 0; j < m; j++ {
+		pairs = append(pairs, listPair{ib[j], j})
+	}
+	for i := 0; i < n; i++ {
+		if ja[i] < 0 && !dup[i] {
+			pairs = append(pairs, listPair{i, -1})
+		}
+	}
+	return pairs
+}
+
+// walkList compares slices or arrays a and b with WithListMode or
+// WithListKeys
+func (w *valueWalker) walkList(path string, a, b reflect.Value) {
+	var keysA, keysB []string
+	if len(w.opts.listKeys) > 0 {
+		keysA, keysB = make([]string, a.Len()), make([]string, b.Len())
+		ok := true
+		for i := 0; i < len(keysA) && ok; i++ {
+			keysA[i], ok = w.listKey(a.Index(i))
+		}
+		for j := 0; j < len(keysB) && ok; j++ {
+			keysB[j], ok = w.listKey(b.Index(j))
+		}
+		if !ok {
+			keysA, keysB = nil, nil
+		}
+	}
+	// candidates are compared at the path of the element so IgnorePath
+	// applies while matching
+	equal := func(i, j int) bool {
+		sub := &valueWalker{opts: w.opts, root: w.root, visited: make(map[visit]bool)}
+		sub.walk(path+"["+strconv.Itoa(j)+"]", a.Index(i), b.Index(j))
+		return changeStats(sub.changes).changed() == 0
+	}
+
+	for _, p := range matchList(a.Len(), b.Len(), w.opts.listMode, keysA, keysB, equal) {
+		switch {
+		case p.j < 0:
+			w.removed(path+"["+strconv.Itoa(p.i)+"]", a.Index(p.i))
+		case p.i < 0:
+			w.added(path+"["+strconv.Itoa(p.j)+"]", b.Index(p.j))
+		default:
+			w.walk(path+"["+strconv.Itoa(p.j)+"]", a.Index(p.i), b.Index(p.j))
+		}
+	}
+}
+
+// listKey formats the fields of v named by WithListKeys, it returns false
+// if v is not a struct or a map with string keys
+func (w *valueWalker) listKey(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	parts := make([]string, len(w.opts.listKeys))
+	for i, name := range w.opts.listKeys {
+		var f reflect.Value
+		switch {
+		case v.Kind() == reflect.Struct:
+			f = v.FieldByName(name)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			f = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return "", false
+		}
+		parts[i] = formatValue(f)
+	}
+	return strings.Join(parts, ","), true
+}
+
+// walkList compares arrays a and b with WithListMode or WithListKeys
+func (w *jsonWalker) walkList(path string, a, b []interface{}) {
+	var keysA, keysB []string
+	if len(w.opts.listKeys) > 0 {
+		keysA, keysB = make([]string, len(a)), make([]string, len(b))
+		ok := true
+		for i := 0; i < len(a) && ok; i++ {
+			keysA[i], ok = w.listKey(a[i])
+		}
+		for j := 0; j < len(b) && ok; j++ {
+			keysB[j], ok = w.listKey(b[j])
+		}
+		if !ok {
+			keysA, keysB = nil, nil
+		}
+	}
+	index := func(i int) string {
+		return path + "/" + strconv.Itoa(i)
+	}
+	equal := func(i, j int) bool {
+		sub := &jsonWalker{opts: w.opts}
+		sub.walk(index(j), a[i], b[j])
+		return changeStats(sub.changes).changed() == 0
+	}
+
+	for _, p := range matchList(len(a), len(b), w.opts.listMode, keysA, keysB, equal) {
+		switch {
+		case p.j < 0:
+			w.add(change{typ: removed, path: index(p.i), exp: formatJSON(a[p.i])})
+		case p.i < 0:
+			w.add(change{typ: added, path: index(p.j), act: formatJSON(b[p.j])})
+		default:
+			w.walk(index(p.j), a[p.i], b[p.j])
+		}
+	}
+}
+
+// listKey formats the fields of v named by WithListKeys, it returns false
+// if v is not an object
+func (w *jsonWalker) listKey(v interface{}) (string, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	parts := make([]string, len(w.opts.listKeys))
+	for i, name := range w.opts.listKeys {
+		f, ok := obj[name]
+		if !ok {
+			continue
+		}
+		parts[i] = formatJSON(f)
+	}
+	return strings.Join(parts, ","), true
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchList(t *testing.T) {
+	equal := func(a, b []int) func(i, j int) bool {
+		return func(i, j int) bool { return a[i] == b[j] }
+	}
+
+	a, b := []int{1, 2, 3}, []int{3, 1, 4}
+	assert.Equal(t, []listPair{{2, 0}, {0, 1}, {1, 2}}, matchList(3, 3, ListMultiset, nil, nil, equal(a, b)))
+
+	a, b = []int{1, 1, 2}, []int{2, 1}
+	assert.Equal(t, []listPair{{2, 0}, {0, 1}, {1, -1}}, matchList(3, 2, ListMultiset, nil, nil, equal(a, b)))
+	assert.Equal(t, []listPair{{2, 0}, {0, 1}}, matchList(3, 2, ListSet, nil, nil, equal(a, b)))
+
+	a, b = []int{1}, []int{1, 1, 5}
+	assert.Equal(t, []listPair{{0, 0}, {0, 1}, {-1, 2}}, matchList(1, 3, ListSet, nil, nil, equal(a, b)))
+
+	keysA, keysB := []string{"x", "y", "x"}, []string{"y", "z", "x", "x"}
+	assert.Equal(t, []listPair{{1, 0}, {-1, 1}, {0, 2}, {2, 3}}, matchList(3, 4, ListOrdered, keysA, keysB, nil))
+}
+
+type listUser struct {
+	ID   int
+	Name string
+}
+
+func TestWithListMode(t *testing.T) {
+	a := []string{"a", "b", "c"}
+	b := []string{"c", "a", "b"}
+	assert.False(t, DiffValues(a, b).Equal())
+	assert.True(t, DiffValues(a, b, WithListMode(ListMultiset)).Equal())
+
+	d := DiffValues([]string{"a", "b", "b"}, []string{"b", "a", "x"}, WithNoColor(), WithListMode(ListMultiset))
+	assert.Equal(t, "[2]:\n-\"b\"\n+\"x\"\n", d.String())
+
+	assert.False(t, DiffValues([]string{"a", "a", "b"}, []string{"b", "a"}, WithListMode(ListMultiset)).Equal())
+	assert.True(t, DiffValues([]string{"a", "a", "b"}, []string{"b", "a"}, WithListMode(ListSet)).Equal())
+}
+
+func TestWithListKeys(t *testing.T) {
+	a := []listUser{{1, "ann"}, {2, "bob"}, {3, "cy"}}
+	b := []*listUser{{3, "cy"}, {1, "ann"}, {2, "rob"}, {4, "dee"}}
+	d := DiffValues(a, []listUser{*b[0], *b[1], *b[2], *b[3]}, WithNoColor(), WithListKeys("ID"))
+	assert.Equal(t, "[2].Name:\n-\"bob\"\n+\"rob\"\n[3]:\n+tools.listUser{ID:4, Name:\"dee\"}\n", d.String())
+
+	m := []map[string]int{{"id": 1, "n": 1}, {"id": 2, "n": 2}}
+	assert.True(t, DiffValues(m, []map[string]int{m[1], m[0]}, WithListKeys("id")).Equal())
+}
+
+func TestDiffJSONListMode(t *testing.T) {
+	a := []byte(`{"tags":["a","b"],"users":[{"id":1,"name":"ann"},{"id":2,"name":"bob"}]}`)
+	b := []byte(`{"tags":["b","a","a"],"users":[{"id":2,"name":"rob"},{"id":1,"name":"ann"}]}`)
+
+	d := DiffJSON(a, b, WithNoColor(), WithListMode(ListSet), WithListKeys("id"))
+	assert.Equal(t, "/users/0/name:\n-\"bob\"\n+\"rob\"\n", d.String())
+
+	d = DiffJSON(a, b, WithNoColor(), WithListMode(ListMultiset))
+	assert.Equal(t, "/tags/2:\n+\"a\"\n/users/0/name:\n-\"bob\"\n+\"rob\"\n", d.String())
+
+	d = DiffJSON(a, b, WithNoColor(), WithListKeys("id"))
+	assert.Equal(t, "/tags/0:\n-\"a\"\n+\"b\"\n/tags/1:\n-\"b\"\n+\"a\"\n/tags/2:\n+\"a\"\n/users/0/name:\n-\"bob\"\n+\"rob\"\n", d.String())
+}
+
+type listEvent struct {
+	Name string
+	TS   int
+}
+
+type listDoc struct {
+	Items []listEvent
+}
+
+func TestListModeIgnorePath(t *testing.T) {
+	a := listDoc{Items: []listEvent{{"a", 1}, {"b", 2}}}
+	b := listDoc{Items: []listEvent{{"b", 3}, {"a", 4}}}
+	assert.True(t, DiffValues(a, b, WithListMode(ListMultiset), IgnorePath("listDoc.Items[*].TS")).Equal())
+	assert.True(t, DiffValues(a, b, WithListMode(ListSet), IgnorePath("listDoc.Items[*].TS")).Equal())
+	d := DiffValues(a, listDoc{Items: []listEvent{{"c", 3}, {"a", 4}}}, WithNoColor(), WithListMode(ListMultiset), IgnorePath("listDoc.Items[*].TS"))
+	assert.False(t, d.Equal())
+	assert.Equal(t, "listDoc.Items[0].Name:\n-\"b\"\n+\"c\"\nlistDoc.Items[0].TS:\n-2\n+3\nlistDoc.Items[1].TS:\n-1\n+4\n", d.String())
+
+	ja := []byte(`{"items":[{"name":"a","ts":1},{"name":"b","ts":2}]}`)
+	jb := []byte(`{"items":[{"name":"b","ts":3},{"name":"a","ts":4}]}`)
+	assert.True(t, DiffJSON(ja, jb, WithListMode(ListMultiset), IgnorePath("/items/*/ts")).Equal())
+	assert.True(t, DiffJSON(ja, jb, WithListMode(ListSet), IgnorePath("/items/*/ts")).Equal())
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+//MarkdownStyle selects how Markdown lays out a diff
+type MarkdownStyle int
+
+const (
+	//Fenced puts the whole diff in one ```diff code block, which GitHub and
+	//GitLab color by line
+	Fenced MarkdownStyle = iota
+
+	//Collapsed puts each hunk with its file header in a ```diff code block
+	//inside a <details> element summarized by its @@ line, so a long diff in
+	//a review comment can be expanded hunk by hunk. Diffs without hunks are
+	//Fenced.
+	Collapsed
+)
+
+// mdSection is a hunk of a rendered diff with the file header before it
+type mdSection struct {
+	label string
+	lines []string
+}
+
+// writeMarkdown writes what render writes without color as Markdown
+func writeMarkdown(w io.Writer, render func(w io.Writer, p *palette), style MarkdownStyle) error {
+	var text bytes.Buffer
+	render(&text, newPalette(false, Theme{}))
+	lines := splitLines(text.String())
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], nl)
+	}
+	fence := mdFence(text.String())
+
+	var buf bytes.Buffer
+	sections, ok := mdSections(lines)
+	if style != Collapsed || !ok {
+		fmt.Fprintf(&buf, "%sdiff\n", fence)
+		for _, l := range lines {
+			fmt.Fprintln(&buf, l)
+		}
+		fmt.Fprintln(&buf, fence)
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	for _, s := range sections {
+		fmt.Fprintf(&buf, "<details>\n<summary>%s</summary>\n\n%sdiff\n", html.EscapeString(s.label), fence)
+		for _, l := range s.lines {
+			fmt.Fprintln(&buf, l)
+		}
+		fmt.Fprintf(&buf, "%s\n\n</details>\n", fence)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// mdSections splits the lines of a diff at its @@ lines, repeating the
+// ---/+++ file header of a hunk in each of its sections. ok is false when
+// there are no hunks. Lines before the first hunk, like the summary of a
+// word diff, go in the first section and lines after the last, like
+// "... 2 more hunks", in the last.
+func mdSections(lines []string) ([]mdSection, bool) {
+	var sections []mdSection
+	var header, pending []string
+	file := ""
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if strings.HasPrefix(l, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			header = []string{l, lines[i+1]}
+			file = strings.TrimPrefix(lines[i+1], "+++ ")
+			if file == "/dev/null" {
+				file = strings.TrimPrefix(l, "--- ")
+			}
+			i++
+			continue
+		}
+		if strings.HasPrefix(l, "@@ ") {
+			label := l
+			if file != "" {
+				label = file + " " + l
+			}
+			s := mdSection{label: label}
+			s.lines = append(append(append(s.lines, pending...), header...), l)
+			sections = append(sections, s)
+			pending = nil
+			continue
+		}
+		if len(sections) == 0 {
+			pending = append(pending, l)
+			continue
+		}
+		last := &sections[len(sections)-1]
+		last.lines = append(last.lines, l)
+	}
+	return sections, len(sections) > 0
+}
+
+// mdFence returns a code fence longer than any run of backticks in text
+func mdFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = maxInt(longest, run)
+	}
+	return strings.Repeat("`", maxInt(3, longest+1))
+}
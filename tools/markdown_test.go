@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\n9\n"
+	d := Diff(a, b, WithContextLines(1), WithColor(ColorAlways))
+
+	var buf bytes.Buffer
+	assert.NoError(t, d.Markdown(&buf, Fenced))
+	assert.Equal(t, "```diff\n--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n@@ -8,2 +8,2 @@\n eight\n-nine\n+9\n```\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, d.Markdown(&buf, Collapsed))
+	assert.Equal(t, "<details>\n<summary>b @@ -1,3 +1,3 @@</summary>\n\n```diff\n--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n```\n\n</details>\n"+
+		"<details>\n<summary>b @@ -8,2 +8,2 @@</summary>\n\n```diff\n--- a\n+++ b\n@@ -8,2 +8,2 @@\n eight\n-nine\n+9\n```\n\n</details>\n", buf.String())
+
+	// value diffs have no hunks
+	buf.Reset()
+	assert.NoError(t, DiffJSON([]byte(`{"a":1}`), []byte(`{"a":2}`)).Markdown(&buf, Collapsed))
+	assert.Equal(t, "```diff\n/a:\n-1\n+2\n```\n", buf.String())
+}
+
+func TestMarkdownFence(t *testing.T) {
+	assert.Equal(t, "```", mdFence("no backticks"))
+	assert.Equal(t, "````", mdFence("```go\n```"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, Diff("```\n<b>\n", "```go\n<b>\n").Markdown(&buf, Collapsed))
+	assert.True(t, strings.HasPrefix(buf.String(), "<details>\n<summary>b @@ -1,2 +1,2 @@</summary>\n\n````diff\n"))
+
+	sections, ok := mdSections([]string{"--- a/x", "+++ /dev/null", "@@ -1 +0,0 @@", "-x", "... 1 more hunk"})
+	assert.True(t, ok)
+	assert.Equal(t, []mdSection{{label: "a/x @@ -1 +0,0 @@", lines: []string{"--- a/x", "+++ /dev/null", "@@ -1 +0,0 @@", "-x", "... 1 more hunk"}}}, sections)
+}
@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+//Merge is a three-way merge computed by Diff3
+type Merge struct {
+	//Conflicts is the number of regions changed differently on both sides
+	Conflicts int
+
+	chunks []mergeChunk
+	opts   *options
+}
+
+// mergeChunk is a run of merged lines, or a conflict between ours and
+// theirs with the base they were changed from
+type mergeChunk struct {
+	ours     []string
+	base     []string
+	theirs   []string
+	conflict bool
+}
+
+// edit replaces the base lines [start, end) with lines
+type edit struct {
+	start, end int
+	lines      []string
+}
+
+//Diff3 merges the changes from base to ours and from base to theirs line
+//by line, like git merge-file --diff3. Regions changed on only one side,
+//or the same way on both, are merged, the others are conflicts marked with
+//<<<<<<<, |||||||, ======= and >>>>>>>. The markers are labeled ours, base
+//and theirs, WithLabels sets the ours and theirs labels. Line options such
+//as IgnoreAllSpace or IgnoreSpaceChange apply when finding the changes, so
+//lines that only differ in whitespace from base keep the base text and the
+//whitespace edits of ours and theirs are dropped from the result.
+func Diff3(base, ours, theirs string, opts ...Option) *Merge {
+	o := newOptions(append([]Option{WithLabels("ours", "theirs")}, opts...))
+	lines := splitLines(base)
+	eo := edits(diffLines(base, ours, o))
+	et := edits(diffLines(base, theirs, o))
+
+	m := &Merge{opts: o}
+	pos, i, j := 0, 0, 0
+	for i < len(eo) || j < len(et) {
+		start := len(lines)
+		if i < len(eo) {
+			start = eo[i].start
+		}
+		if j < len(et) && et[j].start < start {
+			start = et[j].start
+		}
+		m.add(lines[pos:start], nil, nil, false)
+
+		// join every edit overlapping the group, or starting where it does
+		end := start
+		var gO, gT []edit
+		for {
+			switch {
+			case i < len(eo) && (eo[i].start < end || eo[i].start == start):
+				end = maxInt(end, eo[i].end)
+				gO = append(gO, eo[i])
+				i++
+				continue
+			case j < len(et) && (et[j].start < end || et[j].start == start):
+				end = maxInt(end, et[j].end)
+				gT = append(gT, et[j])
+				j++
+				continue
+			}
+			break
+		}
+
+		region := lines[start:end]
+		textO := apply(lines, start, end, gO)
+		textT := apply(lines, start, end, gT)
+		switch {
+		case len(gT) == 0:
+			m.add(textO, nil, nil, false)
+		case len(gO) == 0:
+			m.add(textT, nil, nil, false)
+		case strings.Join(textO, "") == strings.Join(textT, ""):
+			m.add(textO, nil, nil, false)
+		default:
+			m.add(textO, region, textT, true)
+		}
+		pos = end
+	}
+	m.add(lines[pos:], nil, nil, false)
+	return m
+}
+
+// add appends a chunk, joining runs of merged lines
+func (m *Merge) add(ours, base, theirs []string, conflict bool) {
+	if conflict {
+		m.Conflicts++
+		m.chunks = append(m.chunks, mergeChunk{ours: ours, base: base, theirs: theirs, conflict: true})
+		return
+	}
+	if len(ours) == 0 {
+		return
+	}
+	if n := len(m.chunks); n > 0 && !m.chunks[n-1].conflict {
+		m.chunks[n-1].ours = append(m.chunks[n-1].ours, ours...)
+		return
+	}
+	m.chunks = append(m.chunks, mergeChunk{ours: append([]string(nil), ours...)})
+}
+
+// edits turns a line diff from base into the base regions it replaces
+func edits(ops []lineOp) []edit {
+	var res []edit
+	var cur *edit
+	i := 0
+	for _, op := range ops {
+		if op.op == dmp.DiffEqual {
+			if cur != nil {
+				res = append(res, *cur)
+				cur = nil
+			}
+			i++
+			continue
+		}
+		if cur == nil {
+			cur = &edit{start: i, end: i}
+		}
+		if op.op == dmp.DiffDelete {
+			i++
+			cur.end = i
+		} else {
+			cur.lines = append(cur.lines, op.text)
+		}
+	}
+	if cur != nil {
+		res = append(res, *cur)
+	}
+	return res
+}
+
+// apply returns the base lines [start, end) with the edits applied
+func apply(lines []string, start, end int, es []edit) []string {
+	var res []string
+	pos := start
+	for _, e := range es {
+		res = append(res, lines[pos:e.start]...)
+		res = append(res, e.lines...)
+		pos = e.end
+	}
+	return append(res, lines[pos:end]...)
+}
+
+//Clean reports whether the merge has no conflicts
+func (m *Merge) Clean() bool {
+	return m.Conflicts == 0
+}
+
+//Result returns the merged text with conflict markers and without color
+func (m *Merge) Result() string {
+	var buf bytes.Buffer
+	m.write(&buf, newPalette(false, Theme{}))
+	return buf.String()
+}
+
+//Print prints the merged text to stdout
+func (m *Merge) Print() {
+	printDiff(m.opts, m.write)
+}
+
+//String returns the merged text, with the conflicts colored when color is
+//enabled
+func (m *Merge) String() string {
+	var buf bytes.Buffer
+	m.write(&buf, m.opts.palette(nil))
+	return buf.String()
+}
+
+//WriteTo writes the merged text to w
+func (m *Merge) WriteTo(w io.Writer) (int64, error) {
+	p := m.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { m.write(w, p) })
+}
+
+func (m *Merge) write(w io.Writer, p *palette) {
+	for _, c := range m.chunks {
+		if !c.conflict {
+			for _, l := range c.ours {
+				io.WriteString(w, l)
+			}
+			continue
+		}
+		writeMergeLines(w, p.mov, []string{"<<<<<<< " + m.opts.labelA})
+		writeMergeLines(w, p.del, c.ours)
+		writeMergeLines(w, p.mov, []string{"||||||| base"})
+		writeMergeLines(w, p.dim, c.base)
+		writeMergeLines(w, p.mov, []string{"======="})
+		writeMergeLines(w, p.ins, c.theirs)
+		writeMergeLines(w, p.mov, []string{">>>>>>> " + m.opts.labelB})
+	}
+}
+
+// writeMergeLines writes conflict lines in color, ending each with a
+// newline so the markers are on their own lines
+func writeMergeLines(w io.Writer, pr printer, lines []string) {
+	for _, l := range lines {
+		pr.Fprint(w, strings.TrimSuffix(l, nl))
+		io.WriteString(w, nl)
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+
+	// changes in different places merge cleanly
+	m := Diff3(base, "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\nf\n")
+	assert.True(t, m.Clean())
+	assert.Equal(t, "A\nb\nc\nd\nE\nf\n", m.Result())
+
+	// the same change on both sides is taken once
+	m = Diff3(base, "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n")
+	assert.True(t, m.Clean())
+	assert.Equal(t, "a\nB\nc\nd\ne\n", m.Result())
+
+	// a deletion on one side merges with an edit elsewhere
+	m = Diff3(base, "a\nc\nd\ne\n", "a\nb\nc\nD\ne\n")
+	assert.True(t, m.Clean())
+	assert.Equal(t, "a\nc\nD\ne\n", m.Result())
+
+	// different changes to the same lines conflict
+	m = Diff3(base, "a\nours\nc\nd\ne", "a\ntheirs\nc\nd\ne", WithNoColor())
+	assert.False(t, m.Clean())
+	assert.Equal(t, 1, m.Conflicts)
+	assert.Equal(t, "a\n<<<<<<< ours\nours\n||||||| base\nb\n=======\ntheirs\n>>>>>>> theirs\nc\nd\ne", m.Result())
+	assert.Equal(t, m.Result(), m.String())
+
+	// insertions at the same place conflict too
+	m = Diff3("x\n", "x\n1\n", "x\n2\n", WithLabels("HEAD", "feature"))
+	assert.Equal(t, "x\n<<<<<<< HEAD\n1\n||||||| base\n=======\n2\n>>>>>>> feature\n", m.Result())
+
+	m = Diff3(base, base, base)
+	assert.True(t, m.Clean())
+	assert.Equal(t, base, m.Result())
+	assert.True(t, Diff3("", "", "").Clean())
+}
+
+func TestDiff3Color(t *testing.T) {
+	p := newPalette(true, globalTheme())
+	m := Diff3("a\n", "b\n", "c\n", WithColor(ColorAlways))
+	assert.Equal(t, p.mov.Sprint("<<<<<<< ours")+"\n"+p.del.Sprint("b")+"\n"+p.mov.Sprint("||||||| base")+"\n"+p.dim.Sprint("a")+"\n"+
+		p.mov.Sprint("=======")+"\n"+p.ins.Sprint("c")+"\n"+p.mov.Sprint(">>>>>>> theirs")+"\n", m.String())
+	assert.NotContains(t, m.Result(), "\x1b[")
+}
+
+func TestDiff3IgnoreSpace(t *testing.T) {
+	// whitespace only edits are not changes, the base text is kept
+	m := Diff3("a\nb\n", "a \nb\n", "a\nc\n", IgnoreAllSpace())
+	assert.True(t, m.Clean())
+	assert.Equal(t, "a\nc\n", m.Result())
+}
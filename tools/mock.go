@@ -24,14 +24,19 @@ type TestMock struct {
 	orig *os.File
 	outc chan string
 	err  bytes.Buffer
+	log  bytes.Buffer
+	name string
+	done []func()
 }
 
 //TestResults contains the output normally sent to *testing.T and os.Stdout
 type TestResults struct {
 	Fail    bool
 	FailNow bool
+	Skipped bool
 	Err     string
 	Out     string
+	Log     string
 }
 
 //Mock creates a new TestMock
@@ -59,11 +64,41 @@ func (t *TestMock) FailNow() {
 	t.res.FailNow = true
 }
 
+//SetName sets the test name returned by Name
+func (t *TestMock) SetName(name string) {
+	t.name = name
+}
+
+//Name returns the test name set with SetName
+func (t *TestMock) Name() string {
+	return t.name
+}
+
+//Helper is a no-op so TestMock can stand in for helpers that call t.Helper
+func (t *TestMock) Helper() {}
+
 //Errorf writes error info
 func (t *TestMock) Errorf(format string, args ...interface{}) {
 	fmt.Fprintf(&t.err, format, args...)
 }
 
+//Cleanup registers f to run when Results is called, like t.Cleanup
+func (t *TestMock) Cleanup(f func()) {
+	t.done = append(t.done, f)
+}
+
+//Skipf marks the test as skipped and logs the message, like t.Skipf but
+//without stopping the test
+func (t *TestMock) Skipf(format string, args ...interface{}) {
+	t.res.Skipped = true
+	fmt.Fprintln(&t.log, fmt.Sprintf(format, args...))
+}
+
+//Log writes log output like t.Log, one line per call
+func (t *TestMock) Log(args ...interface{}) {
+	fmt.Fprintln(&t.log, args...)
+}
+
 func (t *TestMock) run() {
 	var buf bytes.Buffer
 	io.Copy(&buf, t.r)
@@ -73,11 +108,16 @@ func (t *TestMock) run() {
 //Results detaches from stdout and returns the TestResults that capture
 //the info normally sent to testing.T and stdout
 func (t *TestMock) Results() *TestResults {
+	for i := len(t.done) - 1; i >= 0; i-- {
+		t.done[i]()
+	}
+	t.done = nil
 	os.Stdout = t.orig
 	t.w.Close()
 	t.res.Out = <-t.outc
 	t.r.Close()
 	close(t.outc)
 	t.res.Err = t.err.String()
+	t.res.Log = t.log.String()
 	return &t.res
 }
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// moveMinChars is the fewest non blank characters a block of lines needs to
+// be reported as moved, like git's default, so short common lines such as
+// closing braces are not
+const moveMinChars = 20
+
+// markMoves marks blocks of inserted lines that were deleted elsewhere as
+// moved, on both sides, matching each insert with the longest run of
+// identical deleted lines
+func markMoves(ops []lineOp) {
+	dels := make(map[string][]int)
+	for i, l := range ops {
+		if l.op == dmp.DiffDelete && !l.ignored {
+			dels[l.text] = append(dels[l.text], i)
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].op != dmp.DiffInsert || ops[i].ignored {
+			i++
+			continue
+		}
+
+		best, n := -1, 0
+		for _, k := range dels[ops[i].text] {
+			m := 0
+			for i+m < len(ops) && k+m < len(ops) && movable(ops[i+m], ops[k+m]) {
+				m++
+			}
+			if m > n {
+				best, n = k, m
+			}
+		}
+		if n == 0 || blockChars(ops[i:i+n]) < moveMinChars {
+			i++
+			continue
+		}
+		for m := 0; m < n; m++ {
+			ops[i+m].moved = true
+			ops[best+m].moved = true
+		}
+		i += n
+	}
+}
+
+// movable reports whether the inserted line ins can be paired with the
+// deleted line del as part of a moved block
+func movable(ins, del lineOp) bool {
+	return ins.op == dmp.DiffInsert && del.op == dmp.DiffDelete &&
+		!ins.ignored && !del.ignored && !ins.moved && !del.moved &&
+		ins.text == del.text
+}
+
+func blockChars(ops []lineOp) int {
+	n := 0
+	for _, l := range ops {
+		n += len(strings.TrimFunc(l.text, unicode.IsSpace))
+	}
+	return n
+}
+
+// seqOp is one step of aligning two sequences: the elements a[i] and b[j]
+// are walked as a pair, moved from i to j, or only one side exists when the
+// other index is -1
+type seqOp struct {
+	i, j  int
+	moved bool
+}
+
+// alignSeq aligns sequences elements by their keys ka and kb, so inserting
+// or removing an element doesn't show every element after it as changed.
+// With DetectMoves identical elements at different positions are reported
+// as moved. The remaining elements between two matches are paired by
+// position.
+func alignSeq(ka, kb []string, o *options) []seqOp {
+	var ops []seqOp
+	var dels, ins []int
+	gap := func() {
+		for len(dels) > 0 && len(ins) > 0 {
+			ops = append(ops, seqOp{i: dels[0], j: ins[0]})
+			dels, ins = dels[1:], ins[1:]
+		}
+		for _, i := range dels {
+			ops = append(ops, seqOp{i: i, j: -1})
+		}
+		for _, j := range ins {
+			ops = append(ops, seqOp{i: -1, j: j})
+		}
+		dels, ins = nil, nil
+	}
+
+	type step struct {
+		op   dmp.Operation
+		i, j int
+	}
+	var steps []step
+	i, j := 0, 0
+	for _, k := range myersDiff(ka, kb, o, false) {
+		steps = append(steps, step{op: k.op, i: i, j: j})
+		if k.op != dmp.DiffInsert {
+			i++
+		}
+		if k.op != dmp.DiffDelete {
+			j++
+		}
+	}
+
+	// pair the deleted and inserted elements with the same key first
+	deleted := make(map[string][]int)
+	for _, s := range steps {
+		if s.op == dmp.DiffDelete {
+			deleted[ka[s.i]] = append(deleted[ka[s.i]], s.i)
+		}
+	}
+	from := make(map[int]int)
+	moved := make(map[int]bool)
+	for _, s := range steps {
+		if s.op != dmp.DiffInsert || !o.detectMoves {
+			continue
+		}
+		if ds := deleted[kb[s.j]]; len(ds) > 0 {
+			from[s.j] = ds[0]
+			moved[ds[0]] = true
+			deleted[kb[s.j]] = ds[1:]
+		}
+	}
+
+	for _, s := range steps {
+		switch s.op {
+		case dmp.DiffDelete:
+			if !moved[s.i] {
+				dels = append(dels, s.i)
+			}
+		case dmp.DiffInsert:
+			if i, ok := from[s.j]; ok {
+				ops = append(ops, seqOp{i: i, j: s.j, moved: true})
+			} else {
+				ins = append(ins, s.j)
+			}
+		default:
+			gap()
+			ops = append(ops, seqOp{i: s.i, j: s.j})
+		}
+	}
+	gap()
+	return ops
+}
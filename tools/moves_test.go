@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMoves(t *testing.T) {
+	a := "header\nfirst paragraph line\nsecond paragraph line\nthird line of text\nfourth line of text\n"
+	b := "header\nthird line of text\nfourth line of text\nfirst paragraph line\nsecond paragraph line\n"
+
+	// moved lines keep their prefixes so the diff is still a valid patch
+	d := Diff(a, b, WithNoColor(), DetectMoves())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,5 +1,5 @@\n header\n-first paragraph line\n-second paragraph line\n"+
+		" third line of text\n fourth line of text\n+first paragraph line\n+second paragraph line\n", d.String())
+	assert.Equal(t, Stats{Equal: 3, Moved: 2}, d.Stats())
+	assert.False(t, d.Equal())
+
+	edits := d.Hunks()[0].Edits
+	assert.True(t, edits[1].Moved)
+	assert.True(t, edits[3].Moved)
+
+	out := Diff(a, b, WithColor(ColorAlways), DetectMoves()).String()
+	p := newPalette(true, Theme{})
+	assert.Contains(t, out, p.mov.Sprint("-first paragraph line\n"))
+	assert.Contains(t, out, p.mov.Sprint("+first paragraph line\n"))
+
+	// without the option the same lines are deleted and inserted
+	assert.Equal(t, Stats{Inserts: 2, Deletes: 2, Equal: 3}, Diff(a, b).Stats())
+
+	// short blocks are not moves
+	d = Diff("}\n}\nx\n", "x\n}\n}\n", WithNoColor(), DetectMoves())
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Equal: 2}, d.Stats())
+}
+
+func TestDetectMovesStructural(t *testing.T) {
+	a := "items:\n- name: a\n- name: b\n- name: c\n- name: d\n"
+	b := "items:\n- name: d\n- name: a\n- name: b\n- name: C\n"
+
+	d := DiffYAML(a, b, WithNoColor(), DetectMoves())
+	assert.Equal(t, "items[0]:\n~moved from items[3]\nitems[3].name:\n-c\n+C\n", d.String())
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Moved: 1}, d.Stats())
+
+	h := d.Hunks()[0]
+	assert.Equal(t, "items[0]", h.Path)
+	assert.Equal(t, []Edit{{Op: OpInsert, Text: "name: d", Moved: true, From: "items[3]"}}, h.Edits)
+
+	// by index every element differs
+	assert.Equal(t, Stats{Inserts: 4, Deletes: 4}, DiffYAML(a, b).Stats())
+
+	d = DiffJSON([]byte(`[1,2,3,4]`), []byte(`[4,1,2,3,5]`), WithNoColor(), DetectMoves())
+	assert.Equal(t, "/0:\n~moved from /3\n/4:\n+5\n", d.String())
+
+	d = DiffJSON([]byte(`{"ids":[1,2]}`), []byte(`{"ids":[2,1]}`), DetectMoves(), IgnorePath("/ids/*"))
+	assert.True(t, d.Equal())
+}
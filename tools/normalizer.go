@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+//Normalizer rewrites text before it's compared, e.g. to remove parts that
+//change between runs
+type Normalizer interface {
+	Normalize(s string) string
+}
+
+//NormalizerFunc is a func used as a Normalizer
+type NormalizerFunc func(s string) string
+
+//Normalize returns f(s)
+func (f NormalizerFunc) Normalize(s string) string {
+	return f(s)
+}
+
+var (
+	//StripANSI removes ANSI color codes
+	StripANSI Normalizer = NormalizerFunc(stripANSI)
+
+	//SortJSONKeys reformats a JSON document indented with sorted object
+	//keys, other text is left as is
+	SortJSONKeys Normalizer = NormalizerFunc(sortJSONKeys)
+
+	//MaskUUIDs replaces UUIDs with <uuid>
+	MaskUUIDs = Mask(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, "<uuid>")
+)
+
+//Mask returns a Normalizer that replaces the matches of the regular
+//expression pattern with repl, which can refer to submatches like
+//regexp.ReplaceAllString. It panics if pattern is invalid.
+func Mask(pattern, repl string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return NormalizerFunc(func(s string) string {
+		return re.ReplaceAllString(s, repl)
+	})
+}
+
+//Normalizers chains ns into one Normalizer applying them in order, so a
+//project can share its normalization as a single value
+func Normalizers(ns ...Normalizer) Normalizer {
+	return NormalizerFunc(func(s string) string {
+		for _, n := range ns {
+			s = n.Normalize(s)
+		}
+		return s
+	})
+}
+
+func sortJSONKeys(s string) string {
+	v, err := parseJSON([]byte(s))
+	if err != nil {
+		return s
+	}
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return s
+	}
+	if strings.HasSuffix(s, nl) {
+		return string(bs) + nl
+	}
+	return string(bs)
+}
@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithNormalizers(t *testing.T) {
+	a := "\x1b[32mok\x1b[0m {\"id\":\"0f8fad5b-d9cb-469f-a165-70867728950e\"}\n"
+	b := "ok {\"id\":\"7c9e6679-7425-40de-944b-e07fc1f90ae7\"}\n"
+	assert.True(t, Diff(a, b, WithNormalizers(StripANSI, MaskUUIDs)).Equal())
+	assert.False(t, Diff(a, b, WithNormalizers(StripANSI)).Equal())
+
+	a = `{"b":1,"a":[2,3]}`
+	b = "{\n  \"a\": [2, 3],\n  \"b\": 2\n}"
+	d := Diff(a, b, WithNoColor(), WithNormalizers(SortJSONKeys))
+	assert.Equal(t, "--- a\n+++ b\n@@ -3,5 +3,5 @@\n     2,\n     3\n   ],\n-  \"b\": 1\n+  \"b\": 2\n }\n\\ No newline at end of file\n", d.String())
+}
+
+func TestWithNormalizersOrder(t *testing.T) {
+	upper := NormalizerFunc(strings.ToUpper)
+	d := Diff("id 1", "ID 2", WithNormalizers(Mask(`\d`, "N")), WithNormalizers(upper))
+	assert.True(t, d.Equal())
+}
+
+func TestNormalizers(t *testing.T) {
+	n := Normalizers(Mask(`(\w+)@example\.com`, "$1@<host>"), NormalizerFunc(strings.TrimSpace))
+	assert.Equal(t, "mail bob@<host>", n.Normalize(" mail bob@example.com\n"))
+}
+
+func TestSortJSONKeys(t *testing.T) {
+	assert.Equal(t, "{\n  \"a\": 1.50,\n  \"b\": null\n}\n", SortJSONKeys.Normalize("{\"b\":null,\"a\":1.50}\n"))
+	assert.Equal(t, "not json {", SortJSONKeys.Normalize("not json {"))
+}
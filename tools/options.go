@@ -0,0 +1,790 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+//Option configures how a Differ compares and renders its inputs
+type Option func(*options)
+
+//Algorithm selects how line diffs are computed
+type Algorithm int
+
+const (
+	//Myers uses the diffmatchpatch line diff, this is the default
+	Myers Algorithm = iota
+
+	//Patience aligns the diff on lines that occur exactly once on both sides
+	Patience
+
+	//Histogram aligns the diff on the least frequent common lines, like
+	//git diff --histogram, so it still finds anchors in code with few
+	//unique lines
+	Histogram
+)
+
+//Mode selects between word and line diffs
+type Mode int
+
+const (
+	//AutoMode uses a line diff if either side has more than one line and
+	//a word diff otherwise, this is the default
+	AutoMode Mode = iota
+
+	//WordMode always uses a word diff
+	WordMode
+
+	//LineMode always uses a line diff
+	LineMode
+)
+
+//LineEndingMode selects how CR, LF and CRLF line endings and UTF-8 byte
+//order marks are compared and shown in text diffs
+type LineEndingMode int
+
+const (
+	//LineEndingsExact compares line endings and byte order marks like any
+	//other text, this is the default
+	LineEndingsExact LineEndingMode = iota
+
+	//LineEndingsNormalize converts CRLF and CR to LF and removes a leading
+	//byte order mark before comparing
+	LineEndingsNormalize
+
+	//LineEndingsShow compares exactly and shows carriage returns as ^M and
+	//byte order marks as <BOM> in line diffs
+	LineEndingsShow
+
+	//LineEndingsFail compares exactly, but when the sides only differ in line
+	//endings or byte order marks the diff is a one line summary and Error
+	//returns a *LineEndingError
+	LineEndingsFail
+)
+
+//ListMode selects how the elements of slices and arrays in DiffValues, and
+//of arrays in DiffJSON, are matched
+type ListMode int
+
+const (
+	//ListOrdered compares elements by index, this is the default
+	ListOrdered ListMode = iota
+
+	//ListMultiset ignores the order of elements, but each element must
+	//occur as often on both sides
+	ListMultiset
+
+	//ListSet ignores the order of elements and duplicates
+	ListSet
+)
+
+//Cleanup selects how raw diffs are post-processed before rendering
+type Cleanup int
+
+const (
+	//SemanticCleanup aligns edits to be human readable, this is the default
+	SemanticCleanup Cleanup = iota
+
+	//EfficiencyCleanup merges small edits to reduce the number of edits
+	EfficiencyCleanup
+
+	//NoCleanup renders the raw diff
+	NoCleanup
+)
+
+type options struct {
+	ignoreUnexported bool
+	contextLines     int
+	algorithm        Algorithm
+	mode             Mode
+	cleanup          Cleanup
+	timeout          time.Duration
+	color            ColorMode
+	theme            *Theme
+	labelA           string
+	labelB           string
+	ignoreLines      []*regexp.Regexp
+	ignorePaths      []*regexp.Regexp
+	maxHunks         int
+	maxLines         int
+	detectMoves      bool
+	space            spaceMode
+	ignoreBlank      bool
+	ignoreCR         bool
+	foldCase         bool
+	unicodeForm      *norm.Form
+	escapedPatches   bool
+	keyColumns       []string
+	comma            rune
+	minSimilarity    float64
+	pixelTolerance   float64
+	hunkLabel        *regexp.Regexp
+	ctx              context.Context
+	deadline         time.Time
+	comparers        []valueFunc
+	transformers     []valueFunc
+	delta            float64
+	epsilon          float64
+	highlight        bool
+	lang             string
+	lineNumbers      bool
+	noPager          bool
+	ignoreFields     []string
+	fit              fitMode
+	fitWidth         int
+	fullLines        bool
+	lineEndings      LineEndingMode
+	showWhitespace   bool
+	normalizers      []Normalizer
+	listMode         ListMode
+	listKeys         []string
+	keySummary       bool
+	ignoreExtraKeys  bool
+}
+
+// valueFunc is a func registered with WithComparer or WithTransformer for
+// values of type typ
+type valueFunc struct {
+	typ reflect.Type
+	fn  reflect.Value
+}
+
+// applies reports whether f is registered for values of type t, the same
+// type or an interface t implements
+func (f valueFunc) applies(t reflect.Type) bool {
+	if f.typ.Kind() == reflect.Interface {
+		return t.Implements(f.typ)
+	}
+	return t == f.typ
+}
+
+// newValueFunc checks that fn is a func of in values of one type returning
+// out values and panics if not
+func newValueFunc(name string, fn interface{}, in int, out func(reflect.Type) bool) valueFunc {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != in || t.NumOut() != 1 || !out(t.Out(0)) || (in == 2 && t.In(0) != t.In(1)) {
+		panic(fmt.Sprintf("%s: invalid func %T", name, fn))
+	}
+	return valueFunc{typ: t.In(0), fn: v}
+}
+
+// spaceMode selects how whitespace within a line is compared
+type spaceMode int
+
+const (
+	exactSpace spaceMode = iota
+	ignoreTrailingSpace
+	ignoreSpaceChange
+	ignoreAllSpace
+)
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		contextLines:   3,
+		timeout:        time.Second,
+		labelA:         "a",
+		labelB:         "b",
+		comma:          ',',
+		minSimilarity:  1,
+		pixelTolerance: 0.1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+const ignoredKey = "\x00ignored\x00"
+
+// lineKeys returns the keys lines are compared by
+func (o *options) lineKeys(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, l := range lines {
+		keys[i] = o.lineKey(l)
+	}
+	return keys
+}
+
+func (o *options) lineKey(line string) string {
+	text := strings.TrimSuffix(line, nl)
+	for i, re := range o.ignoreLines {
+		if re.MatchString(text) {
+			return ignoredKey + strconv.Itoa(i)
+		}
+	}
+	if o.ignoreBlank && strings.TrimSpace(text) == "" {
+		return ignoredKey + "blank"
+	}
+	if key := o.normalize(text); key != text {
+		return key + line[len(text):]
+	}
+	return line
+}
+
+// normalize applies the whitespace, case and Unicode options to the text of
+// a line
+func (o *options) normalize(text string) string {
+	text = o.foldText(text)
+	if o.ignoreCR {
+		text = strings.TrimSuffix(text, "\r")
+	}
+	switch o.space {
+	case ignoreTrailingSpace:
+		return strings.TrimRightFunc(text, unicode.IsSpace)
+	case ignoreSpaceChange:
+		var buf strings.Builder
+		space := false
+		for _, r := range strings.TrimRightFunc(text, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space {
+				buf.WriteByte(' ')
+				space = false
+			}
+			buf.WriteRune(r)
+		}
+		return buf.String()
+	case ignoreAllSpace:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	return text
+}
+
+// foldText applies the Unicode normalization and case folding options, which
+// unlike the whitespace options also apply to word diffs
+func (o *options) foldText(text string) string {
+	if o.unicodeForm != nil {
+		text = o.unicodeForm.String(text)
+	}
+	if o.foldCase {
+		text = cases.Fold().String(text)
+	}
+	return text
+}
+
+// folds reports whether any option makes different texts compare equal
+func (o *options) folds() bool {
+	return o.unicodeForm != nil || o.foldCase
+}
+
+func isIgnoredKey(key string) bool {
+	return strings.HasPrefix(key, ignoredKey)
+}
+
+// ignoredPath reports whether a structural diff path is ignored
+func (o *options) ignoredPath(path string) bool {
+	for _, re := range o.ignorePaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// palette returns the printers for output written to w
+func (o *options) palette(w io.Writer) *palette {
+	t := globalTheme()
+	if o.theme != nil {
+		t = *o.theme
+	}
+	return newPalette(useColor(o.color, w), t)
+}
+
+func (o *options) dmp() *dmp.DiffMatchPatch {
+	gd := dmp.New()
+	gd.DiffTimeout = o.timeout
+	if !o.deadline.IsZero() {
+		// 0 means no limit to diffmatchpatch, so a spent budget is 1ns
+		gd.DiffTimeout = maxDuration(time.Until(o.deadline), time.Nanosecond)
+	}
+	return gd
+}
+
+// budget returns a copy of o whose diffmatchpatch calls share one deadline,
+// the timeout from now or the context deadline, whichever is earlier
+func (o *options) budget() *options {
+	b := *o
+	if o.timeout > 0 {
+		b.deadline = time.Now().Add(o.timeout)
+	}
+	if o.ctx != nil {
+		if d, ok := o.ctx.Deadline(); ok && (b.deadline.IsZero() || d.Before(b.deadline)) {
+			b.deadline = d
+		}
+	}
+	return &b
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (o *options) cleanupDiffs(gd *dmp.DiffMatchPatch, diffs []dmp.Diff) []dmp.Diff {
+	switch o.cleanup {
+	case EfficiencyCleanup:
+		return gd.DiffCleanupEfficiency(diffs)
+	case NoCleanup:
+		return diffs
+	default:
+		return gd.DiffCleanupSemantic(diffs)
+	}
+}
+
+//IgnoreUnexported skips unexported struct fields when walking values
+func IgnoreUnexported() Option {
+	return func(o *options) {
+		o.ignoreUnexported = true
+	}
+}
+
+//WithContextLines sets the number of unchanged lines shown around each
+//change in line diffs, the default is 3
+func WithContextLines(n int) Option {
+	return func(o *options) {
+		if n < 0 {
+			n = 0
+		}
+		o.contextLines = n
+	}
+}
+
+//WithLineNumbers prefixes each line of line diffs with its old and new
+//line number, e.g. "42 43 | text", left blank on the side a deleted or
+//inserted line isn't on. The output can't be applied with patch(1).
+func WithLineNumbers() Option {
+	return func(o *options) {
+		o.lineNumbers = true
+	}
+}
+
+//WithNoPager prints the diff directly instead of in $PAGER when Print is
+//used in a terminal and the diff is taller than it
+func WithNoPager() Option {
+	return func(o *options) {
+		o.noPager = true
+	}
+}
+
+//WithNormalizers applies ns in order to both sides of Diff before they are
+//compared, e.g. WithNormalizers(StripANSI, SortJSONKeys, MaskUUIDs). It can
+//be given more than once, the normalizers run in the order they were given.
+func WithNormalizers(ns ...Normalizer) Option {
+	return func(o *options) {
+		o.normalizers = append(o.normalizers, ns...)
+	}
+}
+
+//WithTruncateLines cuts lines of line diffs wider than width columns,
+//ending them with …, and notes how many were cut below the diff. width 0
+//uses the terminal width, 120 when stdout isn't a terminal.
+func WithTruncateLines(width int) Option {
+	return func(o *options) {
+		o.fit = fitTruncate
+		o.fitWidth = width
+	}
+}
+
+//WithWrapLines wraps lines of line diffs wider than width columns, the
+//continued parts start with the line's +, - or space. width 0 uses the
+//terminal width, 120 when stdout isn't a terminal.
+func WithWrapLines(width int) Option {
+	return func(o *options) {
+		o.fit = fitWrap
+		o.fitWidth = width
+	}
+}
+
+//WithFullLines shows long lines in full despite WithTruncateLines or
+//WithWrapLines, as does setting LOUPE_FULL_LINES=1 for all diffs
+func WithFullLines() Option {
+	return func(o *options) {
+		o.fullLines = true
+	}
+}
+
+//WithShowWhitespace shows tabs as →, trailing spaces as · and characters
+//that don't print, like zero width spaces, as escapes such as \u200b in the
+//changed lines of line diffs, so lines that look the same can be told apart
+func WithShowWhitespace() Option {
+	return func(o *options) {
+		o.showWhitespace = true
+	}
+}
+
+//WithNoColor disables color output
+func WithNoColor() Option {
+	return WithColor(ColorNever)
+}
+
+//WithColor sets when output is colored, overriding the package level
+//ForceColor and DisableColor settings
+func WithColor(m ColorMode) Option {
+	return func(o *options) {
+		o.color = m
+	}
+}
+
+//WithTheme sets the colors of the Differ, overriding SetTheme
+func WithTheme(t Theme) Option {
+	return func(o *options) {
+		o.theme = &t
+	}
+}
+
+//WithSyntax highlights the lines of line diffs as lang, a chroma lexer
+//name or alias such as "go", "json" or "yaml". An empty lang detects the
+//language from the file names in the labels, falling back to the text.
+//Terminal output is only highlighted when it's colored, changed lines are
+//then shown on a red or green background, HTML is always highlighted.
+func WithSyntax(lang string) Option {
+	return func(o *options) {
+		o.highlight = true
+		o.lang = lang
+	}
+}
+
+//WithAlgorithm sets the algorithm used for line diffs
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = a
+	}
+}
+
+//WithMode forces a word or line diff instead of choosing automatically
+func WithMode(m Mode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+//WithLineEndings sets how line endings and byte order marks are compared
+//and shown, see LineEndingMode
+func WithLineEndings(m LineEndingMode) Option {
+	return func(o *options) {
+		o.lineEndings = m
+	}
+}
+
+//WithCleanup sets how raw diffs are post-processed
+func WithCleanup(c Cleanup) Option {
+	return func(o *options) {
+		o.cleanup = c
+	}
+}
+
+//WithTimeout limits how long a diff is computed before settling for a
+//coarser result, 0 means no limit, the default is 1s. The budget is shared
+//by the whole diff, so a pathological input can't take it once per line.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+//WithLabels sets the names shown in the ---/+++ header of line diffs,
+//the defaults are a and b
+func WithLabels(a, b string) Option {
+	return func(o *options) {
+		o.labelA = a
+		o.labelB = b
+	}
+}
+
+//WithEscapedPatches renders the patches of word diffs in the escaped
+//diffmatchpatch patch format, as returned by PatchToText, instead of as
+//plain text. It is meant for debugging the diff itself.
+func WithEscapedPatches() Option {
+	return func(o *options) {
+		o.escapedPatches = true
+	}
+}
+
+//DetectMoves reports blocks of lines that were deleted in one place and
+//inserted in another as moved, and JSON and YAML list elements that were
+//reordered as moved from their old path, instead of as unrelated changes
+func DetectMoves() Option {
+	return func(o *options) {
+		o.detectMoves = true
+	}
+}
+
+//WithListMode sets how list elements are matched, see ListMode. Elements
+//without a match are compared in order with the remaining elements of the
+//other side, and otherwise reported as removed or added.
+func WithListMode(m ListMode) Option {
+	return func(o *options) {
+		o.listMode = m
+	}
+}
+
+//WithListKeys matches list elements that are structs, maps or JSON objects
+//by the values of the named fields, e.g. WithListKeys("ID"), and compares
+//the matched elements, regardless of their order. Duplicate keys are
+//matched in order.
+func WithListKeys(names ...string) Option {
+	return func(o *options) {
+		o.listKeys = names
+	}
+}
+
+//WithKeySummary starts structural diffs with the paths of the map keys and
+//object members missing from b, and those only in b, before the changes
+func WithKeySummary() Option {
+	return func(o *options) {
+		o.keySummary = true
+	}
+}
+
+//IgnoreExtraKeys leaves out map keys and object members that are only in b,
+//so a matches as a subset of b. Keys missing from b are still reported. It
+//applies to DiffValues, DiffJSON, DiffYAML and DiffTOML.
+func IgnoreExtraKeys() Option {
+	return func(o *options) {
+		o.ignoreExtraKeys = true
+	}
+}
+
+//WithKeyColumns aligns the rows of DiffCSV, and the records of DiffJSONL,
+//by the values of the named columns or fields instead of by position, so
+//rows can be reordered
+func WithKeyColumns(names ...string) Option {
+	return func(o *options) {
+		o.keyColumns = names
+	}
+}
+
+//IgnoreFields leaves the named fields out of each record of DiffJSONL, e.g.
+//IgnoreFields("ts", "/http/duration"). A name starting with / is a JSON
+//pointer to a nested field, others are top level fields.
+func IgnoreFields(names ...string) Option {
+	return func(o *options) {
+		o.ignoreFields = append(o.ignoreFields, names...)
+	}
+}
+
+//WithComma sets the field delimiter of DiffCSV, e.g. '\t' for TSV, the
+//default is ','
+func WithComma(r rune) Option {
+	return func(o *options) {
+		o.comma = r
+	}
+}
+
+//WithMinSimilarity makes DiffImages Equal if the images are at least min
+//similar, from 0 to 1, the default of 1 requires every pixel to match within
+//the pixel tolerance
+func WithMinSimilarity(min float64) Option {
+	return func(o *options) {
+		o.minSimilarity = min
+	}
+}
+
+//WithPixelTolerance sets how different, from 0 to 1, the colors of two
+//pixels can be before DiffImages counts them as different, the default is
+//0.1 so anti-aliasing noise is ignored
+func WithPixelTolerance(t float64) Option {
+	return func(o *options) {
+		o.pixelTolerance = t
+	}
+}
+
+//WithMaxHunks stops rendering after n hunks, or n paths for structural
+//diffs, and ends the output with a summary of what was left out. 0 means no
+//limit, which is the default.
+func WithMaxHunks(n int) Option {
+	return func(o *options) {
+		o.maxHunks = n
+	}
+}
+
+//WithMaxLines stops rendering line and structural diffs after n lines and
+//ends the output with a summary of what was left out. 0 means no limit,
+//which is the default.
+func WithMaxLines(n int) Option {
+	return func(o *options) {
+		o.maxLines = n
+	}
+}
+
+//IgnoreLines ignores differences in lines matching any of the regular
+//expressions, e.g. IgnoreLines(`^Date:`). Ignored lines are still shown,
+//dimmed, but don't count as changes. It panics if a pattern is invalid.
+func IgnoreLines(patterns ...string) Option {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return func(o *options) {
+		o.ignoreLines = append(o.ignoreLines, res...)
+	}
+}
+
+//WithHunkLabels shows the nearest line before each hunk of a line diff that
+//matches the regular expression in its @@ header, like the enclosing
+//function git diff shows, e.g. WithHunkLabels(`^func `) or
+//WithHunkLabels(`^\[.*\]`) for INI sections. It panics if the pattern is
+//invalid.
+func WithHunkLabels(pattern string) Option {
+	re := regexp.MustCompile(pattern)
+	return func(o *options) {
+		o.hunkLabel = re
+	}
+}
+
+//IgnorePath ignores differences at, or below, the given paths of structural
+//diffs. Paths are written the way the diff renders them, e.g. "metadata.uid"
+//for DiffYAML and DiffTOML, "/metadata/uid" for DiffJSON or "User.ID" for
+//DiffValues, and * matches a single path element, e.g. "items[*].uid".
+//Ignored differences are still shown, dimmed.
+func IgnorePath(paths ...string) Option {
+	res := make([]*regexp.Regexp, len(paths))
+	for i, p := range paths {
+		res[i] = pathRegexp(p)
+	}
+	return func(o *options) {
+		o.ignorePaths = append(o.ignorePaths, res...)
+	}
+}
+
+//IgnoreAllSpace compares lines ignoring all whitespace, like git diff -w.
+//Whitespace only differences are rendered as context, with the old text.
+func IgnoreAllSpace() Option {
+	return func(o *options) {
+		o.space = ignoreAllSpace
+	}
+}
+
+//IgnoreSpaceChange compares lines treating runs of whitespace as a single
+//space and ignoring whitespace at the end of lines, like git diff -b
+func IgnoreSpaceChange() Option {
+	return func(o *options) {
+		o.space = ignoreSpaceChange
+	}
+}
+
+//IgnoreTrailingWhitespace compares lines ignoring whitespace at the end of
+//lines, like git diff --ignore-space-at-eol
+func IgnoreTrailingWhitespace() Option {
+	return func(o *options) {
+		o.space = ignoreTrailingSpace
+	}
+}
+
+//IgnoreBlankLines ignores inserted and deleted lines that are empty or only
+//contain whitespace, like git diff --ignore-blank-lines. They are still
+//shown, dimmed.
+func IgnoreBlankLines() Option {
+	return func(o *options) {
+		o.ignoreBlank = true
+	}
+}
+
+//IgnoreLineEndings compares CRLF and LF line endings as equal, see
+//WithLineEndings for other ways to handle them
+func IgnoreLineEndings() Option {
+	return func(o *options) {
+		o.ignoreCR = true
+	}
+}
+
+//WithComparer sets how DiffValues compares values of one type, fn is a
+//func(a, b T) bool that reports whether they are equal, e.g.
+//
+//	WithComparer(func(a, b float64) bool { return math.Abs(a-b) < 1e-9 })
+//
+//An interface T applies to every type that implements it. Comparers are
+//tried in the order given, values of unexported fields are compared as
+//usual. It panics if fn is not such a func.
+func WithComparer(fn interface{}) Option {
+	f := newValueFunc("WithComparer", fn, 2, func(t reflect.Type) bool {
+		return t.Kind() == reflect.Bool
+	})
+	return func(o *options) {
+		o.comparers = append(o.comparers, f)
+	}
+}
+
+//WithTransformer makes DiffValues compare values of one type after
+//converting them with fn, a func(T) R, e.g. to canonicalize a slice by
+//sorting it or truncate a time.Time. The result is compared at the same
+//path and the transformer isn't applied to its own result again, so R can
+//be T. It panics if fn is not such a func.
+func WithTransformer(fn interface{}) Option {
+	f := newValueFunc("WithTransformer", fn, 1, func(reflect.Type) bool {
+		return true
+	})
+	return func(o *options) {
+		o.transformers = append(o.transformers, f)
+	}
+}
+
+//WithTolerance makes DiffValues and DiffJSON compare numbers within a
+//tolerance, so only values that differ by more than delta, or by more than
+//epsilon relative to the expected value, are reported. Use it for floating
+//point results that pick up noise like 1e-15. Either can be 0.
+func WithTolerance(delta, epsilon float64) Option {
+	return func(o *options) {
+		o.delta = delta
+		o.epsilon = epsilon
+	}
+}
+
+// withinTolerance reports whether got is within the WithTolerance delta or
+// epsilon of exp
+func (o *options) withinTolerance(exp, got float64) bool {
+	if o.delta == 0 && o.epsilon == 0 {
+		return false
+	}
+	d := math.Abs(exp - got)
+	return d <= o.delta || d <= o.epsilon*math.Abs(exp)
+}
+
+//IgnoreCase compares text with Unicode case folding, so "Straße" and
+//"STRASSE" are equal
+func IgnoreCase() Option {
+	return func(o *options) {
+		o.foldCase = true
+	}
+}
+
+//WithNormalization compares text after converting it to the Unicode
+//normalization form f, e.g. norm.NFC so decomposed macOS file names equal
+//their composed form, or norm.NFKC to also fold compatibility characters
+//such as ligatures and full width letters
+func WithNormalization(f norm.Form) Option {
+	return func(o *options) {
+		o.unicodeForm = &f
+	}
+}
+
+// pathRegexp matches the path p and anything nested below it
+func pathRegexp(p string) *regexp.Regexp {
+	parts := strings.Split(p, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile(`^` + strings.Join(parts, `[^./\[\]]*`) + `([./\[].*)?$`)
+}
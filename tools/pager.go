@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	isatty "github.com/mattn/go-isatty"
+	"golang.org/x/term"
+)
+
+var pagerOff int32
+
+//DisablePager stops Print from paging long diffs
+func DisablePager() {
+	atomic.StoreInt32(&pagerOff, 1)
+}
+
+//EnablePager restores paging of long diffs by Print, the default
+func EnablePager() {
+	atomic.StoreInt32(&pagerOff, 0)
+}
+
+// terminalHeight returns the rows of the terminal on stdout, ok is false
+// when stdout isn't a terminal
+var terminalHeight = func() (int, bool) {
+	fd := os.Stdout.Fd()
+	if !isatty.IsTerminal(fd) {
+		return 0, false
+	}
+	_, h, err := term.GetSize(int(fd))
+	return h, err == nil
+}
+
+// page shows out in $PAGER, less -R by default, when stdout is a terminal
+// and out is taller than it. It reports whether the pager showed it, an
+// empty $PAGER or cat disable paging like in git, and a pager that isn't
+// found falls back to printing.
+func (o *options) page(out []byte) bool {
+	if o.noPager || atomic.LoadInt32(&pagerOff) != 0 {
+		return false
+	}
+	height, ok := terminalHeight()
+	if !ok || bytes.Count(out, []byte(nl)) < height {
+		return false
+	}
+	pager, set := os.LookupEnv("PAGER")
+	if !set {
+		pager = "less -R"
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 || fields[0] == "cat" {
+		return false
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return false
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", pager)
+	}
+	cmd.Stdin = bytes.NewReader(out)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	cmd.Wait()
+	return true
+}
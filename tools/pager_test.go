@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPager(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sed as the pager")
+	}
+	orig := terminalHeight
+	defer func() { terminalHeight = orig }()
+	pager, set := os.LookupEnv("PAGER")
+	defer func() {
+		if set {
+			os.Setenv("PAGER", pager)
+		} else {
+			os.Unsetenv("PAGER")
+		}
+	}()
+	os.Setenv("PAGER", "sed 's/^/paged: /'")
+
+	show := func(height int, opts ...Option) string {
+		terminalHeight = func() (int, bool) { return height, height > 0 }
+		mock := Mock()
+		Diff("a\nb\nc\n", "a\nB\nc\n", append([]Option{WithNoColor()}, opts...)...).Print()
+		return mock.Results().Out
+	}
+
+	// taller than the terminal
+	out := show(3)
+	assert.Equal(t, "paged: --- a\npaged: +++ b\npaged: @@ -1,3 +1,3 @@\npaged:  a\npaged: -b\npaged: +B\npaged:  c\npaged: \n", out)
+
+	// fits, not a terminal, or disabled
+	plain := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n\n"
+	assert.Equal(t, plain, show(100))
+	assert.Equal(t, plain, show(0))
+	assert.Equal(t, plain, show(3, WithNoPager()))
+	DisablePager()
+	assert.Equal(t, plain, show(3))
+	EnablePager()
+
+	os.Setenv("PAGER", "")
+	assert.Equal(t, plain, show(3))
+
+	// a missing pager falls back to printing
+	os.Setenv("PAGER", "/nonexistent/pager")
+	assert.Contains(t, show(3), "-b\n+B\n")
+}
@@ -0,0 +1,341 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+//DiffProto creates a Differ that compares protobuf messages field by field
+//with protoreflect. Fields are matched by number and reported by name, e.g.
+//"user.addresses[0].city" or `labels["env"]`, so IgnorePath can skip
+//them. Unknown fields are compared by number and reported as "#5".
+func DiffProto(a, b proto.Message, opts ...Option) Differ {
+	o := newOptions(opts)
+	w := &protoWalker{opts: o}
+	ma, mb := protoMessage(a), protoMessage(b)
+	switch {
+	case ma == nil && mb == nil:
+	case ma == nil:
+		w.add(change{typ: added, act: formatProto(mb, 0)})
+	case mb == nil:
+		w.add(change{typ: removed, exp: formatProto(ma, 0)})
+	default:
+		w.walk("", ma, mb)
+	}
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+func protoMessage(m proto.Message) protoreflect.Message {
+	if m == nil {
+		return nil
+	}
+	pm := m.ProtoReflect()
+	if !pm.IsValid() {
+		return nil
+	}
+	return pm
+}
+
+type protoWalker struct {
+	opts    *options
+	changes []change
+}
+
+func (w *protoWalker) add(c change) {
+	if c.path == "" {
+		c.path = "(root)"
+	}
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func (w *protoWalker) walk(path string, a, b protoreflect.Message) {
+	if a.Descriptor().FullName() != b.Descriptor().FullName() {
+		w.add(change{typ: changed, path: path, exp: formatProto(a, 0), act: formatProto(b, 0)})
+		return
+	}
+
+	for _, fd := range protoFields(a, b) {
+		fp := protoKey(path, fd.TextName())
+		if fd.IsExtension() {
+			fp = fmt.Sprintf("%s[%s]", path, fd.FullName())
+		}
+		hasA, hasB := a.Has(fd), b.Has(fd)
+		switch {
+		case !hasA && !hasB:
+		case !hasB:
+			w.add(change{typ: removed, path: fp, exp: formatProtoField(fd, a.Get(fd), 0)})
+		case !hasA:
+			w.add(change{typ: added, path: fp, act: formatProtoField(fd, b.Get(fd), 0)})
+		case fd.IsList():
+			w.walkList(fp, fd, a.Get(fd).List(), b.Get(fd).List())
+		case fd.IsMap():
+			w.walkMap(fp, fd, a.Get(fd).Map(), b.Get(fd).Map())
+		default:
+			w.walkValue(fp, fd, a.Get(fd), b.Get(fd))
+		}
+	}
+
+	w.walkUnknown(path, a.GetUnknown(), b.GetUnknown())
+}
+
+// protoFields returns the fields of the message type and the extensions set
+// on either side, ordered by field number
+func protoFields(a, b protoreflect.Message) []protoreflect.FieldDescriptor {
+	var fields []protoreflect.FieldDescriptor
+	fds := a.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fields = append(fields, fds.Get(i))
+	}
+	seen := make(map[protoreflect.FullName]bool)
+	for _, m := range []protoreflect.Message{a, b} {
+		m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if fd.IsExtension() && !seen[fd.FullName()] {
+				seen[fd.FullName()] = true
+				fields = append(fields, fd)
+			}
+			return true
+		})
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Number() < fields[j].Number()
+	})
+	return fields
+}
+
+func (w *protoWalker) walkList(path string, fd protoreflect.FieldDescriptor, a, b protoreflect.List) {
+	n := a.Len()
+	if b.Len() > n {
+		n = b.Len()
+	}
+	for i := 0; i < n; i++ {
+		ip := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= b.Len():
+			w.add(change{typ: removed, path: ip, exp: formatProtoValue(fd, a.Get(i), 0)})
+		case i >= a.Len():
+			w.add(change{typ: added, path: ip, act: formatProtoValue(fd, b.Get(i), 0)})
+		default:
+			w.walkValue(ip, fd, a.Get(i), b.Get(i))
+		}
+	}
+}
+
+func (w *protoWalker) walkMap(path string, fd protoreflect.FieldDescriptor, a, b protoreflect.Map) {
+	keys := make(map[string]protoreflect.MapKey)
+	for _, m := range []protoreflect.Map{a, b} {
+		m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys[protoMapKey(k)] = k
+			return true
+		})
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vd := fd.MapValue()
+	for _, name := range names {
+		k := keys[name]
+		kp := fmt.Sprintf("%s[%s]", path, name)
+		hasA, hasB := a.Has(k), b.Has(k)
+		switch {
+		case !hasB:
+			w.add(change{typ: removed, path: kp, exp: formatProtoValue(vd, a.Get(k), 0)})
+		case !hasA:
+			w.add(change{typ: added, path: kp, act: formatProtoValue(vd, b.Get(k), 0)})
+		default:
+			w.walkValue(kp, vd, a.Get(k), b.Get(k))
+		}
+	}
+}
+
+func protoMapKey(k protoreflect.MapKey) string {
+	if s, ok := k.Interface().(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return k.String()
+}
+
+// walkValue compares a single value of fd, i.e. not a whole list or map
+func (w *protoWalker) walkValue(path string, fd protoreflect.FieldDescriptor, a, b protoreflect.Value) {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		w.walk(path, a.Message(), b.Message())
+		return
+	}
+	if !protoScalarEqual(fd, a, b) {
+		w.add(change{typ: changed, path: path, exp: formatProtoValue(fd, a, 0), act: formatProtoValue(fd, b, 0)})
+	}
+}
+
+func protoScalarEqual(fd protoreflect.FieldDescriptor, a, b protoreflect.Value) bool {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		fa, fb := a.Float(), b.Float()
+		return fa == fb || (math.IsNaN(fa) && math.IsNaN(fb))
+	default:
+		return a.Interface() == b.Interface()
+	}
+}
+
+// walkUnknown compares unknown fields grouped by field number
+func (w *protoWalker) walkUnknown(path string, a, b protoreflect.RawFields) {
+	if bytes.Equal(a, b) {
+		return
+	}
+	ua, ub := unknownFields(a), unknownFields(b)
+	for _, n := range unknownNumbers(ua, ub) {
+		np := protoKey(path, fmt.Sprintf("#%d", n))
+		va, okA := ua[n]
+		vb, okB := ub[n]
+		switch {
+		case !okB:
+			w.add(change{typ: removed, path: np, exp: strings.Join(va, ", ")})
+		case !okA:
+			w.add(change{typ: added, path: np, act: strings.Join(vb, ", ")})
+		case strings.Join(va, nl) != strings.Join(vb, nl):
+			w.add(change{typ: changed, path: np, exp: strings.Join(va, ", "), act: strings.Join(vb, ", ")})
+		}
+	}
+}
+
+func protoKey(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// unknownNumbers returns the field numbers used in any of fields in order
+func unknownNumbers(fields ...map[protowire.Number][]string) []protowire.Number {
+	seen := make(map[protowire.Number]bool)
+	var nums []protowire.Number
+	for _, f := range fields {
+		for n := range f {
+			if !seen[n] {
+				seen[n] = true
+				nums = append(nums, n)
+			}
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool {
+		return nums[i] < nums[j]
+	})
+	return nums
+}
+
+// unknownFields decodes raw fields into their formatted values by number
+func unknownFields(raw protoreflect.RawFields) map[protowire.Number][]string {
+	fields := make(map[protowire.Number][]string)
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			fields[0] = append(fields[0], fmt.Sprintf("%q", []byte(raw)))
+			return fields
+		}
+		m := protowire.ConsumeFieldValue(num, typ, raw[n:])
+		if m < 0 {
+			fields[num] = append(fields[num], fmt.Sprintf("%q", []byte(raw[n:])))
+			return fields
+		}
+		val := raw[n : n+m]
+		var s string
+		switch typ {
+		case protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(val)
+			s = fmt.Sprintf("%d", v)
+		case protowire.Fixed32Type:
+			v, _ := protowire.ConsumeFixed32(val)
+			s = fmt.Sprintf("0x%08x", v)
+		case protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(val)
+			s = fmt.Sprintf("0x%016x", v)
+		case protowire.BytesType:
+			v, _ := protowire.ConsumeBytes(val)
+			s = fmt.Sprintf("%q", v)
+		default:
+			s = fmt.Sprintf("%q", []byte(val))
+		}
+		fields[num] = append(fields[num], s)
+		raw = raw[n+m:]
+	}
+	return fields
+}
+
+// formatProto renders a message as indented name: value lines. It doesn't
+// use prototext, whose output is deliberately unstable.
+func formatProto(m protoreflect.Message, depth int) string {
+	var lines []string
+	indent := strings.Repeat("  ", depth+1)
+	for _, fd := range protoFields(m, m) {
+		if !m.Has(fd) {
+			continue
+		}
+		name := fd.TextName()
+		if fd.IsExtension() {
+			name = fmt.Sprintf("[%s]", fd.FullName())
+		}
+		lines = append(lines, fmt.Sprintf("%s%s: %s", indent, name, formatProtoField(fd, m.Get(fd), depth+1)))
+	}
+	unknown := unknownFields(m.GetUnknown())
+	for _, n := range unknownNumbers(unknown) {
+		lines = append(lines, fmt.Sprintf("%s#%d: %s", indent, n, strings.Join(unknown[n], ", ")))
+	}
+	if len(lines) == 0 {
+		return "{}"
+	}
+	return "{" + nl + strings.Join(lines, nl) + nl + strings.Repeat("  ", depth) + "}"
+}
+
+// formatProtoField renders the whole value of fd, including lists and maps
+func formatProtoField(fd protoreflect.FieldDescriptor, v protoreflect.Value, depth int) string {
+	indent := strings.Repeat("  ", depth+1)
+	switch {
+	case fd.IsList():
+		l := v.List()
+		var items []string
+		for i := 0; i < l.Len(); i++ {
+			items = append(items, indent+formatProtoValue(fd, l.Get(i), depth+1))
+		}
+		return "[" + nl + strings.Join(items, ","+nl) + nl + strings.Repeat("  ", depth) + "]"
+	case fd.IsMap():
+		var items []string
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			items = append(items, fmt.Sprintf("%s%s: %s", indent, protoMapKey(k), formatProtoValue(fd.MapValue(), mv, depth+1)))
+			return true
+		})
+		sort.Strings(items)
+		return "{" + nl + strings.Join(items, nl) + nl + strings.Repeat("  ", depth) + "}"
+	default:
+		return formatProtoValue(fd, v, depth)
+	}
+}
+
+// formatProtoValue renders a single value of fd
+func formatProtoValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, depth int) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return formatProto(v.Message(), depth)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprintf("%d", v.Enum())
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", v.Bytes())
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDiffProto(t *testing.T) {
+	a := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Dependency: []string{"x.proto", "y.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:  proto.String("id"),
+				Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}},
+		}},
+	}
+	b := proto.Clone(a).(*descriptorpb.FileDescriptorProto)
+	assert.True(t, DiffProto(a, b).Equal())
+
+	b.Name = proto.String("b.proto")
+	b.Dependency = b.Dependency[:1]
+	b.MessageType[0].Field[0].Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	b.Syntax = proto.String("proto3")
+	d := DiffProto(a, b, WithNoColor())
+	assert.Equal(t, `name:
+-"a.proto"
++"b.proto"
+dependency[1]:
+-"y.proto"
+message_type[0].field[0].label:
+-LABEL_OPTIONAL
++LABEL_REPEATED
+syntax:
++"proto3"
+`, d.String())
+
+	d = DiffProto(a, b, IgnorePath("name", "message_type[*].field"))
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Ignored: 2}, d.Stats())
+
+	// maps are compared by key
+	sa, _ := structpb.NewStruct(map[string]interface{}{"env": "prod", "n": 1})
+	sb, _ := structpb.NewStruct(map[string]interface{}{"env": "dev", "n": 1})
+	d = DiffProto(sa, sb, WithNoColor())
+	assert.Equal(t, "fields[\"env\"].string_value:\n-\"prod\"\n+\"dev\"\n", d.String())
+
+	// unknown fields are compared by number
+	ua := proto.Clone(a)
+	ub := proto.Clone(a)
+	ua.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1))
+	ub.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 2))
+	d = DiffProto(ua, ub, WithNoColor())
+	assert.Equal(t, "#99:\n-1\n+2\n", d.String())
+
+	d = DiffProto(nil, &descriptorpb.DescriptorProto{Name: proto.String("A")}, WithNoColor())
+	assert.Equal(t, "(root):\n+{\n+  name: \"A\"\n+}\n", d.String())
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+//Report collects the diffs of several named items, e.g. one per generated
+//file of a code generator test, and renders a summary table of them
+//followed by the diffs of the items that differ. It's safe for concurrent
+//use.
+type Report struct {
+	mu    sync.Mutex
+	items []reportItem
+	opts  *options
+}
+
+type reportItem struct {
+	name string
+	d    Differ
+}
+
+//NewReport creates an empty Report, opts set the colors of the summary
+//and how Print pages it. The diffs keep their own options.
+func NewReport(opts ...Option) *Report {
+	return &Report{opts: newOptions(opts)}
+}
+
+//Add adds the diff d of the item name
+func (r *Report) Add(name string, d Differ) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = append(r.items, reportItem{name: name, d: d})
+}
+
+//Failed reports whether any item differs or has an error
+func (r *Report) Failed() bool {
+	for _, it := range r.snapshot() {
+		if it.failed() {
+			return true
+		}
+	}
+	return false
+}
+
+func (it reportItem) failed() bool {
+	return it.d.Error() != nil || !it.d.Equal()
+}
+
+func (r *Report) snapshot() []reportItem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]reportItem(nil), r.items...)
+}
+
+//Print writes the report to stdout
+func (r *Report) Print() {
+	printDiff(r.opts, r.write)
+}
+
+//String returns the summary table and the diffs of the failed items
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	r.write(&buf, r.opts.palette(nil))
+	return buf.String()
+}
+
+//WriteTo writes the report to w
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	p := r.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { r.write(w, p) })
+}
+
+// write renders a table with the inserted and deleted lines of each item
+// and whether it's equal, then the diff of each failed item under its name
+func (r *Report) write(w io.Writer, p *palette) {
+	items := r.snapshot()
+	rows := [][]string{{"name", "+", "-", "equal"}}
+	for _, it := range items {
+		s := it.d.Stats()
+		equal := "yes"
+		switch {
+		case it.d.Error() != nil:
+			equal = "error"
+		case !it.d.Equal():
+			equal = "no"
+		}
+		rows = append(rows, []string{it.name, strconv.Itoa(s.Inserts), strconv.Itoa(s.Deletes), equal})
+	}
+
+	widths := make([]int, 3)
+	for _, row := range rows {
+		for i := range widths {
+			widths[i] = maxInt(widths[i], len(row[i]))
+		}
+	}
+	failed := 0
+	for i, row := range rows {
+		line := fmt.Sprintf("%-*s  %*s  %*s  ", widths[0], row[0], widths[1], row[1], widths[2], row[2])
+		switch {
+		case i == 0:
+			p.dim.Fprint(w, line+row[3])
+		case row[3] == "yes":
+			io.WriteString(w, line)
+			p.ins.Fprint(w, row[3])
+		default:
+			failed++
+			io.WriteString(w, line)
+			p.del.Fprint(w, row[3])
+		}
+		io.WriteString(w, nl)
+	}
+	fmt.Fprintf(w, "%d of %d differ\n", failed, len(items))
+
+	for _, it := range items {
+		if !it.failed() {
+			continue
+		}
+		fmt.Fprintf(w, "\n=== %s\n", it.name)
+		if err := it.d.Error(); err != nil {
+			fmt.Fprintf(w, "ERROR: %v\n", err)
+		}
+		if it.d.Equal() {
+			continue
+		}
+		var buf bytes.Buffer
+		if fd, ok := it.d.(interface {
+			diff(w io.Writer, p *palette)
+		}); ok {
+			fd.diff(&buf, p)
+		} else {
+			buf.WriteString(it.d.String())
+		}
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte(nl)) {
+			buf.WriteString(nl)
+		}
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport(WithNoColor())
+	r.Add("gen/a.go", Diff("package a\n", "package a\n", WithNoColor()))
+	r.Add("gen/models.go", Diff("a\nb\nc\n", "a\nB\nc\n", WithNoColor(), WithLabels("want", "got")))
+	r.Add("gen/b.json", DiffJSON([]byte(`{"a":1}`), []byte(`{"a":1`), WithNoColor()))
+	assert.True(t, r.Failed())
+
+	exp := "name           +  -  equal\n" +
+		"gen/a.go       0  0  yes\n" +
+		"gen/models.go  1  1  no\n" +
+		"gen/b.json     0  1  error\n" +
+		"2 of 3 differ\n" +
+		"\n=== gen/models.go\n" +
+		"--- want\n+++ got\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+		"\n=== gen/b.json\n" +
+		"ERROR: "
+	out := r.String()
+	assert.Equal(t, exp, out[:len(exp)])
+
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(out)), n)
+	assert.Equal(t, out, buf.String())
+
+	mock := Mock()
+	r.Print()
+	assert.Equal(t, out+"\n", mock.Results().Out)
+
+	ok := NewReport()
+	ok.Add("a", Diff("x", "x"))
+	assert.False(t, ok.Failed())
+	assert.False(t, NewReport().Failed())
+}
+
+func TestReportColor(t *testing.T) {
+	r := NewReport(WithColor(ColorAlways))
+	r.Add("a", Diff("x", "x"))
+	r.Add("b", &fallbackDiff{Diff("x", "x"), errors.New("boom")})
+	p := newPalette(true, globalTheme())
+	assert.Equal(t, p.dim.Sprint("name  +  -  equal")+"\na     0  0  "+p.ins.Sprint("yes")+"\nb     0  0  "+p.del.Sprint("error")+"\n1 of 2 differ\n\n=== b\nERROR: boom\n", r.String())
+}
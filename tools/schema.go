@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL is the location the schema is compiled from
+const schemaURL = "schema.json"
+
+//DiffJSONSchema validates the JSON document doc against the JSON Schema
+//schema. Each violation is a change at the JSON pointer of the offending
+//value, with the requirement it breaks as expected and the value as actual,
+//so it's rendered like DiffJSON. If the schema or doc is invalid the diff
+//has a single change describing it and Error returns the error.
+func DiffJSONSchema(schema, doc []byte, opts ...Option) Differ {
+	o := newOptions(opts)
+	c := jsonschema.NewCompiler()
+	s, err := compileSchema(c, schema)
+	if err != nil {
+		return schemaError(o, "(schema)", err)
+	}
+	v, err := parseJSON(doc)
+	if err != nil {
+		return schemaError(o, "(root)", err)
+	}
+
+	err = s.Validate(v)
+	if err == nil {
+		return &valueDiff{opts: o}
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return schemaError(o, "(root)", err)
+	}
+
+	var changes []change
+	for _, leaf := range schemaLeaves(ve) {
+		path := leaf.InstanceLocation
+		if path == "" {
+			path = "(root)"
+		}
+		// the keyword's location in the schema after following references
+		loc := leaf.AbsoluteKeywordLocation
+		if i := strings.IndexByte(loc, '#'); i >= 0 {
+			loc = loc[i+1:]
+		}
+		c := change{
+			typ:  changed,
+			path: path,
+			exp:  fmt.Sprintf("%s (%s)", leaf.Message, loc),
+			act:  formatJSON(jsonPointerValue(v, leaf.InstanceLocation)),
+		}
+		c.ignored = o.ignoredPath(c.path)
+		changes = append(changes, c)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].path != changes[j].path {
+			return changes[i].path < changes[j].path
+		}
+		return changes[i].exp < changes[j].exp
+	})
+	return &valueDiff{changes: changes, opts: o}
+}
+
+func compileSchema(c *jsonschema.Compiler, schema []byte) (*jsonschema.Schema, error) {
+	if err := c.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return c.Compile(schemaURL)
+}
+
+func schemaError(o *options, path string, err error) Differ {
+	d := &valueDiff{opts: o, changes: []change{{typ: removed, path: path, exp: err.Error()}}}
+	return &fallbackDiff{d, err}
+}
+
+// schemaLeaves returns the violations without causes, which are the
+// specific requirements that failed
+func schemaLeaves(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(ve.Causes) == 0 {
+		return []*jsonschema.ValidationError{ve}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, c := range ve.Causes {
+		leaves = append(leaves, schemaLeaves(c)...)
+	}
+	return leaves
+}
+
+// jsonPointerValue returns the value at ptr in v, or nil
+func jsonPointerValue(v interface{}, ptr string) interface{} {
+	if ptr == "" {
+		return v
+	}
+	for _, tok := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		tok = pointerUnescaper.Replace(tok)
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			v = vv[tok]
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(vv) {
+				return nil
+			}
+			v = vv[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const userSchema = `{
+  "type": "object",
+  "required": ["id", "name"],
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string", "minLength": 1},
+    "age": {"type": "integer", "minimum": 0},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+func TestDiffJSONSchema(t *testing.T) {
+	d := DiffJSONSchema([]byte(userSchema), []byte(`{"id": 1, "name": "ann", "tags": ["a"]}`))
+	assert.True(t, d.Equal())
+	assert.NoError(t, d.Error())
+
+	d = DiffJSONSchema([]byte(userSchema), []byte(`{"id": 1.5, "age": -3, "tags": ["a", 2]}`), WithNoColor())
+	assert.False(t, d.Equal())
+	assert.NoError(t, d.Error())
+	assert.Equal(t, `(root):
+-missing properties: 'name' (/required)
++{"age":-3,"id":1.5,"tags":["a",2]}
+/age:
+-must be >= 0 but found -3 (/properties/age/minimum)
++-3
+/id:
+-expected integer, but got number (/properties/id/type)
++1.5
+/tags/1:
+-expected string, but got number (/properties/tags/items/type)
++2
+`, d.String())
+
+	d = DiffJSONSchema([]byte(userSchema), []byte(`{"id": 1, "age": -1, "name": "a"}`), IgnorePath("/age"))
+	assert.True(t, d.Equal())
+}
+
+func TestDiffJSONSchemaInvalid(t *testing.T) {
+	d := DiffJSONSchema([]byte(`{"type": 1}`), []byte(`{}`))
+	assert.Error(t, d.Error())
+	assert.False(t, d.Equal())
+
+	d = DiffJSONSchema([]byte(userSchema), []byte(`{"id":`))
+	assert.Error(t, d.Error())
+	assert.Contains(t, d.String(), "(root):")
+}
+
+func TestJSONPointerValue(t *testing.T) {
+	v, err := parseJSON([]byte(`{"a/b": [1, {"c~d": true}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, true, jsonPointerValue(v, "/a~1b/1/c~0d"))
+	assert.Nil(t, jsonPointerValue(v, "/a~1b/5"))
+	assert.Nil(t, jsonPointerValue(v, "/x/y"))
+}
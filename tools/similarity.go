@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"unicode/utf8"
+)
+
+//EditDistance returns the Levenshtein distance between a and b, the number
+//of characters inserted, deleted or substituted to turn a into b. It is
+//computed from a character diff, so a diff cut short by WithTimeout can
+//overestimate it. IgnoreCase and WithNormalization apply.
+func EditDistance(a, b string, opts ...Option) int {
+	o := newOptions(opts)
+	a, b = o.foldText(a), o.foldText(b)
+	gd := o.dmp()
+	return gd.DiffLevenshtein(gd.DiffMain(a, b, false))
+}
+
+//Similarity returns how similar a and b are from 0 to 1, 1 minus their
+//EditDistance relative to the length of the longer one, so a 95% match is
+//Similarity(want, got) >= 0.95. Two empty strings are identical.
+func Similarity(a, b string, opts ...Option) float64 {
+	o := newOptions(opts)
+	n := maxInt(utf8.RuneCountInString(o.foldText(a)), utf8.RuneCountInString(o.foldText(b)))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(EditDistance(a, b, opts...))/float64(n)
+}
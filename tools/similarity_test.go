@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		opts []Option
+		dist int
+		sim  float64
+	}{
+		{"", "", nil, 0, 1},
+		{"kitten", "sitting", nil, 3, 1 - 3.0/7},
+		{"abc", "", nil, 3, 0},
+		{"größe", "grösse", nil, 2, 1 - 2.0/6},
+		{"Hello World", "hello world", nil, 2, 1 - 2.0/11},
+		{"Hello World", "hello world", []Option{IgnoreCase()}, 0, 1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.dist, EditDistance(test.a, test.b, test.opts...), "%q %q", test.a, test.b)
+		assert.InDelta(t, test.sim, Similarity(test.a, test.b, test.opts...), 1e-9, "%q %q", test.a, test.b)
+	}
+}
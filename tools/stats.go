@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+//Stats counts the parts of a diff: lines for line diffs, characters for word
+//diffs and values for structural diffs, which don't count Equal. Ignored
+//counts differences excluded by IgnoreLines or IgnorePath, and Moved the
+//lines or values found by DetectMoves, once for both sides.
+type Stats struct {
+	Inserts int
+	Deletes int
+	Equal   int
+	Ignored int
+	Moved   int
+}
+
+// changed returns the number of inserted, deleted and moved parts
+func (s Stats) changed() int {
+	return s.Inserts + s.Deletes + s.Moved
+}
+
+func lineStats(ops []lineOp) Stats {
+	var s Stats
+	for _, l := range ops {
+		s.add(l)
+	}
+	return s
+}
+
+func (s *Stats) add(l lineOp) {
+	switch {
+	case l.ignored:
+		s.Ignored++
+	case l.moved:
+		if l.op == dmp.DiffInsert {
+			s.Moved++
+		}
+	case l.op == dmp.DiffDelete:
+		s.Deletes++
+	case l.op == dmp.DiffInsert:
+		s.Inserts++
+	default:
+		s.Equal++
+	}
+}
+
+func textStats(diffs []dmp.Diff) Stats {
+	var s Stats
+	for _, diff := range diffs {
+		n := utf8.RuneCountInString(diff.Text)
+		switch diff.Type {
+		case dmp.DiffDelete:
+			s.Deletes += n
+		case dmp.DiffInsert:
+			s.Inserts += n
+		default:
+			s.Equal += n
+		}
+	}
+	return s
+}
+
+func changeStats(changes []change) Stats {
+	var s Stats
+	for _, c := range changes {
+		switch {
+		case c.ignored:
+			s.Ignored++
+			continue
+		case c.typ == moved:
+			s.Moved++
+			continue
+		case c.typ != added:
+			s.Deletes++
+		}
+		if c.typ != removed {
+			s.Inserts++
+		}
+	}
+	return s
+}
+
+// limits tracks how much of a diff has been rendered against the
+// WithMaxHunks and WithMaxLines options
+type limits struct {
+	maxHunks int
+	maxLines int
+	hunks    int
+	lines    int
+}
+
+func (o *options) limits() *limits {
+	return &limits{maxHunks: o.maxHunks, maxLines: o.maxLines}
+}
+
+// hunk reports whether another hunk may be rendered and counts it
+func (l *limits) hunk() bool {
+	if l.maxHunks > 0 && l.hunks >= l.maxHunks {
+		return false
+	}
+	l.hunks++
+	return true
+}
+
+// line reports whether another line may be rendered and counts it
+func (l *limits) line() bool {
+	if l.maxLines > 0 && l.lines >= l.maxLines {
+		return false
+	}
+	l.lines++
+	return true
+}
+
+// summary formats the footer written when output is truncated, e.g.
+// "... 42 more hunks, 1893 more changed lines"
+func summary(n int, noun string, lines int) string {
+	var parts []string
+	if n > 0 {
+		parts = append(parts, plural(n, "more "+noun))
+	}
+	if lines > 0 {
+		parts = append(parts, plural(lines, "more changed line"))
+	}
+	return "... " + strings.Join(parts, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
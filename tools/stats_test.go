@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	d := Diff("a\nb\nc\n", "a\nB\nc\nd\n", WithCleanup(NoCleanup))
+	assert.Equal(t, Stats{Inserts: 2, Deletes: 1, Equal: 2}, d.Stats())
+
+	d = Diff("aaabbb", "aaaccc", WithCleanup(NoCleanup))
+	assert.Equal(t, Stats{Inserts: 3, Deletes: 3, Equal: 3}, d.Stats())
+
+	d = Diff("Date: 1\nx\n", "Date: 2\ny\n", IgnoreLines(`^Date:`))
+	assert.Equal(t, Stats{Inserts: 1, Deletes: 1, Ignored: 2}, d.Stats())
+
+	d = DiffValues(map[string]int{"a": 1, "b": 2}, map[string]int{"a": 2, "c": 3})
+	assert.Equal(t, Stats{Inserts: 2, Deletes: 2}, d.Stats())
+}
+
+func TestTruncate(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 100; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+		if i%10 == 0 {
+			b = append(b, fmt.Sprintf("LINE %d", i))
+		} else {
+			b = append(b, a[i])
+		}
+	}
+	textA, textB := strings.Join(a, nl)+nl, strings.Join(b, nl)+nl
+
+	d := Diff(textA, textB, WithNoColor(), WithContextLines(0), WithMaxHunks(2))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1 +1 @@\n-line 0\n+LINE 0\n@@ -11 +11 @@\n-line 10\n+LINE 10\n... 8 more hunks, 16 more changed lines\n", d.String())
+
+	d = Diff(textA, textB, WithNoColor(), WithContextLines(0), WithMaxLines(3))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1 +1 @@\n-line 0\n+LINE 0\n@@ -11 +11 @@\n-line 10\n... 8 more hunks, 17 more changed lines\n", d.String())
+
+	d = DiffValues([]int{1, 2, 3}, []int{4, 5, 6}, WithNoColor(), WithMaxHunks(1))
+	assert.Equal(t, "[0]:\n-1\n+4\n... 2 more paths, 4 more changed lines\n", d.String())
+
+	d = DiffValues([]int{1, 2}, []int{4, 5}, WithNoColor(), WithMaxLines(4))
+	assert.Equal(t, "[0]:\n-1\n+4\n[1]:\n... 2 more changed lines\n", d.String())
+}
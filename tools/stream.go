@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// streamChunk is the number of lines of each side held in memory at once
+const streamChunk = 1 << 14
+
+//DiffReaders creates a line Differ for a and b that reads both inputs once,
+//a chunk of lines at a time, so large files can be compared without loading
+//them into memory. Identical lines are skipped as they are read, and the
+//rest are diffed by a hash of each line within a window of lines, only the
+//hunks are kept. A change that spans more lines than the window may be
+//reported as a delete and reinsert of lines further apart. Read errors are
+//returned by Error and reported in the rendered output.
+func DiffReaders(a, b io.Reader, opts ...Option) Differ {
+	return diffReaders(a, b, newOptions(opts), streamChunk)
+}
+
+func diffReaders(a, b io.Reader, o *options, chunk int) *streamDiff {
+	d := &streamDiff{opts: o}
+	h := newHunker(o)
+	ra := &lineReader{r: bufio.NewReader(a), o: o}
+	rb := &lineReader{r: bufio.NewReader(b), o: o}
+
+	for {
+		ra.fill(chunk)
+		rb.fill(chunk)
+		if ra.err != nil || rb.err != nil {
+			break
+		}
+
+		// skip the common prefix without diffing it
+		n := 0
+		for n < len(ra.lines) && n < len(rb.lines) && ra.lines[n] == rb.lines[n] {
+			h.add(lineOp{op: dmp.DiffEqual, text: ra.lines[n]})
+			n++
+		}
+		ra.drop(n)
+		rb.drop(n)
+		if n > 0 && !(ra.eof && rb.eof) {
+			continue
+		}
+		if len(ra.lines) == 0 && len(rb.lines) == 0 {
+			break
+		}
+
+		ops := diffKeyed(ra.lines, rb.lines, ra.keys, rb.keys, o)
+		end := len(ops)
+		if !ra.eof || !rb.eof {
+			// leave the changes after the last equal line for the next
+			// window, they may match lines that have not been read yet
+			for end > 0 && ops[end-1].op != dmp.DiffEqual {
+				end--
+			}
+			if end == 0 {
+				end = len(ops)
+			}
+		}
+
+		na, nb := 0, 0
+		for _, l := range ops[:end] {
+			h.add(l)
+			if l.op != dmp.DiffInsert {
+				na++
+			}
+			if l.op != dmp.DiffDelete {
+				nb++
+			}
+		}
+		ra.drop(na)
+		rb.drop(nb)
+	}
+
+	d.hunks = h.finish()
+	d.stats = h.stats
+	if ra.err != nil {
+		d.err = ra.err
+	} else {
+		d.err = rb.err
+	}
+	return d
+}
+
+// lineReader buffers a window of lines and their hashed keys
+type lineReader struct {
+	r     *bufio.Reader
+	o     *options
+	lines []string
+	keys  []string
+	eof   bool
+	err   error
+}
+
+func (r *lineReader) fill(n int) {
+	for !r.eof && r.err == nil && len(r.lines) < n {
+		line, err := r.r.ReadString('\n')
+		if line != "" {
+			r.lines = append(r.lines, line)
+			r.keys = append(r.keys, hashKey(r.o.lineKey(line)))
+		}
+		switch {
+		case err == io.EOF:
+			r.eof = true
+		case err != nil:
+			r.err = err
+		}
+	}
+}
+
+func (r *lineReader) drop(n int) {
+	r.lines = r.lines[n:]
+	r.keys = r.keys[n:]
+}
+
+// hashKey shortens a line key to its 64 bit FNV hash, ignored keys are kept
+// as they are so they still compare equal
+func hashKey(key string) string {
+	if isIgnoredKey(key) {
+		return key
+	}
+	h := fnv.New64a()
+	io.WriteString(h, key)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], h.Sum64())
+	return string(buf[:])
+}
+
+// hunker groups a stream of line ops into hunks like makeHunks, keeping only
+// the current hunk and the last n equal lines in memory
+type hunker struct {
+	n        int
+	labels   *hunkLabeler
+	hunks    []hunk
+	cur      *hunk
+	trailing int
+	ctx      []lineOp
+	oldNo    int
+	newNo    int
+	stats    Stats
+}
+
+func newHunker(o *options) *hunker {
+	return &hunker{n: o.contextLines, labels: newHunkLabeler(o), oldNo: 1, newNo: 1}
+}
+
+func (h *hunker) add(l lineOp) {
+	h.stats.add(l)
+
+	switch {
+	case l.isChange():
+		if h.cur == nil {
+			h.cur = &hunk{oldStart: h.oldNo, newStart: h.newNo}
+			for _, c := range h.ctx {
+				if c.op != dmp.DiffInsert {
+					h.cur.oldStart--
+				}
+				if c.op != dmp.DiffDelete {
+					h.cur.newStart--
+				}
+			}
+			h.cur.label = h.labels.label(h.cur.oldStart)
+			h.cur.ops = append(h.cur.ops, h.ctx...)
+			h.ctx = nil
+		}
+		h.cur.ops = append(h.cur.ops, l)
+		h.trailing = 0
+	case h.cur != nil:
+		h.cur.ops = append(h.cur.ops, l)
+		h.trailing++
+		if h.trailing > 2*h.n {
+			ops := h.cur.ops
+			cut := len(ops) - h.trailing + h.n
+			h.close(ops[:cut])
+			h.ctx = append([]lineOp{}, ops[len(ops)-h.n:]...)
+		}
+	default:
+		h.ctx = append(h.ctx, l)
+		if len(h.ctx) > h.n {
+			h.ctx = h.ctx[1:]
+		}
+	}
+
+	if l.op != dmp.DiffInsert {
+		h.labels.add(h.oldNo, l.text)
+		h.oldNo++
+	}
+	if l.op != dmp.DiffDelete {
+		h.newNo++
+	}
+}
+
+// close ends the current hunk with ops
+func (h *hunker) close(ops []lineOp) {
+	c := h.cur
+	c.ops = ops
+	for _, l := range ops {
+		if l.op != dmp.DiffInsert {
+			c.oldLines++
+		}
+		if l.op != dmp.DiffDelete {
+			c.newLines++
+		}
+	}
+	h.hunks = append(h.hunks, *c)
+	h.cur = nil
+	h.trailing = 0
+}
+
+func (h *hunker) finish() []hunk {
+	if h.cur != nil {
+		ops := h.cur.ops
+		if h.trailing > h.n {
+			ops = ops[:len(ops)-h.trailing+h.n]
+		}
+		h.close(ops)
+	}
+	return h.hunks
+}
+
+type streamDiff struct {
+	hunks []hunk
+	stats Stats
+	err   error
+	opts  *options
+}
+
+func (d *streamDiff) Print() {
+	printDiff(d.opts, d.diff)
+}
+
+func (d *streamDiff) String() string {
+	var buf bytes.Buffer
+	d.diff(&buf, d.opts.palette(nil))
+	return buf.String()
+}
+
+func (d *streamDiff) WriteTo(w io.Writer) (int64, error) {
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *streamDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *streamDiff) HTML(w io.Writer, layout Layout) error {
+	return writeHTML(w, func(w io.Writer) {
+		htmlHunks(w, d.hunks, layout, d.opts, d.opts.syntax("", ""))
+	})
+}
+
+func (d *streamDiff) Hunks() []Hunk {
+	return lineHunks(d.hunks)
+}
+
+func (d *streamDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *streamDiff) Equal() bool {
+	return d.err == nil && d.stats.changed() == 0
+}
+
+func (d *streamDiff) Error() error {
+	return d.err
+}
+
+func (d *streamDiff) Stats() Stats {
+	return d.stats
+}
+
+func (d *streamDiff) diff(w io.Writer, p *palette) {
+	if d.err != nil {
+		fmt.Fprintf(w, "ERROR: read failed: %v\n", d.err)
+		return
+	}
+	var s *syntax
+	if p.color {
+		s = d.opts.syntax("", "")
+	}
+	writeHunks(w, p, d.hunks, d.opts, s)
+}
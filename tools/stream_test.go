@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+func TestDiffReaders(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 1000; i++ {
+		a = append(a, fmt.Sprintf("line %d\n", i))
+		switch {
+		case i%97 == 0:
+			b = append(b, fmt.Sprintf("LINE %d\n", i))
+		case i%101 == 0:
+		case i%103 == 0:
+			b = append(b, a[i], "extra\n")
+		default:
+			b = append(b, a[i])
+		}
+	}
+	textA, textB := strings.Join(a, ""), strings.Join(b, "")
+
+	// small windows must give the same result as the in memory diff
+	for _, chunk := range []int{7, 50, streamChunk} {
+		for _, n := range []int{0, 1, 3} {
+			o := newOptions([]Option{WithNoColor(), WithContextLines(n), WithCleanup(NoCleanup), WithHunkLabels(`0$`)})
+			exp := Diff(textA, textB, WithNoColor(), WithContextLines(n), WithCleanup(NoCleanup), WithHunkLabels(`0$`))
+			d := diffReaders(strings.NewReader(textA), strings.NewReader(textB), o, chunk)
+			assert.Equal(t, exp.String(), d.String(), "chunk %d context %d", chunk, n)
+			assert.Equal(t, exp.Stats(), d.Stats(), "chunk %d context %d", chunk, n)
+		}
+	}
+
+	d := DiffReaders(strings.NewReader("a\nb"), strings.NewReader("a\nc"), WithNoColor())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n", d.String())
+
+	d = DiffReaders(strings.NewReader(textA), strings.NewReader(textA))
+	assert.Equal(t, "", d.String())
+	assert.Equal(t, Stats{Equal: 1000}, d.Stats())
+
+	d = DiffReaders(strings.NewReader("a\n"), errReader{})
+	assert.Equal(t, "ERROR: read failed: boom\n", d.String())
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"io"
+	"sync"
+)
+
+//SyncWriter serializes writes to a destination shared by goroutines, e.g. a
+//log file written by parallel tests. A Differ's WriteTo renders the whole
+//diff before writing it to a SyncWriter in one write, so diffs never
+//interleave. Colors are decided by the wrapped writer.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+//NewSyncWriter wraps w in a SyncWriter
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+//Write writes p to the wrapped writer, holding the lock for the whole write
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parallelDiffs returns n colored multi line diffs that are easy to tell apart
+func parallelDiffs(n int) []Differ {
+	var ds []Differ
+	for i := 0; i < n; i++ {
+		var a, b strings.Builder
+		for j := 0; j < 50; j++ {
+			fmt.Fprintf(&a, "diff %d line %d\n", i, j)
+			fmt.Fprintf(&b, "diff %d line %d changed\n", i, j)
+		}
+		ds = append(ds, Diff(a.String(), b.String(), WithColor(ColorAlways)))
+	}
+	return ds
+}
+
+func assertContiguous(t *testing.T, out string, ds []Differ) {
+	for i, d := range ds {
+		assert.Contains(t, out, d.String(), "diff %d interleaved", i)
+	}
+}
+
+func TestSyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSyncWriter(&buf)
+	ds := parallelDiffs(20)
+
+	var wg sync.WaitGroup
+	for _, d := range ds {
+		wg.Add(1)
+		go func(d Differ) {
+			defer wg.Done()
+			n, err := d.WriteTo(w)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(d.String())), n)
+		}(d)
+	}
+	wg.Wait()
+	assertContiguous(t, buf.String(), ds)
+}
+
+func TestPrintParallel(t *testing.T) {
+	ds := parallelDiffs(20)
+
+	mock := Mock()
+	var wg sync.WaitGroup
+	for _, d := range ds {
+		wg.Add(1)
+		go func(d Differ) {
+			defer wg.Done()
+			d.Print()
+		}(d)
+	}
+	wg.Wait()
+	res := mock.Results()
+	assertContiguous(t, res.Out, ds)
+}
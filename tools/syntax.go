@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// the styles used in the terminal, which is usually dark, and in HTML
+// pages, which are light
+const (
+	terminalStyle = "monokai"
+	htmlStyle     = "github"
+)
+
+// 256 color backgrounds of changed lines and their changed spans
+const (
+	delBackground     = "48;5;52"
+	delSpanBackground = "48;5;88"
+	insBackground     = "48;5;22"
+	insSpanBackground = "48;5;28"
+)
+
+// syntax highlights the lines of a line diff. Both sides are tokenized
+// as a whole so constructs spanning lines are colored, lines that can't be
+// found, e.g. in streamed diffs, are tokenized on their own.
+type syntax struct {
+	lexer chroma.Lexer
+	a, b  [][]chroma.Token
+}
+
+// segment is a run of a line with one token type, changed is set for the
+// spans highlighted by the inline diff
+type segment struct {
+	text    string
+	typ     chroma.TokenType
+	changed bool
+}
+
+// syntax returns the highlighter for the sides a and b, or nil when
+// WithSyntax isn't set or no lexer matches
+func (o *options) syntax(a, b string) *syntax {
+	if !o.highlight {
+		return nil
+	}
+	var lexer chroma.Lexer
+	switch {
+	case o.lang != "":
+		lexer = lexers.Get(o.lang)
+	default:
+		for _, label := range []string{o.labelB, o.labelA} {
+			if lexer = lexers.Match(path.Base(label)); lexer != nil {
+				break
+			}
+		}
+		if lexer == nil && a+b != "" {
+			lexer = lexers.Analyse(a + b)
+		}
+	}
+	if lexer == nil {
+		return nil
+	}
+	s := &syntax{lexer: chroma.Coalesce(lexer)}
+	s.a = s.lines(a)
+	s.b = s.lines(b)
+	return s
+}
+
+// lines tokenizes text and splits the tokens into lines
+func (s *syntax) lines(text string) [][]chroma.Token {
+	if text == "" {
+		return nil
+	}
+	it, err := s.lexer.Tokenise(nil, text)
+	if err != nil {
+		return nil
+	}
+	return chroma.SplitTokensIntoLines(it.Tokens())
+}
+
+// tokens returns the tokens of l, line n of its side
+func (s *syntax) tokens(l lineOp, n int) []chroma.Token {
+	side := s.a
+	if l.op == dmp.DiffInsert {
+		side = s.b
+	}
+	text := strings.TrimSuffix(l.text, nl)
+	if n >= 1 && n <= len(side) {
+		var b strings.Builder
+		for _, t := range side[n-1] {
+			b.WriteString(t.Value)
+		}
+		if strings.TrimSuffix(b.String(), nl) == text {
+			return side[n-1]
+		}
+	}
+	it, err := s.lexer.Tokenise(nil, text)
+	if err != nil {
+		return []chroma.Token{{Type: chroma.Text, Value: text}}
+	}
+	return it.Tokens()
+}
+
+// segments splits line n of its side into tokens, and further where the
+// inline diff of a changed line starts or ends a changed span
+func (s *syntax) segments(l lineOp, n int, inline []dmp.Diff) []segment {
+	var changed []bool
+	if inline != nil && l.op != dmp.DiffEqual {
+		for _, d := range inline {
+			switch d.Type {
+			case dmp.DiffEqual:
+				changed = append(changed, make([]bool, len(d.Text))...)
+			case l.op:
+				for i := 0; i < len(d.Text); i++ {
+					changed = append(changed, true)
+				}
+			}
+		}
+	}
+
+	var segs []segment
+	pos := 0
+	for _, t := range s.tokens(l, n) {
+		v := strings.TrimSuffix(t.Value, nl)
+		for v != "" {
+			c := pos < len(changed) && changed[pos]
+			end := 1
+			for end < len(v) && (pos+end < len(changed) && changed[pos+end]) == c {
+				end++
+			}
+			segs = append(segs, segment{text: v[:end], typ: t.Type, changed: c})
+			pos += end
+			v = v[end:]
+		}
+	}
+	return segs
+}
+
+// writeSyntax writes a line with its tokens in the terminal style, changed
+// lines on a red or green background and their changed spans brighter.
+// Segments with the same colors are written as one.
+func writeSyntax(w io.Writer, segs []segment, op dmp.Operation) {
+	style := styles.Get(terminalStyle)
+	var last, text string
+	flush := func() {
+		if last == "" {
+			io.WriteString(w, text)
+		} else if text != "" {
+			fmt.Fprintf(w, "\x1b[%sm%s\x1b[0m", last, text)
+		}
+	}
+	for _, seg := range segs {
+		var codes []string
+		switch {
+		case op == dmp.DiffDelete && seg.changed:
+			codes = append(codes, delSpanBackground)
+		case op == dmp.DiffDelete:
+			codes = append(codes, delBackground)
+		case op == dmp.DiffInsert && seg.changed:
+			codes = append(codes, insSpanBackground)
+		case op == dmp.DiffInsert:
+			codes = append(codes, insBackground)
+		}
+		e := style.Get(seg.typ)
+		if e.Colour.IsSet() {
+			codes = append(codes, fmt.Sprintf("38;5;%d", ansi256(e.Colour)))
+		}
+		if e.Bold == chroma.Yes {
+			codes = append(codes, "1")
+		}
+		if e.Italic == chroma.Yes {
+			codes = append(codes, "3")
+		}
+		if c := strings.Join(codes, ";"); c != last {
+			flush()
+			last, text = c, ""
+		}
+		text += seg.text
+	}
+	flush()
+}
+
+// ansi256 maps c to the closest color of the 6x6x6 cube of 256 color
+// terminals
+func ansi256(c chroma.Colour) int {
+	q := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return 16 + 36*q(c.Red()) + 6*q(c.Green()) + q(c.Blue())
+}
+
+// htmlSyntax renders a line as spans colored in the HTML style, with the
+// changed spans in <del> or <ins>
+func htmlSyntax(segs []segment, op dmp.Operation) string {
+	style := styles.Get(htmlStyle)
+	var buf bytes.Buffer
+	for _, seg := range segs {
+		text := html.EscapeString(seg.text)
+		if e := style.Get(seg.typ); e.Colour.IsSet() || e.Bold == chroma.Yes || e.Italic == chroma.Yes {
+			var css []string
+			if e.Colour.IsSet() {
+				css = append(css, "color: "+e.Colour.String())
+			}
+			if e.Bold == chroma.Yes {
+				css = append(css, "font-weight: bold")
+			}
+			if e.Italic == chroma.Yes {
+				css = append(css, "font-style: italic")
+			}
+			text = fmt.Sprintf("<span style=\"%s\">%s</span>", strings.Join(css, "; "), text)
+		}
+		if seg.changed {
+			tag := "del"
+			if op == dmp.DiffInsert {
+				tag = "ins"
+			}
+			text = fmt.Sprintf("<%s>%s</%s>", tag, text, tag)
+		}
+		buf.WriteString(text)
+	}
+	return buf.String()
+}
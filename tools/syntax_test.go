@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSyntax(t *testing.T) {
+	a := "package main\n\n/* a\ncomment */\nfunc f() int { return 1 }\n"
+	b := "package main\n\n/* a\ncomment */\nfunc f() int { return 2 }\n"
+
+	// without color the output is unchanged
+	plain := Diff(a, b, WithNoColor()).String()
+	assert.Equal(t, plain, Diff(a, b, WithNoColor(), WithSyntax("go")).String())
+
+	d := Diff(a, b, WithColor(ColorAlways), WithSyntax("go"))
+	out := d.String()
+	assert.Equal(t, plain, stripANSI(out))
+	assert.NotEqual(t, Diff(a, b, WithColor(ColorAlways)).String(), out)
+
+	// keywords use the terminal style, the comment is colored as one
+	// across lines, and changed lines get a background
+	assert.Contains(t, out, "\x1b[38;5;")
+	assert.Contains(t, out, "mcomment */\x1b[0m\n")
+	assert.Contains(t, out, delBackground)
+	assert.Contains(t, out, insSpanBackground+";38;5;")
+
+	// the language is detected from the labels
+	detected := Diff(a, b, WithColor(ColorAlways), WithSyntax(""), WithLabels("a/main.go", "b/main.go")).String()
+	assert.Equal(t, strings.Replace(out, "--- a\n+++ b", "--- a/main.go\n+++ b/main.go", 1), detected)
+
+	// unknown languages aren't highlighted
+	assert.Equal(t, Diff(a, b, WithColor(ColorAlways)).String(), Diff(a, b, WithColor(ColorAlways), WithSyntax("nosuchlang")).String())
+}
+
+func TestWithSyntaxHTML(t *testing.T) {
+	a := "{\n  \"a\": 1,\n  \"b\": true\n}\n"
+	b := "{\n  \"a\": 2,\n  \"b\": true\n}\n"
+	for _, layout := range []Layout{Inline, SideBySide} {
+		var buf bytes.Buffer
+		assert.NoError(t, Diff(a, b, WithSyntax("json")).HTML(&buf, layout))
+		page := buf.String()
+		assert.Contains(t, page, "<span style=\"color: #")
+		assert.Contains(t, page, "<ins><span style=")
+		assert.Contains(t, page, "&#34;b&#34;")
+	}
+}
+
+func TestAnsi256(t *testing.T) {
+	assert.Equal(t, 16, ansi256(chroma.MustParseColour("#000000")))
+	assert.Equal(t, 231, ansi256(chroma.MustParseColour("#ffffff")))
+	assert.Equal(t, 196, ansi256(chroma.MustParseColour("#ff0000")))
+}
@@ -0,0 +1,243 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+var regExTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+//DiffTOML compares a and b as TOML documents by their tables and keys, so
+//key and table order, comments and formatting don't cause differences.
+//Paths are dotted keys like server.port or servers[0].name, with keys that
+//aren't bare quoted. Integers and floats compare by value, dates and times
+//by their TOML text. a and b can be strings, []byte or io.Readers, other
+//values are encoded as TOML first. If either side is not valid TOML it
+//falls back to a text Diff and Error returns the parse error.
+func DiffTOML(a, b interface{}, opts ...Option) Differ {
+	textA, errA := tomlText(a)
+	textB, errB := tomlText(b)
+	if errA != nil || errB != nil {
+		return &fallbackDiff{Diff(textA, textB, opts...), firstError(errA, errB)}
+	}
+
+	var ta, tb map[string]interface{}
+	_, errA = toml.Decode(textA, &ta)
+	_, errB = toml.Decode(textB, &tb)
+	if errA != nil || errB != nil {
+		return &fallbackDiff{Diff(textA, textB, opts...), firstError(errA, errB)}
+	}
+
+	o := newOptions(opts)
+	w := &tomlWalker{opts: o}
+	w.walk("", ta, tb)
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+func tomlText(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	case io.Reader:
+		bs, err := ioutil.ReadAll(s)
+		return string(bs), err
+	default:
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(v)
+		return buf.String(), err
+	}
+}
+
+type tomlWalker struct {
+	opts    *options
+	changes []change
+}
+
+func (w *tomlWalker) walk(path string, a, b interface{}) {
+	a, b = tomlArray(a), tomlArray(b)
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			w.changed(path, a, b)
+			return
+		}
+		keys := make([]string, 0, len(va)+len(vb))
+		for k := range va {
+			keys = append(keys, k)
+		}
+		for k := range vb {
+			if _, ok := va[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kp := tomlKey(path, k)
+			ea, okA := va[k]
+			eb, okB := vb[k]
+			switch {
+			case !okB:
+				w.add(change{typ: removed, path: kp, exp: formatTOML(ea), key: true})
+			case !okA:
+				if !w.opts.ignoreExtraKeys {
+					w.add(change{typ: added, path: kp, act: formatTOML(eb), key: true})
+				}
+			default:
+				w.walk(kp, ea, eb)
+			}
+		}
+
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			w.changed(path, a, b)
+			return
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			ip := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(vb):
+				w.add(change{typ: removed, path: ip, exp: formatTOML(va[i])})
+			case i >= len(va):
+				w.add(change{typ: added, path: ip, act: formatTOML(vb[i])})
+			default:
+				w.walk(ip, va[i], vb[i])
+			}
+		}
+
+	case int64, float64:
+		fa, _ := tomlFloat(a)
+		fb, ok := tomlFloat(b)
+		if !ok {
+			w.changed(path, a, b)
+			return
+		}
+		ia, intA := a.(int64)
+		ib, intB := b.(int64)
+		if intA && intB && ia == ib {
+			return
+		}
+		if fa != fb && !w.opts.withinTolerance(fa, fb) {
+			w.changed(path, a, b)
+		}
+
+	default:
+		// strings and bools compare directly, dates and times by their text
+		if formatTOML(a) != formatTOML(b) {
+			w.changed(path, a, b)
+		}
+	}
+}
+
+func (w *tomlWalker) add(c change) {
+	if c.path == "" {
+		c.path = "(root)"
+	}
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func (w *tomlWalker) changed(path string, a, b interface{}) {
+	w.add(change{typ: changed, path: path, exp: formatTOML(a), act: formatTOML(b)})
+}
+
+// tomlArray returns arrays of tables as []interface{} like other arrays
+func tomlArray(v interface{}) interface{} {
+	tables, ok := v.([]map[string]interface{})
+	if !ok {
+		return v
+	}
+	vals := make([]interface{}, len(tables))
+	for i, t := range tables {
+		vals[i] = t
+	}
+	return vals
+}
+
+func tomlFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func tomlKey(path, key string) string {
+	if !regExTOMLKey.MatchString(key) {
+		key = strconv.Quote(key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// formatTOML renders a value as TOML, tables inline with sorted keys
+func formatTOML(v interface{}) string {
+	switch t := tomlArray(v).(type) {
+	case string:
+		return strconv.Quote(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		switch {
+		case math.IsInf(t, 1):
+			return "inf"
+		case math.IsInf(t, -1):
+			return "-inf"
+		case math.IsNaN(t):
+			return "nan"
+		}
+		s := strconv.FormatFloat(t, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		// the decoder marks local dates and times with these zones
+		switch t.Location().String() {
+		case "date-local":
+			return t.Format("2006-01-02")
+		case "time-local":
+			return t.Format("15:04:05.999999999")
+		case "datetime-local":
+			return t.Format("2006-01-02T15:04:05.999999999")
+		}
+		return t.Format(time.RFC3339Nano)
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = formatTOML(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = tomlKey("", k) + " = " + formatTOML(t[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprintf("%v", v)
+}
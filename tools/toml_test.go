@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTOML(t *testing.T) {
+	a := `# app config
+title = "app"
+started = 2024-01-02T03:04:05Z
+
+[server]
+host = "localhost"
+port = 8080
+timeout = 1.5
+
+[[servers]]
+name = "a"
+
+[[servers]]
+name = "b"
+`
+	b := `title = "app"
+started = 2024-01-02T03:04:05Z
+
+[[servers]]
+name = "a"
+
+[servers.labels]
+"zone name" = "eu"
+
+[server]
+timeout = 1.5
+port = 8080
+host = "localhost"
+`
+	d := DiffTOML(a, a)
+	assert.NoError(t, d.Error())
+	assert.True(t, d.Equal())
+
+	d = DiffTOML(a, strings.Replace(b, "port = 8080", "port = 8080.0", 1))
+	assert.NoError(t, d.Error())
+	assert.False(t, d.Equal())
+	assert.Equal(t, "servers[0].labels:\n+{\"zone name\" = \"eu\"}\nservers[1]:\n-{name = \"b\"}\n", d.String())
+
+	d = DiffTOML("a = 1\nwhen = 07:00:00\n", "a = 2\nwhen = 07:30:00\n", WithTolerance(1, 0))
+	assert.Equal(t, "when:\n-07:00:00\n+07:30:00\n", d.String())
+
+	d = DiffTOML(`[x]`+"\na = [1, 2]\n", "x = 1\n", IgnorePath("x"))
+	assert.True(t, d.Equal())
+	assert.Equal(t, `{a = [1, 2]}`, formatTOML(map[string]interface{}{"a": []interface{}{int64(1), int64(2)}}))
+
+	d = DiffTOML(struct{ Port int }{80}, map[string]interface{}{"Port": 81})
+	assert.Equal(t, "Port:\n-80\n+81\n", d.String())
+
+	d = DiffTOML("a = ", "a = 1\n")
+	assert.Error(t, d.Error())
+	assert.Contains(t, d.String(), "+a = 1")
+}
@@ -0,0 +1,490 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+type changeType int
+
+const (
+	changed changeType = iota
+	removed
+	added
+
+	// moved is a value found at another path, exp holds the old path
+	moved
+)
+
+// a single difference found while walking two values
+type change struct {
+	typ     changeType
+	path    string
+	exp     string
+	act     string
+	ignored bool
+
+	// key is set for map keys and object members only on one side
+	key bool
+}
+
+//DiffValues creates a Differ that walks structs, maps, slices and arrays
+//with reflection and reports each differing field by path, expected and
+//actual value instead of diffing the flattened text
+func DiffValues(a, b interface{}, opts ...Option) Differ {
+	va := indirect(reflect.ValueOf(a))
+	vb := indirect(reflect.ValueOf(b))
+
+	o := newOptions(opts)
+	w := &valueWalker{
+		opts:    o,
+		visited: make(map[visit]bool),
+	}
+
+	root := va
+	if !root.IsValid() {
+		root = vb
+	}
+	if root.IsValid() {
+		w.root = root.Type().String()
+		if name := root.Type().Name(); name != "" {
+			w.root = name
+		}
+	}
+
+	if va.IsValid() && vb.IsValid() && va.Type() == vb.Type() && va.Type().Name() != "" {
+		w.walk(va.Type().Name(), va, vb)
+	} else {
+		w.walk("", va, vb)
+	}
+
+	return &valueDiff{changes: w.changes, opts: o, root: w.root}
+}
+
+// indirect follows pointers the same way Value does, but on reflect values
+func indirect(v reflect.Value) reflect.Value {
+	if v.IsValid() && v.Kind() == reflect.Ptr && !v.IsNil() {
+		return v.Elem()
+	}
+	return v
+}
+
+// visit is a pair of references already compared, used for cycle detection
+type visit struct {
+	a   uintptr
+	b   uintptr
+	typ reflect.Type
+}
+
+type valueWalker struct {
+	opts    *options
+	root    string
+	visited map[visit]bool
+	changes []change
+}
+
+func (w *valueWalker) walk(path string, a, b reflect.Value) {
+	w.compare(path, a, b, 0)
+}
+
+// compare walks a and b after applying the transformers from index next on
+func (w *valueWalker) compare(path string, a, b reflect.Value, next int) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			w.changed(path, a, b)
+		}
+		return
+	}
+
+	if a.Type() != b.Type() {
+		w.add(change{
+			typ:  changed,
+			path: path,
+			exp:  fmt.Sprintf("(%s) %s", a.Type(), formatValue(a)),
+			act:  fmt.Sprintf("(%s) %s", b.Type(), formatValue(b)),
+		})
+		return
+	}
+
+	if a.CanInterface() && b.CanInterface() {
+		for i := next; i < len(w.opts.transformers); i++ {
+			if f := w.opts.transformers[i]; f.applies(a.Type()) {
+				ta := f.fn.Call([]reflect.Value{a})[0]
+				tb := f.fn.Call([]reflect.Value{b})[0]
+				w.compare(path, ta, tb, i+1)
+				return
+			}
+		}
+		for _, f := range w.opts.comparers {
+			if f.applies(a.Type()) {
+				if !f.fn.Call([]reflect.Value{a, b})[0].Bool() {
+					w.changed(path, a, b)
+				}
+				return
+			}
+		}
+	}
+
+	if w.seen(a, b) {
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				w.changed(path, a, b)
+			}
+			return
+		}
+		w.walk(path, a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && w.opts.ignoreUnexported {
+				continue
+			}
+			w.walk(path+"."+f.Name, a.Field(i), b.Field(i))
+		}
+
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			w.changed(path, a, b)
+			return
+		}
+		for _, k := range mapKeys(a, b) {
+			kp := fmt.Sprintf("%s[%s]", path, formatValue(k))
+			ea := a.MapIndex(k)
+			eb := b.MapIndex(k)
+			switch {
+			case !eb.IsValid():
+				w.add(change{typ: removed, path: kp, exp: formatValue(ea), key: true})
+			case !ea.IsValid():
+				if !w.opts.ignoreExtraKeys {
+					w.add(change{typ: added, path: kp, act: formatValue(eb), key: true})
+				}
+			default:
+				w.walk(kp, ea, eb)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() {
+			w.changed(path, a, b)
+			return
+		}
+		if w.opts.unordered() {
+			w.walkList(path, a, b)
+			return
+		}
+		n := a.Len()
+		if b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			ip := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= b.Len():
+				w.removed(ip, a.Index(i))
+			case i >= a.Len():
+				w.added(ip, b.Index(i))
+			default:
+				w.walk(ip, a.Index(i), b.Index(i))
+			}
+		}
+
+	case reflect.Func:
+		// like reflect.DeepEqual, funcs are only equal if both are nil
+		if !a.IsNil() || !b.IsNil() {
+			w.changed(path, a, b)
+		}
+
+	default:
+		if !scalarEqual(a, b) && !w.withinTolerance(a, b) {
+			w.changed(path, a, b)
+		}
+	}
+}
+
+// seen records references so cyclic structures terminate
+func (w *valueWalker) seen(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+	default:
+		return false
+	}
+	if a.IsNil() || b.IsNil() {
+		return false
+	}
+
+	v := visit{a.Pointer(), b.Pointer(), a.Type()}
+	if w.visited[v] {
+		return true
+	}
+	w.visited[v] = true
+	return false
+}
+
+func (w *valueWalker) add(c change) {
+	if c.path == "" {
+		c.path = w.root
+	}
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func (w *valueWalker) changed(path string, a, b reflect.Value) {
+	w.add(change{typ: changed, path: path, exp: formatValue(a), act: formatValue(b)})
+}
+
+func (w *valueWalker) removed(path string, a reflect.Value) {
+	w.add(change{typ: removed, path: path, exp: formatValue(a)})
+}
+
+func (w *valueWalker) added(path string, b reflect.Value) {
+	w.add(change{typ: added, path: path, act: formatValue(b)})
+}
+
+// mapKeys returns the union of keys in a and b in a stable order
+func mapKeys(a, b reflect.Value) []reflect.Value {
+	keys := a.MapKeys()
+	for _, k := range b.MapKeys() {
+		if !a.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return formatValue(keys[i]) < formatValue(keys[j])
+	})
+	return keys
+}
+
+func (w *valueWalker) withinTolerance(a, b reflect.Value) bool {
+	fa, okA := floatValue(a)
+	fb, okB := floatValue(b)
+	return okA && okB && w.opts.withinTolerance(fa, fb)
+}
+
+// floatValue converts a value of a numeric kind to float64
+func floatValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// scalarEqual compares values of the remaining kinds without calling
+// Interface so unexported fields can be compared
+func scalarEqual(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	default:
+		panic(fmt.Sprintf("unknown kind %v", a.Kind()))
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+type valueDiff struct {
+	changes []change
+	opts    *options
+
+	// root is the path of changes at the root, set by DiffValues
+	root string
+}
+
+func (d *valueDiff) Print() {
+	printDiff(d.opts, d.diff)
+}
+
+func (d *valueDiff) String() string {
+	var buf bytes.Buffer
+	d.diff(&buf, d.opts.palette(nil))
+	return buf.String()
+}
+
+func (d *valueDiff) WriteTo(w io.Writer) (int64, error) {
+	p := d.opts.palette(w)
+	return writeTo(w, func(w io.Writer) { d.diff(w, p) })
+}
+
+func (d *valueDiff) Markdown(w io.Writer, style MarkdownStyle) error {
+	return writeMarkdown(w, d.diff, style)
+}
+
+func (d *valueDiff) HTML(w io.Writer, layout Layout) error {
+	return writeHTML(w, func(w io.Writer) {
+		for _, c := range d.changes {
+			exp, act := html.EscapeString(c.exp), html.EscapeString(c.act)
+			path := html.EscapeString(c.path)
+			if c.ignored {
+				path += " (ignored)"
+			}
+			switch {
+			case c.typ == moved && layout == SideBySide:
+				fmt.Fprintf(w, "<tr class=\"path\"><td colspan=\"2\">%s</td></tr>\n", path)
+				fmt.Fprintf(w, "<tr class=\"mov\"><td colspan=\"2\">moved from %s</td></tr>\n", exp)
+			case c.typ == moved:
+				fmt.Fprintf(w, "<tr class=\"path\"><td>%s</td></tr>\n", path)
+				fmt.Fprintf(w, "<tr class=\"mov\"><td>~moved from %s</td></tr>\n", exp)
+			case layout == SideBySide:
+				fmt.Fprintf(w, "<tr class=\"path\"><td colspan=\"2\">%s</td></tr>\n", path)
+				left, right := "<td></td>", "<td></td>"
+				if c.typ != added {
+					left = fmt.Sprintf("<td class=\"del\">%s</td>", exp)
+				}
+				if c.typ != removed {
+					right = fmt.Sprintf("<td class=\"ins\">%s</td>", act)
+				}
+				fmt.Fprintf(w, "<tr>%s%s</tr>\n", left, right)
+			default:
+				fmt.Fprintf(w, "<tr class=\"path\"><td>%s</td></tr>\n", path)
+				if c.typ != added {
+					fmt.Fprintf(w, "<tr class=\"del\"><td>-%s</td></tr>\n", exp)
+				}
+				if c.typ != removed {
+					fmt.Fprintf(w, "<tr class=\"ins\"><td>+%s</td></tr>\n", act)
+				}
+			}
+		}
+	})
+}
+
+func (d *valueDiff) Hunks() []Hunk {
+	return changeHunks(d.changes)
+}
+
+func (d *valueDiff) MarshalJSON() ([]byte, error) {
+	return marshalHunks(d.Hunks())
+}
+
+func (d *valueDiff) Equal() bool {
+	return d.Stats().changed() == 0
+}
+
+func (d *valueDiff) Error() error {
+	return nil
+}
+
+func (d *valueDiff) Stats() Stats {
+	return changeStats(d.changes)
+}
+
+func (d *valueDiff) diff(w io.Writer, p *palette) {
+	if d.opts.keySummary {
+		d.keySummary(w, p)
+	}
+	lim := d.opts.limits()
+	for ci, c := range d.changes {
+		if !lim.hunk() || !lim.line() {
+			p.dim.Fprintln(w, summary(len(d.changes)-ci, "path", changedValueLines(d.changes[ci:])))
+			return
+		}
+		if c.ignored {
+			p.dim.Fprintf(w, "%s:\n", c.path)
+		} else {
+			fmt.Fprintf(w, "%s:\n", c.path)
+		}
+
+		lines := c.lines()
+		for i, l := range lines {
+			if !lim.line() {
+				n := len(lines) - i + changedValueLines(d.changes[ci+1:])
+				p.dim.Fprintln(w, summary(len(d.changes)-ci-1, "path", n))
+				return
+			}
+			switch {
+			case l.ignored:
+				p.dim.Fprintf(w, "%c%s\n", linePrefix(l.op), l.text)
+			case l.moved:
+				p.mov.Fprintf(w, "~%s\n", l.text)
+			case l.op == dmp.DiffDelete:
+				p.del.Fprintf(w, "-%s\n", l.text)
+			default:
+				p.ins.Fprintf(w, "+%s\n", l.text)
+			}
+		}
+	}
+}
+
+// keySummary lists the paths of keys missing from b and only in b
+func (d *valueDiff) keySummary(w io.Writer, p *palette) {
+	var missing, extra []string
+	for _, c := range d.changes {
+		switch {
+		case !c.key || c.ignored:
+		case c.typ == removed:
+			missing = append(missing, c.path)
+		default:
+			extra = append(extra, c.path)
+		}
+	}
+	if len(missing) > 0 {
+		p.del.Fprintf(w, "missing keys: %s\n", strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		p.ins.Fprintf(w, "extra keys: %s\n", strings.Join(extra, ", "))
+	}
+}
+
+// lines splits the expected and actual values into rendered lines
+func (c change) lines() []lineOp {
+	if c.typ == moved {
+		return []lineOp{{op: dmp.DiffInsert, text: "moved from " + c.exp, ignored: c.ignored, moved: true}}
+	}
+
+	var lines []lineOp
+	if c.typ != added {
+		for _, line := range strings.Split(c.exp, nl) {
+			lines = append(lines, lineOp{op: dmp.DiffDelete, text: line, ignored: c.ignored})
+		}
+	}
+	if c.typ != removed {
+		for _, line := range strings.Split(c.act, nl) {
+			lines = append(lines, lineOp{op: dmp.DiffInsert, text: line, ignored: c.ignored})
+		}
+	}
+	return lines
+}
+
+func changedValueLines(changes []change) int {
+	n := 0
+	for _, c := range changes {
+		if !c.ignored {
+			n += len(c.lines())
+		}
+	}
+	return n
+}
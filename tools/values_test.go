@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct {
+	Name string
+	Next *node
+	tag  string
+}
+
+type reading struct {
+	At    time.Time
+	Value float64
+	Tags  []string
+}
+
+var (
+	approx = WithComparer(func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	})
+	seconds = WithComparer(func(a, b time.Time) bool {
+		return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+	})
+	sorted = WithTransformer(func(s []string) []string {
+		s = append([]string(nil), s...)
+		sort.Strings(s)
+		return s
+	})
+)
+
+func TestDiffValues(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cycleA := &node{Name: "a"}
+	cycleA.Next = cycleA
+	cycleB := &node{Name: "b"}
+	cycleB.Next = cycleB
+
+	tests := []struct {
+		name string
+		a, b interface{}
+		opts []Option
+		exp  string
+	}{
+		{
+			name: "equal",
+			a:    internal.StructsA[0],
+			b:    &internal.StructsA[0],
+			exp:  "",
+		},
+		{
+			name: "struct fields",
+			a:    &internal.StructsA[0],
+			b:    internal.StructsA[1],
+			exp: "TestStruct.a:\n-\"foo\"\n+\"bar\"\n" +
+				"TestStruct.c:\n-false\n+true\n",
+		},
+		{
+			name: "unexported skipped",
+			a:    internal.StructsA[0],
+			b:    internal.StructsA[1],
+			opts: []Option{IgnoreUnexported()},
+			exp:  "",
+		},
+		{
+			name: "maps",
+			a:    map[string]int{"a": 1, "b": 2, "c": 3},
+			b:    map[string]int{"a": 1, "b": 5, "d": 4},
+			exp: "[\"b\"]:\n-2\n+5\n" +
+				"[\"c\"]:\n-3\n" +
+				"[\"d\"]:\n+4\n",
+		},
+		{
+			name: "slices",
+			a:    []int{1, 2, 3},
+			b:    []int{1, 4},
+			exp:  "[1]:\n-2\n+4\n[2]:\n-3\n",
+		},
+		{
+			name: "types",
+			a:    []interface{}{5},
+			b:    []interface{}{"5"},
+			exp:  "[0]:\n-(int) 5\n+(string) \"5\"\n",
+		},
+		{
+			name: "cycles",
+			a:    cycleA,
+			b:    cycleB,
+			exp:  "node.Name:\n-\"a\"\n+\"b\"\nnode.Next.Name:\n-\"a\"\n+\"b\"\n",
+		},
+		{
+			name: "nil",
+			a:    nil,
+			b:    []int(nil),
+			exp:  "[]int:\n-nil\n+[]int(nil)\n",
+		},
+		{
+			name: "tolerance",
+			a:    reading{Value: 0.3, Tags: []string{}},
+			b:    reading{Value: 0.1 + 0.2, Tags: []string{}},
+			opts: []Option{WithTolerance(1e-12, 0)},
+			exp:  "",
+		},
+		{
+			name: "out of tolerance only",
+			a:    []interface{}{1.0, 2, uint(3)},
+			b:    []interface{}{1.0000001, 4, uint(3)},
+			opts: []Option{WithTolerance(0, 0.01)},
+			exp:  "[1]:\n-2\n+4\n",
+		},
+		{
+			name: "comparers and transformers",
+			a:    reading{At: at, Value: 0.3, Tags: []string{"b", "a"}},
+			b:    reading{At: at.Add(time.Millisecond), Value: 0.1 + 0.2, Tags: []string{"a", "b"}},
+			opts: []Option{approx, seconds, sorted},
+			exp:  "",
+		},
+		{
+			name: "out of tolerance",
+			a:    reading{At: at, Value: 0.3, Tags: []string{"b", "a"}},
+			b:    reading{At: at, Value: 0.31, Tags: []string{"a", "c"}},
+			opts: []Option{approx, sorted},
+			exp:  "reading.Value:\n-0.3\n+0.31\nreading.Tags[1]:\n-\"b\"\n+\"c\"\n",
+		},
+		{
+			name: "interface comparer",
+			a:    []fmt.Stringer{time.Second, time.Minute},
+			b:    []fmt.Stringer{time.Duration(1e9), time.Hour},
+			opts: []Option{WithComparer(func(a, b fmt.Stringer) bool { return a.String() == b.String() })},
+			exp:  "[1]:\n-60000000000\n+3600000000000\n",
+		},
+		{
+			name: "transformer to another type",
+			a:    map[string]int{"a": 1},
+			b:    map[string]int{"a": 2},
+			opts: []Option{WithTransformer(func(i int) string { return fmt.Sprint(i % 2) })},
+			exp:  "[\"a\"]:\n-\"1\"\n+\"0\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		d := DiffValues(test.a, test.b, test.opts...).String()
+		d = regExColor.ReplaceAllString(d, "")
+		assert.Equal(t, test.exp, d, test.name)
+	}
+}
+
+func TestValueFuncPanics(t *testing.T) {
+	assert.Panics(t, func() { WithComparer(func(a int, b string) bool { return true }) })
+	assert.Panics(t, func() { WithComparer(func(a, b int) int { return 0 }) })
+	assert.Panics(t, func() { WithTransformer(func(a, b int) int { return 0 }) })
+	assert.Panics(t, func() { WithTransformer(42) })
+}
+
+func TestDiffValuesKeys(t *testing.T) {
+	a := map[string]int{"a": 1, "b": 2, "c": 3}
+	b := map[string]int{"a": 1, "c": 4, "d": 5}
+
+	d := DiffValues(a, b, WithKeySummary(), WithNoColor())
+	assert.Equal(t, "missing keys: [\"b\"]\nextra keys: [\"d\"]\n[\"b\"]:\n-2\n[\"c\"]:\n-3\n+4\n[\"d\"]:\n+5\n", d.String())
+
+	d = DiffValues(a, b, IgnoreExtraKeys(), WithNoColor())
+	assert.Equal(t, "[\"b\"]:\n-2\n[\"c\"]:\n-3\n+4\n", d.String())
+	assert.True(t, DiffValues(map[string]int{"a": 1}, b, IgnoreExtraKeys()).Equal())
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// showWhitespace makes the whitespace and non-printing characters of line,
+// rendered with its color codes, visible: tabs as →, trailing spaces as ·
+// and other characters that don't print as Go escapes like \u200b
+func showWhitespace(line string) string {
+	text := strings.TrimSuffix(line, nl)
+	// the byte offset after the last printed character that isn't a space
+	end := 0
+	for i := 0; i < len(text); {
+		if loc := regExColor.FindStringIndex(text[i:]); loc != nil && loc[0] == 0 {
+			i += loc[1]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(text[i:])
+		i += n
+		if r != ' ' {
+			end = i
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if loc := regExColor.FindStringIndex(text[i:]); loc != nil && loc[0] == 0 {
+			b.WriteString(text[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\t':
+			b.WriteString("→")
+		case r == ' ' && i >= end:
+			b.WriteString("·")
+		case r == utf8.RuneError && n == 1:
+			b.WriteString(`\x` + strconv.FormatInt(int64(text[i]), 16))
+		case !unicode.IsPrint(r):
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		default:
+			b.WriteString(text[i : i+n])
+		}
+		i += n
+	}
+	return b.String() + line[len(text):]
+}
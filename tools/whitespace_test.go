@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithShowWhitespace(t *testing.T) {
+	d := Diff("if x {\n\treturn 1\n}\n", "if x {\n    return 1  \n}\n", WithNoColor(), WithShowWhitespace())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n if x {\n-→return 1\n+    return 1··\n }\n", d.String())
+
+	d = Diff("a\nb c\n", "a\nb\u200bc\u00a0\n", WithNoColor(), WithShowWhitespace())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b c\n+b\\u200bc\\u00a0\n", d.String())
+}
+
+func TestShowWhitespace(t *testing.T) {
+	assert.Equal(t, "\x1b[31m-a→b\x1b[0m··\x1b[0m\n", showWhitespace("\x1b[31m-a\tb\x1b[0m  \x1b[0m\n"))
+	assert.Equal(t, `+\x01\r`, showWhitespace("+\x01\r"))
+	assert.Equal(t, "·", showWhitespace(" "))
+}
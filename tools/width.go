@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	isatty "github.com/mattn/go-isatty"
+	"golang.org/x/term"
+)
+
+// fitMode selects what happens to lines wider than the output
+type fitMode int
+
+const (
+	fitNone fitMode = iota
+	fitTruncate
+	fitWrap
+)
+
+// defaultWidth is used for WithTruncateLines(0) and WithWrapLines(0) when
+// stdout isn't a terminal
+const defaultWidth = 120
+
+// terminalWidth returns the columns of the terminal on stdout, ok is false
+// when stdout isn't a terminal
+var terminalWidth = func() (int, bool) {
+	fd := os.Stdout.Fd()
+	if !isatty.IsTerminal(fd) {
+		return 0, false
+	}
+	w, _, err := term.GetSize(int(fd))
+	return w, err == nil
+}
+
+// lineFit cuts or wraps the lines of a line diff to width columns and
+// counts the lines it cut
+type lineFit struct {
+	width int
+	wrap  bool
+	cut   int
+}
+
+// lineFit returns nil when lines are shown in full
+func (o *options) lineFit() *lineFit {
+	if o.fit == fitNone || o.fullLines || os.Getenv("LOUPE_FULL_LINES") != "" {
+		return nil
+	}
+	width := o.fitWidth
+	if width <= 0 {
+		width = defaultWidth
+		if w, ok := terminalWidth(); ok {
+			width = w
+		}
+	}
+	return &lineFit{width: maxInt(width, 10), wrap: o.fit == fitWrap}
+}
+
+// write writes line, rendered with its color codes, to w. A line wider than
+// f.width is cut and ended with an ellipsis, or wrapped with its parts
+// after the first starting with indent.
+func (f *lineFit) write(w io.Writer, line, indent string) {
+	line = strings.TrimSuffix(line, nl)
+	if visibleWidth(line) <= f.width {
+		io.WriteString(w, line+nl)
+		return
+	}
+
+	var b strings.Builder
+	col, sgr := 0, ""
+	limit := f.width
+	if !f.wrap {
+		f.cut++
+		// room for the ellipsis
+		limit--
+	}
+	for i := 0; i < len(line); {
+		if loc := regExColor.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+			seq := line[i : i+loc[1]]
+			b.WriteString(seq)
+			if seq == "\x1b[0m" || seq == "\x1b[m" {
+				sgr = ""
+			} else {
+				sgr += seq
+			}
+			i += loc[1]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(line[i:])
+		rw := runeWidth(r, col)
+		if col+rw > limit && col > 0 {
+			if sgr != "" {
+				b.WriteString("\x1b[0m")
+			}
+			if !f.wrap {
+				b.WriteString(sgr + "…")
+				if sgr != "" {
+					b.WriteString("\x1b[0m")
+				}
+				break
+			}
+			b.WriteString(nl + sgr + indent)
+			col = visibleWidth(indent)
+			rw = runeWidth(r, col)
+		}
+		b.WriteString(line[i : i+n])
+		col += rw
+		i += n
+	}
+	io.WriteString(w, b.String()+nl)
+}
+
+// note tells how to see the lines that were cut
+func (f *lineFit) note(w io.Writer, p *palette) {
+	if f.cut == 0 {
+		return
+	}
+	p.dim.Fprintln(w, fmt.Sprintf("%s cut at %d columns, use WithFullLines() or LOUPE_FULL_LINES=1 to show them in full", plural(f.cut, "line"), f.width))
+}
+
+// visibleWidth returns the columns s takes in a terminal, without color
+// codes and with tabs stopping every 8 columns
+func visibleWidth(s string) int {
+	col := 0
+	for _, r := range regExColor.ReplaceAllString(s, "") {
+		col += runeWidth(r, col)
+	}
+	return col
+}
+
+func runeWidth(r rune, col int) int {
+	if r == '\t' {
+		return 8 - col%8
+	}
+	return 1
+}
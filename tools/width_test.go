@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTruncateLines(t *testing.T) {
+	long := strings.Repeat("x", 30)
+	a := "short\n" + long + "1\n"
+	b := "short\n" + long + "2\n"
+
+	d := Diff(a, b, WithMode(LineMode), WithNoColor(), WithTruncateLines(20))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n short\n-xxxxxxxxxxxxxxxxxx…\n+xxxxxxxxxxxxxxxxxx…\n"+
+		"2 lines cut at 20 columns, use WithFullLines() or LOUPE_FULL_LINES=1 to show them in full\n", d.String())
+
+	d = Diff(a, b, WithMode(LineMode), WithNoColor(), WithTruncateLines(20), WithFullLines())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n short\n-"+long+"1\n+"+long+"2\n", d.String())
+
+	os.Setenv("LOUPE_FULL_LINES", "1")
+	defer os.Unsetenv("LOUPE_FULL_LINES")
+	d = Diff(a, b, WithMode(LineMode), WithNoColor(), WithTruncateLines(20))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n short\n-"+long+"1\n+"+long+"2\n", d.String())
+}
+
+func TestWithWrapLines(t *testing.T) {
+	a := "abcdefghijklmnopqrstuvwxyz\n"
+	b := "abcdefghijklmnopqrstuvwxyZ\n"
+	d := Diff(a, b, WithMode(LineMode), WithNoColor(), WithWrapLines(10), WithLineNumbers())
+	assert.Equal(t, "--- a\n+++ b\n@@ -1 +1 @@\n"+
+		"1   | -abc\n      -def\n      -ghi\n      -jkl\n      -mno\n      -pqr\n      -stu\n      -vwx\n      -yz\n"+
+		"  1 | +abc\n      +def\n      +ghi\n      +jkl\n      +mno\n      +pqr\n      +stu\n      +vwx\n      +yZ\n", d.String())
+
+	orig := terminalWidth
+	terminalWidth = func() (int, bool) { return 12, true }
+	defer func() { terminalWidth = orig }()
+	d = Diff(a, b, WithMode(LineMode), WithNoColor(), WithWrapLines(0))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1 +1 @@\n-abcdefghijk\n-lmnopqrstuv\n-wxyz\n+abcdefghijk\n+lmnopqrstuv\n+wxyZ\n", d.String())
+}
+
+func TestFitLine(t *testing.T) {
+	f := &lineFit{width: 5}
+	var b strings.Builder
+	f.write(&b, "\x1b[31m-abcdefg\x1b[0m\n", "-")
+	assert.Equal(t, "\x1b[31m-abc\x1b[0m\x1b[31m…\x1b[0m\n", b.String())
+	assert.Equal(t, 1, f.cut)
+
+	f = &lineFit{width: 5, wrap: true}
+	b.Reset()
+	f.write(&b, "\x1b[32m+abc\tde\x1b[0m\n", "+")
+	assert.Equal(t, "\x1b[32m+abc\x1b[0m\n\x1b[32m+\t\x1b[0m\n\x1b[32m+de\x1b[0m\n", b.String())
+	assert.Equal(t, 0, f.cut)
+	assert.Equal(t, 10, visibleWidth("\x1b[1mab\x1b[0m\tcd"))
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/prasek/loupe/pretty"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var regExYAMLKey = regexp.MustCompile(`^[A-Za-z0-9_\-/]+$`)
+
+//DiffYAML creates a Differ that parses a and b as YAML and compares the
+//resulting node trees, so key order, comments and formatting don't cause
+//differences. Changed values are rendered from their nodes, including any
+//comments attached to them. Multi document streams are compared document by
+//document. a and b can be strings, []byte or io.Readers, other values are
+//marshaled to YAML first. If either side is not valid YAML it falls back to a
+//text Diff and Error returns the parse error.
+func DiffYAML(a, b interface{}, opts ...Option) Differ {
+	textA, errA := yamlText(a)
+	textB, errB := yamlText(b)
+	if errA != nil || errB != nil {
+		return &fallbackDiff{Diff(textA, textB, opts...), firstError(errA, errB)}
+	}
+
+	docsA, errA := parseYAML(textA)
+	docsB, errB := parseYAML(textB)
+	if errA != nil || errB != nil {
+		return &fallbackDiff{Diff(textA, textB, opts...), firstError(errA, errB)}
+	}
+
+	o := newOptions(opts)
+	w := &yamlWalker{opts: o}
+	n := len(docsA)
+	if len(docsB) > n {
+		n = len(docsB)
+	}
+	for i := 0; i < n; i++ {
+		path := ""
+		if n > 1 {
+			path = fmt.Sprintf("doc[%d]", i)
+		}
+		switch {
+		case i >= len(docsB):
+			w.add(change{typ: removed, path: path, exp: formatYAML(docsA[i])})
+		case i >= len(docsA):
+			w.add(change{typ: added, path: path, act: formatYAML(docsB[i])})
+		default:
+			w.walk(path, docsA[i], docsB[i])
+		}
+	}
+
+	return &valueDiff{changes: w.changes, opts: o}
+}
+
+func yamlText(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	case io.Reader:
+		bs, err := ioutil.ReadAll(s)
+		return string(bs), err
+	default:
+		bs, err := yaml.Marshal(v)
+		return string(bs), err
+	}
+}
+
+func parseYAML(text string) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(text))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+type yamlWalker struct {
+	opts    *options
+	changes []change
+}
+
+func (w *yamlWalker) walk(path string, a, b *yaml.Node) {
+	a, b = resolveYAML(a), resolveYAML(b)
+
+	if a.Kind != b.Kind {
+		w.changed(path, a, b)
+		return
+	}
+
+	switch a.Kind {
+	case yaml.DocumentNode:
+		if len(a.Content) == 0 || len(b.Content) == 0 {
+			if len(a.Content) != len(b.Content) {
+				w.changed(path, a, b)
+			}
+			return
+		}
+		w.walk(path, a.Content[0], b.Content[0])
+
+	case yaml.MappingNode:
+		keysA, valsA := yamlMapping(a)
+		keysB, valsB := yamlMapping(b)
+
+		// keep document order: keys of a, then keys only in b
+		keys := keysA
+		for _, k := range keysB {
+			if _, ok := valsA[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			kp := yamlKey(path, k)
+			va, okA := valsA[k]
+			vb, okB := valsB[k]
+			switch {
+			case !okB:
+				w.add(change{typ: removed, path: kp, exp: formatYAML(va), key: true})
+			case !okA:
+				if !w.opts.ignoreExtraKeys {
+					w.add(change{typ: added, path: kp, act: formatYAML(vb), key: true})
+				}
+			default:
+				w.walk(kp, va, vb)
+			}
+		}
+
+	case yaml.SequenceNode:
+		if w.opts.detectMoves {
+			w.walkMoves(path, a.Content, b.Content)
+			return
+		}
+		n := len(a.Content)
+		if len(b.Content) > n {
+			n = len(b.Content)
+		}
+		for i := 0; i < n; i++ {
+			ip := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(b.Content):
+				w.add(change{typ: removed, path: ip, exp: formatYAML(a.Content[i])})
+			case i >= len(a.Content):
+				w.add(change{typ: added, path: ip, act: formatYAML(b.Content[i])})
+			default:
+				w.walk(ip, a.Content[i], b.Content[i])
+			}
+		}
+
+	default:
+		var va, vb interface{}
+		errA := a.Decode(&va)
+		errB := b.Decode(&vb)
+		if errA != nil || errB != nil || !reflect.DeepEqual(va, vb) {
+			w.changed(path, a, b)
+		}
+	}
+}
+
+// walkMoves aligns sequence elements by value so reordered elements are
+// reported as moved
+func (w *yamlWalker) walkMoves(path string, a, b []*yaml.Node) {
+	keys := func(nodes []*yaml.Node) []string {
+		ks := make([]string, len(nodes))
+		for i, n := range nodes {
+			var v interface{}
+			if err := resolveYAML(n).Decode(&v); err != nil {
+				ks[i] = formatYAML(n)
+				continue
+			}
+			ks[i] = pretty.Sprint(v)
+		}
+		return ks
+	}
+	index := func(i int) string {
+		return fmt.Sprintf("%s[%d]", path, i)
+	}
+
+	for _, s := range alignSeq(keys(a), keys(b), w.opts) {
+		switch {
+		case s.moved:
+			w.add(change{typ: moved, path: index(s.j), exp: index(s.i), act: formatYAML(b[s.j])})
+		case s.j < 0:
+			w.add(change{typ: removed, path: index(s.i), exp: formatYAML(a[s.i])})
+		case s.i < 0:
+			w.add(change{typ: added, path: index(s.j), act: formatYAML(b[s.j])})
+		default:
+			w.walk(index(s.j), a[s.i], b[s.j])
+		}
+	}
+}
+
+func (w *yamlWalker) add(c change) {
+	if c.path == "" {
+		c.path = "(root)"
+	}
+	c.ignored = w.opts.ignoredPath(c.path)
+	w.changes = append(w.changes, c)
+}
+
+func (w *yamlWalker) changed(path string, a, b *yaml.Node) {
+	w.add(change{typ: changed, path: path, exp: formatYAML(a), act: formatYAML(b)})
+}
+
+func resolveYAML(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// yamlMapping returns the keys of a mapping node in order and their values
+func yamlMapping(n *yaml.Node) ([]string, map[string]*yaml.Node) {
+	var keys []string
+	vals := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := resolveYAML(n.Content[i]).Value
+		if _, ok := vals[k]; !ok {
+			keys = append(keys, k)
+		}
+		vals[k] = n.Content[i+1]
+	}
+	return keys, vals
+}
+
+func yamlKey(path, key string) string {
+	if !regExYAMLKey.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// formatYAML renders a node back to YAML, keeping its comments
+func formatYAML(n *yaml.Node) string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return fmt.Sprintf("%v", n.Value)
+	}
+	enc.Close()
+	return strings.TrimSuffix(buf.String(), nl)
+}
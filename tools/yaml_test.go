@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		exp  string
+	}{
+		{
+			name: "equal",
+			a:    "# deployment\nkind: Deployment\nmetadata:\n  name: web\n  labels: {app: web, tier: front}\n",
+			b:    "metadata:\n  labels:\n    tier: front # tier\n    app: web\n  name: web\nkind: Deployment\n",
+			exp:  "",
+		},
+		{
+			name: "changes",
+			a:    "spec:\n  replicas: 2\n  containers:\n  - name: web\n    image: web:1.0\n",
+			b:    []byte("spec:\n  replicas: \"2\"\n  containers:\n  - name: web\n    image: web:1.1 # bumped\n  paused: true\n"),
+			exp: "spec.replicas:\n-2\n+\"2\"\n" +
+				"spec.containers[0].image:\n-web:1.0\n+web:1.1 # bumped\n" +
+				"spec.paused:\n+true\n",
+		},
+		{
+			name: "documents",
+			a:    "a: 1\n---\nb: 2\n",
+			b:    "a: 1\n---\nb: 3\n---\nc: 4\n",
+			exp:  "doc[1].b:\n-2\n+3\ndoc[2]:\n+c: 4\n",
+		},
+		{
+			name: "values",
+			a:    map[string]interface{}{"a.b": []int{1, 2}},
+			b:    "a.b: [1, 2, 3]\n",
+			exp:  "[\"a.b\"][2]:\n+3\n",
+		},
+	}
+
+	for _, test := range tests {
+		d := DiffYAML(test.a, test.b, WithNoColor()).String()
+		assert.Equal(t, test.exp, d, test.name)
+	}
+}
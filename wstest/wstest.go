@@ -0,0 +1,234 @@
+package wstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prasek/loupe/golden"
+	"github.com/prasek/loupe/tools"
+	"golang.org/x/net/websocket"
+)
+
+//TestingT defines what we require from *testing.T
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+//DefaultTimeout is how long a Conn waits for a message by default
+const DefaultTimeout = 5 * time.Second
+
+//Conn is a websocket client connection whose Expect methods receive
+//messages and compare them with what the test expects
+type Conn struct {
+	ws      *websocket.Conn
+	timeout time.Duration
+	opts    []tools.Option
+}
+
+//Dial connects to the websocket endpoint at rawurl and stops the test if
+//it can't. http and https URLs, like httptest.Server.URL, are dialed as ws
+//and wss. The connection is closed with t.Cleanup. opts configure the
+//Differ used for mismatches.
+func Dial(t TestingT, rawurl string, opts ...tools.Option) *Conn {
+	t.Helper()
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Errorf("Dial %s: %v", rawurl, err)
+		t.FailNow()
+		return nil
+	}
+	origin := *u
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme, origin.Scheme = "ws", "http"
+	case "https", "wss":
+		u.Scheme, origin.Scheme = "wss", "https"
+	}
+	origin.Path, origin.RawQuery = "", ""
+
+	ws, err := websocket.Dial(u.String(), "", origin.String())
+	if err != nil {
+		t.Errorf("Dial %s: %v", u, err)
+		t.FailNow()
+		return nil
+	}
+	t.Cleanup(func() {
+		ws.Close()
+	})
+	return &Conn{ws: ws, timeout: DefaultTimeout, opts: opts}
+}
+
+//SetTimeout sets how long Receive and the Expect methods wait for each
+//message, DefaultTimeout if it's not set
+func (c *Conn) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+//Close closes the connection
+func (c *Conn) Close() error {
+	return c.ws.Close()
+}
+
+//Send sends msg as a text message
+func (c *Conn) Send(t TestingT, msg string) bool {
+	t.Helper()
+	if err := websocket.Message.Send(c.ws, msg); err != nil {
+		t.Errorf("Send: %v", err)
+		return false
+	}
+	return true
+}
+
+//SendJSON sends v encoded as JSON, strings and []byte are sent as is
+func (c *Conn) SendJSON(t TestingT, v interface{}) bool {
+	t.Helper()
+	bs, err := jsonBytes(v)
+	if err != nil {
+		t.Errorf("SendJSON: %v", err)
+		return false
+	}
+	return c.Send(t, string(bs))
+}
+
+//Receive waits for the next message, it fails the test if none arrives
+//before the timeout
+func (c *Conn) Receive(t TestingT) (string, bool) {
+	t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(c.timeout))
+	var msg string
+	if err := websocket.Message.Receive(c.ws, &msg); err != nil {
+		if strings.Contains(err.Error(), "timeout") {
+			t.Errorf("Receive: no message within %s", c.timeout)
+		} else {
+			t.Errorf("Receive: %v", err)
+		}
+		return "", false
+	}
+	return msg, true
+}
+
+//Expect receives a message and verifies it's want, failing with a text
+//diff if not
+func (c *Conn) Expect(t TestingT, want string) bool {
+	t.Helper()
+	got, ok := c.Receive(t)
+	if !ok {
+		return false
+	}
+	d := tools.Diff(want, got, c.opts...)
+	if !d.Equal() {
+		t.Errorf("Message Not Equal (-want +got)\n%s", d)
+		return false
+	}
+	return true
+}
+
+//ExpectJSON receives a message and verifies it's semantically equal to
+//want, failing with a JSON diff if not. want can be a JSON string or
+//[]byte, any other value is marshaled with encoding/json.
+func (c *Conn) ExpectJSON(t TestingT, want interface{}) bool {
+	t.Helper()
+	bs, err := jsonBytes(want)
+	if err != nil {
+		t.Errorf("Invalid want JSON: %v", err)
+		return false
+	}
+	got, ok := c.Receive(t)
+	if !ok {
+		return false
+	}
+	d := tools.DiffJSON(bs, []byte(got), c.opts...)
+	if err := d.Error(); err != nil {
+		t.Errorf("Message JSON: %v", err)
+		return false
+	}
+	if !d.Equal() {
+		t.Errorf("Message JSON Not Equal (-want +got)\n%s", d)
+		return false
+	}
+	return true
+}
+
+//ExpectJSONSet receives len(want) messages and verifies they're equal to
+//the wanted JSON messages in any order. Messages without an equal wanted
+//message are compared with the unmatched ones in order and fail with a
+//JSON diff each.
+func (c *Conn) ExpectJSONSet(t TestingT, want ...interface{}) bool {
+	t.Helper()
+	wants := make([][]byte, len(want))
+	for i, w := range want {
+		bs, err := jsonBytes(w)
+		if err != nil {
+			t.Errorf("Invalid want JSON %d: %v", i, err)
+			return false
+		}
+		wants[i] = bs
+	}
+	gots := make([][]byte, len(want))
+	for i := range gots {
+		got, ok := c.Receive(t)
+		if !ok {
+			t.Errorf("Message set: expected %d messages, got %d", len(want), i)
+			return false
+		}
+		gots[i] = []byte(got)
+	}
+
+	matched := make([]bool, len(wants))
+	var unmatched []int
+	for j, got := range gots {
+		found := false
+		for i, w := range wants {
+			if !matched[i] && tools.DiffJSON(w, got, c.opts...).Equal() {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, j)
+		}
+	}
+
+	i := 0
+	for _, j := range unmatched {
+		for matched[i] {
+			i++
+		}
+		matched[i] = true
+		t.Errorf("Message set: message %d has no match (-want %d +got)\n%s", j, i, tools.DiffJSON(wants[i], gots[j], c.opts...))
+	}
+	return len(unmatched) == 0
+}
+
+//ExpectGolden receives a message and compares it with the golden file at
+//path, written with -update
+func (c *Conn) ExpectGolden(t TestingT, path string) bool {
+	t.Helper()
+	got, ok := c.Receive(t)
+	if !ok {
+		return false
+	}
+	return golden.Assert(t, got, path)
+}
+
+func jsonBytes(v interface{}) ([]byte, error) {
+	var bs []byte
+	switch s := v.(type) {
+	case string:
+		bs = []byte(s)
+	case []byte:
+		bs = s
+	default:
+		return json.Marshal(v)
+	}
+	if !json.Valid(bs) {
+		return nil, fmt.Errorf("%q", bs)
+	}
+	return bs, nil
+}
@@ -0,0 +1,78 @@
+package wstest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasek/loupe/tools"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// echo sends a hello message, then echoes every message and sends the
+// events of a "subscribe" message in reverse order
+func echo() *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, `{"event":"hello","version":1}`)
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			if msg == "subscribe" {
+				websocket.Message.Send(ws, `{"event":"b","n":2}`)
+				websocket.Message.Send(ws, `{"event":"a","n":1}`)
+				continue
+			}
+			websocket.Message.Send(ws, msg)
+		}
+	}))
+}
+
+type greeting struct {
+	Text string `json:"text"`
+}
+
+func TestConn(t *testing.T) {
+	srv := echo()
+	defer srv.Close()
+
+	c := Dial(t, srv.URL)
+	assert.True(t, c.ExpectGolden(t, "testdata/hello.golden"))
+	assert.True(t, c.Send(t, "ping"))
+	assert.True(t, c.Expect(t, "ping"))
+	assert.True(t, c.SendJSON(t, greeting{"hi"}))
+	assert.True(t, c.ExpectJSON(t, `{ "text": "hi" }`))
+	assert.True(t, c.Send(t, "subscribe"))
+	assert.True(t, c.ExpectJSONSet(t, `{"event":"a","n":1}`, map[string]interface{}{"event": "b", "n": 2}))
+}
+
+func TestConnFail(t *testing.T) {
+	srv := echo()
+	defer srv.Close()
+	c := Dial(t, srv.URL, tools.WithNoColor())
+	c.SetTimeout(100 * time.Millisecond)
+
+	m := tools.Mock()
+	assert.False(t, c.ExpectJSON(m, `{"event":"hello","version":2}`))
+	assert.Equal(t, "Message JSON Not Equal (-want +got)\n/version:\n-2\n+1\n", m.Results().Err)
+
+	m = tools.Mock()
+	c.Send(t, "subscribe")
+	assert.False(t, c.ExpectJSONSet(m, `{"event":"a","n":1}`, `{"event":"b","n":3}`))
+	assert.Equal(t, "Message set: message 0 has no match (-want 1 +got)\n/n:\n-3\n+2\n", m.Results().Err)
+
+	m = tools.Mock()
+	assert.False(t, c.Expect(m, "more"))
+	assert.Equal(t, "Receive: no message within 100ms", m.Results().Err)
+}
+
+func TestDialFail(t *testing.T) {
+	m := tools.Mock()
+	assert.Nil(t, Dial(m, "http://127.0.0.1:1/ws"))
+	res := m.Results()
+	assert.True(t, res.FailNow)
+	assert.True(t, strings.HasPrefix(res.Err, "Dial ws://127.0.0.1:1/ws:"), res.Err)
+}